@@ -1,113 +1,164 @@
 package main
 
 import (
-	"goapi/internal/config"
-	"goapi/internal/handlers"
-	"goapi/internal/middleware"
-	"goapi/internal/models"
-	"goapi/internal/repository"
-	"goapi/internal/services"
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"fmt"
-
+	"goapi/internal/app"
+	"goapi/internal/config"
+	"goapi/internal/server"
+	"goapi/pkg/buildinfo"
 	"goapi/pkg/logger"
+	"goapi/pkg/utils"
 
-	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
+	bootStart := time.Now()
+
+	// --db=memory runs against in-memory repositories (development only, data is lost on exit)
+	dbMode := flag.String("db", "postgres", "data store: postgres or memory")
+	flag.Parse()
+
 	// Initialize Logger
 	logger.Init()
 
 	// Load config
-	cfg := config.Load()
-
-	// Initialize database
-	db, err := config.InitDB(cfg)
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		logger.Fatal("Failed to load configuration", "error", err)
+	}
+	logger.Info("Starting application", "build", buildinfo.Get(), "config", cfg, "db_mode", *dbMode, "json_encoder", utils.JSONEncoder)
+
+	// Canceled on SIGINT/SIGTERM: aborts startup queries (e.g. waiting on the migration lock)
+	// and, once serving, starts the graceful shutdown
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	var (
+		db   *gorm.DB
+		opts []app.Option
+	)
+	switch *dbMode {
+	case "postgres":
+		// Initialize database
+		start := time.Now()
+		db, err = config.InitDB(cfg)
+		if err != nil {
+			logger.Fatal("Failed to connect to database", "error", err)
+		}
+		logger.Info("Component initialized", "component", "postgres", "duration", time.Since(start).String())
+	case "memory":
+		if cfg.App.IsProduction() {
+			logger.Fatal("In-memory data store is not allowed in production")
+		}
+		opts = append(opts, app.WithRepositories(app.InMemoryRepositories()))
+		logger.Warn("Using in-memory repositories, data will not persist")
+	default:
+		logger.Fatal("Unknown data store", "db", *dbMode)
 	}
 
 	// Initialize Redis
+	start := time.Now()
 	redisClient, err := config.InitRedis(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to Redis:", err)
+		logger.Fatal("Failed to connect to Redis", "error", err)
 	}
+	logger.Info("Component initialized", "component", "redis", "duration", time.Since(start).String())
 
 	// Auto-migrate models
-	log.Println("Run database migration...")
-	err = db.AutoMigrate(&models.User{}, &models.Post{})
+	if db != nil {
+		start = time.Now()
+		if err := config.Migrate(signalCtx, db); err != nil {
+			logger.Fatal("Failed to migrate database", "error", err)
+		}
+		logger.Info("Component initialized", "component", "migrations", "schema_version", config.SchemaVersion, "duration", time.Since(start).String())
+
+		// Catch tables edited by hand, or a schema applied by a newer build
+		if cfg.DB.SchemaDrift != config.SchemaDriftOff {
+			drift, err := config.CheckSchema(signalCtx, db)
+			if err != nil {
+				logger.Fatal("Failed to check database schema", "error", err)
+			}
+			if len(drift) > 0 && cfg.DB.SchemaDrift == config.SchemaDriftFail {
+				logger.Fatal("Database schema has drifted", "differences", drift)
+			}
+			if len(drift) > 0 {
+				logger.Warn("Database schema has drifted", "differences", drift)
+			}
+		}
+	}
+
+	// Wire repositories, services, handlers and middleware
+	container, err := app.New(signalCtx, cfg, db, redisClient, opts...)
+	if err != nil {
+		logger.Fatal("Failed to build application", "error", err)
+	}
+
+	// Background workers: usage flusher and scheduled jobs
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	container.StartWorkers(workerCtx)
+	logger.Info("Component initialized", "component", "workers", "jobs", container.Scheduler.Names())
+
+	router, err := server.New(cfg, container)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		logger.Fatal("Failed to build router", "error", err)
 	}
 
-	// Initialize repository, service, handler
-	userRepo := repository.NewUserRepository(db)
-	userService := services.NewUserService(userRepo, redisClient)
-	userHandler := handlers.NewUserHandler(userService)
-
-	postRepo := repository.NewPostRepository(db)
-	postService := services.NewPostService(postRepo, redisClient)
-	postHandler := handlers.NewPostHandler(postService)
-
-	// Setup Gin router (Use New() to avoid default Logger)
-	router := gin.New()
-	router.Use(middleware.CustomRecovery())
-
-	// Global middleware
-	router.Use(middleware.RequestID()) // Add Request ID first
-	router.Use(middleware.Logger())    // Add Custom Logger
-	router.Use(middleware.CORS())
-	router.Use(middleware.DataLoaderMiddleware(userRepo)) // Add DataLoader for N+1 prevention
-
-	// Global Rate Limiter: 100 requests per minute
-	router.Use(middleware.RateLimiter(redisClient, 100, time.Minute))
-
-	// Health check
-	healthHandler := handlers.NewHealthHandler(db, redisClient)
-	router.GET("/health", healthHandler.Check)
-
-	// API routes v1
-	v1 := router.Group("/api/v1")
-	{
-		// Public routes
-		// Strict Rate Limiter for Auth: 5 requests per minute
-		authLimiter := middleware.RateLimiter(redisClient, 5, time.Minute)
-
-		v1.POST("/register", authLimiter, userHandler.Register)
-		v1.POST("/login", authLimiter, userHandler.Login)
-
-		// Protected routes
-		authorized := v1.Group("")
-		authorized.Use(middleware.JWTAuth())
-		{
-			// User routes
-			authorized.GET("/users", userHandler.GetAllUsers)
-			authorized.GET("/users/:id", userHandler.GetUserByID)
-			authorized.PUT("/users/:id", userHandler.UpdateUser)
-			authorized.DELETE("/users/:id", userHandler.DeleteUser)
-			authorized.GET("/me", userHandler.GetCurrentUser)
-
-			// Post routes (demonstrates DataLoader usage)
-			authorized.POST("/posts", postHandler.CreatePost)
-			authorized.GET("/posts", postHandler.GetAllPosts) // Batches user loading, supports ?user_id=X
-			authorized.GET("/posts/:id", postHandler.GetPost)
-			authorized.DELETE("/posts/:id", postHandler.DeletePost)
+	// Run server
+	server := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	go func() {
+		logger.Info("Server listening", "addr", server.Addr, "boot_duration", time.Since(bootStart).String())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", "error", err)
 		}
+	}()
+
+	// Graceful shutdown
+	<-signalCtx.Done()
+
+	shutdownStart := time.Now()
+	logger.Info("Shutdown started", "signal", "received")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server shutdown failed", "error", err)
+	} else {
+		logger.Info("Component stopped", "component", "http")
 	}
 
-	fmt.Println(`
- ______     ______        ______     ______   __    
-/\  ___\   /\  __ \      /\  __ \   /\  == \ /\ \   
-\ \ \__ \  \ \ \/\ \     \ \  __ \  \ \  _-/ \ \ \  
- \ \_____\  \ \_____\     \ \_\ \_\  \ \_\    \ \_\ 
-  \/_____/   \/_____/      \/_/\/_/   \/_/     \/_/ `)
+	stopWorkers()
+	logger.Info("Component stopped", "component", "workers")
 
-	// Run server
-	log.Printf("Server starting on port %s", cfg.ServerPort)
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := redisClient.Close(); err != nil {
+		logger.Error("Redis close failed", "error", err)
+	} else {
+		logger.Info("Component stopped", "component", "redis")
 	}
+
+	if db != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				logger.Error("Database close failed", "error", err)
+			} else {
+				logger.Info("Component stopped", "component", "postgres")
+			}
+		}
+	}
+
+	logger.Info("Shutdown complete", "duration", time.Since(shutdownStart).String(), "uptime", time.Since(bootStart).String())
 }
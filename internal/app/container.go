@@ -0,0 +1,280 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/internal/handlers"
+	"goapi/internal/jobs"
+	"goapi/internal/middleware"
+	"goapi/internal/repository"
+	"goapi/internal/services"
+	"goapi/internal/templates"
+	"goapi/pkg/cache"
+	"goapi/pkg/featureflag"
+	"goapi/pkg/httpclient"
+	"goapi/pkg/i18n"
+	"goapi/pkg/mailer"
+	"goapi/pkg/password"
+	"goapi/pkg/signedurl"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Repositories is the data access provider set
+type Repositories struct {
+	User    repository.UserRepository
+	Post    repository.PostRepository
+	Billing repository.BillingRepository
+	Usage   repository.UsageRepository
+	Logins  repository.LoginEventRepository
+	Drafts  repository.PostDraftRepository
+	// Translations holds post content in locales other than the original
+	Translations repository.PostTranslationRepository
+	// PasswordResets holds hashed single-use reset tokens
+	PasswordResets repository.PasswordResetRepository
+	// MagicLinks holds hashed single-use login link tokens
+	MagicLinks repository.MagicLinkRepository
+	// Permissions holds permissions and their grants to roles
+	Permissions repository.PermissionRepository
+	Categories  repository.CategoryRepository
+	Passkeys    repository.PasskeyRepository
+}
+
+// Services is the business logic provider set
+type Services struct {
+	Token     services.TokenService
+	Avatar    services.AvatarService
+	User      services.UserService
+	Post      services.PostService
+	Billing   services.BillingService
+	Quota     services.QuotaService
+	Metering  services.MeteringService
+	Retention services.RetentionService
+	// SignupGuard flags likely bot registrations for review
+	SignupGuard   services.SignupGuard
+	PasswordReset services.PasswordResetService
+	// MagicLink logs users in with a link emailed to them
+	MagicLink services.MagicLinkService
+	// AccountDeletion schedules DELETE /me and erases accounts once their grace period ends
+	AccountDeletion services.AccountDeletionService
+	Category        services.CategoryService
+	// Passkey registers WebAuthn credentials and logs users in with them
+	Passkey services.PasskeyService
+	// Leaderboard also receives post events to keep its counts current
+	Leaderboard services.LeaderboardService
+	PostCounter services.PostCounter
+	Permission  services.PermissionService
+	Session     services.SessionService
+}
+
+// Handlers is the HTTP handler provider set
+type Handlers struct {
+	User    *handlers.UserHandler
+	Post    *handlers.PostHandler
+	Billing *handlers.BillingHandler
+	Usage   *handlers.UsageHandler
+	Health  *handlers.HealthHandler
+	Cache   *handlers.CacheHandler
+	// Password serves the forgot/reset password flow
+	Password    *handlers.PasswordHandler
+	MagicLink   *handlers.MagicLinkHandler
+	Leaderboard *handlers.LeaderboardHandler
+	Jobs        *handlers.JobHandler
+	Features    *handlers.FeatureHandler
+	AdminUsers  *handlers.AdminUserHandler
+	Config      *handlers.ConfigHandler
+	Categories  *handlers.CategoryHandler
+	Passkeys    *handlers.PasskeyHandler
+
+	EmailPreview *handlers.EmailPreviewHandler
+}
+
+// Middlewares is the configured middleware provider set
+type Middlewares struct {
+	// DeviceToken issues and verifies anonymous device tokens; it runs before the limiters
+	DeviceToken   gin.HandlerFunc
+	GlobalLimiter gin.HandlerFunc
+	AuthLimiter   gin.HandlerFunc
+	DataLoader    gin.HandlerFunc
+	Partner       gin.HandlerFunc
+	Auth          gin.HandlerFunc
+	CSRF          gin.HandlerFunc
+	PlanLimiter   gin.HandlerFunc
+	UsageMeter    gin.HandlerFunc
+	AdminOnly     gin.HandlerFunc
+	DraftLimiter  gin.HandlerFunc
+	// StreamAuth also accepts the token from the access_token query parameter, for
+	// EventSource and WebSocket clients that cannot set headers
+	StreamAuth gin.HandlerFunc
+	// Coalesce shares one execution between concurrent identical anonymous GETs
+	Coalesce gin.HandlerFunc
+	// SignedURL admits requests carrying a valid share link; it runs before Auth
+	SignedURL gin.HandlerFunc
+	// Language negotiates Content-Language and localizes error messages
+	Language gin.HandlerFunc
+}
+
+// Infra is the shared infrastructure every other provider set builds on
+type Infra struct {
+	Config *config.Config
+	DB     *gorm.DB
+	Redis  *redis.Client
+	Cache  *cache.Cache
+	I18n   *i18n.Bundle
+	Emails *templates.Renderer
+	Mailer mailer.Sender
+	// Signer mints and verifies share links (?exp=&sig=)
+	Signer *signedurl.Signer
+	// Features decides which flagged features are on for a user (FEATURE_FLAGS)
+	Features *featureflag.Set
+}
+
+// Container holds every application component, wired once at startup by the wire
+// injector in wire.go. Infra's fields and helpers are promoted.
+type Container struct {
+	*Infra
+	Scheduler *jobs.Scheduler
+	// Warmer preloads hot cache entries at startup (CACHE_WARMUP)
+	Warmer services.CacheWarmer
+
+	Repositories Repositories
+	Services     Services
+	Handlers     Handlers
+	Middlewares  Middlewares
+}
+
+// Overrides are components supplied through options. They are used as-is instead of
+// the defaults; nil fields are built as usual.
+type Overrides struct {
+	Repositories Repositories
+	Services     Services
+	Mailer       mailer.Sender
+	Signer       *signedurl.Signer
+	Features     *featureflag.Set
+	Warmer       services.CacheWarmer
+}
+
+// Option customizes the container before components are built
+type Option func(*Overrides)
+
+// WithRepositories replaces the default GORM repositories; nil fields keep the default
+func WithRepositories(repos Repositories) Option {
+	return func(o *Overrides) {
+		o.Repositories = repos
+	}
+}
+
+// InMemoryRepositories returns repositories backed by process memory, for running without Postgres
+func InMemoryRepositories() Repositories {
+	return Repositories{
+		User:    repository.NewInMemoryUserRepository(),
+		Post:    repository.NewInMemoryPostRepository(),
+		Billing: repository.NewInMemoryBillingRepository(),
+		Usage:   repository.NewInMemoryUsageRepository(),
+		Logins:  repository.NewInMemoryLoginEventRepository(),
+		Drafts:  repository.NewInMemoryPostDraftRepository(),
+
+		Translations:   repository.NewInMemoryPostTranslationRepository(),
+		PasswordResets: repository.NewInMemoryPasswordResetRepository(),
+		MagicLinks:     repository.NewInMemoryMagicLinkRepository(),
+		Permissions:    repository.NewInMemoryPermissionRepository(),
+		Categories:     repository.NewInMemoryCategoryRepository(),
+		Passkeys:       repository.NewInMemoryPasskeyRepository(),
+	}
+}
+
+// WithMailer replaces the mailer built from SMTP_HOST
+func WithMailer(sender mailer.Sender) Option {
+	return func(o *Overrides) {
+		o.Mailer = sender
+	}
+}
+
+// WithServices replaces default services; nil fields keep the default
+func WithServices(svcs Services) Option {
+	return func(o *Overrides) {
+		o.Services = svcs
+	}
+}
+
+// New builds the container: infrastructure, repositories, then services, handlers and
+// middleware. Components supplied through options are used as-is instead of the defaults.
+func New(ctx context.Context, cfg *config.Config, db *gorm.DB, redisClient *redis.Client, opts ...Option) (*Container, error) {
+	var overrides Overrides
+	for _, opt := range opts {
+		opt(&overrides)
+	}
+	return initContainer(ctx, cfg, db, redisClient, overrides)
+}
+
+// HTTPClient returns an outbound client for the named integration using the shared settings.
+// Each integration gets its own circuit breaker.
+func (c *Infra) HTTPClient(name string) *httpclient.Client {
+	cfg := c.Config.HTTPClient
+	return httpclient.New(httpclient.Config{
+		Name:             name,
+		Timeout:          cfg.Timeout,
+		MaxRetries:       cfg.MaxRetries,
+		RetryBackoff:     cfg.RetryBackoff,
+		BreakerThreshold: cfg.BreakerThreshold,
+		BreakerCooldown:  cfg.BreakerCooldown,
+	})
+}
+
+// CookieConfig derives cookie token delivery settings from the auth config
+func (c *Infra) CookieConfig() utils.CookieConfig {
+	return utils.CookieConfig{
+		Enabled: c.Config.Auth.Mode == "cookie",
+		Domain:  c.Config.Auth.CookieDomain,
+		Secure:  c.Config.Auth.CookieSecure,
+		MaxAge:  c.Config.Auth.TokenTTL,
+	}
+}
+
+// CachePolicy returns the read-through policy for a cached type with the given TTL.
+// Services set NotFound themselves.
+func (c *Infra) CachePolicy(ttl time.Duration) cache.Policy {
+	return cache.Policy{
+		TTL:    ttl,
+		Jitter: float64(c.Config.Cache.JitterPercent) / 100,
+	}
+}
+
+// PasswordHasher returns the hasher for new passwords configured by PASSWORD_HASH
+func (c *Infra) PasswordHasher() *password.Hasher {
+	cfg := c.Config.Password
+	return password.NewHasher(password.Options{
+		Algorithm: cfg.Algorithm,
+		Argon2: password.Argon2Params{
+			Memory:      uint32(cfg.Argon2Memory),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		BcryptCost: cfg.BcryptCost,
+	})
+}
+
+// tokenExtractors returns where JWTAuth looks for the access token: the Authorization
+// header, then the httpOnly cookie in cookie mode
+func (c *Infra) tokenExtractors() []middleware.TokenExtractor {
+	extractors := []middleware.TokenExtractor{middleware.FromAuthHeader()}
+	if c.CookieConfig().Enabled {
+		extractors = append(extractors, middleware.FromCookie(utils.AccessTokenCookie))
+	}
+	return extractors
+}
+
+// StartWorkers launches the usage flusher and scheduled jobs until ctx is canceled,
+// and the cache warm-up the readiness probe waits for
+func (c *Container) StartWorkers(ctx context.Context) {
+	c.Services.Metering.Start(ctx)
+	c.Scheduler.Start(ctx)
+	c.Warmer.Start(ctx)
+}
@@ -0,0 +1,370 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"goapi/internal/config"
+	"goapi/internal/handlers"
+	"goapi/internal/jobs"
+	"goapi/internal/middleware"
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/internal/services"
+	"goapi/internal/templates"
+	"goapi/pkg/cache"
+	"goapi/pkg/featureflag"
+	"goapi/pkg/i18n"
+	"goapi/pkg/mailer"
+	"goapi/pkg/signedurl"
+	"goapi/pkg/stripe"
+	"goapi/pkg/utctime"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Provider sets, one per layer. A new component gets a provider in its layer's set; the
+// injector in wire.go picks it up once wire_gen.go is regenerated (go generate ./internal/app).
+var (
+	InfraSet      = wire.NewSet(provideInfra)
+	RepositorySet = wire.NewSet(provideRepositories)
+	ServiceSet    = wire.NewSet(provideServices, providePlanLimits, provideScheduler, provideWarmer)
+	HandlerSet    = wire.NewSet(provideHandlers)
+	MiddlewareSet = wire.NewSet(provideMiddlewares)
+	ContainerSet  = wire.NewSet(InfraSet, RepositorySet, ServiceSet, HandlerSet, MiddlewareSet, newContainer)
+)
+
+// PlanLimits maps plan keys to per-minute request limits for the plan rate limiter
+type PlanLimits map[string]int
+
+func newContainer(infra *Infra, repos Repositories, svcs Services, scheduler *jobs.Scheduler, warmer services.CacheWarmer, h Handlers, mw Middlewares) *Container {
+	return &Container{
+		Infra:        infra,
+		Scheduler:    scheduler,
+		Warmer:       warmer,
+		Repositories: repos,
+		Services:     svcs,
+		Handlers:     h,
+		Middlewares:  mw,
+	}
+}
+
+// provideInfra builds the cache, templates, mailer, share link signer and feature flags
+func provideInfra(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, o Overrides) (*Infra, error) {
+	utctime.SetPrecision(cfg.App.TimestampPrecision)
+
+	c := &Infra{
+		Config: cfg,
+		DB:     db,
+		Redis:  redisClient,
+		Cache: cache.New(redisClient, cache.Options{
+			// Every type stored in the cache must be listed so shape changes start a new namespace
+			Namespace:         cache.Namespace(cfg.Cache.Namespace, config.SchemaVersion, models.UserResponse{}, models.PostResponse{}, models.PostPage{}, models.AuthState{}, models.Leaderboard{}, models.RolePermissions{}, models.User{}),
+			CompressThreshold: cfg.Cache.CompressThreshold,
+			NegativeTTL:       cfg.Cache.NegativeTTL,
+		}),
+		Mailer:   o.Mailer,
+		Signer:   o.Signer,
+		Features: o.Features,
+	}
+
+	bundle, err := i18n.Load()
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := templates.NewRenderer(bundle)
+	if err != nil {
+		return nil, err
+	}
+	c.I18n, c.Emails = bundle, renderer
+
+	if c.Mailer == nil {
+		c.Mailer = mailer.NewLog()
+		if mail := c.Config.Mail; mail.SMTPHost != "" {
+			c.Mailer = mailer.NewSMTP(mailer.SMTPConfig{
+				Host:     mail.SMTPHost,
+				Port:     mail.SMTPPort,
+				Username: mail.SMTPUsername,
+				Password: mail.SMTPPassword,
+				From:     mail.From,
+			})
+		}
+	}
+	if c.Signer == nil {
+		c.Signer = signedurl.New(c.Config.Auth.JWTSecret)
+	}
+	if c.Features == nil {
+		c.Features = featureflag.New(c.Config.Features.Rollouts)
+	}
+	return c, nil
+}
+
+// provideRepositories returns the GORM repositories, keeping any supplied as overrides
+func provideRepositories(c *Infra, o Overrides) Repositories {
+	r := &o.Repositories
+	if r.User == nil {
+		r.User = repository.NewUserRepository(c.DB)
+	}
+	if r.Post == nil {
+		r.Post = repository.NewPostRepository(c.DB)
+	}
+	if r.Billing == nil {
+		r.Billing = repository.NewBillingRepository(c.DB)
+	}
+	if r.Usage == nil {
+		r.Usage = repository.NewUsageRepository(c.DB)
+	}
+	if r.Logins == nil {
+		r.Logins = repository.NewLoginEventRepository(c.DB)
+	}
+	if r.Drafts == nil {
+		r.Drafts = repository.NewPostDraftRepository(c.DB)
+	}
+	if r.Translations == nil {
+		r.Translations = repository.NewPostTranslationRepository(c.DB)
+	}
+	if r.PasswordResets == nil {
+		r.PasswordResets = repository.NewPasswordResetRepository(c.DB)
+	}
+	if r.MagicLinks == nil {
+		r.MagicLinks = repository.NewMagicLinkRepository(c.DB)
+	}
+	if r.Permissions == nil {
+		r.Permissions = repository.NewPermissionRepository(c.DB)
+	}
+	if r.Categories == nil {
+		r.Categories = repository.NewCategoryRepository(c.DB)
+	}
+	if r.Passkeys == nil {
+		r.Passkeys = repository.NewPasskeyRepository(c.DB)
+	}
+	if c.Config.Cache.UserRepository {
+		r.User = repository.NewCachedUserRepository(r.User, c.Cache, c.CachePolicy(c.Config.Cache.UserTTL))
+	}
+	return *r
+}
+
+// provideServices returns the business logic services, keeping any supplied as overrides
+func provideServices(c *Infra, r Repositories, o Overrides) Services {
+	s, cfg := &o.Services, c.Config
+	if s.Token == nil {
+		s.Token = services.NewTokenService(services.TokenOptions{
+			Secret:      cfg.Auth.JWTSecret,
+			TTL:         cfg.Auth.TokenTTL,
+			Issuer:      cfg.Auth.Issuer,
+			Audience:    cfg.Auth.Audience,
+			Leeway:      cfg.Auth.TokenLeeway,
+			MaxLifetime: cfg.Auth.TokenMaxLifetime,
+		})
+	}
+	if s.Avatar == nil {
+		s.Avatar = services.NewAvatarService(c.Redis, c.HTTPClient("gravatar"), services.AvatarOptions{
+			Size:     cfg.Avatar.Size,
+			Fallback: cfg.Avatar.Fallback,
+			CacheTTL: cfg.Avatar.CacheTTL,
+		})
+	}
+	if s.PostCounter == nil {
+		s.PostCounter = services.NewPostCounter(r.Post, c.Redis)
+	}
+	if s.Session == nil {
+		s.Session = services.NewSessionService(c.Redis)
+	}
+	if s.User == nil {
+		s.User = services.NewUserService(r.User, r.Logins, r.Post, c.Cache, s.Token, s.Avatar, s.PostCounter, s.Session, services.UsernamePolicy{
+			Reserved: cfg.Username.Reserved,
+			Pattern:  regexp.MustCompile(cfg.Username.Pattern), // validated by config.Load
+		}, c.PasswordHasher(), services.DeletionPolicy{
+			PostAction: cfg.UserDeletion.PostAction,
+			ReassignTo: cfg.UserDeletion.ReassignTo,
+		}, c.CachePolicy(cfg.Cache.UserTTL), c.CachePolicy(cfg.Cache.AuthStateTTL))
+	}
+	if s.Quota == nil {
+		s.Quota = services.NewQuotaService(r.User, r.Billing, s.PostCounter, c.Redis)
+	}
+	if s.Metering == nil {
+		s.Metering = services.NewMeteringService(r.Usage)
+	}
+	if s.Permission == nil {
+		s.Permission = services.NewPermissionService(r.Permissions, c.Cache, c.CachePolicy(cfg.Cache.TTL))
+	}
+	if s.Leaderboard == nil {
+		s.Leaderboard = services.NewLeaderboardService(c.Redis, c.Cache, s.Avatar, c.CachePolicy(cfg.Cache.LeaderboardTTL))
+	}
+	if s.Post == nil {
+		s.Post = services.NewPostService(r.Post, r.Drafts, r.Translations, r.Categories, c.Redis, c.Cache, s.Quota, s.Metering, s.Avatar, s.Leaderboard, s.PostCounter, s.Permission, services.ContentPolicy{
+			MinTitleLength:   3,
+			MaxTitleLength:   cfg.Content.MaxTitleLength,
+			MaxContentLength: cfg.Content.MaxContentLength,
+			HTML:             cfg.Content.HTMLPolicy,
+			ExcerptWords:     cfg.Content.ExcerptWords,
+		}, services.DuplicatePolicy{
+			Window: cfg.Content.DuplicateWindow,
+			Action: cfg.Content.DuplicateAction,
+		}, c.CachePolicy(cfg.Cache.PostTTL), c.CachePolicy(cfg.Cache.ListTTL))
+	}
+	if s.Category == nil {
+		s.Category = services.NewCategoryService(r.Categories, r.Post, c.Cache)
+	}
+	if s.Retention == nil {
+		s.Retention = services.NewRetentionService(r.User, r.Post, cfg.Retention.PurgeAfter(), cfg.Retention.PurgeBatchSize)
+	}
+	if s.SignupGuard == nil {
+		s.SignupGuard = services.NewSignupGuard(c.Redis, services.SignupGuardOptions{
+			Secret:      cfg.Auth.JWTSecret,
+			MinFillTime: cfg.Signup.MinFillTime,
+			MaxPerIP:    cfg.Signup.MaxPerIP,
+			Window:      cfg.Signup.Window,
+		})
+	}
+	if s.PasswordReset == nil {
+		s.PasswordReset = services.NewPasswordResetService(r.User, r.PasswordResets, c.Cache, c.PasswordHasher(), c.Emails, c.Mailer, services.PasswordResetOptions{
+			TTL: cfg.PasswordReset.TokenTTL,
+			URL: cfg.PasswordReset.URL,
+		})
+	}
+	if s.MagicLink == nil {
+		s.MagicLink = services.NewMagicLinkService(r.User, r.MagicLinks, s.User, c.Redis, c.Emails, c.Mailer, services.MagicLinkOptions{
+			TTL:         cfg.MagicLink.TokenTTL,
+			URL:         cfg.MagicLink.URL,
+			MaxPerEmail: cfg.MagicLink.MaxPerEmail,
+			Window:      cfg.MagicLink.Window,
+		})
+	}
+	if s.Passkey == nil {
+		s.Passkey = services.NewPasskeyService(r.Passkeys, r.User, s.User, c.Redis, services.PasskeyOptions{
+			RPID:    cfg.Passkey.RPID,
+			RPName:  cfg.Passkey.RPName,
+			Origins: cfg.Passkey.Origins,
+			Timeout: cfg.Passkey.Timeout,
+		})
+	}
+	if s.AccountDeletion == nil {
+		s.AccountDeletion = services.NewAccountDeletionService(r.User, s.User, c.Cache, c.PasswordHasher(), c.Emails, c.Mailer, services.AccountDeletionOptions{
+			Grace:          cfg.UserDeletion.Grace,
+			ReminderBefore: cfg.UserDeletion.ReminderBefore,
+			RestoreURL:     cfg.UserDeletion.RestoreURL,
+		})
+	}
+	if s.Billing == nil {
+		s.Billing = services.NewBillingService(r.Billing, r.User, stripe.NewClient(cfg.Billing.StripeSecretKey, c.HTTPClient("stripe")), c.Redis, c.Cache, services.BillingOptions{
+			WebhookSecret: cfg.Billing.StripeWebhookSecret,
+			SuccessURL:    cfg.Billing.SuccessURL,
+			CancelURL:     cfg.Billing.CancelURL,
+		})
+	}
+	return *s
+}
+
+// providePlanLimits creates the default permissions and plans and returns each plan's
+// per-minute request limit
+func providePlanLimits(ctx context.Context, c *Infra, s Services) (PlanLimits, error) {
+	if err := s.Permission.EnsureDefaults(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.Billing.EnsureDefaultPlans(ctx, c.Config.Billing.StripeProPriceID); err != nil {
+		return nil, err
+	}
+	return s.Billing.RateLimits(ctx)
+}
+
+func provideHandlers(c *Infra, s Services, scheduler *jobs.Scheduler, warmer services.CacheWarmer) Handlers {
+	return Handlers{
+		User:    handlers.NewUserHandler(s.User, s.SignupGuard, s.AccountDeletion, c.CookieConfig()),
+		Post:    handlers.NewPostHandler(s.Post, c.Signer, c.Config.SignedURL.MaxTTL),
+		Billing: handlers.NewBillingHandler(s.Billing),
+		Usage:   handlers.NewUsageHandler(s.Metering),
+		Health:  handlers.NewHealthHandler(c.DB, c.Redis, warmer, time.Now()),
+		Cache:   handlers.NewCacheHandler(c.Cache, c.Config.Cache.MemoryBudget),
+
+		Password:     handlers.NewPasswordHandler(s.PasswordReset, c.I18n),
+		MagicLink:    handlers.NewMagicLinkHandler(s.MagicLink, c.I18n, c.CookieConfig()),
+		Leaderboard:  handlers.NewLeaderboardHandler(s.Leaderboard),
+		Jobs:         handlers.NewJobHandler(scheduler),
+		Features:     handlers.NewFeatureHandler(c.Features),
+		AdminUsers:   handlers.NewAdminUserHandler(s.User, s.PasswordReset),
+		Config:       handlers.NewConfigHandler(c.Config),
+		Categories:   handlers.NewCategoryHandler(s.Category),
+		Passkeys:     handlers.NewPasskeyHandler(s.Passkey, c.CookieConfig()),
+		EmailPreview: handlers.NewEmailPreviewHandler(c.Emails, c.I18n),
+	}
+}
+
+func provideMiddlewares(c *Infra, r Repositories, s Services, planLimits PlanLimits) Middlewares {
+	cfg := c.Config
+	auth := middleware.JWTAuthOptions{Extractors: c.tokenExtractors(), FreshUserState: cfg.Auth.FreshUserState}
+	streamAuth := auth
+	streamAuth.Extractors = append(c.tokenExtractors(), middleware.FromQuery(middleware.AccessTokenQueryParam))
+	deviceToken := func(ctx *gin.Context) { ctx.Next() }
+	if cfg.RateLimit.DeviceTokens {
+		deviceToken = middleware.DeviceToken(c.Redis, middleware.DeviceTokenOptions{
+			Secret:        cfg.Auth.JWTSecret,
+			TTL:           cfg.RateLimit.DeviceTokenTTL,
+			MaxIssuePerIP: cfg.RateLimit.DeviceTokenIssue,
+			MaxPerIP:      cfg.RateLimit.DeviceTokenMaxPerIP,
+			Period:        cfg.RateLimit.Period,
+			Cookie:        c.CookieConfig(),
+		})
+	}
+
+	return Middlewares{
+		DeviceToken:   deviceToken,
+		GlobalLimiter: middleware.RateLimiter(c.Redis, "global", cfg.RateLimit.GlobalRequests, cfg.RateLimit.Period, middleware.KeyStrategy(cfg.RateLimit.GlobalKey)),
+		AuthLimiter:   middleware.RateLimiter(c.Redis, "auth", cfg.RateLimit.AuthRequests, cfg.RateLimit.Period, middleware.KeyStrategy(cfg.RateLimit.AuthKey)),
+		DataLoader:    middleware.DataLoaderMiddleware(r.User, s.PostCounter, c.loaderOptions()),
+		Partner:       middleware.SignatureAuth(c.Redis, cfg.Auth.APIKeys, cfg.Auth.SignatureMaxSkew),
+		Auth:          middleware.JWTAuth(s.Token, s.User, s.Session, auth),
+		StreamAuth:    middleware.JWTAuth(s.Token, s.User, s.Session, streamAuth),
+		CSRF:          middleware.CSRF(),
+		PlanLimiter:   middleware.PlanRateLimiter(c.Redis, planLimits, cfg.RateLimit.PlanDefaultRequests, cfg.RateLimit.Period, middleware.KeyStrategy(cfg.RateLimit.PlanKey)),
+		UsageMeter:    middleware.UsageMeter(s.Metering),
+		AdminOnly:     middleware.AdminOnly(),
+		DraftLimiter:  middleware.RateLimiter(c.Redis, "draft", cfg.RateLimit.DraftRequests, cfg.RateLimit.Period, middleware.KeyByUser),
+		SignedURL:     middleware.SignedURL(c.Signer),
+		Coalesce:      middleware.Coalesce(),
+		Language:      middleware.Language(c.I18n),
+	}
+}
+
+// loaderOptions tunes the request-scoped dataloaders
+func (c *Infra) loaderOptions() utils.LoaderOptions {
+	return utils.LoaderOptions{
+		Wait:          c.Config.DataLoader.Wait,
+		BatchCapacity: c.Config.DataLoader.BatchCapacity,
+		Cache:         c.Config.DataLoader.Cache,
+	}
+}
+
+func provideWarmer(c *Infra, r Repositories, s Services, o Overrides) services.CacheWarmer {
+	if o.Warmer != nil {
+		return o.Warmer
+	}
+	return services.NewCacheWarmer(s.Post, s.User, s.Leaderboard, r.User,
+		middleware.WithLoaders(r.User, s.PostCounter, c.loaderOptions()),
+		services.CacheWarmerOptions{
+			Enabled: c.Config.Cache.Warmup,
+			Timeout: c.Config.Cache.WarmupTimeout,
+			Users:   c.Config.Cache.WarmupUsers,
+		})
+}
+
+func provideScheduler(c *Infra, s Services) *jobs.Scheduler {
+	scheduler := jobs.NewScheduler(c.Redis)
+	scheduler.Register("usage_rollup", time.Hour, s.Metering.Rollup)
+	scheduler.Register("purge_soft_deleted", 24*time.Hour, func(ctx context.Context) error {
+		_, err := s.Retention.PurgeSoftDeleted(ctx)
+		return err
+	})
+	scheduler.Register("user_deletion", time.Hour, func(ctx context.Context) error {
+		_, err := s.AccountDeletion.Run(ctx)
+		return err
+	})
+	scheduler.Register("reconcile_post_counts", time.Hour, s.PostCounter.Reconcile)
+	scheduler.Register("cache_usage", c.Config.Cache.UsageInterval, services.ReportCacheUsage(c.Cache, c.Config.Cache.MemoryBudget))
+	return scheduler
+}
@@ -0,0 +1,20 @@
+//go:build wireinject
+
+package app
+
+import (
+	"context"
+
+	"goapi/internal/config"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// initContainer is the wire injector. wire_gen.go holds the generated body; regenerate it
+// with go generate ./internal/app after changing a provider.
+func initContainer(ctx context.Context, cfg *config.Config, db *gorm.DB, redisClient *redis.Client, overrides Overrides) (*Container, error) {
+	wire.Build(ContainerSet)
+	return nil, nil
+}
@@ -0,0 +1,37 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package app
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"goapi/internal/config"
+	"gorm.io/gorm"
+)
+
+// Injectors from wire.go:
+
+// initContainer is the wire injector. wire_gen.go holds the generated body; regenerate it
+// with go generate ./internal/app after changing a provider.
+func initContainer(ctx context.Context, cfg *config.Config, db *gorm.DB, redisClient *redis.Client, overrides Overrides) (*Container, error) {
+	infra, err := provideInfra(cfg, db, redisClient, overrides)
+	if err != nil {
+		return nil, err
+	}
+	repositories := provideRepositories(infra, overrides)
+	services := provideServices(infra, repositories, overrides)
+	scheduler := provideScheduler(infra, services)
+	cacheWarmer := provideWarmer(infra, repositories, services, overrides)
+	handlers := provideHandlers(infra, services, scheduler, cacheWarmer)
+	planLimits, err := providePlanLimits(ctx, infra, services)
+	if err != nil {
+		return nil, err
+	}
+	middlewares := provideMiddlewares(infra, repositories, services, planLimits)
+	container := newContainer(infra, repositories, services, scheduler, cacheWarmer, handlers, middlewares)
+	return container, nil
+}
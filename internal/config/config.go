@@ -1,59 +1,1142 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/pkg/featureflag"
+	"goapi/pkg/pii"
+	"goapi/pkg/utils"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// DefaultJWTSecret is the development fallback; Validate rejects it in production
+const DefaultJWTSecret = "your-secret-key"
+
+// DefaultExcerptWords is the POST_EXCERPT_WORDS default, also used to backfill excerpts
+const DefaultExcerptWords = 50
+
+// Development fallbacks for the PII keys; Validate rejects them in production
+const (
+	DefaultPIIEncryptionKey = "2/W+ANwID0Ks4Tg3MPTlHE8cZXavyUnUSaKuWYKMkog="
+	DefaultPIIBlindIndexKey = "vUUgscUjETG/G/2n4ZwhM1fT6tUaRi8ZGFOyin1Xywc="
+)
+
 type Config struct {
-	ServerPort string
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	RedisHost  string
-	RedisPort  string
-	JWTSecret  string
-}
-
-func Load() *Config {
+	App       AppConfig
+	Server    ServerConfig
+	DB        DBConfig
+	Redis     RedisConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
+	Cache     CacheConfig
+	Billing   BillingConfig
+	Retention RetentionConfig
+	// HTTPClient tunes outbound calls to external integrations
+	HTTPClient HTTPClientConfig
+	Avatar     AvatarConfig
+	Signup     SignupConfig
+	Content    ContentConfig
+	Username   UsernameConfig
+	Password   PasswordConfig
+	PII        PIIConfig
+	DataLoader DataLoaderConfig
+	// UserDeletion decides what happens to a deleted user's posts and how long a
+	// deletion the user asked for waits before it runs
+	UserDeletion UserDeletionConfig
+	// Mail configures outgoing email; without SMTP_HOST messages are only logged
+	Mail          MailConfig
+	PasswordReset PasswordResetConfig
+	MagicLink     MagicLinkConfig
+	Passkey       PasskeyConfig
+	SignedURL     SignedURLConfig
+	Features      FeaturesConfig
+
+	// Sources maps every variable Load read to where its value came from: SourceDefault,
+	// SourceDotenv or SourceEnvironment
+	Sources map[string]string
+}
+
+// Where a configuration value came from
+const (
+	SourceDefault     = "default"
+	SourceDotenv      = ".env"
+	SourceEnvironment = "environment"
+)
+
+type AppConfig struct {
+	// Env is the deployment environment: "development" or "production"
+	Env string
+	// TimestampPrecision is the unit JSON timestamps are truncated to; 0 keeps nanoseconds
+	TimestampPrecision time.Duration
+}
+
+// IsProduction reports whether the app runs with production safeguards
+func (a AppConfig) IsProduction() bool {
+	return a.Env == "production"
+}
+
+type ServerConfig struct {
+	Port            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+}
+
+type DBConfig struct {
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// SchemaDrift is what startup does when the live schema differs from the models
+	// after migrating: SchemaDriftWarn logs it, SchemaDriftFail aborts, SchemaDriftOff
+	// skips the check
+	SchemaDrift string
+}
+
+// DB_SCHEMA_DRIFT values
+const (
+	SchemaDriftOff  = "off"
+	SchemaDriftWarn = "warn"
+	SchemaDriftFail = "fail"
+)
+
+type RedisConfig struct {
+	Host     string
+	Port     string
+	Password string
+	DB       int
+}
+
+type AuthConfig struct {
+	JWTSecret string
+	TokenTTL  time.Duration
+	// Issuer and Audience are set on issued tokens and required when verifying them
+	Issuer   string
+	Audience string
+	// TokenLeeway tolerates clock skew when checking exp, nbf and iat
+	TokenLeeway time.Duration
+	// TokenMaxLifetime rejects tokens whose exp is further than this after iat
+	TokenMaxLifetime time.Duration
+	// FreshUserState makes JWTAuth check the current role and active flag instead of trusting the token
+	FreshUserState bool
+
+	// Mode selects how tokens are delivered: "header" (JSON body + Authorization header)
+	// or "cookie" (httpOnly cookies with double-submit CSRF protection)
+	Mode         string
+	CookieDomain string
+	CookieSecure bool
+
+	// APIKeys holds partner credentials for signed requests, parsed from API_KEYS="key_id:secret,key_id2:secret2"
+	APIKeys          map[string]string
+	SignatureMaxSkew time.Duration
+}
+
+type RateLimitConfig struct {
+	// GlobalRequests is the per-IP limit applied to every route
+	GlobalRequests int
+	// AuthRequests is the per-IP limit for login, register and other credential routes
+	AuthRequests int
+	// DraftRequests is the per-user limit for draft autosaves
+	DraftRequests int
+	// PlanDefaultRequests applies to users whose plan has no configured limit
+	PlanDefaultRequests int
+	Period              time.Duration
+
+	// Key strategies per limiter group: "ip", "ip_route", "user", "user_route", "device"
+	// or "device_route". Route strategies give each endpoint its own counter.
+	GlobalKey string
+	AuthKey   string
+	PlanKey   string
+
+	// DeviceTokens issues anonymous clients a signed device token, which the device
+	// strategies count by instead of the IP once the client sends it back
+	DeviceTokens bool
+	// DeviceTokenIssue caps the new device tokens issued to one IP per Period
+	DeviceTokenIssue int
+	// DeviceTokenMaxPerIP caps the valid tokens issued to one IP within DeviceTokenTTL
+	DeviceTokenMaxPerIP int
+	// DeviceTokenTTL is how long a device token stays valid
+	DeviceTokenTTL time.Duration
+}
+
+type CacheConfig struct {
+	TTL time.Duration
+	// UserTTL and PostTTL override TTL for cached users (and their auth state) and posts
+	UserTTL time.Duration
+	PostTTL time.Duration
+	// AuthStateTTL is kept short: it bounds how long a ban or demotion takes to apply
+	AuthStateTTL time.Duration
+	// LeaderboardTTL is how long a rendered leaderboard is served before counts are re-read
+	LeaderboardTTL time.Duration
+	// JitterPercent randomizes each TTL by up to ±JitterPercent so entries do not expire together
+	JitterPercent int
+	// Namespace is the app prefix of cache keys; the schema version and response shapes are appended
+	Namespace string
+	// CompressThreshold is the value size in bytes above which cache entries are gzipped
+	CompressThreshold int
+	// NegativeTTL is how long "not found" lookups are cached; 0 disables negative caching
+	NegativeTTL time.Duration
+	// ListTTL is how long the first page of a post list and related posts stay cached;
+	// it defaults to PostTTL
+	ListTTL time.Duration
+	// MemoryBudget is the size in bytes above which the current namespace is reported
+	// as over budget; 0 disables the warning. Redis maxmemory still does the evicting.
+	MemoryBudget int64
+	// UsageInterval is how often the memory held by each namespace is measured and logged
+	UsageInterval time.Duration
+	// Warmup preloads hot entries at startup; readiness waits for it up to WarmupTimeout
+	Warmup        bool
+	WarmupTimeout time.Duration
+	// WarmupUsers is how many recently active users are preloaded
+	WarmupUsers int
+	// UserRepository caches user repository reads for UserTTL (see
+	// repository.CachedUserRepository)
+	UserRepository bool
+}
+
+type RetentionConfig struct {
+	// PurgeAfterDays is how long soft-deleted users and posts are kept before permanent deletion
+	PurgeAfterDays int
+	// PurgeBatchSize caps the rows deleted per transaction
+	PurgeBatchSize int
+}
+
+// PurgeAfter returns the retention period for soft-deleted rows
+func (r RetentionConfig) PurgeAfter() time.Duration {
+	return time.Duration(r.PurgeAfterDays) * 24 * time.Hour
+}
+
+type UserDeletionConfig struct {
+	// PostAction is "delete" (soft-delete the posts), "reassign" (move them to ReassignTo)
+	// or "anonymize" (keep the posts and scrub the author instead of deleting them)
+	PostAction string
+	// ReassignTo is the ID of the system account that receives posts in "reassign" mode
+	ReassignTo uint
+	// Grace is how long an account stays pending deletion after DELETE /me
+	Grace time.Duration
+	// ReminderBefore is how long before the deletion the reminder email goes out; 0
+	// sends none
+	ReminderBefore time.Duration
+	// RestoreURL is the frontend login page the reminder links to
+	RestoreURL string
+}
+
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+}
+
+type PasswordResetConfig struct {
+	// TokenTTL is how long a reset link stays valid
+	TokenTTL time.Duration
+	// URL is the frontend page reset links point to; the token is added as ?token=
+	URL string
+}
+
+type MagicLinkConfig struct {
+	// TokenTTL is how long a login link stays valid
+	TokenTTL time.Duration
+	// URL is the page login links point to; the token is added as ?token=
+	URL string
+	// MaxPerEmail links can be requested for one email within Window
+	MaxPerEmail int
+	Window      time.Duration
+}
+
+type PasskeyConfig struct {
+	// RPID is the domain passkeys are bound to, without scheme or port. Changing it
+	// orphans every registered passkey.
+	RPID string
+	// RPName is the site name authenticators show when creating a passkey
+	RPName string
+	// Origins are the frontend origins allowed to register and use passkeys
+	Origins []string
+	// Timeout is how long a registration or login ceremony may take
+	Timeout time.Duration
+}
+
+type SignedURLConfig struct {
+	// MaxTTL caps how long a share link minted by an owner stays valid
+	MaxTTL time.Duration
+}
+
+// FeaturesConfig holds the feature flag rollouts from FEATURE_FLAGS
+type FeaturesConfig struct {
+	Rollouts map[string]featureflag.Rollout
+}
+
+type HTTPClientConfig struct {
+	Timeout          time.Duration
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type AvatarConfig struct {
+	Size int
+	// Fallback is a Gravatar style ("identicon", "retro", "mp", ...) or a URL template with {hash}
+	Fallback string
+	CacheTTL time.Duration
+}
+
+// SignupConfig tunes bot detection on registration
+type SignupConfig struct {
+	MinFillTime time.Duration
+	MaxPerIP    int
+	Window      time.Duration
+}
+
+// DataLoaderConfig tunes the request-scoped dataloaders that batch author lookups
+type DataLoaderConfig struct {
+	// Wait is how long a loader gathers keys before querying; it adds up to this much latency
+	Wait          time.Duration
+	BatchCapacity int
+	// Cache memoizes loaded records for the rest of the request
+	Cache bool
+}
+
+// UsernameConfig restricts the usernames accepted at registration and rename
+type UsernameConfig struct {
+	// Reserved names can never be taken, compared ignoring case, '.', '_' and '-'
+	Reserved []string
+	// Pattern is a regular expression the whole username must match
+	Pattern string
+}
+
+// PasswordConfig selects how new password hashes are computed. Existing hashes of
+// any supported algorithm keep verifying and are upgraded on login.
+type PasswordConfig struct {
+	// Algorithm is "argon2id" or "bcrypt"
+	Algorithm         string
+	Argon2Memory      int // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+	// BcryptCost applies when Algorithm is "bcrypt"; raising it re-hashes users as they log in
+	BcryptCost int
+}
+
+// PIIConfig holds the keys protecting personal data at rest. Both are base64-encoded
+// 32-byte keys, typically injected from a KMS or secret manager. Changing either one
+// makes existing rows unreadable (encryption) or unfindable (blind index).
+type PIIConfig struct {
+	EncryptionKey string
+	BlindIndexKey string
+}
+
+// Keys decodes the encryption and blind index keys
+func (p PIIConfig) Keys() (encryption, index []byte, err error) {
+	encryption, err = base64.StdEncoding.DecodeString(p.EncryptionKey)
+	if err != nil || len(encryption) != 32 {
+		return nil, nil, errors.New("PII_ENCRYPTION_KEY must be a base64-encoded 32-byte key")
+	}
+	index, err = base64.StdEncoding.DecodeString(p.BlindIndexKey)
+	if err != nil || len(index) != 32 {
+		return nil, nil, errors.New("PII_BLIND_INDEX_KEY must be a base64-encoded 32-byte key")
+	}
+	return encryption, index, nil
+}
+
+// ContentConfig limits user-submitted posts
+type ContentConfig struct {
+	MaxTitleLength   int
+	MaxContentLength int
+	// HTMLPolicy is "strip" (remove tags) or "deny" (reject input with tags)
+	HTMLPolicy string
+	// DuplicateWindow is how long identical posts by one user are detected
+	DuplicateWindow time.Duration
+	// DuplicateAction is "reject" (409) or "warn" (create with a warning)
+	DuplicateAction string
+	// ExcerptWords is the length of post excerpts shown in lists
+	ExcerptWords int
+}
+
+type BillingConfig struct {
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripeProPriceID    string
+	SuccessURL          string
+	CancelURL           string
+}
+
+// Load reads the configuration from the environment (and .env when present),
+// applying defaults and returning an error that lists every invalid setting
+func Load() (*Config, error) {
+	// godotenv.Load never overrides the environment, so a value equal to the .env entry
+	// came from the file
+	dotenv, _ := godotenv.Read()
 	_ = godotenv.Load()
 
-	return &Config{
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5433"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "goapi"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6380"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+	p := &envParser{dotenv: dotenv, sources: make(map[string]string)}
+	cacheTTL := p.getDuration("CACHE_TTL", 10*time.Minute)
+	postTTL := p.getDuration("CACHE_POST_TTL", cacheTTL)
+	rollouts, err := featureflag.Parse(p.getString("FEATURE_FLAGS", ""))
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("FEATURE_FLAGS: %w", err))
 	}
+	cfg := &Config{
+		App: AppConfig{
+			Env:                p.getString("APP_ENV", "development"),
+			TimestampPrecision: p.getDuration("TIMESTAMP_PRECISION", time.Millisecond),
+		},
+		Server: ServerConfig{
+			Port:            p.getString("SERVER_PORT", "8080"),
+			ReadTimeout:     p.getDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:    p.getDuration("SERVER_WRITE_TIMEOUT", 15*time.Second),
+			ShutdownTimeout: p.getDuration("SERVER_SHUTDOWN_TIMEOUT", 10*time.Second),
+		},
+		DB: DBConfig{
+			Host:            p.getString("DB_HOST", "localhost"),
+			Port:            p.getString("DB_PORT", "5433"),
+			User:            p.getString("DB_USER", "postgres"),
+			Password:        p.getString("DB_PASSWORD", "postgres"),
+			Name:            p.getString("DB_NAME", "goapi"),
+			MaxOpenConns:    p.getInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    p.getInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: p.getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			SchemaDrift:     p.getString("DB_SCHEMA_DRIFT", SchemaDriftWarn),
+		},
+		Redis: RedisConfig{
+			Host:     p.getString("REDIS_HOST", "localhost"),
+			Port:     p.getString("REDIS_PORT", "6380"),
+			Password: p.getString("REDIS_PASSWORD", ""),
+			DB:       p.getInt("REDIS_DB", 0),
+		},
+		Auth: AuthConfig{
+			JWTSecret:        p.getString("JWT_SECRET", DefaultJWTSecret),
+			TokenTTL:         p.getDuration("JWT_TTL", 24*time.Hour),
+			Issuer:           p.getString("JWT_ISSUER", "goapi"),
+			Audience:         p.getString("JWT_AUDIENCE", "goapi"),
+			TokenLeeway:      p.getDuration("JWT_LEEWAY", 30*time.Second),
+			TokenMaxLifetime: p.getDuration("JWT_MAX_LIFETIME", 7*24*time.Hour),
+			FreshUserState:   p.getBool("AUTH_FRESH_USER_STATE", true),
+			Mode:             p.getString("AUTH_MODE", "header"),
+			CookieDomain:     p.getString("COOKIE_DOMAIN", ""),
+			CookieSecure:     p.getBool("COOKIE_SECURE", false),
+			APIKeys:          parseAPIKeys(p.getString("API_KEYS", "")),
+			SignatureMaxSkew: p.getDuration("SIGNATURE_MAX_SKEW", 5*time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			GlobalRequests:      p.getInt("RATE_LIMIT_GLOBAL", 100),
+			AuthRequests:        p.getInt("RATE_LIMIT_AUTH", 5),
+			PlanDefaultRequests: p.getInt("RATE_LIMIT_PLAN_DEFAULT", 100),
+			DraftRequests:       p.getInt("RATE_LIMIT_DRAFT", 30),
+			Period:              p.getDuration("RATE_LIMIT_PERIOD", time.Minute),
+			GlobalKey:           p.getString("RATE_LIMIT_GLOBAL_KEY", "device_route"),
+			AuthKey:             p.getString("RATE_LIMIT_AUTH_KEY", "ip_route"),
+			PlanKey:             p.getString("RATE_LIMIT_PLAN_KEY", "user"),
+			DeviceTokens:        p.getBool("RATE_LIMIT_DEVICE_TOKENS", true),
+			DeviceTokenIssue:    p.getInt("RATE_LIMIT_DEVICE_TOKEN_ISSUE", 10),
+			DeviceTokenMaxPerIP: p.getInt("RATE_LIMIT_DEVICE_TOKEN_MAX_PER_IP", 50),
+			DeviceTokenTTL:      p.getDuration("RATE_LIMIT_DEVICE_TOKEN_TTL", 24*time.Hour),
+		},
+		Cache: CacheConfig{
+			TTL:               cacheTTL,
+			UserTTL:           p.getDuration("CACHE_USER_TTL", cacheTTL),
+			PostTTL:           postTTL,
+			AuthStateTTL:      p.getDuration("CACHE_AUTH_STATE_TTL", 30*time.Second),
+			LeaderboardTTL:    p.getDuration("CACHE_LEADERBOARD_TTL", time.Minute),
+			JitterPercent:     p.getInt("CACHE_TTL_JITTER_PERCENT", 10),
+			Namespace:         p.getString("CACHE_NAMESPACE", "goapi"),
+			CompressThreshold: p.getInt("CACHE_COMPRESS_THRESHOLD", 1024),
+			NegativeTTL:       p.getDuration("CACHE_NEGATIVE_TTL", 30*time.Second),
+			ListTTL:           p.getDuration("CACHE_LIST_TTL", postTTL),
+			MemoryBudget:      int64(p.getInt("CACHE_MEMORY_BUDGET_MB", 0)) << 20,
+			UsageInterval:     p.getDuration("CACHE_USAGE_INTERVAL", 15*time.Minute),
+			Warmup:            p.getBool("CACHE_WARMUP", false),
+			WarmupTimeout:     p.getDuration("CACHE_WARMUP_TIMEOUT", 10*time.Second),
+			WarmupUsers:       p.getInt("CACHE_WARMUP_USERS", 100),
+			UserRepository:    p.getBool("CACHE_USER_REPOSITORY", false),
+		},
+		Billing: BillingConfig{
+			StripeSecretKey:     p.getString("STRIPE_SECRET_KEY", ""),
+			StripeWebhookSecret: p.getString("STRIPE_WEBHOOK_SECRET", ""),
+			StripeProPriceID:    p.getString("STRIPE_PRO_PRICE_ID", ""),
+			SuccessURL:          p.getString("BILLING_SUCCESS_URL", "http://localhost:3000/billing/success"),
+			CancelURL:           p.getString("BILLING_CANCEL_URL", "http://localhost:3000/billing/cancel"),
+		},
+		HTTPClient: HTTPClientConfig{
+			Timeout:          p.getDuration("HTTP_CLIENT_TIMEOUT", 10*time.Second),
+			MaxRetries:       p.getInt("HTTP_CLIENT_MAX_RETRIES", 2),
+			RetryBackoff:     p.getDuration("HTTP_CLIENT_RETRY_BACKOFF", 200*time.Millisecond),
+			BreakerThreshold: p.getInt("HTTP_CLIENT_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  p.getDuration("HTTP_CLIENT_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		Avatar: AvatarConfig{
+			Size:     p.getInt("AVATAR_SIZE", 200),
+			Fallback: p.getString("AVATAR_FALLBACK", "identicon"),
+			CacheTTL: p.getDuration("AVATAR_CACHE_TTL", 24*time.Hour),
+		},
+		Signup: SignupConfig{
+			MinFillTime: p.getDuration("SIGNUP_MIN_FILL_TIME", 3*time.Second),
+			MaxPerIP:    p.getInt("SIGNUP_MAX_PER_IP", 5),
+			Window:      p.getDuration("SIGNUP_WINDOW", time.Hour),
+		},
+		Username: UsernameConfig{
+			Reserved: splitList(p.getString("USERNAME_RESERVED", "admin,administrator,api,me,root,support,system,staff,help,security,billing,www,mail,null,undefined")),
+			Pattern:  p.getString("USERNAME_PATTERN", `^[a-zA-Z][a-zA-Z0-9_.-]*$`),
+		},
+		Password: PasswordConfig{
+			Algorithm:         p.getString("PASSWORD_HASH", "argon2id"),
+			Argon2Memory:      p.getInt("ARGON2_MEMORY_KIB", 64*1024),
+			Argon2Iterations:  p.getInt("ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: p.getInt("ARGON2_PARALLELISM", 2),
+			BcryptCost:        p.getInt("BCRYPT_COST", 10),
+		},
+		DataLoader: DataLoaderConfig{
+			Wait:          p.getDuration("DATALOADER_WAIT", 16*time.Millisecond),
+			BatchCapacity: p.getInt("DATALOADER_BATCH_CAPACITY", 100),
+			Cache:         p.getBool("DATALOADER_CACHE", true),
+		},
+		PII: PIIConfig{
+			EncryptionKey: p.getString("PII_ENCRYPTION_KEY", DefaultPIIEncryptionKey),
+			BlindIndexKey: p.getString("PII_BLIND_INDEX_KEY", DefaultPIIBlindIndexKey),
+		},
+		Content: ContentConfig{
+			MaxTitleLength:   p.getInt("POST_MAX_TITLE_LENGTH", 200),
+			MaxContentLength: p.getInt("POST_MAX_CONTENT_LENGTH", 10000),
+			HTMLPolicy:       p.getString("POST_HTML_POLICY", "strip"),
+			DuplicateWindow:  p.getDuration("POST_DUPLICATE_WINDOW", 10*time.Minute),
+			DuplicateAction:  p.getString("POST_DUPLICATE_ACTION", "reject"),
+			ExcerptWords:     p.getInt("POST_EXCERPT_WORDS", DefaultExcerptWords),
+		},
+		Retention: RetentionConfig{
+			PurgeAfterDays: p.getInt("SOFT_DELETE_RETENTION_DAYS", 30),
+			PurgeBatchSize: p.getInt("PURGE_BATCH_SIZE", 500),
+		},
+		UserDeletion: UserDeletionConfig{
+			PostAction: p.getString("USER_DELETE_POSTS", "delete"),
+			ReassignTo: uint(max(p.getInt("USER_DELETE_REASSIGN_TO", 0), 0)),
+
+			Grace:          p.getDuration("USER_DELETE_GRACE", 14*24*time.Hour),
+			ReminderBefore: p.getDuration("USER_DELETE_REMINDER", 3*24*time.Hour),
+			RestoreURL:     p.getString("USER_DELETE_RESTORE_URL", "http://localhost:3000/login"),
+		},
+		Mail: MailConfig{
+			SMTPHost:     p.getString("SMTP_HOST", ""),
+			SMTPPort:     p.getInt("SMTP_PORT", 587),
+			SMTPUsername: p.getString("SMTP_USERNAME", ""),
+			SMTPPassword: p.getString("SMTP_PASSWORD", ""),
+			From:         p.getString("MAIL_FROM", "GoAPI <no-reply@localhost>"),
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL: p.getDuration("PASSWORD_RESET_TTL", time.Hour),
+			URL:      p.getString("PASSWORD_RESET_URL", "http://localhost:3000/reset"),
+		},
+		MagicLink: MagicLinkConfig{
+			TokenTTL:    p.getDuration("MAGIC_LINK_TTL", 15*time.Minute),
+			URL:         p.getString("MAGIC_LINK_URL", "http://localhost:8080/api/v1/login/magic"),
+			MaxPerEmail: p.getInt("MAGIC_LINK_MAX_PER_EMAIL", 3),
+			Window:      p.getDuration("MAGIC_LINK_WINDOW", time.Hour),
+		},
+		Passkey: PasskeyConfig{
+			RPID:    p.getString("WEBAUTHN_RP_ID", "localhost"),
+			RPName:  p.getString("WEBAUTHN_RP_NAME", "GoAPI"),
+			Origins: splitList(p.getString("WEBAUTHN_ORIGINS", "http://localhost:3000")),
+			Timeout: p.getDuration("WEBAUTHN_TIMEOUT", 5*time.Minute),
+		},
+		SignedURL: SignedURLConfig{
+			MaxTTL: p.getDuration("SIGNED_URL_MAX_TTL", 7*24*time.Hour),
+		},
+		Features: FeaturesConfig{
+			Rollouts: rollouts,
+		},
+		Sources: p.sources,
+	}
+
+	if err := errors.Join(append(p.errs, cfg.Validate())...); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+// Validate checks value ranges and enumerations that parsing alone cannot catch
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.App.Env != "development" && c.App.Env != "production" {
+		errs = append(errs, fmt.Errorf("APP_ENV must be 'development' or 'production', got %q", c.App.Env))
+	}
+	if c.App.TimestampPrecision < 0 || c.App.TimestampPrecision > time.Second {
+		errs = append(errs, errors.New("TIMESTAMP_PRECISION must be between 0 and 1s"))
+	}
+	if c.App.IsProduction() && (c.Auth.JWTSecret == DefaultJWTSecret || len(c.Auth.JWTSecret) < 32) {
+		errs = append(errs, errors.New("JWT_SECRET must be set to a random value of at least 32 characters in production"))
+	}
+	if _, _, err := c.PII.Keys(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.App.IsProduction() && (c.PII.EncryptionKey == DefaultPIIEncryptionKey || c.PII.BlindIndexKey == DefaultPIIBlindIndexKey) {
+		errs = append(errs, errors.New("PII_ENCRYPTION_KEY and PII_BLIND_INDEX_KEY must be set in production"))
+	}
+	if c.Auth.TokenLeeway < 0 || c.Auth.TokenLeeway > 5*time.Minute {
+		errs = append(errs, errors.New("JWT_LEEWAY must be between 0 and 5m"))
+	}
+	if c.Auth.TokenTTL > c.Auth.TokenMaxLifetime {
+		errs = append(errs, errors.New("JWT_TTL must not exceed JWT_MAX_LIFETIME"))
+	}
+	if c.Server.Port == "" {
+		errs = append(errs, errors.New("SERVER_PORT must not be empty"))
+	}
+	if c.Auth.Mode != "header" && c.Auth.Mode != "cookie" {
+		errs = append(errs, fmt.Errorf("AUTH_MODE must be 'header' or 'cookie', got %q", c.Auth.Mode))
+	}
+	if c.DB.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS must be at least 1"))
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS"))
+	}
+	if c.DB.SchemaDrift != SchemaDriftOff && c.DB.SchemaDrift != SchemaDriftWarn && c.DB.SchemaDrift != SchemaDriftFail {
+		errs = append(errs, fmt.Errorf("DB_SCHEMA_DRIFT must be 'off', 'warn' or 'fail', got %q", c.DB.SchemaDrift))
+	}
+	if c.Redis.DB < 0 {
+		errs = append(errs, errors.New("REDIS_DB must not be negative"))
+	}
+	if c.RateLimit.GlobalRequests < 1 || c.RateLimit.AuthRequests < 1 || c.RateLimit.PlanDefaultRequests < 1 || c.RateLimit.DraftRequests < 1 {
+		errs = append(errs, errors.New("rate limits must be at least 1 request"))
+	}
+	if c.RateLimit.DeviceTokenIssue < 1 || c.RateLimit.DeviceTokenMaxPerIP < c.RateLimit.DeviceTokenIssue {
+		errs = append(errs, errors.New("RATE_LIMIT_DEVICE_TOKEN_ISSUE must be at least 1 and RATE_LIMIT_DEVICE_TOKEN_MAX_PER_IP at least RATE_LIMIT_DEVICE_TOKEN_ISSUE"))
+	}
+	if c.RateLimit.DeviceTokenTTL < time.Hour || c.RateLimit.DeviceTokenTTL > 24*time.Hour {
+		errs = append(errs, errors.New("RATE_LIMIT_DEVICE_TOKEN_TTL must be between 1h and 24h"))
+	}
+
+	for key, strategy := range map[string]string{
+		"RATE_LIMIT_GLOBAL_KEY": c.RateLimit.GlobalKey,
+		"RATE_LIMIT_AUTH_KEY":   c.RateLimit.AuthKey,
+		"RATE_LIMIT_PLAN_KEY":   c.RateLimit.PlanKey,
+	} {
+		switch strategy {
+		case "ip", "ip_route", "user", "user_route", "device", "device_route":
+		default:
+			errs = append(errs, fmt.Errorf("%s must be one of ip, ip_route, user, user_route, device, device_route, got %q", key, strategy))
+		}
+	}
+	// One IP can hold several device tokens, each with its own budget
+	if c.RateLimit.AuthKey == "device" || c.RateLimit.AuthKey == "device_route" {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_AUTH_KEY must not be a device strategy, got %q", c.RateLimit.AuthKey))
+	}
+	if c.Retention.PurgeAfterDays < 1 || c.Retention.PurgeBatchSize < 1 {
+		errs = append(errs, errors.New("SOFT_DELETE_RETENTION_DAYS and PURGE_BATCH_SIZE must be at least 1"))
+	}
+
+	if c.Avatar.Size < 1 || c.Avatar.Size > 2048 {
+		errs = append(errs, errors.New("AVATAR_SIZE must be between 1 and 2048"))
+	}
+	if c.Content.MaxTitleLength < 3 || c.Content.MaxContentLength < 1 {
+		errs = append(errs, errors.New("POST_MAX_TITLE_LENGTH must be at least 3 and POST_MAX_CONTENT_LENGTH at least 1"))
+	}
+	if c.Password.Algorithm != "argon2id" && c.Password.Algorithm != "bcrypt" {
+		errs = append(errs, fmt.Errorf("PASSWORD_HASH must be 'argon2id' or 'bcrypt', got %q", c.Password.Algorithm))
+	}
+	if c.Password.Argon2Memory < 8*1024 || c.Password.Argon2Iterations < 1 || c.Password.Argon2Parallelism < 1 || c.Password.Argon2Parallelism > 255 {
+		errs = append(errs, errors.New("ARGON2_MEMORY_KIB must be at least 8192, ARGON2_ITERATIONS at least 1 and ARGON2_PARALLELISM between 1 and 255"))
+	}
+	if c.Password.BcryptCost < 4 || c.Password.BcryptCost > 31 {
+		errs = append(errs, errors.New("BCRYPT_COST must be between 4 and 31"))
+	}
+	if _, err := regexp.Compile(c.Username.Pattern); err != nil {
+		errs = append(errs, fmt.Errorf("USERNAME_PATTERN is not a valid regular expression: %w", err))
+	}
+	if c.Content.ExcerptWords < 1 || c.Content.ExcerptWords > 500 {
+		errs = append(errs, errors.New("POST_EXCERPT_WORDS must be between 1 and 500"))
+	}
+	if c.Content.HTMLPolicy != "strip" && c.Content.HTMLPolicy != "deny" {
+		errs = append(errs, fmt.Errorf("POST_HTML_POLICY must be 'strip' or 'deny', got %q", c.Content.HTMLPolicy))
+	}
+	if c.Content.DuplicateAction != "reject" && c.Content.DuplicateAction != "warn" {
+		errs = append(errs, fmt.Errorf("POST_DUPLICATE_ACTION must be 'reject' or 'warn', got %q", c.Content.DuplicateAction))
+	}
+	switch c.UserDeletion.PostAction {
+	case "delete", "anonymize":
+	case "reassign":
+		if c.UserDeletion.ReassignTo == 0 {
+			errs = append(errs, errors.New("USER_DELETE_REASSIGN_TO must be set to a user ID when USER_DELETE_POSTS is 'reassign'"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("USER_DELETE_POSTS must be 'delete', 'reassign' or 'anonymize', got %q", c.UserDeletion.PostAction))
+	}
+	if c.UserDeletion.Grace < time.Hour || c.UserDeletion.Grace > 90*24*time.Hour {
+		errs = append(errs, errors.New("USER_DELETE_GRACE must be between 1h and 2160h"))
+	}
+	if c.UserDeletion.ReminderBefore < 0 || c.UserDeletion.ReminderBefore >= c.UserDeletion.Grace {
+		errs = append(errs, errors.New("USER_DELETE_REMINDER must be at least 0 and shorter than USER_DELETE_GRACE"))
+	}
+	if u, err := url.Parse(c.UserDeletion.RestoreURL); err != nil || !u.IsAbs() {
+		errs = append(errs, fmt.Errorf("USER_DELETE_RESTORE_URL must be an absolute URL, got %q", c.UserDeletion.RestoreURL))
+	}
+	if c.App.IsProduction() && c.Mail.SMTPHost == "" {
+		errs = append(errs, errors.New("SMTP_HOST must be set in production; without it emails, reset links included, are only logged"))
+	}
+	if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+		errs = append(errs, errors.New("SMTP_PORT must be between 1 and 65535"))
+	}
+	if c.PasswordReset.TokenTTL < 5*time.Minute || c.PasswordReset.TokenTTL > 24*time.Hour {
+		errs = append(errs, errors.New("PASSWORD_RESET_TTL must be between 5m and 24h"))
+	}
+	if u, err := url.Parse(c.PasswordReset.URL); err != nil || !u.IsAbs() {
+		errs = append(errs, fmt.Errorf("PASSWORD_RESET_URL must be an absolute URL, got %q", c.PasswordReset.URL))
+	}
+	if c.MagicLink.TokenTTL < 5*time.Minute || c.MagicLink.TokenTTL > time.Hour {
+		errs = append(errs, errors.New("MAGIC_LINK_TTL must be between 5m and 1h"))
+	}
+	if u, err := url.Parse(c.MagicLink.URL); err != nil || !u.IsAbs() {
+		errs = append(errs, fmt.Errorf("MAGIC_LINK_URL must be an absolute URL, got %q", c.MagicLink.URL))
+	}
+	if c.MagicLink.MaxPerEmail < 1 || c.MagicLink.Window < time.Minute {
+		errs = append(errs, errors.New("MAGIC_LINK_MAX_PER_EMAIL must be at least 1 and MAGIC_LINK_WINDOW at least 1m"))
+	}
+	if c.Passkey.RPID == "" || strings.ContainsAny(c.Passkey.RPID, ":/") {
+		errs = append(errs, fmt.Errorf("WEBAUTHN_RP_ID must be a domain without scheme or port, got %q", c.Passkey.RPID))
+	}
+	if c.Passkey.RPName == "" {
+		errs = append(errs, errors.New("WEBAUTHN_RP_NAME must not be empty"))
+	}
+	if len(c.Passkey.Origins) == 0 {
+		errs = append(errs, errors.New("WEBAUTHN_ORIGINS must list at least one origin"))
+	}
+	for _, origin := range c.Passkey.Origins {
+		if u, err := url.Parse(origin); err != nil || !u.IsAbs() || u.Host == "" {
+			errs = append(errs, fmt.Errorf("WEBAUTHN_ORIGINS must hold absolute origins like https://example.com, got %q", origin))
+		}
+	}
+	if c.Passkey.Timeout < 30*time.Second || c.Passkey.Timeout > 10*time.Minute {
+		errs = append(errs, errors.New("WEBAUTHN_TIMEOUT must be between 30s and 10m"))
+	}
+	if c.SignedURL.MaxTTL < time.Minute || c.SignedURL.MaxTTL > 30*24*time.Hour {
+		errs = append(errs, errors.New("SIGNED_URL_MAX_TTL must be between 1m and 720h"))
+	}
+	if c.Cache.Namespace == "" || strings.ContainsAny(c.Cache.Namespace, "*?[]: ") {
+		errs = append(errs, errors.New("CACHE_NAMESPACE must be non-empty and contain no glob characters, colons or spaces"))
+	}
+	if c.Cache.CompressThreshold < 0 {
+		errs = append(errs, errors.New("CACHE_COMPRESS_THRESHOLD must not be negative"))
+	}
+	if c.Cache.JitterPercent < 0 || c.Cache.JitterPercent > 50 {
+		errs = append(errs, errors.New("CACHE_TTL_JITTER_PERCENT must be between 0 and 50"))
+	}
+	if c.Cache.NegativeTTL < 0 {
+		errs = append(errs, errors.New("CACHE_NEGATIVE_TTL must not be negative"))
+	}
+	if c.Cache.MemoryBudget < 0 {
+		errs = append(errs, errors.New("CACHE_MEMORY_BUDGET_MB must not be negative"))
+	}
+	if c.Cache.WarmupUsers < 0 || c.Cache.WarmupUsers > 1000 {
+		errs = append(errs, errors.New("CACHE_WARMUP_USERS must be between 0 and 1000"))
+	}
+	if c.DataLoader.Wait < 0 || c.DataLoader.Wait > 100*time.Millisecond {
+		errs = append(errs, errors.New("DATALOADER_WAIT must be between 0 and 100ms"))
+	}
+	if c.DataLoader.BatchCapacity < 1 {
+		errs = append(errs, errors.New("DATALOADER_BATCH_CAPACITY must be at least 1"))
+	}
+	if c.Signup.MaxPerIP < 1 {
+		errs = append(errs, errors.New("SIGNUP_MAX_PER_IP must be at least 1"))
+	}
+	if c.Signup.MinFillTime < 0 {
+		errs = append(errs, errors.New("SIGNUP_MIN_FILL_TIME must not be negative"))
+	}
+	if c.HTTPClient.MaxRetries < 0 || c.HTTPClient.BreakerThreshold < 0 {
+		errs = append(errs, errors.New("HTTP_CLIENT_MAX_RETRIES and HTTP_CLIENT_BREAKER_THRESHOLD must not be negative"))
+	}
+
+	positive := map[string]time.Duration{
+		"SERVER_READ_TIMEOUT":     c.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":    c.Server.WriteTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT": c.Server.ShutdownTimeout,
+		"JWT_TTL":                 c.Auth.TokenTTL,
+		"JWT_MAX_LIFETIME":        c.Auth.TokenMaxLifetime,
+		"SIGNATURE_MAX_SKEW":      c.Auth.SignatureMaxSkew,
+		"RATE_LIMIT_PERIOD":       c.RateLimit.Period,
+		"CACHE_TTL":               c.Cache.TTL,
+		"CACHE_USER_TTL":          c.Cache.UserTTL,
+		"CACHE_POST_TTL":          c.Cache.PostTTL,
+		"CACHE_AUTH_STATE_TTL":    c.Cache.AuthStateTTL,
+		"CACHE_LEADERBOARD_TTL":   c.Cache.LeaderboardTTL,
+		"CACHE_LIST_TTL":          c.Cache.ListTTL,
+		"CACHE_USAGE_INTERVAL":    c.Cache.UsageInterval,
+		"CACHE_WARMUP_TIMEOUT":    c.Cache.WarmupTimeout,
+		"HTTP_CLIENT_TIMEOUT":     c.HTTPClient.Timeout,
+		"AVATAR_CACHE_TTL":        c.Avatar.CacheTTL,
+		"SIGNUP_WINDOW":           c.Signup.Window,
+		"POST_DUPLICATE_WINDOW":   c.Content.DuplicateWindow,
+	}
+	for key, value := range positive {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration", key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// envParser reads typed environment variables, collecting parse errors instead of failing on the first one.
+// It records the source of every variable it reads.
+type envParser struct {
+	errs    []error
+	dotenv  map[string]string
+	sources map[string]string
+}
+
+// lookup returns the value of key, or "" when unset, and records its source
+func (p *envParser) lookup(key string) string {
+	value := os.Getenv(key)
+	switch {
+	case value == "":
+		p.sources[key] = SourceDefault
+	case p.dotenv[key] == value:
+		p.sources[key] = SourceDotenv
+	default:
+		p.sources[key] = SourceEnvironment
+	}
+	return value
+}
+
+func (p *envParser) getString(key, defaultValue string) string {
+	if value := p.lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
+func (p *envParser) getInt(key string, defaultValue int) int {
+	value := p.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+func (p *envParser) getBool(key string, defaultValue bool) bool {
+	value := p.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s must be a boolean, got %q", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+func (p *envParser) getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := p.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s must be a duration like 30s or 5m, got %q", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// splitList parses a comma-separated list, dropping blanks
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAPIKeys parses "key_id:secret,key_id2:secret2" into a key ID -> secret map
+func parseAPIKeys(raw string) map[string]string {
+	secrets := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && id != "" && secret != "" {
+			secrets[id] = secret
+		}
+	}
+	return secrets
+}
+
 func InitDB(cfg *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+	// Encrypted columns are read and written through the pii serializer, so its keys
+	// must be installed before the first query
+	encryptionKey, indexKey, err := cfg.PII.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if err := pii.Init(encryptionKey, indexKey); err != nil {
+		return nil, err
+	}
+
+	// Sessions run in UTC so SQL date functions agree with the UTC times GORM writes
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+		cfg.DB.Host, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.Port)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Outside production, queries that would outlive a canceled request fail loudly
+	if !cfg.App.IsProduction() {
+		if err := utils.RequireContext(db); err != nil {
+			return nil, err
+		}
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
 
-	log.Println("✅ Database connected successfully")
 	return db, nil
-}
\ No newline at end of file
+}
+
+// LogValue implements slog.LogValuer so the config can be logged with secrets redacted
+func (c *Config) LogValue() slog.Value {
+	apiKeyIDs := make([]string, 0, len(c.Auth.APIKeys))
+	for id := range c.Auth.APIKeys {
+		apiKeyIDs = append(apiKeyIDs, id)
+	}
+
+	return slog.GroupValue(
+		slog.Group("app",
+			slog.String("env", c.App.Env),
+			slog.Duration("timestamp_precision", c.App.TimestampPrecision),
+		),
+		slog.Group("server",
+			slog.String("port", c.Server.Port),
+			slog.Duration("read_timeout", c.Server.ReadTimeout),
+			slog.Duration("write_timeout", c.Server.WriteTimeout),
+			slog.Duration("shutdown_timeout", c.Server.ShutdownTimeout),
+		),
+		slog.Group("db",
+			slog.String("host", c.DB.Host),
+			slog.String("port", c.DB.Port),
+			slog.String("user", c.DB.User),
+			slog.String("password", redact(c.DB.Password)),
+			slog.String("name", c.DB.Name),
+			slog.Int("max_open_conns", c.DB.MaxOpenConns),
+			slog.Int("max_idle_conns", c.DB.MaxIdleConns),
+			slog.Duration("conn_max_lifetime", c.DB.ConnMaxLifetime),
+			slog.String("schema_drift", c.DB.SchemaDrift),
+		),
+		slog.Group("redis",
+			slog.String("host", c.Redis.Host),
+			slog.String("port", c.Redis.Port),
+			slog.String("password", redact(c.Redis.Password)),
+			slog.Int("db", c.Redis.DB),
+		),
+		slog.Group("auth",
+			slog.String("jwt_secret", redact(c.Auth.JWTSecret)),
+			slog.Duration("token_ttl", c.Auth.TokenTTL),
+			slog.String("issuer", c.Auth.Issuer),
+			slog.String("audience", c.Auth.Audience),
+			slog.Duration("token_leeway", c.Auth.TokenLeeway),
+			slog.Duration("token_max_lifetime", c.Auth.TokenMaxLifetime),
+			slog.Bool("fresh_user_state", c.Auth.FreshUserState),
+			slog.String("mode", c.Auth.Mode),
+			slog.String("cookie_domain", c.Auth.CookieDomain),
+			slog.Bool("cookie_secure", c.Auth.CookieSecure),
+			slog.Any("api_key_ids", apiKeyIDs),
+			slog.Duration("signature_max_skew", c.Auth.SignatureMaxSkew),
+		),
+		slog.Group("rate_limit",
+			slog.Int("global", c.RateLimit.GlobalRequests),
+			slog.Int("auth", c.RateLimit.AuthRequests),
+			slog.Int("plan_default", c.RateLimit.PlanDefaultRequests),
+			slog.Int("draft", c.RateLimit.DraftRequests),
+			slog.Duration("period", c.RateLimit.Period),
+			slog.String("global_key", c.RateLimit.GlobalKey),
+			slog.String("auth_key", c.RateLimit.AuthKey),
+			slog.String("plan_key", c.RateLimit.PlanKey),
+			slog.Bool("device_tokens", c.RateLimit.DeviceTokens),
+			slog.Int("device_token_issue", c.RateLimit.DeviceTokenIssue),
+			slog.Int("device_token_max_per_ip", c.RateLimit.DeviceTokenMaxPerIP),
+			slog.Duration("device_token_ttl", c.RateLimit.DeviceTokenTTL),
+		),
+		slog.Group("cache",
+			slog.Duration("ttl", c.Cache.TTL),
+			slog.Duration("user_ttl", c.Cache.UserTTL),
+			slog.Duration("post_ttl", c.Cache.PostTTL),
+			slog.Duration("auth_state_ttl", c.Cache.AuthStateTTL),
+			slog.Duration("leaderboard_ttl", c.Cache.LeaderboardTTL),
+			slog.Int("jitter_percent", c.Cache.JitterPercent),
+			slog.String("namespace", c.Cache.Namespace),
+			slog.Int("compress_threshold", c.Cache.CompressThreshold),
+			slog.Duration("negative_ttl", c.Cache.NegativeTTL),
+			slog.Duration("list_ttl", c.Cache.ListTTL),
+			slog.Int64("memory_budget", c.Cache.MemoryBudget),
+			slog.Duration("usage_interval", c.Cache.UsageInterval),
+			slog.Bool("warmup", c.Cache.Warmup),
+			slog.Duration("warmup_timeout", c.Cache.WarmupTimeout),
+			slog.Int("warmup_users", c.Cache.WarmupUsers),
+			slog.Bool("user_repository", c.Cache.UserRepository),
+		),
+		slog.Group("billing",
+			slog.String("stripe_secret_key", redact(c.Billing.StripeSecretKey)),
+			slog.String("stripe_webhook_secret", redact(c.Billing.StripeWebhookSecret)),
+			slog.String("stripe_pro_price_id", c.Billing.StripeProPriceID),
+			slog.String("success_url", c.Billing.SuccessURL),
+			slog.String("cancel_url", c.Billing.CancelURL),
+		),
+		slog.Group("http_client",
+			slog.Duration("timeout", c.HTTPClient.Timeout),
+			slog.Int("max_retries", c.HTTPClient.MaxRetries),
+			slog.Duration("retry_backoff", c.HTTPClient.RetryBackoff),
+			slog.Int("breaker_threshold", c.HTTPClient.BreakerThreshold),
+			slog.Duration("breaker_cooldown", c.HTTPClient.BreakerCooldown),
+		),
+		slog.Group("avatar",
+			slog.Int("size", c.Avatar.Size),
+			slog.String("fallback", c.Avatar.Fallback),
+			slog.Duration("cache_ttl", c.Avatar.CacheTTL),
+		),
+		slog.Group("signup",
+			slog.Duration("min_fill_time", c.Signup.MinFillTime),
+			slog.Int("max_per_ip", c.Signup.MaxPerIP),
+			slog.Duration("window", c.Signup.Window),
+		),
+		slog.Group("username",
+			slog.Any("reserved", c.Username.Reserved),
+			slog.String("pattern", c.Username.Pattern),
+		),
+		slog.Group("password",
+			slog.String("algorithm", c.Password.Algorithm),
+			slog.Int("argon2_memory_kib", c.Password.Argon2Memory),
+			slog.Int("argon2_iterations", c.Password.Argon2Iterations),
+			slog.Int("argon2_parallelism", c.Password.Argon2Parallelism),
+			slog.Int("bcrypt_cost", c.Password.BcryptCost),
+		),
+		slog.Group("dataloader",
+			slog.Duration("wait", c.DataLoader.Wait),
+			slog.Int("batch_capacity", c.DataLoader.BatchCapacity),
+			slog.Bool("cache", c.DataLoader.Cache),
+		),
+		slog.Group("pii",
+			slog.String("encryption_key", redact(c.PII.EncryptionKey)),
+			slog.String("blind_index_key", redact(c.PII.BlindIndexKey)),
+		),
+		slog.Group("content",
+			slog.Int("max_title_length", c.Content.MaxTitleLength),
+			slog.Int("max_content_length", c.Content.MaxContentLength),
+			slog.String("html_policy", c.Content.HTMLPolicy),
+			slog.Duration("duplicate_window", c.Content.DuplicateWindow),
+			slog.String("duplicate_action", c.Content.DuplicateAction),
+			slog.Int("excerpt_words", c.Content.ExcerptWords),
+		),
+		slog.Group("retention",
+			slog.Int("purge_after_days", c.Retention.PurgeAfterDays),
+			slog.Int("purge_batch_size", c.Retention.PurgeBatchSize),
+		),
+		slog.Group("mail",
+			slog.String("smtp_host", c.Mail.SMTPHost),
+			slog.Int("smtp_port", c.Mail.SMTPPort),
+			slog.String("smtp_username", c.Mail.SMTPUsername),
+			slog.String("smtp_password", redact(c.Mail.SMTPPassword)),
+			slog.String("from", c.Mail.From),
+		),
+		slog.Group("password_reset",
+			slog.Duration("token_ttl", c.PasswordReset.TokenTTL),
+			slog.String("url", c.PasswordReset.URL),
+		),
+		slog.Group("magic_link",
+			slog.Duration("token_ttl", c.MagicLink.TokenTTL),
+			slog.String("url", c.MagicLink.URL),
+			slog.Int("max_per_email", c.MagicLink.MaxPerEmail),
+			slog.Duration("window", c.MagicLink.Window),
+		),
+		slog.Group("passkey",
+			slog.String("rp_id", c.Passkey.RPID),
+			slog.String("rp_name", c.Passkey.RPName),
+			slog.Any("origins", c.Passkey.Origins),
+			slog.Duration("timeout", c.Passkey.Timeout),
+		),
+		slog.Group("signed_url",
+			slog.Duration("max_ttl", c.SignedURL.MaxTTL),
+		),
+		slog.Group("user_deletion",
+			slog.String("post_action", c.UserDeletion.PostAction),
+			slog.Uint64("reassign_to", uint64(c.UserDeletion.ReassignTo)),
+			slog.Duration("grace", c.UserDeletion.Grace),
+			slog.Duration("reminder_before", c.UserDeletion.ReminderBefore),
+			slog.String("restore_url", c.UserDeletion.RestoreURL),
+		),
+		slog.Any("features", c.Features.Rollouts),
+	)
+}
+
+// Report is the effective configuration as served to admins: the values LogValue logs,
+// secrets redacted, with the source of every variable and the ones set explicitly
+type Report struct {
+	Config    map[string]any    `json:"config"`
+	Sources   map[string]string `json:"sources"`
+	Overrides []string          `json:"overrides"`
+}
+
+// Report builds the admin view of c. It goes through LogValue, so a setting added to
+// the log is reported too, and redacted the same way.
+func (c *Config) Report() Report {
+	overrides := make([]string, 0)
+	for key, source := range c.Sources {
+		if source != SourceDefault {
+			overrides = append(overrides, key)
+		}
+	}
+	sort.Strings(overrides)
+	return Report{Config: groupMap(c.LogValue().Group()), Sources: c.Sources, Overrides: overrides}
+}
+
+// groupMap converts slog attributes to nested maps; durations become "30s" strings
+func groupMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindGroup:
+			m[attr.Key] = groupMap(value.Group())
+		case slog.KindDuration:
+			m[attr.Key] = value.Duration().String()
+		default:
+			m[attr.Key] = value.Any()
+		}
+	}
+	return m
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+	"goapi/pkg/pii"
+	"goapi/pkg/utctime"
+
+	"gorm.io/gorm"
+)
+
+// SchemaVersion must be bumped whenever a model change requires a migration
+const SchemaVersion = 25
+
+// migrationLockKey identifies the advisory lock held while migrating. It is below 2^32,
+// so pg_locks reports it as classid 0, objid migrationLockKey.
+const migrationLockKey = 72616001
+
+// Migrate auto-migrates all models and records the applied schema version. Instances
+// starting together migrate one at a time: each holds a session-level advisory lock for
+// the whole run and records itself in migration_locks, which /readyz reports. Canceling
+// ctx aborts the run, including a wait for another instance's lock.
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	if err := db.AutoMigrate(&models.MigrationLock{}); err != nil {
+		return err
+	}
+
+	// Session-level advisory locks belong to one connection, so pin one for the run
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec(`SELECT pg_advisory_lock(?)`, migrationLockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec(`SELECT pg_advisory_unlock(?)`, migrationLockKey)
+
+		host, _ := os.Hostname()
+		run := models.MigrationLock{ID: 1, Version: SchemaVersion, Holder: fmt.Sprintf("%s:%d", host, os.Getpid()), StartedAt: utctime.Now()}
+		if err := conn.Save(&run).Error; err != nil {
+			return err
+		}
+
+		if err := migrate(conn); err != nil {
+			return err
+		}
+
+		return conn.Model(&run).Update("finished_at", utctime.Now()).Error
+	})
+}
+
+// migratedModels are the models migrate keeps in sync with their tables
+var migratedModels = []interface{}{
+	&models.SchemaMigration{},
+	&models.User{},
+	&models.Tag{},
+	&models.Category{},
+	&models.Post{},
+	&models.Plan{},
+	&models.Subscription{},
+	&models.UsageEvent{},
+	&models.UsageRollup{},
+	&models.LoginEvent{},
+	&models.PostDraft{},
+	&models.PostTranslation{},
+	&models.PasswordResetToken{},
+	&models.MagicLinkToken{},
+	&models.Passkey{},
+	&models.Permission{},
+	&models.RolePermission{},
+}
+
+func migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(migratedModels...)
+	if err != nil {
+		return err
+	}
+	if err := ensurePostSearchVector(db); err != nil {
+		return err
+	}
+	if err := ensureUserSearchIndexes(db); err != nil {
+		return err
+	}
+	if err := backfillReadingStats(db); err != nil {
+		return err
+	}
+	if err := backfillExcerpts(db); err != nil {
+		return err
+	}
+	if err := encryptEmails(db); err != nil {
+		return err
+	}
+	if err := ensureEmailIndex(db); err != nil {
+		return err
+	}
+
+	return db.Where(models.SchemaMigration{Version: SchemaVersion}).
+		Attrs(models.SchemaMigration{AppliedAt: utctime.Now()}).
+		FirstOrCreate(&models.SchemaMigration{}).Error
+}
+
+// CurrentSchemaVersion returns the latest schema version applied to the database
+func CurrentSchemaVersion(db *gorm.DB) (int, error) {
+	var migration models.SchemaMigration
+	if err := db.Order("version DESC").First(&migration).Error; err != nil {
+		return 0, err
+	}
+	return migration.Version, nil
+}
+
+// encryptEmails encrypts emails still stored in plaintext and fills their blind index,
+// in batches so a large table is not locked in one transaction. Rows are read as raw
+// columns to bypass the serializer and written with plain SQL so nothing is encrypted twice.
+func encryptEmails(db *gorm.DB) error {
+	const batchSize = 500
+
+	for {
+		var rows []struct {
+			ID    uint
+			Email string
+		}
+		err := db.Table("users").Select("id, email").
+			Where("email_index IS NULL OR email_index = ''").
+			Order("id").Limit(batchSize).Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				plaintext, err := pii.Decrypt(row.Email)
+				if err != nil {
+					return err
+				}
+				encrypted, err := pii.Encrypt(plaintext)
+				if err != nil {
+					return err
+				}
+				index, err := pii.BlindIndex(models.NormalizeEmail(plaintext))
+				if err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE users SET email = ?, email_index = ? WHERE id = ?`, encrypted, index, row.ID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info("Encrypted user emails", "count", len(rows))
+	}
+}
+
+// backfillReadingStats computes the word count and reading time of posts written
+// before they were stored. It mirrors services.applyReadingStats (200 words/minute).
+func backfillReadingStats(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE posts SET word_count = w.n, reading_time_minutes = (w.n + 199) / 200
+		FROM (
+			SELECT id, cardinality(regexp_split_to_array(btrim(content), '\s+')) AS n
+			FROM posts
+			WHERE word_count = 0 AND btrim(coalesce(content, '')) <> ''
+		) w
+		WHERE posts.id = w.id
+	`).Error
+}
+
+// backfillExcerpts fills the excerpt of posts written before excerpts were stored. It
+// mirrors services.ContentPolicy.Excerpt with DefaultExcerptWords; stored content is
+// already free of markup.
+func backfillExcerpts(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE posts SET excerpt = array_to_string(w.words[1:?], ' ') ||
+			CASE WHEN cardinality(w.words) > ? THEN '…' ELSE '' END
+		FROM (
+			SELECT id, regexp_split_to_array(btrim(content), '\s+') AS words
+			FROM posts
+			WHERE excerpt = '' AND btrim(coalesce(content, '')) <> ''
+		) w
+		WHERE posts.id = w.id
+	`, DefaultExcerptWords, DefaultExcerptWords).Error
+}
+
+// MigrationState compares the database schema with the version this build expects
+type MigrationState struct {
+	ExpectedVersion int `json:"expected_version"`
+	AppliedVersion  int `json:"applied_version"`
+	// Running is true while any instance holds the migration lock
+	Running bool                  `json:"running"`
+	LastRun *models.MigrationLock `json:"last_run,omitempty"`
+}
+
+// Ready reports whether the schema matches this build and no migration is in progress
+func (s MigrationState) Ready() bool {
+	return !s.Running && s.AppliedVersion == s.ExpectedVersion
+}
+
+// CurrentMigrationState reads the applied schema version and whether a migration is running
+func CurrentMigrationState(db *gorm.DB) (MigrationState, error) {
+	state := MigrationState{ExpectedVersion: SchemaVersion}
+
+	err := db.Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND classid = 0 AND objid = ? AND objsubid = 1 AND granted
+		)
+	`, migrationLockKey).Scan(&state.Running).Error
+	if err != nil {
+		return state, err
+	}
+
+	var run models.MigrationLock
+	if err := db.Limit(1).Find(&run, 1).Error; err != nil {
+		return state, err
+	}
+	if run.ID != 0 {
+		state.LastRun = &run
+	}
+
+	// No row yet means the first migration has not finished
+	var applied []int
+	if err := db.Model(&models.SchemaMigration{}).Order("version DESC").Limit(1).Pluck("version", &applied).Error; err != nil {
+		return state, err
+	}
+	if len(applied) > 0 {
+		state.AppliedVersion = applied[0]
+	}
+	return state, nil
+}
+
+// emailDuplicate is a group of live accounts sharing a normalized email
+type emailDuplicate struct {
+	EmailIndex string
+	IDs        string
+}
+
+// ensureEmailIndex enforces case-insensitive email uniqueness with a unique index on
+// the blind index. Existing duplicate accounts would make the index fail, so they are
+// reported and the index is skipped until they are merged or renamed. The indexes on
+// the email column itself are dropped: ciphertext is never compared.
+func ensureEmailIndex(db *gorm.DB) error {
+	for _, index := range []string{"idx_users_email", "idx_users_email_lower"} {
+		if err := db.Exec(`DROP INDEX IF EXISTS ` + index).Error; err != nil {
+			return err
+		}
+	}
+
+	var duplicates []emailDuplicate
+	err := db.Raw(`
+		SELECT email_index, string_agg(id::text, ',' ORDER BY id) AS ids
+		FROM users
+		WHERE deleted_at IS NULL
+		GROUP BY email_index
+		HAVING count(*) > 1
+	`).Scan(&duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) > 0 {
+		for _, d := range duplicates {
+			logger.Warn("Accounts share the same email", "user_ids", d.IDs)
+		}
+		logger.Warn("Skipping unique email index until duplicate accounts are resolved", "duplicates", len(duplicates))
+		return nil
+	}
+
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users (email_index) WHERE deleted_at IS NULL`).Error
+}
+
+// ensurePostSearchVector stores the full-text document of each post in the generated
+// column search_vector, which Postgres keeps current on every write, and indexes it for
+// search and related posts. Adding the column rewrites the table once. The expression
+// index it replaces is dropped.
+func ensurePostSearchVector(db *gorm.DB) error {
+	for _, statement := range []string{
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (` + repository.PostSearchDocument + `) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+		`DROP INDEX IF EXISTS idx_posts_search`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureUserSearchIndexes adds the trigram indexes behind the ILIKE search of GET /users?q=.
+// pg_trgm needs a privileged role on some hosts; without it the search still works,
+// scanning the table, so the indexes are skipped with a warning instead of failing.
+func ensureUserSearchIndexes(db *gorm.DB) error {
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
+		logger.Warn("Skipping user search indexes, pg_trgm is unavailable", "error", err)
+		return nil
+	}
+	for _, column := range []string{"username", "full_name"} {
+		index := "idx_users_" + column + "_trgm"
+		if err := db.Exec(`CREATE INDEX IF NOT EXISTS ` + index + ` ON users USING GIN (` + column + ` gin_trgm_ops)`).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,21 +3,21 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/redis/go-redis/v9"
 )
 
 func InitRedis(cfg *Config) (*redis.Client, error) {
-	redisAddr := fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort)
+	redisAddr := fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port)
 	client := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
+		Addr:     redisAddr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
 
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, err
 	}
 
-	log.Println("✅ Redis connected successfully")
 	return client, nil
 }
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"goapi/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// rawColumns are the columns migrate adds with plain SQL, outside the models
+var rawColumns = map[string][]string{"posts": {"search_vector"}}
+
+// rawIndexes are the indexes migrate always creates with plain SQL. The unique email
+// index and the trigram indexes are left out: migrate skips them, with a warning, when
+// duplicate accounts exist or pg_trgm is unavailable.
+var rawIndexes = map[string][]string{"posts": {"idx_posts_search_vector"}}
+
+// CheckSchema compares the live schema with SchemaVersion and the models, and returns
+// one line per difference. It runs after Migrate, which adds whatever is missing, so what
+// it finds is drift AutoMigrate does not repair: a schema applied by a newer build,
+// columns added or dropped by hand, constraints changed behind its back, lost indexes.
+func CheckSchema(ctx context.Context, db *gorm.DB) ([]string, error) {
+	db = db.WithContext(ctx)
+	var drift []string
+
+	version, err := CurrentSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version != SchemaVersion {
+		drift = append(drift, fmt.Sprintf("schema version %d is applied, this build expects %d", version, SchemaVersion))
+	}
+
+	migrator := db.Migrator()
+	for _, model := range append([]interface{}{&models.MigrationLock{}}, migratedModels...) {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		columns, err := migrator.ColumnTypes(model)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, diffColumns(stmt.Schema, columns)...)
+
+		indexes := slices.Clone(rawIndexes[stmt.Schema.Table])
+		for name := range stmt.Schema.ParseIndexes() {
+			indexes = append(indexes, name)
+		}
+		sort.Strings(indexes)
+		for _, index := range indexes {
+			if !migrator.HasIndex(model, index) {
+				drift = append(drift, fmt.Sprintf("%s: index %s is missing", stmt.Schema.Table, index))
+			}
+		}
+	}
+	return drift, nil
+}
+
+// diffColumns compares the fields of model with the live columns of its table
+func diffColumns(model *schema.Schema, columns []gorm.ColumnType) []string {
+	table := model.Table
+	var drift []string
+
+	live := make(map[string]gorm.ColumnType, len(columns))
+	for _, column := range columns {
+		live[column.Name()] = column
+		if field := model.FieldsByDBName[column.Name()]; (field == nil || field.IgnoreMigration) && !slices.Contains(rawColumns[table], column.Name()) {
+			drift = append(drift, fmt.Sprintf("%s.%s is not in the model", table, column.Name()))
+		}
+	}
+
+	for _, name := range model.DBNames {
+		field := model.FieldsByDBName[name]
+		if field.IgnoreMigration {
+			continue
+		}
+		column, ok := live[name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s.%s is missing", table, name))
+			continue
+		}
+		notNull := field.NotNull || field.PrimaryKey
+		if nullable, ok := column.Nullable(); ok && nullable == notNull {
+			want := "nullable"
+			if notNull {
+				want = "NOT NULL"
+			}
+			drift = append(drift, fmt.Sprintf("%s.%s should be %s", table, name, want))
+		}
+	}
+	return drift
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"database/sql"
+	"slices"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type driftModel struct {
+	ID    uint   `gorm:"primaryKey"`
+	Title string `gorm:"not null"`
+	Note  *string
+}
+
+func liveColumn(name string, nullable bool) gorm.ColumnType {
+	return migrator.ColumnType{
+		NameValue:     sql.NullString{String: name, Valid: true},
+		NullableValue: sql.NullBool{Bool: nullable, Valid: true},
+	}
+}
+
+func TestDiffColumns(t *testing.T) {
+	model, err := schema.Parse(&driftModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		columns []gorm.ColumnType
+		want    []string
+	}{
+		{"in sync", []gorm.ColumnType{liveColumn("id", false), liveColumn("title", false), liveColumn("note", true)}, nil},
+		{"column added by hand", []gorm.ColumnType{liveColumn("id", false), liveColumn("title", false), liveColumn("note", true), liveColumn("legacy_flag", true)},
+			[]string{"drift_models.legacy_flag is not in the model"}},
+		{"column dropped", []gorm.ColumnType{liveColumn("id", false), liveColumn("title", false)},
+			[]string{"drift_models.note is missing"}},
+		{"constraints changed", []gorm.ColumnType{liveColumn("id", false), liveColumn("title", true), liveColumn("note", false)},
+			[]string{"drift_models.title should be NOT NULL", "drift_models.note should be nullable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffColumns(model, tt.columns); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/i18n"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminUserHandler serves /admin/users; the admin group already requires the admin role
+type AdminUserHandler struct {
+	service services.UserService
+	resets  services.PasswordResetService
+}
+
+func NewAdminUserHandler(service services.UserService, resets services.PasswordResetService) *AdminUserHandler {
+	return &AdminUserHandler{service: service, resets: resets}
+}
+
+// Ban deactivates a user and revokes their tokens. Body: optional {"reason": "..."}, logged.
+func (h *AdminUserHandler) Ban(c *gin.Context) {
+	id, ok := h.targetID(c, "ban")
+	if !ok {
+		return
+	}
+	var req models.BanUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	user, err := h.service.Ban(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		adminUserError(c, "Failed to ban user", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User banned", user)
+}
+
+// Unban reactivates a banned user; they log in again with their password
+func (h *AdminUserHandler) Unban(c *gin.Context) {
+	id, ok := h.targetID(c, "unban")
+	if !ok {
+		return
+	}
+
+	user, err := h.service.Unban(c.Request.Context(), id)
+	if err != nil {
+		adminUserError(c, "Failed to unban user", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User unbanned", user)
+}
+
+// ForcePasswordReset invalidates the user's password and sessions and emails them a
+// reset link in the default language
+func (h *AdminUserHandler) ForcePasswordReset(c *gin.Context) {
+	id, ok := h.targetID(c, "reset the password of")
+	if !ok {
+		return
+	}
+
+	user, err := h.service.ForcePasswordReset(c.Request.Context(), id)
+	if err != nil {
+		adminUserError(c, "Failed to force password reset", err)
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		if err := h.resets.Forgot(ctx, user.Email, i18n.DefaultLanguage); err != nil {
+			logger.FromContext(ctx).Error("Password reset email failed", "user_id", user.ID, "error", err)
+		}
+	}()
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Password reset forced, a reset link is being sent", user)
+}
+
+// ChangeRole sets a user's role. Body: {"role": "admin" | "user"}.
+func (h *AdminUserHandler) ChangeRole(c *gin.Context) {
+	id, ok := h.targetID(c, "change the role of")
+	if !ok {
+		return
+	}
+	var req models.ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	user, err := h.service.ChangeRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		adminUserError(c, "Failed to change role", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Role changed", user)
+}
+
+// GetDeleted pages through soft-deleted users awaiting the retention purge
+func (h *AdminUserHandler) GetDeleted(c *gin.Context) {
+	var page models.PageRequest
+	if err := c.ShouldBindQuery(&page); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid pagination", err.Error())
+		return
+	}
+
+	users, info, err := h.service.GetDeleted(c.Request.Context(), page)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get deleted users", err.Error())
+		return
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, "Deleted users retrieved successfully", users, pageMeta(page, info))
+}
+
+// List pages through all users, including soft-deleted ones awaiting the retention purge
+func (h *AdminUserHandler) List(c *gin.Context) {
+	var page models.PageRequest
+	if err := c.ShouldBindQuery(&page); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid pagination", err.Error())
+		return
+	}
+
+	users, info, err := h.service.ListAll(c.Request.Context(), page)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get users", err.Error())
+		return
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, "Users retrieved successfully", users, pageMeta(page, info))
+}
+
+// Restore undoes a soft delete before the retention purge. The user logs in again
+// with their old password.
+func (h *AdminUserHandler) Restore(c *gin.Context) {
+	id, ok := h.targetID(c, "restore")
+	if !ok {
+		return
+	}
+
+	user, err := h.service.Restore(c.Request.Context(), id)
+	if err != nil {
+		adminUserError(c, "Failed to restore user", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User restored", user)
+}
+
+// targetID parses :id and refuses actions on the admin's own account, so an admin
+// cannot lock themself out. It reports false after writing an error response.
+func (h *AdminUserHandler) targetID(c *gin.Context, action string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return 0, false
+	}
+	if uint(id) == c.GetUint("user_id") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", "you cannot "+action+" your own account")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func adminUserError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, services.ErrVersionConflict), errors.Is(err, services.ErrEmailInUse), errors.Is(err, services.ErrUsernameInUse):
+		utils.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BillingHandler struct {
+	service services.BillingService
+}
+
+func NewBillingHandler(service services.BillingService) *BillingHandler {
+	return &BillingHandler{service: service}
+}
+
+// GetPlans lists the available subscription plans
+func (h *BillingHandler) GetPlans(c *gin.Context) {
+	plans, err := h.service.GetPlans(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get plans", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Plans retrieved successfully", plans)
+}
+
+// CreateCheckout starts a Stripe checkout session for the current user
+func (h *BillingHandler) CreateCheckout(c *gin.Context) {
+	var req models.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "user not authenticated")
+		return
+	}
+
+	checkout, err := h.service.CreateCheckout(c.Request.Context(), userID.(uint), req.Plan)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create checkout", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Checkout session created", checkout)
+}
+
+// Webhook receives Stripe events; the raw body is required for signature verification
+func (h *BillingHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<16))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid payload", err.Error())
+		return
+	}
+
+	if err := h.service.HandleWebhook(c.Request.Context(), payload, c.GetHeader("Stripe-Signature")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Webhook processing failed", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook processed", nil)
+}
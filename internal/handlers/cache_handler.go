@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	cache *cache.Cache
+	// budget is CACHE_MEMORY_BUDGET_MB in bytes, 0 when unset
+	budget int64
+}
+
+func NewCacheHandler(cache *cache.Cache, budget int64) *CacheHandler {
+	return &CacheHandler{cache: cache, budget: budget}
+}
+
+type flushCacheRequest struct {
+	// Pattern is a Redis glob within the namespace (e.g. "post:*"); empty flushes everything
+	Pattern string `json:"pattern"`
+}
+
+// Flush deletes cached entries of the current namespace (admin only)
+func (h *CacheHandler) Flush(c *gin.Context) {
+	var req flushCacheRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	deleted, err := h.cache.Flush(c.Request.Context(), req.Pattern)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Cache flush failed", err.Error())
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).Info("Cache flushed", "namespace", h.cache.Namespace(), "pattern", req.Pattern, "deleted", deleted, "admin_id", c.GetUint("user_id"))
+	utils.SuccessResponse(c, http.StatusOK, "Cache flushed", gin.H{
+		"namespace": h.cache.Namespace(),
+		"deleted":   deleted,
+	})
+}
+
+// ListKeys pages through cached entries of the current namespace with SCAN (admin only).
+// ?pattern= is a Redis glob (e.g. "post:12*"), ?cursor= resumes a scan and ?limit=
+// (default 100, max 1000) sizes the page; a returned cursor of 0 ends the scan.
+func (h *CacheHandler) ListKeys(c *gin.Context) {
+	var query struct {
+		Pattern string `form:"pattern"`
+		Cursor  uint64 `form:"cursor"`
+		Limit   int    `form:"limit,default=100" binding:"min=1,max=1000"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	keys, cursor, err := h.cache.Keys(c.Request.Context(), query.Pattern, query.Cursor, query.Limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list cache keys", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Cache keys retrieved", gin.H{
+		"namespace": h.cache.Namespace(),
+		"keys":      keys,
+		"cursor":    strconv.FormatUint(cursor, 10),
+	})
+}
+
+// EvictKey deletes one cached entry by its key within the namespace (admin only)
+func (h *CacheHandler) EvictKey(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" || strings.ContainsAny(key, "*?[]") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid cache key", "key must be an exact key, not a pattern; use POST /admin/cache/flush for patterns")
+		return
+	}
+
+	existed, err := h.cache.Evict(c.Request.Context(), key)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Cache eviction failed", err.Error())
+		return
+	}
+	if !existed {
+		utils.ErrorResponse(c, http.StatusNotFound, "Cache key not found", nil)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).Info("Cache key evicted", "namespace", h.cache.Namespace(), "key", key, "admin_id", c.GetUint("user_id"))
+	utils.SuccessResponse(c, http.StatusOK, "Cache key evicted", gin.H{"key": key})
+}
+
+// Usage reports the keys and memory held by each cache namespace, the current one
+// flagged, largest first (admin only). It scans every key, so it is slow on large caches.
+func (h *CacheHandler) Usage(c *gin.Context) {
+	usage, err := h.cache.Usage(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to measure cache", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Cache usage retrieved", gin.H{
+		"namespaces":   usage,
+		"budget_bytes": h.budget,
+	})
+}
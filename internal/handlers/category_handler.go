@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryHandler serves /categories. Any user can read them; the routes that change
+// them require the admin role.
+type CategoryHandler struct {
+	service services.CategoryService
+}
+
+func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
+func (h *CategoryHandler) List(c *gin.Context) {
+	categories, err := h.service.List(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve categories", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Categories retrieved successfully", categories)
+}
+
+func (h *CategoryHandler) Get(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+
+	category, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		categoryError(c, "Failed to retrieve category", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category retrieved successfully", category)
+}
+
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	category, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		categoryError(c, "Failed to create category", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Category created successfully", category)
+}
+
+// Update replaces the name and description of a category
+func (h *CategoryHandler) Update(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	category, err := h.service.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		categoryError(c, "Failed to update category", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category updated successfully", category)
+}
+
+// Delete removes a category; its posts are kept without one
+func (h *CategoryHandler) Delete(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		categoryError(c, "Failed to delete category", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category deleted successfully", nil)
+}
+
+func categoryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid category ID", err.Error())
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func categoryError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, services.ErrCategoryExists):
+		utils.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/internal/config"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler shows operators the configuration the instance is running with
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{cfg: cfg}
+}
+
+// Get returns the effective configuration with secrets redacted, where each variable
+// came from, and the variables that override a default
+func (h *ConfigHandler) Get(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Configuration retrieved successfully", h.cfg.Report())
+}
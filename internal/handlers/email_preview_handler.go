@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/internal/templates"
+	"goapi/pkg/i18n"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailPreviewHandler renders email templates with sample data (development only)
+type EmailPreviewHandler struct {
+	renderer *templates.Renderer
+	bundle   *i18n.Bundle
+}
+
+func NewEmailPreviewHandler(renderer *templates.Renderer, bundle *i18n.Bundle) *EmailPreviewHandler {
+	return &EmailPreviewHandler{renderer: renderer, bundle: bundle}
+}
+
+// List returns the available templates and languages
+func (h *EmailPreviewHandler) List(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Email templates retrieved", gin.H{
+		"templates": h.renderer.Names(),
+		"languages": h.bundle.Languages(),
+	})
+}
+
+// Preview renders one template as HTML (?lang=id, otherwise Accept-Language)
+func (h *EmailPreviewHandler) Preview(c *gin.Context) {
+	name := c.Param("name")
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = h.bundle.Match(c.GetHeader("Accept-Language"))
+	}
+
+	email, err := h.renderer.Render(name, lang, templates.SampleData(name))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Email template not found", err.Error())
+		return
+	}
+
+	c.Header("X-Email-Subject", email.Subject)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.HTML))
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/pkg/featureflag"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeatureHandler struct {
+	flags *featureflag.Set
+}
+
+func NewFeatureHandler(flags *featureflag.Set) *FeatureHandler {
+	return &FeatureHandler{flags: flags}
+}
+
+// GetMyFeatures lists the feature flags enabled for the current user, so clients can
+// show soft-launched features only to users who can reach them
+func (h *FeatureHandler) GetMyFeatures(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Features retrieved", gin.H{
+		"features": h.flags.EnabledFor(c.GetUint("user_id")),
+	})
+}
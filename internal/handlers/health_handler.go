@@ -3,29 +3,39 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"runtime"
 	"time"
 
+	"goapi/internal/config"
+	"goapi/internal/services"
+	"goapi/pkg/buildinfo"
+	"goapi/pkg/utctime"
+	"goapi/pkg/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 type HealthHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
+	db        *gorm.DB
+	redis     *redis.Client
+	warmer    services.CacheWarmer
+	startedAt time.Time
 }
 
-func NewHealthHandler(db *gorm.DB, redis *redis.Client) *HealthHandler {
-	return &HealthHandler{db: db, redis: redis}
+func NewHealthHandler(db *gorm.DB, redis *redis.Client, warmer services.CacheWarmer, startedAt time.Time) *HealthHandler {
+	return &HealthHandler{db: db, redis: redis, warmer: warmer, startedAt: startedAt}
 }
 
 func (h *HealthHandler) Check(c *gin.Context) {
 	status := "healthy"
 	components := make(map[string]string)
 
-	// Check DB
-	sqlDB, err := h.db.DB()
-	if err != nil {
+	// Check DB (nil when running with in-memory repositories)
+	if h.db == nil {
+		components["db"] = "memory"
+	} else if sqlDB, err := h.db.DB(); err != nil {
 		status = "unhealthy"
 		components["db"] = "failed to get instance"
 	} else if err := sqlDB.Ping(); err != nil {
@@ -52,7 +62,110 @@ func (h *HealthHandler) Check(c *gin.Context) {
 		"status":     status,
 		"timestamp":  time.Now().Unix(),
 		"service":    "goapi",
-		"version":    "1.0.0",
+		"version":    buildinfo.Get().Version,
 		"components": components,
 	})
 }
+
+// Ready is the load balancer readiness probe. It returns 503 while any instance is
+// migrating or when the database schema differs from the version this build expects,
+// so traffic only reaches instances whose code matches the schema. A fresh instance
+// also waits for its cache warm-up (CACHE_WARMUP).
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if !h.warmer.Warmed() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": "cache warming"})
+		return
+	}
+
+	// In-memory repositories have no schema to wait for
+	if h.db == nil {
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+		return
+	}
+
+	state, err := config.CurrentMigrationState(h.db.WithContext(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": "schema check failed: " + err.Error()})
+		return
+	}
+
+	switch {
+	case state.Running:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": "migration running", "migration": state})
+	case !state.Ready():
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": "schema version mismatch", "migration": state})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "migration": state})
+	}
+}
+
+// Version reports the build of the running binary
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
+
+// Details is the admin-only triage view: pool stats, dependency latency and runtime metrics
+func (h *HealthHandler) Details(c *gin.Context) {
+	ctx := c.Request.Context()
+	details := gin.H{}
+
+	// Database pool
+	if h.db == nil {
+		details["db"] = gin.H{"mode": "memory"}
+	} else if sqlDB, err := h.db.DB(); err != nil {
+		details["db"] = gin.H{"error": err.Error()}
+	} else {
+		start := time.Now()
+		pingErr := sqlDB.PingContext(ctx)
+		stats := sqlDB.Stats()
+		db := gin.H{
+			"latency_ms":           float64(time.Since(start).Microseconds()) / 1000,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"max_open_connections": stats.MaxOpenConnections,
+			"wait_count":           stats.WaitCount,
+			"wait_duration":        stats.WaitDuration.String(),
+		}
+		if pingErr != nil {
+			db["error"] = pingErr.Error()
+		}
+		details["db"] = db
+	}
+
+	// Redis latency
+	start := time.Now()
+	redisInfo := gin.H{}
+	if err := h.redis.Ping(ctx).Err(); err != nil {
+		redisInfo["error"] = err.Error()
+	}
+	redisInfo["latency_ms"] = float64(time.Since(start).Microseconds()) / 1000
+	poolStats := h.redis.PoolStats()
+	redisInfo["total_connections"] = poolStats.TotalConns
+	redisInfo["idle_connections"] = poolStats.IdleConns
+	details["redis"] = redisInfo
+
+	// Go runtime
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	details["runtime"] = gin.H{
+		"goroutines":    runtime.NumGoroutine(),
+		"heap_alloc_mb": float64(mem.HeapAlloc) / (1 << 20),
+		"heap_sys_mb":   float64(mem.HeapSys) / (1 << 20),
+		"gc_cycles":     mem.NumGC,
+		"go_version":    runtime.Version(),
+		"uptime":        time.Since(h.startedAt).Round(time.Second).String(),
+		"started_at":    utctime.From(h.startedAt),
+	}
+
+	// Schema
+	if h.db == nil {
+		details["migration"] = gin.H{"expected_version": config.SchemaVersion, "applied_version": nil}
+	} else if version, err := config.CurrentSchemaVersion(h.db.WithContext(ctx)); err != nil {
+		details["migration"] = gin.H{"expected_version": config.SchemaVersion, "error": err.Error()}
+	} else {
+		details["migration"] = gin.H{"expected_version": config.SchemaVersion, "applied_version": version}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Health details retrieved", details)
+}
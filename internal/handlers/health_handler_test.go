@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockingPosts holds the posts warm-up step until released
+type blockingPosts struct {
+	services.PostService
+	release chan struct{}
+}
+
+func (p blockingPosts) GetAll(ctx context.Context, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error) {
+	<-p.release
+	return nil, models.PageInfo{}, nil
+}
+
+type emptyLeaderboard struct{ services.LeaderboardService }
+
+func (emptyLeaderboard) TopAuthors(ctx context.Context, limit int) (*models.Leaderboard, error) {
+	return &models.Leaderboard{}, nil
+}
+
+func quietContext() context.Context {
+	return logger.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func readyStatus(router *gin.Engine) int {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code
+}
+
+func TestReadyWaitsForCacheWarmup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	warmer := services.NewCacheWarmer(blockingPosts{release: release}, nil, emptyLeaderboard{}, nil,
+		func(ctx context.Context) context.Context { return ctx },
+		services.CacheWarmerOptions{Enabled: true, Timeout: time.Minute})
+
+	router := gin.New()
+	router.GET("/readyz", NewHealthHandler(nil, nil, warmer, time.Now()).Ready)
+
+	if code := readyStatus(router); code != http.StatusServiceUnavailable {
+		t.Fatalf("before Start: got %d, want 503", code)
+	}
+	warmer.Start(quietContext())
+	if code := readyStatus(router); code != http.StatusServiceUnavailable {
+		t.Fatalf("while warming: got %d, want 503", code)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for readyStatus(router) != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("readyz still not ready after warm-up finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postIncludes are the relations post endpoints embed on request. Comments are not
+// modeled yet, so "comments.author" is rejected like any other unknown relation.
+var postIncludes = includeSpec{allowed: []string{"author"}, defaults: []string{"author"}}
+
+// includeSpec is one endpoint's ?include= whitelist. Without the parameter the
+// defaults are embedded, so clients that predate it keep their response shape.
+type includeSpec struct {
+	allowed  []string
+	defaults []string
+}
+
+// includeSet is the relations a request asked for; nested paths are dot-separated
+type includeSet map[string]bool
+
+// includes parses ?include=author,comments.author against spec. An empty value embeds
+// nothing. It reports false after writing an error response.
+func includes(c *gin.Context, spec includeSpec) (includeSet, bool) {
+	raw, ok := c.GetQuery("include")
+	if !ok {
+		raw = strings.Join(spec.defaults, ",")
+	}
+
+	set := includeSet{}
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !slices.Contains(spec.allowed, path) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters",
+				"include "+path+" is not supported here, allowed: "+strings.Join(spec.allowed, ","))
+			return nil, false
+		}
+		set[path] = true
+	}
+	return set, true
+}
+
+// applyToList drops the relations posts embed by default but the request left out
+func (set includeSet) applyToList(posts []models.PostListItem) {
+	if set["author"] {
+		return
+	}
+	for i := range posts {
+		posts[i].Author = nil
+	}
+}
+
+// applyToSearch is applyToList for search results
+func (set includeSet) applyToSearch(results []models.PostSearchResult) {
+	if set["author"] {
+		return
+	}
+	for i := range results {
+		results[i].Author = nil
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"goapi/internal/jobs"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	scheduler *jobs.Scheduler
+}
+
+func NewJobHandler(scheduler *jobs.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// ListFailed returns the most recent failed job runs, newest first (admin only)
+func (h *JobHandler) ListFailed(c *gin.Context) {
+	failures, err := h.scheduler.Failed(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list failed jobs", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Failed jobs retrieved successfully", failures)
+}
+
+// Retry runs the job of a failed run again in the background and drops the failure;
+// a new failure is recorded if the retry fails too (admin only)
+func (h *JobHandler) Retry(c *gin.Context) {
+	failure, err := h.scheduler.Retry(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrFailureNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Failed job not found", err.Error())
+		case errors.Is(err, jobs.ErrUnknownJob):
+			utils.ErrorResponse(c, http.StatusConflict, "Retry failed", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Retry failed", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Job retry started", failure)
+}
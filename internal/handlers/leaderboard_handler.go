@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LeaderboardHandler struct {
+	service services.LeaderboardService
+}
+
+func NewLeaderboardHandler(service services.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{service: service}
+}
+
+// GetAuthors ranks authors by posts published this ISO week (UTC). ?limit= (default 10,
+// max 100) sizes the board; the week resets every Monday.
+func (h *LeaderboardHandler) GetAuthors(c *gin.Context) {
+	var query struct {
+		Limit int `form:"limit,default=10" binding:"min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	board, err := h.service.TopAuthors(c.Request.Context(), query.Limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve leaderboard", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Leaderboard retrieved successfully", board)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/i18n"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MagicLinkHandler serves passwordless login: POST /login/magic-link emails a link and
+// GET /login/magic, the link itself, exchanges it for a token.
+type MagicLinkHandler struct {
+	service   services.MagicLinkService
+	bundle    *i18n.Bundle
+	cookieCfg utils.CookieConfig
+}
+
+func NewMagicLinkHandler(service services.MagicLinkService, bundle *i18n.Bundle, cookieCfg utils.CookieConfig) *MagicLinkHandler {
+	return &MagicLinkHandler{service: service, bundle: bundle, cookieCfg: cookieCfg}
+}
+
+// Request emails a login link. Like ForgotPassword, it answers the same way whether or
+// not the address has an account; only the per-email limit can turn it into a 429.
+func (h *MagicLinkHandler) Request(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.Throttle(c.Request.Context(), req.Email); err != nil {
+		if errors.Is(err, services.ErrMagicLinkThrottled) {
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Login link not sent", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Login link not sent", err.Error())
+		return
+	}
+
+	lang := h.bundle.Match(c.GetHeader("Accept-Language"))
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		if err := h.service.Send(ctx, req.Email, lang); err != nil {
+			logger.FromContext(ctx).Error("Magic link email failed", "error", err)
+		}
+	}()
+
+	utils.SuccessResponse(c, http.StatusAccepted, "If the email belongs to an account, a login link has been sent", nil)
+}
+
+// Login answers like POST /login. Unknown, expired and used links are a 401.
+func (h *MagicLinkHandler) Login(c *gin.Context) {
+	var req models.MagicLinkLoginRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	req.IP, req.UserAgent = c.ClientIP(), c.Request.UserAgent()
+
+	token, user, err := h.service.Login(c.Request.Context(), &req)
+	if err != nil {
+		loginFailed(c, err)
+		return
+	}
+	loginSucceeded(c, h.cookieCfg, token, user)
+}
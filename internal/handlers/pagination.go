@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+)
+
+// pageMeta builds the response meta block for a page
+func pageMeta(page models.PageRequest, info models.PageInfo) utils.Meta {
+	return utils.Meta{
+		Page:       page.Page,
+		Limit:      page.Limit,
+		Total:      info.Total,
+		HasMore:    info.HasMore,
+		NextCursor: info.NextCursor,
+	}
+}
+
+// postPageMeta is pageMeta for post lists; a page read by cursor has no page number
+func postPageMeta(req models.PostListRequest, info models.PageInfo) utils.Meta {
+	meta := pageMeta(req.PageRequest, info)
+	if req.After != 0 {
+		meta.Page = 0
+	}
+	return meta
+}
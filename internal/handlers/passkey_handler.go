@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasskeyHandler serves passkey login under /login/passkey and the current user's
+// passkeys under /me/passkeys. The begin endpoints return WebAuthn options for the
+// browser; the finish endpoints take the credential it returns.
+type PasskeyHandler struct {
+	service   services.PasskeyService
+	cookieCfg utils.CookieConfig
+}
+
+func NewPasskeyHandler(service services.PasskeyService, cookieCfg utils.CookieConfig) *PasskeyHandler {
+	return &PasskeyHandler{service: service, cookieCfg: cookieCfg}
+}
+
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	options, err := h.service.BeginRegistration(c.Request.Context(), userID.(uint))
+	if err != nil {
+		passkeyError(c, "Failed to begin passkey registration", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Passkey registration started", options)
+}
+
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req models.PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	req.Normalize()
+
+	passkey, err := h.service.FinishRegistration(c.Request.Context(), userID.(uint), &req)
+	if err != nil {
+		passkeyError(c, "Failed to register passkey", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Passkey registered successfully", passkey)
+}
+
+func (h *PasskeyHandler) List(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	passkeys, err := h.service.List(c.Request.Context(), userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve passkeys", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Passkeys retrieved successfully", passkeys)
+}
+
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid passkey ID", err.Error())
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID.(uint), uint(id)); err != nil {
+		passkeyError(c, "Failed to delete passkey", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Passkey deleted successfully", nil)
+}
+
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	options, err := h.service.BeginLogin(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to begin passkey login", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Passkey login started", options)
+}
+
+// FinishLogin answers like POST /login
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var req models.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	req.IP, req.UserAgent = c.ClientIP(), c.Request.UserAgent()
+
+	token, user, err := h.service.FinishLogin(c.Request.Context(), &req)
+	if err != nil {
+		loginFailed(c, err)
+		return
+	}
+	loginSucceeded(c, h.cookieCfg, token, user)
+}
+
+// passkeyError maps passkey service errors to status codes
+func passkeyError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrPasskeyNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, services.ErrPasskeyLimit):
+		utils.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	case errors.Is(err, services.ErrPasskeyInvalid):
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
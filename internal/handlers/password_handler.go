@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/i18n"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	service services.PasswordResetService
+	bundle  *i18n.Bundle
+}
+
+func NewPasswordHandler(service services.PasswordResetService, bundle *i18n.Bundle) *PasswordHandler {
+	return &PasswordHandler{service: service, bundle: bundle}
+}
+
+// ForgotPassword emails a reset link. It answers the same way, and before any lookup
+// or email is done, whether or not the address has an account.
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	lang := h.bundle.Match(c.GetHeader("Accept-Language"))
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		if err := h.service.Forgot(ctx, req.Email, lang); err != nil {
+			logger.FromContext(ctx).Error("Password reset email failed", "error", err)
+		}
+	}()
+
+	utils.SuccessResponse(c, http.StatusAccepted, "If the email belongs to an account, a reset link has been sent", nil)
+}
+
+// ResetPassword sets a new password from a reset link token and logs out every session
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.Reset(c.Request.Context(), &req); err != nil {
+		if errors.Is(err, services.ErrResetTokenInvalid) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Password reset failed", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Password reset failed", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password reset successfully, please log in again", nil)
+}
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"goapi/internal/models"
 	"goapi/internal/services"
+	"goapi/pkg/signedurl"
+	"goapi/pkg/utctime"
 	"goapi/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -13,10 +20,13 @@ import (
 
 type PostHandler struct {
 	service services.PostService
+	signer  *signedurl.Signer
+	// maxShareTTL caps the lifetime of links minted by SharePost
+	maxShareTTL time.Duration
 }
 
-func NewPostHandler(service services.PostService) *PostHandler {
-	return &PostHandler{service: service}
+func NewPostHandler(service services.PostService, signer *signedurl.Signer, maxShareTTL time.Duration) *PostHandler {
+	return &PostHandler{service: service, signer: signer, maxShareTTL: maxShareTTL}
 }
 
 // CreatePost creates a new post
@@ -36,6 +46,21 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	post, err := h.service.Create(c.Request.Context(), &req, userID.(uint))
 	if err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Quota exceeded", quotaErr)
+			return
+		}
+		var duplicateErr *services.DuplicatePostError
+		if errors.As(err, &duplicateErr) {
+			utils.ErrorResponse(c, http.StatusConflict, "Duplicate post", duplicateErr)
+			return
+		}
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Validation failed", validationErr)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create post", err.Error())
 		return
 	}
@@ -50,46 +75,229 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
 		return
 	}
+	include, ok := includes(c, postIncludes)
+	if !ok {
+		return
+	}
 
 	post, err := h.service.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err.Error())
 		return
 	}
+	// Archived posts are visible to their author and to holders of a signed link only
+	if post.ArchivedAt != nil && post.UserID != c.GetUint("user_id") && !c.GetBool(signedurl.ContextKey) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Post not found", services.ErrPostNotFound.Error())
+		return
+	}
+	localized := []models.PostResponse{*post}
+	if !h.localize(c, localized) {
+		return
+	}
+	post = &localized[0]
+	if !include["author"] {
+		post.Author = nil
+	}
 
+	utils.SetETag(c, post.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Post retrieved successfully", post)
 }
 
-// GetAllPosts retrieves all posts (demonstrates DataLoader batching)
-// Supports optional ?user_id=X query parameter to filter by user
+// defaultShareTTL is the lifetime of a share link when the request names none
+const defaultShareTTL = 24 * time.Hour
+
+// SharePost mints a signed GET /posts/:id link that works without logging in, also for
+// archived posts, until it expires (author only)
+func (h *PostHandler) SharePost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	var req models.SharePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	ttl := defaultShareTTL
+	if req.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", "expires_in must be a positive duration such as \"24h\"")
+			return
+		}
+	}
+	if ttl > h.maxShareTTL {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", "expires_in must not exceed "+h.maxShareTTL.String())
+		return
+	}
+
+	post, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err.Error())
+		return
+	}
+	if post.UserID != c.GetUint("user_id") {
+		utils.ErrorResponse(c, http.StatusForbidden, "Failed to share post", services.ErrNotPostAuthor.Error())
+		return
+	}
+
+	// The link is relative to the API host: this request's path without "/share"
+	path := strings.TrimSuffix(c.Request.URL.Path, "/share")
+	expires := utctime.Now().Add(ttl)
+	link := url.URL{Path: path, RawQuery: h.signer.Query(http.MethodGet, path, expires).Encode()}
+	utils.SuccessResponse(c, http.StatusOK, "Share link created", models.SharePostResponse{
+		URL:       link.String(),
+		ExpiresAt: utctime.From(expires),
+	})
+}
+
+// GetRelatedPosts returns posts similar to a post (?limit=, default 5, max 20)
+func (h *PostHandler) GetRelatedPosts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	var query struct {
+		Limit int `form:"limit,default=5" binding:"min=1,max=20"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+	include, ok := includes(c, postIncludes)
+	if !ok {
+		return
+	}
+
+	posts, err := h.service.GetRelated(c.Request.Context(), uint(id), query.Limit)
+	if err != nil {
+		if errors.Is(err, services.ErrPostNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve related posts", err.Error())
+		return
+	}
+	if !h.localizeList(c, posts) {
+		return
+	}
+	include.applyToList(posts)
+
+	utils.SuccessResponse(c, http.StatusOK, "Related posts retrieved successfully", posts)
+}
+
+// GetAllPosts retrieves a page of posts (demonstrates DataLoader batching)
+// Query parameters (?user_id=, ?tag=, ?page=, ?limit=, ?after=, ?sort=, ?format=) bind to models.ListPostsQuery;
+// ?include= is checked against postIncludes
 func (h *PostHandler) GetAllPosts(c *gin.Context) {
+	var query models.ListPostsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+	if query.Format == "ndjson" {
+		h.ExportPosts(c)
+		return
+	}
+	include, ok := includes(c, postIncludes)
+	if !ok {
+		return
+	}
+	if query.After != 0 && !query.Keyset() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", "after requires sort=newest")
+		return
+	}
+	req := query.PostListRequest
+	req.Tag = models.NormalizeTag(req.Tag)
+	if query.UserID != 0 {
+		req.IncludeArchived = query.UserID == c.GetUint("user_id")
+	}
+	if query.Stream {
+		h.streamPosts(c, query.UserID, req, include)
+		return
+	}
+
 	// Check if filtering by user_id
-	userIDParam := c.Query("user_id")
-	if userIDParam != "" {
-		userID, err := strconv.ParseUint(userIDParam, 10, 32)
+	if query.UserID != 0 {
+
+		posts, info, err := h.service.GetByUserID(c.Request.Context(), query.UserID, req)
 		if err != nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+			listPostsError(c, err)
 			return
 		}
-
-		posts, err := h.service.GetByUserID(c.Request.Context(), uint(userID))
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve posts", err.Error())
+		if !h.localizeList(c, posts) {
 			return
 		}
+		include.applyToList(posts)
 
-		utils.SuccessResponse(c, http.StatusOK, "Posts retrieved successfully", posts)
+		utils.PaginatedResponse(c, http.StatusOK, "Posts retrieved successfully", posts, postPageMeta(req, info))
 		return
 	}
 
 	// Get all posts
-	posts, err := h.service.GetAll(c.Request.Context())
+	posts, info, err := h.service.GetAll(c.Request.Context(), req)
+	if err != nil {
+		listPostsError(c, err)
+		return
+	}
+	if !h.localizeList(c, posts) {
+		return
+	}
+	include.applyToList(posts)
+
+	utils.PaginatedResponse(c, http.StatusOK, "Posts retrieved successfully", posts, postPageMeta(req, info))
+}
+
+// SearchPosts runs a full-text search over unarchived posts (?q=, ?page=, ?limit=,
+// ?pagination=, ?include=), best match first. Results stay in each post's original
+// language: the highlights are fragments of that text, so translations are not applied.
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	var query models.PostSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+	include, ok := includes(c, postIncludes)
+	if !ok {
+		return
+	}
+
+	results, info, err := h.service.Search(c.Request.Context(), query)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search posts", err.Error())
+		return
+	}
+	include.applyToSearch(results)
+
+	utils.PaginatedResponse(c, http.StatusOK, "Posts retrieved successfully", results, pageMeta(query.PageRequest, info))
+}
+
+// ListTags returns the tags in use with their post counts, most used first (?limit=,
+// default 50, max 200)
+func (h *PostHandler) ListTags(c *gin.Context) {
+	var query models.ListTagsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	tags, err := h.service.ListTags(c.Request.Context(), query.Limit)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve posts", err.Error())
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve tags", err.Error())
 		return
 	}
+	utils.SuccessResponse(c, http.StatusOK, "Tags retrieved successfully", tags)
+}
 
-	utils.SuccessResponse(c, http.StatusOK, "Posts retrieved successfully", posts)
+func listPostsError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidCursor) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve posts", err.Error())
 }
 
 // DeletePost deletes a post (only by owner)
@@ -107,10 +315,209 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(c.Request.Context(), uint(id), userID.(uint)); err != nil {
-		utils.ErrorResponse(c, http.StatusForbidden, "Failed to delete post", err.Error())
+	if err := h.service.Delete(c.Request.Context(), uint(id), userID.(uint), c.GetString("role")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrForbidden):
+			utils.ErrorResponse(c, http.StatusForbidden, "Failed to delete post", err.Error())
+		case errors.Is(err, services.ErrPostNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete post", err.Error())
+		}
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Post deleted successfully", nil)
 }
+
+// ArchivePost hides a post from public lists without deleting it (author only)
+func (h *PostHandler) ArchivePost(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchivePost restores an archived post (author only)
+func (h *PostHandler) UnarchivePost(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *PostHandler) setArchived(c *gin.Context, archived bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	archive, message := h.service.Unarchive, "Post unarchived"
+	if archived {
+		archive, message = h.service.Archive, "Post archived"
+	}
+
+	post, err := archive(c.Request.Context(), uint(id), c.GetUint("user_id"))
+	if err != nil {
+		h.postError(c, "Failed to update post", err)
+		return
+	}
+
+	utils.SetETag(c, post.Version)
+	utils.SuccessResponse(c, http.StatusOK, message, post)
+}
+
+// SaveDraft autosaves unpublished edits to a post (author only). Saves with an older
+// revision than the stored draft get 409 with the stored draft.
+func (h *PostHandler) SaveDraft(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	var req models.SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	draft, err := h.service.SaveDraft(c.Request.Context(), uint(id), c.GetUint("user_id"), &req)
+	if err != nil {
+		var staleErr *services.StaleDraftError
+		if errors.As(err, &staleErr) {
+			utils.ErrorResponse(c, http.StatusConflict, staleErr.Error(), staleErr.Current)
+			return
+		}
+		h.postError(c, "Failed to save draft", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Draft saved", draft)
+}
+
+// GetDraft returns the saved draft of a post (author only)
+func (h *PostHandler) GetDraft(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	draft, err := h.service.GetDraft(c.Request.Context(), uint(id), c.GetUint("user_id"))
+	if err != nil {
+		h.postError(c, "Failed to get draft", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Draft retrieved", draft)
+}
+
+// localize swaps in the translations matching the Accept-Language header. It reports
+// false after writing an error response.
+func (h *PostHandler) localize(c *gin.Context, posts []models.PostResponse) bool {
+	c.Header("Vary", "Accept-Language")
+	if err := h.service.Localize(c.Request.Context(), posts, c.GetHeader("Accept-Language")); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load translations", err.Error())
+		return false
+	}
+	return true
+}
+
+// localizeList is localize for list endpoints
+func (h *PostHandler) localizeList(c *gin.Context, items []models.PostListItem) bool {
+	c.Header("Vary", "Accept-Language")
+	if err := h.service.LocalizeList(c.Request.Context(), items, c.GetHeader("Accept-Language")); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load translations", err.Error())
+		return false
+	}
+	return true
+}
+
+// TranslatePost adds or replaces a translation of a post (author only)
+func (h *PostHandler) TranslatePost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	var req models.TranslatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	translation, err := h.service.TranslatePost(c.Request.Context(), uint(id), c.GetUint("user_id"), c.Param("locale"), &req)
+	if err != nil {
+		h.postError(c, "Failed to save translation", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Translation saved", translation)
+}
+
+// GetTranslations lists the translations of a post
+func (h *PostHandler) GetTranslations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err.Error())
+		return
+	}
+
+	translations, err := h.service.GetTranslations(c.Request.Context(), uint(id), c.GetUint("user_id"), c.GetBool(signedurl.ContextKey))
+	if err != nil {
+		h.postError(c, "Failed to get translations", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Translations retrieved", translations)
+}
+
+// postError maps errors of the author-only post operations to responses
+func (h *PostHandler) postError(c *gin.Context, message string, err error) {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.Is(err, services.ErrPostNotFound), errors.Is(err, services.ErrDraftNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, services.ErrNotPostAuthor):
+		utils.ErrorResponse(c, http.StatusForbidden, message, err.Error())
+	case errors.Is(err, services.ErrVersionConflict):
+		utils.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	case errors.As(err, &validationErr):
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Validation failed", validationErr)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+// streamPosts writes every post of the list as one JSON response without holding the
+// list in memory; page, limit and pagination are ignored and no meta is returned
+func (h *PostHandler) streamPosts(c *gin.Context, userID uint, req models.PostListRequest, include includeSet) {
+	ctx := c.Request.Context()
+	c.Header("Vary", "Accept-Language")
+	utils.StreamJSON(c, "Posts retrieved successfully", func(emit func(any) error) error {
+		return h.service.StreamList(ctx, userID, req, func(posts []models.PostListItem) error {
+			if err := h.service.LocalizeList(ctx, posts, c.GetHeader("Accept-Language")); err != nil {
+				return err
+			}
+			include.applyToList(posts)
+			for _, post := range posts {
+				if err := emit(post); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// ExportPosts streams all posts (or ?user_id= posts) as NDJSON
+func (h *PostHandler) ExportPosts(c *gin.Context) {
+	var query models.ExportPostsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	utils.StreamNDJSON(c, func(emit func(any) error) error {
+		return h.service.Export(c.Request.Context(), query.UserID, func(post models.PostResponse) error {
+			return emit(post)
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageHandler struct {
+	service services.MeteringService
+}
+
+func NewUsageHandler(service services.MeteringService) *UsageHandler {
+	return &UsageHandler{service: service}
+}
+
+// GetMyUsage returns the current user's usage rollups (?period=day|month, default month)
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "user not authenticated")
+		return
+	}
+
+	period, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.service.GetUsage(c.Request.Context(), userID.(uint), period)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve usage", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Usage retrieved successfully", usage)
+}
+
+// GetReport returns usage of all users for one period (?period=day|month&start=YYYY-MM-DD)
+func (h *UsageHandler) GetReport(c *gin.Context) {
+	period, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if period == models.PeriodDay {
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if startParam := c.Query("start"); startParam != "" {
+		parsed, err := time.Parse("2006-01-02", startParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid start date", err.Error())
+			return
+		}
+		start = parsed
+	}
+
+	report, err := h.service.GetReport(c.Request.Context(), period, start)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to build usage report", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Usage report generated", report)
+}
+
+func parsePeriod(c *gin.Context) (string, bool) {
+	period := c.DefaultQuery("period", models.PeriodMonth)
+	if period != models.PeriodDay && period != models.PeriodMonth {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid period", "period must be 'day' or 'month'")
+		return "", false
+	}
+	return period, true
+}
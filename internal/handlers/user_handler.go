@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"goapi/internal/models"
 	"goapi/internal/services"
 	"goapi/pkg/utils"
@@ -11,11 +12,19 @@ import (
 )
 
 type UserHandler struct {
-	service services.UserService
+	service   services.UserService
+	guard     services.SignupGuard
+	deletion  services.AccountDeletionService
+	cookieCfg utils.CookieConfig
 }
 
-func NewUserHandler(service services.UserService) *UserHandler {
-	return &UserHandler{service: service}
+func NewUserHandler(service services.UserService, guard services.SignupGuard, deletion services.AccountDeletionService, cookieCfg utils.CookieConfig) *UserHandler {
+	return &UserHandler{service: service, guard: guard, deletion: deletion, cookieCfg: cookieCfg}
+}
+
+// FormToken issues the signed timestamp the registration form must submit back
+func (h *UserHandler) FormToken(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Form token issued", gin.H{"form_token": h.guard.IssueFormToken()})
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -25,12 +34,24 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	req.ReviewFlags = h.guard.Inspect(c.Request.Context(), c.ClientIP(), &req)
+
 	user, err := h.service.Register(c.Request.Context(), &req)
 	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Validation failed", validationErr)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "Registration failed", err.Error())
 		return
 	}
 
+	if user.ReviewStatus == models.ReviewPending {
+		utils.SuccessResponse(c, http.StatusAccepted, "Registration received and pending review", user)
+		return
+	}
+
 	utils.SuccessResponse(c, http.StatusCreated, "User registered successfully", user)
 }
 
@@ -40,10 +61,37 @@ func (h *UserHandler) Login(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
+	req.IP, req.UserAgent = c.ClientIP(), c.Request.UserAgent()
 
 	token, user, err := h.service.Login(c.Request.Context(), &req)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Login failed", err.Error())
+		loginFailed(c, err)
+		return
+	}
+	loginSucceeded(c, h.cookieCfg, token, user)
+}
+
+// loginFailed answers a failed login, by password or passkey
+func loginFailed(c *gin.Context, err error) {
+	// The credentials were right; the client can offer to log in again with restore
+	var pendingErr *services.PendingDeletionError
+	if errors.As(err, &pendingErr) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Account pending deletion", pendingErr)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusUnauthorized, "Login failed", err.Error())
+}
+
+// loginSucceeded delivers the token of a new session, by password, passkey or login link
+func loginSucceeded(c *gin.Context, cookieCfg utils.CookieConfig, token string, user *models.UserResponse) {
+	// Cookie mode: deliver the token as an httpOnly cookie instead of the JSON body
+	if cookieCfg.Enabled {
+		if err := utils.SetAuthCookies(c, cookieCfg, token, int(cookieCfg.MaxAge.Seconds())); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed", err.Error())
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "Login successful", gin.H{"user": user})
 		return
 	}
 
@@ -55,14 +103,39 @@ func (h *UserHandler) Login(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Login successful", data)
 }
 
+func (h *UserHandler) Logout(c *gin.Context) {
+	// End the token's session so it stops working even if the client keeps a copy
+	if userID, exists := c.Get("user_id"); exists {
+		if sessionID := c.GetString("session_id"); sessionID != "" {
+			err := h.service.RevokeSession(c.Request.Context(), userID.(uint), sessionID)
+			if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Logout failed", err.Error())
+				return
+			}
+		}
+	}
+
+	if h.cookieCfg.Enabled {
+		utils.ClearAuthCookies(c, h.cookieCfg)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Logout successful", nil)
+}
+
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.service.GetAll(c.Request.Context())
+	var query models.ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	users, info, err := h.service.GetAll(c.Request.Context(), query)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get users", err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Users retrieved successfully", users)
+	utils.PaginatedResponse(c, http.StatusOK, "Users retrieved successfully", users, pageMeta(query.PageRequest, info))
 }
 
 func (h *UserHandler) GetUserByID(c *gin.Context) {
@@ -78,7 +151,72 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", user)
+	// The version is only for If-Match, which only viewers of the full response can send
+	if seesFullUser(c, user.ID) {
+		utils.SetETag(c, user.Version)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", userView(c, user))
+}
+
+// LookupUsers resolves many users by ID or username in one call, for clients rendering
+// lists of people (e.g. comment threads). Body: {"ids": [1, 2], "usernames": ["jane"]}.
+func (h *UserHandler) LookupUsers(c *gin.Context) {
+	var req models.UserLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	users, err := h.service.Lookup(c.Request.Context(), req)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Validation failed", validationErr)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to look up users", err.Error())
+		return
+	}
+
+	views := make([]any, 0, len(users))
+	for i := range users {
+		views = append(views, userView(c, &users[i]))
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Users retrieved successfully", views)
+}
+
+// userView shapes user for the viewer: admins and the user themself get the full
+// response, everyone else the public view with a masked email
+func userView(c *gin.Context, user *models.UserResponse) any {
+	if seesFullUser(c, user.ID) {
+		return user
+	}
+	return user.Public()
+}
+
+func seesFullUser(c *gin.Context, id uint) bool {
+	return c.GetString("role") == "admin" || c.GetUint("user_id") == id
+}
+
+// GetUserStats returns a user's author statistics
+func (h *UserHandler) GetUserStats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	stats, err := h.service.GetStats(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get user stats", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User stats retrieved successfully", stats)
 }
 
 func (h *UserHandler) GetCurrentUser(c *gin.Context) {
@@ -94,9 +232,146 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Current user retrieved", user)
 }
 
+// GetLoginHistory returns the current user's recent login attempts (?limit=, default 20, max 100)
+func (h *UserHandler) GetLoginHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var query struct {
+		Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	events, err := h.service.GetLoginHistory(c.Request.Context(), userID.(uint), query.Limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve login history", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Login history retrieved", events)
+}
+
+// GetSessions lists the current user's active sessions, flagging the one making the request
+func (h *UserHandler) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID.(uint), c.GetString("session_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve sessions", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sessions retrieved", sessions)
+}
+
+// RevokeSession logs out one of the current user's sessions
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID.(uint), c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Session not found", nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session revoked", nil)
+}
+
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.ChangePassword(c.Request.Context(), userID.(uint), &req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Password change failed", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password changed successfully, please log in again", nil)
+}
+
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req models.LogoutAllRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.LogoutAll(c.Request.Context(), userID.(uint), req.Password); err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Logout failed", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Logged out from all devices", nil)
+}
+
+// DeleteAccount schedules the deletion of the current user's account after the grace
+// period and logs them out everywhere. Logging in with restore before then cancels it.
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	scheduled, err := h.deletion.Schedule(c.Request.Context(), userID.(uint), req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Account deletion failed", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Account deletion failed", err.Error())
+		return
+	}
+
+	if h.cookieCfg.Enabled {
+		utils.ClearAuthCookies(c, h.cookieCfg)
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Account scheduled for deletion", gin.H{"deletion_scheduled_at": scheduled})
+}
+
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -104,18 +379,44 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// Users edit their own profile; admins can edit anyone's
+	if c.GetString("role") != "admin" && c.GetUint("user_id") != uint(id) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden", "cannot update another user")
+		return
+	}
+
 	var updates models.User
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	user, err := h.service.Update(c.Request.Context(), uint(id), &updates)
+	// Optimistic locking: the client must send the version it read, via If-Match or the body
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		version = updates.Version
+	}
+	if version == 0 {
+		utils.ErrorResponse(c, http.StatusPreconditionRequired, "Update failed", "If-Match header or version is required")
+		return
+	}
+
+	user, err := h.service.Update(c.Request.Context(), uint(id), version, &updates)
 	if err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusConflict, "Update failed", err.Error())
+			return
+		}
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Validation failed", validationErr)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "Update failed", err.Error())
 		return
 	}
 
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, http.StatusOK, "User updated successfully", user)
 }
 
@@ -127,9 +428,52 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	}
 
 	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Delete failed", err.Error())
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		case errors.Is(err, services.ErrReassignTarget):
+			utils.ErrorResponse(c, http.StatusConflict, "Delete failed", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Delete failed", err.Error())
+		}
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "User deleted successfully", nil)
 }
+
+// ListFlaggedSignups returns signups held for review by bot detection
+func (h *UserHandler) ListFlaggedSignups(c *gin.Context) {
+	users, err := h.service.GetPendingReview(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get flagged signups", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Flagged signups retrieved successfully", users)
+}
+
+func (h *UserHandler) ApproveSignup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	user, err := h.service.ApproveSignup(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to approve signup", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Signup approved successfully", user)
+}
+
+// ExportUsers streams every user as NDJSON (admin only)
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	utils.StreamNDJSON(c, func(emit func(any) error) error {
+		return h.service.Export(c.Request.Context(), func(user models.UserResponse) error {
+			return emit(user)
+		})
+	})
+}
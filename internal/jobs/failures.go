@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"goapi/pkg/logger"
+	"goapi/pkg/utctime"
+)
+
+const (
+	// failedKey is the Redis list of failed runs, newest first
+	failedKey = "job:failed"
+	// maxFailures caps the list; older failures are dropped
+	maxFailures = 100
+)
+
+// ErrFailureNotFound is returned when retrying a failure that is unknown or already retried
+var ErrFailureNotFound = errors.New("failed job not found")
+
+// ErrUnknownJob is returned when retrying a failure of a job that is no longer registered
+var ErrUnknownJob = errors.New("job is not registered")
+
+// Failure is a failed job run kept for inspection and retry
+type Failure struct {
+	ID       string       `json:"id"`
+	Job      string       `json:"job"`
+	Error    string       `json:"error"`
+	FailedAt utctime.Time `json:"failed_at"`
+	// Attempts counts the runs that failed, including earlier retries of this failure
+	Attempts int `json:"attempts"`
+}
+
+// Failed lists the recorded failures, newest first
+func (s *Scheduler) Failed(ctx context.Context) ([]Failure, error) {
+	values, err := s.redis.LRange(ctx, failedKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	failures := make([]Failure, 0, len(values))
+	for _, value := range values {
+		var f Failure
+		if err := json.Unmarshal([]byte(value), &f); err != nil {
+			continue
+		}
+		failures = append(failures, f)
+	}
+	return failures, nil
+}
+
+// Retry removes failure id and runs its job again in the background. A run that fails
+// again is recorded as a new failure with Attempts incremented.
+func (s *Scheduler) Retry(ctx context.Context, id string) (*Failure, error) {
+	values, err := s.redis.LRange(ctx, failedKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		var f Failure
+		if err := json.Unmarshal([]byte(value), &f); err != nil || f.ID != id {
+			continue
+		}
+		job, ok := s.job(f.Job)
+		if !ok {
+			return nil, ErrUnknownJob
+		}
+		// LREM claims the entry, so concurrent retries of one failure run it once
+		removed, err := s.redis.LRem(ctx, failedKey, 1, value).Result()
+		if err != nil {
+			return nil, err
+		}
+		if removed == 0 {
+			return nil, ErrFailureNotFound
+		}
+
+		go s.run(context.WithoutCancel(ctx), job, f.Attempts)
+		return &f, nil
+	}
+	return nil, ErrFailureNotFound
+}
+
+func (s *Scheduler) job(name string) (Job, bool) {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+// recordFailure pushes a failed run onto the list. attempts is the number of earlier
+// failed runs it retried.
+func (s *Scheduler) recordFailure(ctx context.Context, job Job, runErr error, attempts int) {
+	f := Failure{
+		ID:       newFailureID(),
+		Job:      job.Name,
+		Error:    runErr.Error(),
+		FailedAt: utctime.Now(),
+		Attempts: attempts + 1,
+	}
+	value, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.LPush(ctx, failedKey, value)
+	pipe.LTrim(ctx, failedKey, 0, maxFailures-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx).Error("Failed to record job failure", "error", err)
+	}
+}
+
+func newFailureID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Job is a periodic background task
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs registered jobs on fixed intervals. A Redis lock ensures each
+// job runs on only one instance per interval when the API is scaled horizontally.
+type Scheduler struct {
+	redis *redis.Client
+	jobs  []Job
+}
+
+func NewScheduler(redisClient *redis.Client) *Scheduler {
+	return &Scheduler{redis: redisClient}
+}
+
+// Register adds a job; it must be called before Start
+func (s *Scheduler) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Names lists the registered jobs
+func (s *Scheduler) Names() []string {
+	names := make([]string, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+// Start launches every job in its own goroutine until ctx is canceled
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, job)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	lockKey := fmt.Sprintf("job:lock:%s", job.Name)
+	acquired, err := s.redis.SetNX(ctx, lockKey, 1, job.Interval/2).Result()
+	if err != nil {
+		logger.Error("Job lock error", "job", job.Name, "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	s.run(ctx, job, 0)
+}
+
+// run executes job and records a failure for GET /admin/jobs/failed. attempts is the
+// number of failed runs this one retries.
+func (s *Scheduler) run(ctx context.Context, job Job, attempts int) {
+	// Everything the job logs through logger.FromContext is tagged with its name
+	ctx = logger.With(ctx, "job", job.Name)
+	log := logger.FromContext(ctx)
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Error("Job failed", "duration", time.Since(start).String(), "error", err)
+		// Runs cut short by shutdown are not worth retrying
+		if ctx.Err() == nil {
+			s.recordFailure(ctx, job, err, attempts)
+		}
+		return
+	}
+	log.Info("Job completed", "duration", time.Since(start).String())
+}
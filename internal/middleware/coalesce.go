@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coalesceMaxBody is the largest response shared with waiting requests. A leader whose
+// response grows past it still completes, but the others run the handler themselves.
+const coalesceMaxBody = 1 << 20
+
+// Coalesce runs concurrent identical anonymous GET requests once: the first executes the
+// handler and every request that arrived while it ran receives a copy of its response.
+// Requests carrying credentials (Authorization or any cookie) are never coalesced, so
+// it only suits routes whose response does not depend on the caller. Apply it per route,
+// never to streams: waiting requests see nothing until the leader finishes.
+func Coalesce() gin.HandlerFunc {
+	group := &coalesceGroup{calls: make(map[string]*coalesceCall)}
+
+	return func(c *gin.Context) {
+		r := c.Request
+		if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+			c.Next()
+			return
+		}
+
+		key := r.URL.RequestURI() + "\x00" + r.Header.Get("Accept") + "\x00" + r.Header.Get("Accept-Language")
+		call, leader := group.join(key)
+		if leader {
+			rec := &recordingWriter{ResponseWriter: c.Writer}
+			c.Writer = rec
+			defer func() { group.finish(key, call, rec.response()) }()
+			c.Next()
+			rec.complete = true
+			return
+		}
+
+		select {
+		case <-call.done:
+		case <-r.Context().Done():
+			c.Abort()
+			return
+		}
+		if call.res == nil {
+			c.Next()
+			return
+		}
+		call.res.writeTo(c)
+		c.Abort()
+	}
+}
+
+type coalesceGroup struct {
+	mu    sync.Mutex
+	calls map[string]*coalesceCall
+}
+
+type coalesceCall struct {
+	done chan struct{}
+	res  *recordedResponse
+}
+
+// join returns the in-flight call for key, or starts one with the caller as leader
+func (g *coalesceGroup) join(key string) (*coalesceCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+	call := &coalesceCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish publishes the leader's response; nil (a panic or oversized body) makes the
+// waiting requests run the handler themselves
+func (g *coalesceGroup) finish(key string, call *coalesceCall, res *recordedResponse) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	call.res = res
+	close(call.done)
+}
+
+type recordedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// writeTo replays the response. Headers the follower's own middleware already set,
+// such as X-Request-ID and rate limit headers, are kept; cookies are never shared.
+func (res *recordedResponse) writeTo(c *gin.Context) {
+	header := c.Writer.Header()
+	for key, values := range res.header {
+		if _, ok := header[key]; !ok && key != "Set-Cookie" {
+			header[key] = values
+		}
+	}
+	header.Set("X-Coalesced", "true")
+	c.Writer.WriteHeader(res.status)
+	_, _ = c.Writer.Write(res.body)
+}
+
+// recordingWriter copies everything the leader writes, up to coalesceMaxBody
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+	complete bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > coalesceMaxBody {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(data)
+}
+
+// response returns what was recorded, or nil when the body overflowed or the handler
+// panicked before completing
+func (w *recordingWriter) response() *recordedResponse {
+	if w.overflow || !w.complete {
+		return nil
+	}
+	return &recordedResponse{
+		status: w.Status(),
+		header: w.Header().Clone(),
+		body:   bytes.Clone(w.body.Bytes()),
+	}
+}
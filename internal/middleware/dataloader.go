@@ -5,6 +5,7 @@ import (
 
 	"goapi/internal/models"
 	"goapi/internal/repository"
+	"goapi/internal/services"
 	"goapi/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -12,8 +13,18 @@ import (
 )
 
 // DataLoaderMiddleware creates request-scoped dataloaders
-func DataLoaderMiddleware(userRepo repository.UserRepository) gin.HandlerFunc {
+func DataLoaderMiddleware(userRepo repository.UserRepository, posts services.PostCounter, opts utils.LoaderOptions) gin.HandlerFunc {
+	withLoaders := WithLoaders(userRepo, posts, opts)
 	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(withLoaders(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// WithLoaders returns a function storing fresh dataloaders in a context, for work done
+// outside a request (e.g. cache warm-up) that calls services relying on them
+func WithLoaders(userRepo repository.UserRepository, posts services.PostCounter, opts utils.LoaderOptions) func(ctx context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
 		// Create batch function for users
 		userBatchFn := func(ctx context.Context, keys []uint) []*dataloader.Result[*models.User] {
 			// Fetch users from repository in a single query
@@ -38,13 +49,24 @@ func DataLoaderMiddleware(userRepo repository.UserRepository) gin.HandlerFunc {
 			return results
 		}
 
-		// Create loaders instance
-		loaders := utils.NewLoaders(userBatchFn)
+		// Create batch function for author stats: one HMGET of the post counters for all keys
+		statsBatchFn := func(ctx context.Context, keys []uint) []*dataloader.Result[models.AuthorStats] {
+			counts, err := posts.Counts(ctx, keys)
 
-		// Store loaders in context
-		ctx := context.WithValue(c.Request.Context(), utils.LoaderKey, loaders)
-		c.Request = c.Request.WithContext(ctx)
+			results := make([]*dataloader.Result[models.AuthorStats], len(keys))
+			for i, key := range keys {
+				if err != nil {
+					results[i] = &dataloader.Result[models.AuthorStats]{Error: err}
+					continue
+				}
+				results[i] = &dataloader.Result[models.AuthorStats]{Data: models.AuthorStats{UserID: key, PostCount: counts[key]}}
+			}
 
-		c.Next()
+			return results
+		}
+
+		// Create loaders instance and store it in the context
+		loaders := utils.NewLoaders(opts, userBatchFn, statsBatchFn)
+		return context.WithValue(ctx, utils.LoaderKey, loaders)
 	}
 }
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// The device token travels in a cookie for browsers and in a header for other clients,
+// which echo the one from their first response
+const (
+	DeviceTokenCookie = "device_token"
+	DeviceTokenHeader = "X-Device-Token"
+)
+
+// DeviceIDKey is set to the device ID of requests carrying a valid device token
+const DeviceIDKey = "device_id"
+
+// DeviceTokenOptions configures DeviceToken
+type DeviceTokenOptions struct {
+	Secret string
+	// TTL is how long a token stays valid; the cookie lasts as long
+	TTL time.Duration
+	// MaxIssuePerIP new tokens are issued to one IP within Period, and at most MaxPerIP
+	// within TTL, so one IP never holds more than MaxPerIP valid tokens. Past either,
+	// clients from the IP are counted by IP.
+	MaxIssuePerIP int
+	MaxPerIP      int
+	Period        time.Duration
+	Cookie        utils.CookieConfig
+}
+
+// DeviceToken gives anonymous clients a stable identity for rate limiting, so users
+// sharing an IP behind carrier-grade NAT do not share a counter. A request with a valid
+// token sets DeviceIDKey, which the device key strategies prefer over the IP. A request
+// without one is issued a token and still counted by IP: only a token the client sends
+// back counts, so dropping it does not escape the IP counter. Tokens are
+// "<id>.<issued_unix>.<signature>" and hold nothing else.
+func DeviceToken(client *redis.Client, opts DeviceTokenOptions) gin.HandlerFunc {
+	mac := hmac.New(sha256.New, []byte(opts.Secret))
+	mac.Write([]byte("device-token"))
+	key := mac.Sum(nil)
+
+	return func(c *gin.Context) {
+		token := c.GetHeader(DeviceTokenHeader)
+		if token == "" {
+			token, _ = c.Cookie(DeviceTokenCookie)
+		}
+		if id, ok := verifyDeviceToken(key, token, opts.TTL, time.Now()); ok {
+			c.Set(DeviceIDKey, id)
+			c.Next()
+			return
+		}
+
+		if token, ok := issueDeviceToken(c, client, key, opts); ok {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(DeviceTokenCookie, token, int(opts.TTL.Seconds()), "/", opts.Cookie.Domain, opts.Cookie.Secure, true)
+			c.Header(DeviceTokenHeader, token)
+		}
+		c.Next()
+	}
+}
+
+// issueDeviceToken mints a token unless the IP has used up its issue budget. The
+// tokens issued within TTL are kept in a sorted set per IP, scored by issue time, to
+// enforce MaxPerIP. A Redis failure issues none; the request is counted by IP either way.
+func issueDeviceToken(c *gin.Context, client *redis.Client, key []byte, opts DeviceTokenOptions) (string, bool) {
+	ctx := c.Request.Context()
+	counter := fmt.Sprintf("device-token:issued:%s", c.ClientIP())
+	count, err := client.Incr(ctx, counter).Result()
+	if err != nil {
+		logger.FromContext(ctx).Warn("Device token issue check failed", "error", err)
+		return "", false
+	}
+	if count == 1 {
+		client.Expire(ctx, counter, opts.Period)
+	}
+	if count > int64(opts.MaxIssuePerIP) {
+		return "", false
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false
+	}
+	id, now := base64.RawURLEncoding.EncodeToString(b), time.Now()
+
+	// Added before counting, so concurrent requests cannot all see room for one more
+	outstanding := fmt.Sprintf("device-token:outstanding:%s", c.ClientIP())
+	pipe := client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, outstanding, "-inf", strconv.FormatInt(now.Add(-opts.TTL).UnixMilli(), 10))
+	pipe.ZAdd(ctx, outstanding, redis.Z{Score: float64(now.UnixMilli()), Member: id})
+	pipe.Expire(ctx, outstanding, opts.TTL)
+	held := pipe.ZCard(ctx, outstanding)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx).Warn("Device token issue check failed", "error", err)
+		return "", false
+	}
+	if held.Val() > int64(opts.MaxPerIP) {
+		client.ZRem(ctx, outstanding, id)
+		return "", false
+	}
+
+	payload := id + "." + strconv.FormatInt(now.Unix(), 10)
+	return payload + "." + signDeviceToken(key, payload), true
+}
+
+// verifyDeviceToken returns the device ID of token if it is signed with key and
+// younger than ttl
+func verifyDeviceToken(key []byte, token string, ttl time.Duration, now time.Time) (string, bool) {
+	payload, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signDeviceToken(key, payload))) {
+		return "", false
+	}
+	id, issued, _ := strings.Cut(payload, ".")
+	issuedAt, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil || now.Sub(time.Unix(issuedAt, 0)) > ttl {
+		return "", false
+	}
+	return id, true
+}
+
+func signDeviceToken(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func newDeviceRouter(t *testing.T, opts DeviceTokenOptions) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	opts.Secret, opts.TTL, opts.Period = "secret", time.Hour, time.Minute
+	router := gin.New()
+	router.Use(DeviceToken(client, opts))
+	router.Use(RateLimiter(client, "global", 2, time.Minute, KeyByDeviceAndRoute))
+	router.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/login", RateLimiter(client, "auth", 3, time.Minute, KeyByIPAndRoute), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func sendWithDeviceToken(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set(DeviceTokenHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDeviceTokenSeparatesClientsBehindOneIP(t *testing.T) {
+	router := newDeviceRouter(t, DeviceTokenOptions{MaxIssuePerIP: 3, MaxPerIP: 10})
+
+	// First contact is counted by IP and hands out a token
+	first := sendWithDeviceToken(router, http.MethodGet, "/posts", "")
+	second := sendWithDeviceToken(router, http.MethodGet, "/posts", "")
+	alice, bob := first.Header().Get(DeviceTokenHeader), second.Header().Get(DeviceTokenHeader)
+	if first.Code != http.StatusOK || second.Code != http.StatusOK || alice == "" || bob == "" || alice == bob {
+		t.Fatalf("got %d %q and %d %q, want two distinct tokens", first.Code, alice, second.Code, bob)
+	}
+	if rec := sendWithDeviceToken(router, http.MethodGet, "/posts", ""); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request without a token: got %d, want 429", rec.Code)
+	}
+
+	// The IP is used up, but each device has its own counter under a device strategy
+	for _, token := range []string{alice, bob, alice, bob} {
+		if rec := sendWithDeviceToken(router, http.MethodGet, "/posts", token); rec.Code != http.StatusOK {
+			t.Fatalf("request with a device token: got %d, want 200", rec.Code)
+		}
+	}
+	if rec := sendWithDeviceToken(router, http.MethodGet, "/posts", alice); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("device past its limit: got %d, want 429", rec.Code)
+	}
+
+	// A forged token is ignored, and the IP has used up its 3 new tokens
+	rec := sendWithDeviceToken(router, http.MethodGet, "/posts", alice+"x")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get(DeviceTokenHeader) != "" {
+		t.Fatalf("forged token: got %d %q, want 429 counted by IP and no new token", rec.Code, rec.Header().Get(DeviceTokenHeader))
+	}
+}
+
+func TestDeviceTokensDoNotRaiseAuthLimit(t *testing.T) {
+	router := newDeviceRouter(t, DeviceTokenOptions{MaxIssuePerIP: 10, MaxPerIP: 10})
+
+	var tokens []string
+	for range 4 {
+		tokens = append(tokens, sendWithDeviceToken(router, http.MethodGet, "/posts", "").Header().Get(DeviceTokenHeader))
+	}
+	// Every attempt carries a different token, yet the IP ceiling of 3 holds
+	for i, token := range tokens {
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if rec := sendWithDeviceToken(router, http.MethodPost, "/login", token); rec.Code != want {
+			t.Fatalf("login attempt %d: got %d, want %d", i+1, rec.Code, want)
+		}
+	}
+}
+
+func TestDeviceTokenCapsTokensPerIP(t *testing.T) {
+	router := newDeviceRouter(t, DeviceTokenOptions{MaxIssuePerIP: 5, MaxPerIP: 2})
+
+	issued := 0
+	for range 5 {
+		if sendWithDeviceToken(router, http.MethodPost, "/login", "").Header().Get(DeviceTokenHeader) != "" {
+			issued++
+		}
+	}
+	if issued != 2 {
+		t.Fatalf("got %d tokens, want the 2 allowed per IP within the TTL", issued)
+	}
+}
+
+func TestVerifyDeviceTokenExpires(t *testing.T) {
+	key := []byte("key")
+	payload := "device." + strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)
+	token := payload + "." + signDeviceToken(key, payload)
+
+	if id, ok := verifyDeviceToken(key, token, 3*time.Hour, time.Now()); !ok || id != "device" {
+		t.Fatalf("got %q %v, want the device ID", id, ok)
+	}
+	if _, ok := verifyDeviceToken(key, token, time.Hour, time.Now()); ok {
+		t.Fatal("expired token was accepted")
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"goapi/pkg/featureflag"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature hides a route behind feature flag name: users outside the rollout get
+// 404, as if the endpoint did not exist yet. Put it after JWTAuth so the user's cohort
+// is known; anonymous requests only pass once the flag is at 100%.
+func RequireFeature(flags *featureflag.Set, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.Enabled(name, c.GetUint("user_id")) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Not found", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
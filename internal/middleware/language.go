@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"goapi/pkg/i18n"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Language negotiates the response language from Accept-Language and reports it in
+// Content-Language. For any language but the default, error messages are replaced by
+// the bundle's "error.<code>" text; codes with no translation keep the handler's
+// English message.
+func Language(bundle *i18n.Bundle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := bundle.Match(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		if lang != i18n.DefaultLanguage {
+			utils.SetMessageTranslator(c, func(code string) (string, bool) {
+				return bundle.Lookup(lang, "error."+code)
+			})
+		}
+		c.Next()
+	}
+}
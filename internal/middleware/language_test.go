@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapi/pkg/i18n"
+	"goapi/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLanguageLocalizesErrors(t *testing.T) {
+	bundle, err := i18n.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/missing", Language(bundle), func(c *gin.Context) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Post not found", nil)
+	})
+
+	tests := []struct {
+		acceptLanguage string
+		wantLanguage   string
+		wantMessage    string
+	}{
+		{"", "en", `"message":"Post not found"`},
+		{"id-ID,id;q=0.9", "id", `"message":"Sumber daya yang diminta tidak ditemukan."`},
+		{"fr", "en", `"message":"Post not found"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+			t.Errorf("%q: Content-Language %q, want %q", tt.acceptLanguage, got, tt.wantLanguage)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"code":"not_found"`) || !strings.Contains(body, tt.wantMessage) {
+			t.Errorf("%q: got %s", tt.acceptLanguage, body)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"os"
+	"testing"
+
+	"goapi/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+	os.Exit(m.Run())
+}
@@ -1,22 +1,36 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
+	"goapi/internal/services"
 	"goapi/pkg/logger"
+	"goapi/pkg/signedurl"
+	"goapi/pkg/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// AuthSourceKey records whether the request was authenticated by header or cookie
+	AuthSourceKey    = "auth_source"
+	authSourceHeader = "header"
+	authSourceCookie = "cookie"
+
+	// SessionIDKey holds the session (jti) of the request's access token
+	SessionIDKey = "session_id"
 )
 
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token, "+DeviceTokenHeader)
+		c.Writer.Header().Set("Access-Control-Expose-Headers", DeviceTokenHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -31,25 +45,25 @@ func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 
 		c.Next()
 
 		end := time.Now()
 		latency := end.Sub(start)
 
+		// c.Request now holds the context of the innermost middleware, so the logger
+		// carries the request ID, route and, once authenticated, the user ID
+		log := logger.FromContext(c.Request.Context())
+
 		// Log errors if any
 		if len(c.Errors) > 0 {
 			for _, e := range c.Errors.Errors() {
-				logger.Error("Request Error", "error", e)
+				log.Error("Request Error", "error", e)
 			}
 		}
 
-		// Structured Log
-		// RequestID is expected to be set by RequestID middleware
-		reqID := c.GetString("RequestID")
-
-		logger.Info("Request",
+		log.Info("Request",
 			"status", c.Writer.Status(),
 			"method", c.Request.Method,
 			"path", path,
@@ -57,45 +71,128 @@ func Logger() gin.HandlerFunc {
 			"ip", c.ClientIP(),
 			"user_agent", c.Request.UserAgent(),
 			"latency", latency.String(),
-			"request_id", reqID,
 		)
 	}
 }
 
-func JWTAuth() gin.HandlerFunc {
+// redactedQueryParams carry credentials: access tokens for streams, single-use tokens
+// such as login links, and share link signatures
+var redactedQueryParams = []string{AccessTokenQueryParam, "token", "refresh_token", signedurl.SigParam}
+
+// redactQuery hides credentials passed as query parameters from the request log. A
+// query that does not parse is dropped whole, since it cannot be redacted.
+func redactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	redacted := false
+	for _, param := range redactedQueryParams {
+		if values.Has(param) {
+			values.Set(param, "[REDACTED]")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// JWTAuthOptions configures JWTAuth
+type JWTAuthOptions struct {
+	// Extractors are tried in order to find the token (see TokenExtractor)
+	Extractors []TokenExtractor
+	// FreshUserState takes the role from the cached user state instead of the token and
+	// rejects deactivated users, so bans and demotions apply before the token expires
+	FreshUserState bool
+}
+
+// JWTAuth validates the access token and rejects tokens whose version no longer
+// matches the user's current token version (revoked by a security event) or whose
+// session was revoked. Requests without a token pass anonymously when SignedURL
+// verified their link.
+func JWTAuth(tokens services.TokenService, userService services.UserService, sessions services.SessionService, opts JWTAuthOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenString, source, err := extractToken(c, opts.Extractors)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if tokenString == "" {
+			// A verified signed URL grants access on its own; the request stays anonymous
+			if c.GetBool(signedurl.ContextKey) {
+				c.Next()
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 
-		tokenString := parts[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		state, err := userService.GetAuthState(c.Request.Context(), claims.UserID)
+		if err != nil || claims.TokenVersion != state.TokenVersion {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			return
+		}
+
+		// Tokens from before session tracking have no jti and are only checked by version.
+		// When Redis is down the session check is skipped rather than logging everyone out.
+		if claims.SessionID != "" {
+			active, err := sessions.Active(c.Request.Context(), claims.UserID, claims.SessionID)
+			if err != nil {
+				logger.FromContext(c.Request.Context()).Warn("Session check failed", "user_id", claims.UserID, "error", err)
+			} else if !active {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+				return
 			}
-			return []byte("your-secret-key-change-in-production"), nil
-		})
+		}
 
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			return
+		c.Set("user_id", claims.UserID)
+		c.Set(SessionIDKey, claims.SessionID)
+		c.Set("email", claims.Email)
+		c.Request = c.Request.WithContext(logger.With(c.Request.Context(), "user_id", claims.UserID))
+		role := claims.Role
+		if opts.FreshUserState {
+			if !state.Active {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is deactivated"})
+				return
+			}
+			role = state.Role
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("user_id", uint(claims["user_id"].(float64)))
-			c.Set("email", claims["email"].(string))
-			c.Set("role", claims["role"].(string))
-			c.Next()
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		c.Set("role", role)
+		c.Set("plan", state.Plan)
+		c.Set(AuthSourceKey, source)
+		c.Next()
+	}
+}
+
+// RequireRole restricts a route to users whose role (set by JWTAuth) is role. Others,
+// including anonymous signed-URL requests, get 403 in the standard error envelope.
+func RequireRole(role string) gin.HandlerFunc {
+	return RequireAnyRole(role)
+}
+
+// RequireAnyRole restricts a route to users with one of roles
+func RequireAnyRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, c.GetString("role")) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden", "requires role "+strings.Join(roles, " or "))
+			c.Abort()
+			return
 		}
+		c.Next()
 	}
 }
+
+// AdminOnly restricts a route group to users with the admin role.
+// It must run after JWTAuth, which sets "role" in the context.
+func AdminOnly() gin.HandlerFunc {
+	return RequireRole("admin")
+}
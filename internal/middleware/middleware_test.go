@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapi/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoggerRedactsCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		log := slog.New(slog.NewJSONHandler(&logs, nil))
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), log))
+	}, Logger())
+	router.GET("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"no query", "/posts", `"query":""`},
+		{"nothing to redact", "/posts?page=2&limit=20", `"query":"page=2&limit=20"`},
+		{"stream access token", "/posts/stream?access_token=s3cret", `"query":"access_token=%5BREDACTED%5D"`},
+		{"login link token", "/api/v1/login/magic?device=laptop&token=s3cret", `"query":"device=laptop&token=%5BREDACTED%5D"`},
+		{"share link signature", "/api/v1/posts/1?exp=1700000000&sig=s3cret", `"query":"exp=1700000000&sig=%5BREDACTED%5D"`},
+		{"unparsable query", "/api/v1/login/magic?token=s3cret%zz", `"query":"[REDACTED]"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if strings.Contains(logs.String(), "s3cret") || !strings.Contains(logs.String(), tt.want) {
+				t.Fatalf("got %s, want %s", logs.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -12,8 +13,49 @@ import (
 	mredis "github.com/ulule/limiter/v3/drivers/store/redis"
 )
 
-// RateLimiter returns a Gin middleware that limits requests based on IP.
-func RateLimiter(client *redis.Client, requests int, period time.Duration) gin.HandlerFunc {
+// KeyStrategy selects what a rate limiter counts requests by
+type KeyStrategy string
+
+const (
+	KeyByIP           KeyStrategy = "ip"
+	KeyByIPAndRoute   KeyStrategy = "ip_route"
+	KeyByUser         KeyStrategy = "user" // requires JWTAuth; falls back to IP when unauthenticated
+	KeyByUserAndRoute KeyStrategy = "user_route"
+	// Device strategies count by the anonymous device token (see DeviceToken) and fall
+	// back to IP without one. A client can hold several tokens, so never use them for
+	// limiters guarding credentials.
+	KeyByDevice         KeyStrategy = "device"
+	KeyByDeviceAndRoute KeyStrategy = "device_route"
+)
+
+// key builds the counter key for the request. Route strategies use the route
+// template (e.g. "GET /api/v1/posts/:id") so each endpoint gets its own counter.
+func (s KeyStrategy) key(c *gin.Context) string {
+	subject := "ip:" + c.ClientIP()
+	switch s {
+	case KeyByUser, KeyByUserAndRoute:
+		if userID := c.GetUint("user_id"); userID != 0 {
+			subject = fmt.Sprintf("user:%d", userID)
+		}
+	case KeyByDevice, KeyByDeviceAndRoute:
+		if deviceID := c.GetString(DeviceIDKey); deviceID != "" {
+			subject = "device:" + deviceID
+		}
+	}
+
+	if s == KeyByIPAndRoute || s == KeyByUserAndRoute || s == KeyByDeviceAndRoute {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		return subject + ":" + c.Request.Method + " " + route
+	}
+	return subject
+}
+
+// RateLimiter returns a Gin middleware that limits requests per key strategy.
+// name scopes the counters so limiters with different limits never share a key.
+func RateLimiter(client *redis.Client, name string, requests int, period time.Duration, strategy KeyStrategy) gin.HandlerFunc {
 	// 1. Define rate
 	rate := limiter.Rate{
 		Period: period,
@@ -31,8 +73,51 @@ func RateLimiter(client *redis.Client, requests int, period time.Duration) gin.H
 	instance := limiter.New(store, rate)
 
 	return func(c *gin.Context) {
-		key := c.ClientIP() // Simple IP-based limiter
+		key := name + ":" + strategy.key(c)
+
+		context, err := instance.Get(c, key)
+		if err != nil {
+			// Fail open on Redis error (log and proceed)
+			log.Printf("Rate limiter error: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(context.Limit, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(context.Remaining, 10))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(context.Reset, 10))
+
+		if context.Reached {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PlanRateLimiter limits authenticated requests according to the user's plan.
+// It must run after JWTAuth, which sets "user_id" and "plan" in the context.
+func PlanRateLimiter(client *redis.Client, limits map[string]int, defaultLimit int, period time.Duration, strategy KeyStrategy) gin.HandlerFunc {
+	store, err := mredis.NewStore(client)
+	if err != nil {
+		log.Printf("Failed to create rate limiter store: %v", err)
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	instances := make(map[string]*limiter.Limiter, len(limits))
+	for plan, requests := range limits {
+		instances[plan] = limiter.New(store, limiter.Rate{Period: period, Limit: int64(requests)})
+	}
+	fallback := limiter.New(store, limiter.Rate{Period: period, Limit: int64(defaultLimit)})
+
+	return func(c *gin.Context) {
+		instance, ok := instances[c.GetString("plan")]
+		if !ok {
+			instance = fallback
+		}
 
+		key := "plan:" + strategy.key(c)
 		context, err := instance.Get(c, key)
 		if err != nil {
 			// Fail open on Redis error (log and proceed)
@@ -3,19 +3,22 @@ package middleware
 import (
 	"fmt"
 	"goapi/pkg/logger"
+	"goapi/pkg/utctime"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"os"
 	"runtime/debug"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CustomRecovery is a middleware that recovers from any panics and writes a 500 if there was one.
-func CustomRecovery() gin.HandlerFunc {
+// Every panic gets an error ID, logged with the stack and returned to the client for support
+// requests. The panic value itself can leak internals, so it is only returned with exposeDetails
+// (development); production clients see the error ID alone.
+func CustomRecovery(exposeDetails bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -33,7 +36,7 @@ func CustomRecovery() gin.HandlerFunc {
 				stack := debug.Stack()
 				httpRequest, _ := httputil.DumpRequest(c.Request, false)
 				if brokenPipe {
-					logger.Error("Panic Recovered (Broken Pipe)",
+					logger.FromContext(c.Request.Context()).Error("Panic Recovered (Broken Pipe)",
 						"error", err,
 						"request", httpRequest,
 					)
@@ -43,18 +46,24 @@ func CustomRecovery() gin.HandlerFunc {
 					return
 				}
 
-				logger.Error("Panic Recovered",
+				errorID := generateRequestID()
+				logger.FromContext(c.Request.Context()).Error("Panic Recovered",
 					"error", err,
+					"error_id", errorID,
 					"stack", string(stack),
 					"path", c.Request.URL.Path,
-					"request_id", c.GetString(RequestIDKey),
 				)
 
+				message := "An unexpected error occurred"
+				if exposeDetails {
+					message = fmt.Sprintf("Panic: %v", err)
+				}
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 					"error":      "Internal Server Error",
-					"message":    fmt.Sprintf("Panic: %v", err),
+					"message":    message,
+					"error_id":   errorID,
 					"request_id": c.GetString(RequestIDKey),
-					"timestamp":  time.Now().Format(time.RFC3339),
+					"timestamp":  utctime.Now(),
 				})
 			}
 		}()
@@ -4,8 +4,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"goapi/pkg/logger"
+	"goapi/pkg/reqctx"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,14 +18,29 @@ const RequestIDKey = "RequestID"
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check for incoming header
-		requestID := c.GetHeader("X-Request-ID")
+		requestID := c.GetHeader(reqctx.RequestIDHeader)
 		if requestID == "" {
 			requestID = generateRequestID()
 		}
 
 		// Set in context and header
 		c.Set(RequestIDKey, requestID)
-		c.Writer.Header().Set("X-Request-ID", requestID)
+		c.Writer.Header().Set(reqctx.RequestIDHeader, requestID)
+
+		// Expose the ID and incoming trace headers to services and outbound HTTP calls
+		ctx := reqctx.WithRequestID(c.Request.Context(), requestID)
+		ctx = reqctx.WithTraceHeaders(ctx, c.Request.Header)
+
+		// Request-scoped logger: every logger.FromContext line carries the ID and route
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx = logger.NewContext(ctx, logger.Log.With(
+			slog.String("request_id", requestID),
+			slog.String("route", c.Request.Method+" "+route),
+		))
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 	}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const testPartnerSecret = "partner-secret"
+
+func newSignedRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	router := gin.New()
+	router.Any("/partner/posts", SignatureAuth(client, map[string]string{"partner": testPartnerSecret}, 5*time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// signedRequest signs target as a partner would; sendTarget is what is actually sent
+func signedRequest(method, target, sendTarget string, body []byte) *http.Request {
+	u, _ := url.Parse(target)
+	timestamp := time.Now().Unix()
+	signature := signRequest(testPartnerSecret, timestamp, method, u.Path, canonicalQuery(u), body)
+
+	req := httptest.NewRequest(method, sendTarget, bytes.NewReader(body))
+	req.Header.Set(APIKeyHeader, "partner")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, hex.EncodeToString(signature))
+	return req
+}
+
+func TestSignatureAuthCoversQuery(t *testing.T) {
+	router := newSignedRouter(t)
+
+	tests := []struct {
+		name       string
+		signed     string
+		sent       string
+		wantStatus int
+	}{
+		{"signed query", "/partner/posts?page=1&limit=20", "/partner/posts?page=1&limit=20", http.StatusOK},
+		{"reordered query", "/partner/posts?page=2&limit=20", "/partner/posts?limit=20&page=2", http.StatusOK},
+		{"changed parameter", "/partner/posts?page=3&limit=20", "/partner/posts?page=3&limit=100", http.StatusUnauthorized},
+		{"added parameter", "/partner/posts?page=4", "/partner/posts?page=4&format=ndjson", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, signedRequest(http.MethodGet, tt.signed, tt.sent, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestSignatureAuthRejectsLargeBody(t *testing.T) {
+	router := newSignedRouter(t)
+	body := bytes.Repeat([]byte("x"), signatureMaxBody+1)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, signedRequest(http.MethodPost, "/partner/posts", "/partner/posts", body))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got %d, want 413", rec.Code)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"goapi/pkg/signedurl"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignedURL verifies ?exp=&sig= links minted by signer and marks the request with
+// signedurl.ContextKey, which lets it through JWTAuth without a token. Requests without
+// sig pass untouched; a bad or expired signature is rejected. Use it before JWTAuth.
+func SignedURL(signer *signedurl.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if !query.Has(signedurl.SigParam) {
+			c.Next()
+			return
+		}
+
+		if err := signer.Verify(c.Request.Method, c.Request.URL.Path, query, time.Now()); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(signedurl.ContextKey, true)
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authSourceQuery = "query"
+
+	// AccessTokenQueryParam carries the token for clients that cannot set headers (EventSource, WebSocket)
+	AccessTokenQueryParam = "access_token"
+)
+
+var errInvalidAuthHeader = errors.New("invalid authorization header format")
+
+// TokenExtractor finds the access token in a request. It returns an empty token when
+// its source is absent, and an error when the source is present but malformed.
+type TokenExtractor func(c *gin.Context) (token, source string, err error)
+
+// FromAuthHeader reads "Authorization: Bearer <token>"
+func FromAuthHeader() TokenExtractor {
+	return func(c *gin.Context) (string, string, error) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			return "", "", nil
+		}
+		parts := strings.SplitN(authHeader, " ", 2)
+		if !(len(parts) == 2 && parts[0] == "Bearer") {
+			return "", "", errInvalidAuthHeader
+		}
+		return parts[1], authSourceHeader, nil
+	}
+}
+
+// FromCookie reads the token from the named cookie
+func FromCookie(name string) TokenExtractor {
+	return func(c *gin.Context) (string, string, error) {
+		if cookie, err := c.Cookie(name); err == nil && cookie != "" {
+			return cookie, authSourceCookie, nil
+		}
+		return "", "", nil
+	}
+}
+
+// FromQuery reads the token from the named query parameter on GET requests only, so
+// tokens never authorize state changes from a URL. Use it only on streaming routes:
+// URLs end up in proxy logs and browser history.
+func FromQuery(param string) TokenExtractor {
+	return func(c *gin.Context) (string, string, error) {
+		if c.Request.Method != http.MethodGet {
+			return "", "", nil
+		}
+		return c.Query(param), authSourceQuery, nil
+	}
+}
+
+// extractToken returns the token from the first extractor that finds one
+func extractToken(c *gin.Context, extractors []TokenExtractor) (string, string, error) {
+	for _, extract := range extractors {
+		token, source, err := extract(c)
+		if err != nil || token != "" {
+			return token, source, err
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"goapi/internal/models"
+	"goapi/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageMeter records one billable API call per authenticated request.
+// It must run after JWTAuth, which sets "user_id" in the context.
+func UsageMeter(metering services.MeteringService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if userID := c.GetUint("user_id"); userID != 0 {
+			metering.Record(c.Request.Context(), userID, models.MetricAPICalls, 1)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+type Plan struct {
+	ID                uint         `json:"id" gorm:"primaryKey"`
+	Code              string       `json:"code" gorm:"uniqueIndex;not null"`
+	Name              string       `json:"name" gorm:"not null"`
+	StripePriceID     string       `json:"-" gorm:"index"`
+	RequestsPerMinute int          `json:"requests_per_minute" gorm:"not null;default:100"`
+	MaxPostsPerDay    int          `json:"max_posts_per_day" gorm:"not null;default:0"` // 0 means unlimited
+	MaxPosts          int          `json:"max_posts" gorm:"not null;default:0"`         // live posts at once, 0 means unlimited
+	CreatedAt         utctime.Time `json:"created_at"`
+	UpdatedAt         utctime.Time `json:"updated_at"`
+}
+
+type Subscription struct {
+	ID                   uint         `json:"id" gorm:"primaryKey"`
+	UserID               uint         `json:"user_id" gorm:"index;not null"`
+	PlanCode             string       `json:"plan_code" gorm:"not null"`
+	StripeCustomerID     string       `json:"-" gorm:"index"`
+	StripeSubscriptionID string       `json:"-" gorm:"uniqueIndex"`
+	Status               string       `json:"status" gorm:"index"`
+	CurrentPeriodEnd     utctime.Time `json:"current_period_end"`
+	CreatedAt            utctime.Time `json:"created_at"`
+	UpdatedAt            utctime.Time `json:"updated_at"`
+}
+
+type CheckoutRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
+
+type CheckoutResponse struct {
+	SessionID string `json:"session_id"`
+	URL       string `json:"url"`
+}
+
+// IsActive reports whether the subscription currently grants its plan.
+// past_due keeps access while Stripe retries the payment.
+func (s *Subscription) IsActive() bool {
+	return s.Status == "active" || s.Status == "trialing" || s.Status == "past_due"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+
+	"goapi/pkg/utctime"
+)
+
+// Category groups posts; a post is in at most one. Names are unique, ignoring case.
+type Category struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"size:50;uniqueIndex;not null"`
+	Description string       `json:"description" gorm:"size:500;not null;default:''"`
+	CreatedAt   utctime.Time `json:"created_at"`
+	UpdatedAt   utctime.Time `json:"updated_at"`
+}
+
+// CategoryRequest is the body of POST /categories and PUT /categories/:id, which
+// replaces both fields
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// Normalize trims the name and description
+func (r *CategoryRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+}
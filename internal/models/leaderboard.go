@@ -0,0 +1,14 @@
+package models
+
+// LeaderboardEntry is one ranked author. Authors with equal counts share a rank.
+type LeaderboardEntry struct {
+	Rank   int            `json:"rank"`
+	Author AuthorResponse `json:"author"`
+	Posts  int64          `json:"posts"`
+}
+
+// Leaderboard ranks authors by posts published in one ISO week (UTC), e.g. "2026-W42"
+type Leaderboard struct {
+	Week    string             `json:"week"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// Reasons recorded for failed logins
+const (
+	LoginFailedPassword = "invalid_password"
+	LoginFailedPending  = "pending_review"
+	LoginFailedInactive = "inactive"
+	// LoginFailedPendingDeletion is a login to an account pending deletion without Restore
+	LoginFailedPendingDeletion = "pending_deletion"
+)
+
+// LoginEvent records one login attempt against an existing account
+type LoginEvent struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	UserID    uint         `json:"-" gorm:"index:idx_login_event_user_time;not null"`
+	IP        string       `json:"ip" gorm:"size:45"`
+	UserAgent string       `json:"user_agent" gorm:"size:512"`
+	Success   bool         `json:"success"`
+	Reason    string       `json:"reason,omitempty" gorm:"size:50"` // why a failed attempt was rejected
+	CreatedAt utctime.Time `json:"created_at" gorm:"index:idx_login_event_user_time,sort:desc"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// MagicLinkToken is a single-use, time-limited login link. As with PasswordResetToken,
+// only the SHA-256 of the token is stored.
+type MagicLinkToken struct {
+	ID        uint          `json:"-" gorm:"primaryKey"`
+	UserID    uint          `json:"-" gorm:"index;not null"`
+	TokenHash string        `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt utctime.Time  `json:"-" gorm:"not null"`
+	UsedAt    *utctime.Time `json:"-"` // set when the token is redeemed or superseded
+	CreatedAt utctime.Time  `json:"-"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkLoginRequest is the query of GET /login/magic, the link in the email
+type MagicLinkLoginRequest struct {
+	Token string `form:"token" binding:"required"`
+	// Device and Restore work as in LoginRequest
+	Device  string `form:"device" binding:"omitempty,max=100"`
+	Restore bool   `form:"restore"`
+
+	IP        string `form:"-"`
+	UserAgent string `form:"-"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// Pagination modes
+const (
+	PageModeCount   = "count"    // exact total via COUNT(*)
+	PageModeHasMore = "has_more" // fetch limit+1 rows and skip COUNT(*)
+)
+
+// PageRequest selects one page of a collection from ?page=&limit=&pagination= query params
+type PageRequest struct {
+	Page  int    `form:"page,default=1" binding:"min=1"`
+	Limit int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Mode  string `form:"pagination,default=count" binding:"oneof=count has_more"`
+}
+
+// Offset is the number of rows before the requested page
+func (p PageRequest) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// CountTotal reports whether the exact total should be computed
+func (p PageRequest) CountTotal() bool {
+	return p.Mode != PageModeHasMore
+}
+
+// PageInfo describes where a returned page sits in its collection
+type PageInfo struct {
+	Total   *int64 // nil when the count was skipped
+	HasMore bool
+	// NextCursor is the ?after= value of the next page of a keyset-paged list; 0 when
+	// there is none or the list has no cursor
+	NextCursor uint
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+
+	"goapi/pkg/utctime"
+)
+
+// MaxPasskeys caps the passkeys one user can register
+const MaxPasskeys = 10
+
+// Passkey is a WebAuthn credential a user registered to log in without a password. It
+// holds the credential's public key only; the private key never leaves the authenticator.
+type Passkey struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	UserID       uint   `json:"-" gorm:"index;not null"`
+	User         *User  `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Name         string `json:"name" gorm:"size:100;not null"`
+	CredentialID []byte `json:"-" gorm:"uniqueIndex;not null"`
+	PublicKey    []byte `json:"-" gorm:"not null"`
+	// AttestationType is the attestation format the authenticator used when registering
+	AttestationType string `json:"-" gorm:"size:32;not null;default:''"`
+	AAGUID          []byte `json:"-"`
+	// SignCount is the authenticator's signature counter as of the last login; a counter
+	// that goes backwards hints at a cloned authenticator
+	SignCount uint32 `json:"-" gorm:"not null;default:0"`
+	// BackupEligible never changes for a credential; a login reporting otherwise is rejected
+	BackupEligible bool `json:"-" gorm:"not null;default:false"`
+	// BackedUp reports whether the credential is synced, such as to a cloud keychain
+	BackedUp bool `json:"backed_up" gorm:"not null;default:false"`
+	// Transports lists how the browser can reach the authenticator, comma-separated
+	Transports string        `json:"-" gorm:"size:100;not null;default:''"`
+	LastUsedAt *utctime.Time `json:"last_used_at"`
+	CreatedAt  utctime.Time  `json:"created_at"`
+}
+
+// PasskeyRegistrationRequest is the body of POST /me/passkeys/register/finish
+type PasskeyRegistrationRequest struct {
+	// Name labels the passkey in the user's list ("iPhone"); it defaults to "Passkey"
+	Name string `json:"name" binding:"max=100"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.create
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// Normalize trims the name and fills in the default
+func (r *PasskeyRegistrationRequest) Normalize() {
+	if r.Name = strings.TrimSpace(r.Name); r.Name == "" {
+		r.Name = "Passkey"
+	}
+}
+
+// PasskeyLoginRequest is the body of POST /login/passkey/finish
+type PasskeyLoginRequest struct {
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get
+	Credential json.RawMessage `json:"credential" binding:"required"`
+	// Device and Restore work as in LoginRequest
+	Device  string `json:"device" binding:"omitempty,max=100"`
+	Restore bool   `json:"restore"`
+
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// PasswordResetToken is a single-use, time-limited password reset link. Only the
+// SHA-256 of the token is stored, so a leaked table cannot be used to reset passwords.
+type PasswordResetToken struct {
+	ID        uint          `json:"-" gorm:"primaryKey"`
+	UserID    uint          `json:"-" gorm:"index;not null"`
+	TokenHash string        `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt utctime.Time  `json:"-" gorm:"not null"`
+	UsedAt    *utctime.Time `json:"-"` // set when the token is redeemed or superseded
+	CreatedAt utctime.Time  `json:"-"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
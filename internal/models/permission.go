@@ -0,0 +1,35 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// Built-in permissions. Names are "<resource>:<action>:<scope>": "own" covers the
+// caller's own resources, "any" everyone's.
+const (
+	PermPostsDeleteOwn = "posts:delete:own"
+	PermPostsDeleteAny = "posts:delete:any"
+)
+
+// Permission is an action that can be granted to roles
+type Permission struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string       `json:"description"`
+	CreatedAt   utctime.Time `json:"created_at"`
+}
+
+// RolePermission grants a permission to every user with Role
+type RolePermission struct {
+	ID           uint         `json:"-" gorm:"primaryKey"`
+	Role         string       `json:"role" gorm:"size:50;uniqueIndex:idx_role_permission;not null"`
+	PermissionID uint         `json:"permission_id" gorm:"uniqueIndex:idx_role_permission;not null"`
+	Permission   Permission   `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt    utctime.Time `json:"created_at"`
+}
+
+// RolePermissions is the cached set of permission names granted to a role
+type RolePermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
@@ -1,34 +1,203 @@
 package models
 
 import (
-	"time"
+	"goapi/pkg/utctime"
 
 	"gorm.io/gorm"
 )
 
 type Post struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
+	ID        uint           `json:"id" gorm:"primaryKey;index:idx_posts_feed,priority:2,sort:desc"`
 	Title     string         `json:"title" gorm:"not null"`
 	Content   string         `json:"content" gorm:"type:text"`
-	UserID    uint           `json:"user_id" gorm:"index;not null"`
+	Locale    string         `json:"locale" gorm:"size:10;not null;default:'en'"` // base language of Title and Content
+	UserID    uint           `json:"user_id" gorm:"index;index:idx_posts_user_hash,priority:1;not null"`
 	User      *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time      `json:"created_at" gorm:"index:,sort:desc"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	CreatedAt utctime.Time   `json:"created_at" gorm:"index:,sort:desc;index:idx_posts_feed,priority:1,sort:desc"`
+	UpdatedAt utctime.Time   `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	Version   uint           `json:"version" gorm:"not null;default:1"` // Optimistic lock, bumped by every update
+	// ContentHash is the normalized title+content hash used for duplicate detection
+	ContentHash string `json:"-" gorm:"size:64;index:idx_posts_user_hash,priority:2"`
+	// WordCount and ReadingTimeMinutes are computed from Content whenever it is written
+	WordCount          int `json:"word_count" gorm:"not null;default:0"`
+	ReadingTimeMinutes int `json:"reading_time_minutes" gorm:"not null;default:0"`
+	// Excerpt is the opening words of Content, shown in lists instead of the full text
+	Excerpt string `json:"excerpt" gorm:"type:text;not null;default:''"`
+	// ArchivedAt hides the post from everyone but its author until it is unarchived
+	ArchivedAt *utctime.Time `json:"archived_at,omitempty" gorm:"index"`
+	// Tags are sorted by name. Purging a post removes its post_tags rows.
+	Tags []Tag `json:"tags,omitempty" gorm:"many2many:post_tags;constraint:OnDelete:CASCADE"`
+	// CategoryID is the post's optional category. Category is never loaded; it only
+	// declares the foreign key.
+	CategoryID *uint     `json:"category_id" gorm:"index"`
+	Category   *Category `json:"-" gorm:"constraint:OnDelete:SET NULL"`
 }
 
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required,min=3,max=200"`
+	// Length limits are enforced by the service after HTML is stripped
+	Title   string `json:"title" binding:"required"`
 	Content string `json:"content" binding:"required"`
+	// Locale is the language the post is written in; defaults to English
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	// Tags are normalized and checked by the service; duplicates are dropped
+	Tags []string `json:"tags" binding:"omitempty,max=10,dive,max=50"`
+	// CategoryID must name an existing category
+	CategoryID *uint `json:"category_id" binding:"omitempty,min=1"`
 }
 
 type PostResponse struct {
-	ID        uint          `json:"id"`
-	Title     string        `json:"title"`
-	Content   string        `json:"content"`
-	UserID    uint          `json:"user_id"`
-	Author    *UserResponse `json:"author,omitempty"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID        uint            `json:"id"`
+	Title     string          `json:"title"`
+	Excerpt   string          `json:"excerpt"`
+	Content   string          `json:"content"`
+	UserID    uint            `json:"user_id"`
+	Author    *AuthorResponse `json:"author,omitempty"`
+	CreatedAt utctime.Time    `json:"created_at"`
+	Version   uint            `json:"version"`
+	Warnings  []string        `json:"warnings,omitempty"`
+
+	WordCount          int `json:"word_count"`
+	ReadingTimeMinutes int `json:"reading_time_minutes"`
+
+	// Locale is the language of Title and Content as returned: a translation matching
+	// Accept-Language, or OriginalLocale when there is none
+	Locale         string `json:"locale"`
+	OriginalLocale string `json:"original_locale"`
+
+	Tags       []string      `json:"tags"`
+	CategoryID *uint         `json:"category_id"`
+	ArchivedAt *utctime.Time `json:"archived_at,omitempty"`
+}
+
+// Post list sort orders; a leading "-" sorts descending
+const (
+	PostSortNewest          = "newest"
+	PostSortReadingTime     = "reading_time"
+	PostSortReadingTimeDesc = "-reading_time"
+	PostSortWordCount       = "word_count"
+	PostSortWordCountDesc   = "-word_count"
+)
+
+// PostListRequest selects one page of posts from ?page=&limit=&pagination=&sort=
+type PostListRequest struct {
+	PageRequest
+	Sort string `form:"sort,default=newest" binding:"oneof=newest reading_time -reading_time word_count -word_count"`
+	// Tag limits the list to posts carrying it; the handler normalizes it
+	Tag string `form:"tag" binding:"omitempty,max=50"`
+	// CategoryID limits the list to posts in one category
+	CategoryID uint `form:"category_id" binding:"omitempty,min=1"`
+	// After pages by keyset instead of offset: the posts that follow post After in the
+	// newest-first order. Only valid with sort=newest; Page and Mode are then ignored.
+	After uint `form:"after" binding:"omitempty,min=1"`
+	// IncludeArchived is set by the handler when authors list their own posts
+	IncludeArchived bool `form:"-"`
+}
+
+// ListPostsQuery is the query string of GET /posts
+type ListPostsQuery struct {
+	PostListRequest
+	// UserID limits the list to one author's posts
+	UserID uint `form:"user_id" binding:"omitempty,min=1"`
+	// Format "ndjson" streams every matching post instead of a page
+	Format string `form:"format" binding:"omitempty,oneof=json ndjson"`
+	// Stream returns every matching post in the usual envelope, encoded while it is read
+	Stream bool `form:"stream"`
+}
+
+// PostSearchQuery is the query string of GET /posts/search. Q uses web search syntax:
+// plain words must all match, "quoted phrases" match in order, or and -word are honored.
+type PostSearchQuery struct {
+	PageRequest
+	Q string `form:"q" binding:"required,min=2,max=200"`
+}
+
+// PostHighlights are fragments of a matched post with the matched words wrapped in
+// <mark>. Everything else is HTML-escaped, so clients can insert them as markup.
+type PostHighlights struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// PostSearchHit is a post matched by a search, as read by the repository
+type PostSearchHit struct {
+	Post       Post
+	Rank       float64
+	Highlights PostHighlights
+}
+
+// PostSearchResult is one hit of GET /posts/search, best match first
+type PostSearchResult struct {
+	PostListItem
+	Rank       float64        `json:"rank"`
+	Highlights PostHighlights `json:"highlights"`
+}
+
+// ExportPostsQuery is the query string of the NDJSON post exports
+type ExportPostsQuery struct {
+	UserID uint `form:"user_id" binding:"omitempty,min=1"`
+}
+
+// SharePostRequest asks for a signed link to a post; ExpiresIn is a Go duration ("24h")
+type SharePostRequest struct {
+	ExpiresIn string `json:"expires_in"`
+}
+
+// SharePostResponse is a link that reads the post without logging in until ExpiresAt
+type SharePostResponse struct {
+	URL       string       `json:"url"`
+	ExpiresAt utctime.Time `json:"expires_at"`
+}
+
+// OrderBy returns the SQL ORDER BY clause for Sort. Ties are broken newest first, then
+// by ID, so pages stay stable.
+func (r PostListRequest) OrderBy() string {
+	switch r.Sort {
+	case PostSortReadingTime:
+		return "reading_time_minutes ASC, created_at DESC, id DESC"
+	case PostSortReadingTimeDesc:
+		return "reading_time_minutes DESC, created_at DESC, id DESC"
+	case PostSortWordCount:
+		return "word_count ASC, created_at DESC, id DESC"
+	case PostSortWordCountDesc:
+		return "word_count DESC, created_at DESC, id DESC"
+	default:
+		return "created_at DESC, id DESC"
+	}
+}
+
+// Keyset reports whether the list can be paged with After: only the newest-first
+// order has a cursor
+func (r PostListRequest) Keyset() bool {
+	return r.Sort == PostSortNewest || r.Sort == ""
+}
+
+// PostListItem is a post as shown by list endpoints: the excerpt instead of the full
+// content, and none of the fields only the detail endpoint needs
+type PostListItem struct {
+	ID        uint            `json:"id"`
+	Title     string          `json:"title"`
+	Excerpt   string          `json:"excerpt"`
+	UserID    uint            `json:"user_id"`
+	Author    *AuthorResponse `json:"author,omitempty"`
+	CreatedAt utctime.Time    `json:"created_at"`
+
+	WordCount          int `json:"word_count"`
+	ReadingTimeMinutes int `json:"reading_time_minutes"`
+
+	// Locale and OriginalLocale work as in PostResponse
+	Locale         string `json:"locale"`
+	OriginalLocale string `json:"original_locale"`
+
+	Tags       []string      `json:"tags"`
+	CategoryID *uint         `json:"category_id"`
+	ArchivedAt *utctime.Time `json:"archived_at,omitempty"`
+}
+
+// PostPage is one page of post list items, as cached for list endpoints
+type PostPage struct {
+	Posts []PostListItem `json:"posts"`
+	Info  PageInfo       `json:"info"`
 }
 
 // ToResponse converts Post to PostResponse
@@ -36,15 +205,55 @@ func (p *Post) ToResponse() PostResponse {
 	resp := PostResponse{
 		ID:        p.ID,
 		Title:     p.Title,
+		Excerpt:   p.Excerpt,
 		Content:   p.Content,
 		UserID:    p.UserID,
+		Version:   p.Version,
 		CreatedAt: p.CreatedAt,
+
+		WordCount:          p.WordCount,
+		ReadingTimeMinutes: p.ReadingTimeMinutes,
+
+		Locale:         p.Locale,
+		OriginalLocale: p.Locale,
+
+		Tags:       TagNames(p.Tags),
+		CategoryID: p.CategoryID,
+		ArchivedAt: p.ArchivedAt,
 	}
 
 	if p.User != nil {
-		author := p.User.ToResponse()
+		author := p.User.ToAuthor()
 		resp.Author = &author
 	}
 
 	return resp
 }
+
+// ToListItem converts Post to PostListItem
+func (p *Post) ToListItem() PostListItem {
+	item := PostListItem{
+		ID:        p.ID,
+		Title:     p.Title,
+		Excerpt:   p.Excerpt,
+		UserID:    p.UserID,
+		CreatedAt: p.CreatedAt,
+
+		WordCount:          p.WordCount,
+		ReadingTimeMinutes: p.ReadingTimeMinutes,
+
+		Locale:         p.Locale,
+		OriginalLocale: p.Locale,
+
+		Tags:       TagNames(p.Tags),
+		CategoryID: p.CategoryID,
+		ArchivedAt: p.ArchivedAt,
+	}
+
+	if p.User != nil {
+		author := p.User.ToAuthor()
+		item.Author = &author
+	}
+
+	return item
+}
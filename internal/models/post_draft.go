@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"goapi/pkg/utctime"
+)
+
+// PostDraft holds autosaved, unpublished edits to a post. There is one draft per post,
+// overwritten in place, so autosaving never creates revisions.
+type PostDraft struct {
+	ID      uint   `json:"-" gorm:"primaryKey"`
+	PostID  uint   `json:"post_id" gorm:"uniqueIndex;not null"`
+	Title   string `json:"title"`
+	Content string `json:"content" gorm:"type:text"`
+	// Revision is the editor's timestamp for this content; a save only applies when
+	// it is newer than the stored one (last write wins)
+	Revision  utctime.Time `json:"revision" gorm:"not null"`
+	UpdatedAt utctime.Time `json:"updated_at"`
+}
+
+type SaveDraftRequest struct {
+	// Drafts may be incomplete, so only maximum lengths are enforced
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Revision time.Time `json:"revision" binding:"required"`
+}
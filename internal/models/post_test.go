@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPostAuthorOmitsPrivateFields(t *testing.T) {
+	post := &Post{
+		ID:     1,
+		UserID: 7,
+		User: &User{
+			ID:       7,
+			Email:    "jane@example.com",
+			Username: "jane",
+			FullName: "Jane Doe",
+			Role:     "admin",
+			Plan:     "pro",
+			Active:   true,
+		},
+	}
+
+	for name, value := range map[string]any{"response": post.ToResponse(), "list item": post.ToListItem()} {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Author map[string]any `json:"author"`
+		}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"email", "role", "plan", "active", "last_login_at", "full_name"} {
+			if _, ok := decoded.Author[field]; ok {
+				t.Errorf("%s: author exposes %q", name, field)
+			}
+		}
+		if decoded.Author["username"] != "jane" {
+			t.Errorf("%s: author username = %v, want jane", name, decoded.Author["username"])
+		}
+	}
+}
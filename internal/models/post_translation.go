@@ -0,0 +1,22 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// PostTranslation is a post's title and content in a locale other than its original one
+type PostTranslation struct {
+	ID        uint         `json:"-" gorm:"primaryKey"`
+	PostID    uint         `json:"post_id" gorm:"uniqueIndex:idx_post_translation_locale,priority:1;not null"`
+	Locale    string       `json:"locale" gorm:"size:10;uniqueIndex:idx_post_translation_locale,priority:2;not null"` // base language, e.g. "id"
+	Title     string       `json:"title" gorm:"not null"`
+	Content   string       `json:"content" gorm:"type:text"`
+	CreatedAt utctime.Time `json:"created_at"`
+	UpdatedAt utctime.Time `json:"updated_at"`
+}
+
+type TranslatePostRequest struct {
+	// Length limits are enforced by the service after HTML is stripped
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
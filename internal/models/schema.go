@@ -0,0 +1,21 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// SchemaMigration records each schema version applied to the database
+type SchemaMigration struct {
+	Version   int          `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt utctime.Time `json:"applied_at"`
+}
+
+// MigrationLock is the single-row status of the latest migration run. The run itself
+// is serialized by a Postgres advisory lock; this row says who holds it and since when.
+type MigrationLock struct {
+	ID         uint          `json:"-" gorm:"primaryKey;autoIncrement:false"` // always 1
+	Version    int           `json:"version"`                                 // schema version the run migrates to
+	Holder     string        `json:"holder" gorm:"size:255"`                  // hostname:pid of the migrating instance
+	StartedAt  utctime.Time  `json:"started_at"`
+	FinishedAt *utctime.Time `json:"finished_at"` // nil while running or after a crashed run
+}
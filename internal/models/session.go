@@ -0,0 +1,18 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// Session is one issued access token (its jti), tracked so users can list and revoke
+// where they are logged in
+type Session struct {
+	ID        string       `json:"id"`
+	Device    string       `json:"device,omitempty"` // label sent by the client at login
+	IP        string       `json:"ip"`
+	UserAgent string       `json:"user_agent"`
+	IssuedAt  utctime.Time `json:"issued_at"`
+	ExpiresAt utctime.Time `json:"expires_at"`
+	// Current marks the session of the token making the request
+	Current bool `json:"current"`
+}
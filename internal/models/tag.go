@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+
+	"goapi/pkg/utctime"
+)
+
+// Limits on the tags of one post
+const (
+	MaxPostTags  = 10
+	MaxTagLength = 50
+)
+
+// Tag labels posts. Names are stored normalized (see NormalizeTag) and are unique.
+type Tag struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	Name      string       `json:"name" gorm:"size:50;uniqueIndex;not null"`
+	CreatedAt utctime.Time `json:"created_at"`
+}
+
+// TagCount is a tag with the number of live, unarchived posts carrying it
+type TagCount struct {
+	Name  string `json:"name"`
+	Posts int64  `json:"posts" gorm:"column:post_count"`
+}
+
+// ListTagsQuery is the query string of GET /tags
+type ListTagsQuery struct {
+	Limit int `form:"limit,default=50" binding:"min=1,max=200"`
+}
+
+// NormalizeTag trims and lowercases a tag name, so "Golang " and "golang" are one tag
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// TagNames returns the names of tags, never nil
+func TagNames(tags []Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
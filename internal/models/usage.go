@@ -0,0 +1,45 @@
+package models
+
+import (
+	"goapi/pkg/utctime"
+)
+
+// Billable metrics recorded by the metering subsystem
+const (
+	MetricAPICalls     = "api_calls"
+	MetricStorageBytes = "storage_bytes"
+	MetricPostsCreated = "posts_created"
+)
+
+// Rollup periods
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// UsageEvent is a single raw billable event
+type UsageEvent struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	UserID    uint         `json:"user_id" gorm:"index:idx_usage_event_user_time;not null"`
+	Metric    string       `json:"metric" gorm:"not null"`
+	Quantity  int64        `json:"quantity" gorm:"not null"`
+	CreatedAt utctime.Time `json:"created_at" gorm:"index:idx_usage_event_user_time;index"`
+}
+
+// UsageRollup aggregates usage per user, metric and day/month
+type UsageRollup struct {
+	ID          uint         `json:"-" gorm:"primaryKey"`
+	UserID      uint         `json:"user_id" gorm:"uniqueIndex:idx_usage_rollup;not null"`
+	Metric      string       `json:"metric" gorm:"uniqueIndex:idx_usage_rollup;not null"`
+	Period      string       `json:"period" gorm:"uniqueIndex:idx_usage_rollup;not null"`
+	PeriodStart utctime.Time `json:"period_start" gorm:"uniqueIndex:idx_usage_rollup;not null"`
+	Quantity    int64        `json:"quantity" gorm:"not null"`
+	UpdatedAt   utctime.Time `json:"updated_at"`
+}
+
+// UsageReportRow is one line of the admin usage report
+type UsageReportRow struct {
+	UserID   uint   `json:"user_id"`
+	Metric   string `json:"metric"`
+	Quantity int64  `json:"quantity"`
+}
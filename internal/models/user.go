@@ -1,23 +1,43 @@
 package models
 
 import (
-	"time"
+	"strings"
+	"unicode/utf8"
+
+	"goapi/pkg/password"
+	"goapi/pkg/pii"
+	"goapi/pkg/utctime"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// ReviewPending marks signups held for manual review by bot detection
+const ReviewPending = "pending"
+
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"` // Don't expose in JSON
-	FullName  string         `json:"full_name" gorm:"index"`
-	Role      string         `json:"role" gorm:"default:'user'"`
-	Active    bool           `json:"active" gorm:"default:true;index"`
-	CreatedAt time.Time      `json:"created_at" gorm:"index:,sort:desc"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Email        string         `json:"email" gorm:"serializer:encrypted;not null"` // Encrypted at rest
+	EmailIndex   string         `json:"-" gorm:"size:64"`                           // Blind index of the normalized email, unique among live users
+	Username     string         `json:"username" gorm:"uniqueIndex;not null"`
+	Password     string         `json:"-" gorm:"not null"` // Don't expose in JSON
+	FullName     string         `json:"full_name" gorm:"index"`
+	Role         string         `json:"role" gorm:"default:'user';index"`
+	Active       bool           `json:"active" gorm:"default:true;index"`
+	TokenVersion uint           `json:"-" gorm:"not null;default:0"` // Bumped to revoke issued tokens
+	Plan         string         `json:"plan" gorm:"default:'free';index"`
+	AvatarURL    string         `json:"avatar_url" gorm:"size:512" binding:"omitempty,url,max=512"` // Uploaded or remote avatar; empty falls back to Gravatar
+	ReviewStatus string         `json:"-" gorm:"size:20;index"`                                     // ReviewPending while a flagged signup awaits review
+	ReviewFlags  string         `json:"-"`                                                          // Comma-separated bot detection flags
+	Version      uint           `json:"version" gorm:"not null;default:1"`                          // Optimistic lock, bumped by every update
+	LastLoginAt  *utctime.Time  `json:"last_login_at" gorm:"index"`
+	CreatedAt    utctime.Time   `json:"created_at" gorm:"index:,sort:desc"`
+	UpdatedAt    utctime.Time   `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// DeletionScheduledAt is when a deletion the user asked for erases the account;
+	// until then logging in with LoginRequest.Restore cancels it
+	DeletionScheduledAt *utctime.Time `json:"-" gorm:"index"`
+	DeletionRemindedAt  *utctime.Time `json:"-"` // When the reminder before the scheduled deletion was sent
 }
 
 type RegisterRequest struct {
@@ -25,48 +45,225 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=30"`
 	Password string `json:"password" binding:"required,min=6"`
 	FullName string `json:"full_name" binding:"required"`
+
+	// Bot detection: Website is a hidden honeypot field that must stay empty,
+	// FormToken comes from GET /register/form-token when the form is rendered
+	Website     string   `json:"website"`
+	FormToken   string   `json:"form_token"`
+	ReviewFlags []string `json:"-"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// Device optionally names the client ("Work laptop") in the session list
+	Device string `json:"device" binding:"omitempty,max=100"`
+	// Restore cancels a pending deletion of the account. Without it, logging in to such
+	// an account fails with account_pending_deletion.
+	Restore bool `json:"restore"`
+
+	// Set by the handler for the login history
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type LogoutAllRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteAccountRequest confirms DELETE /me with the user's password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// BanUserRequest optionally records why an admin banned a user
+type BanUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ChangeRoleRequest sets a user's role
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin user"`
+}
+
+// DeletedUserResponse is a soft-deleted user awaiting the retention purge
+type DeletedUserResponse struct {
+	UserResponse
+	DeletedAt utctime.Time `json:"deleted_at"`
+}
+
+// ListUsersQuery is the query string of GET /users
+type ListUsersQuery struct {
+	PageRequest
+	// Q matches part of a username or full name, ignoring case, or a whole email address
+	Q      string `form:"q" binding:"omitempty,min=2,max=100"`
+	Role   string `form:"role" binding:"omitempty,oneof=admin user"`
+	Active *bool  `form:"active"`
+}
+
+// AdminUserResponse is a user as admins list them: deleted users are included until
+// the retention purge, with DeletedAt set
+type AdminUserResponse struct {
+	UserResponse
+	DeletedAt *utctime.Time `json:"deleted_at"`
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	FullName  string    `json:"full_name"`
-	Role      string    `json:"role"`
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-// HashPassword hashes the user password
-func (u *User) HashPassword() error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	ID           uint          `json:"id"`
+	Email        string        `json:"email"`
+	Username     string        `json:"username"`
+	FullName     string        `json:"full_name"`
+	Role         string        `json:"role"`
+	Plan         string        `json:"plan"`
+	Active       bool          `json:"active"`
+	AvatarURL    string        `json:"avatar_url"`
+	ReviewStatus string        `json:"review_status,omitempty"`
+	Version      uint          `json:"version"`
+	LastLoginAt  *utctime.Time `json:"last_login_at,omitempty"`
+	PostCount    *int64        `json:"post_count,omitempty"` // Set by list endpoints through the stats loader
+	CreatedAt    utctime.Time  `json:"created_at"`
+
+	// DeletionScheduledAt is set while the account is pending deletion
+	DeletionScheduledAt *utctime.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// MaxUserLookup caps the IDs plus usernames one lookup may ask for
+const MaxUserLookup = 100
+
+// UserLookupRequest is the body of POST /users/lookup; at least one list must be non-empty
+type UserLookupRequest struct {
+	IDs       []uint   `json:"ids" binding:"omitempty,max=100,dive,min=1"`
+	Usernames []string `json:"usernames" binding:"omitempty,max=100,dive,min=1,max=30"`
+}
+
+// AuthorStats summarizes a user's activity as an author
+type AuthorStats struct {
+	UserID    uint  `json:"user_id"`
+	PostCount int64 `json:"post_count"`
+}
+
+// PublicUserResponse is a user as seen by other non-admin users: the email is masked
+// and account status, plan and version fields are left out
+type PublicUserResponse struct {
+	ID        uint         `json:"id"`
+	Email     string       `json:"email"`
+	Username  string       `json:"username"`
+	FullName  string       `json:"full_name"`
+	AvatarURL string       `json:"avatar_url"`
+	CreatedAt utctime.Time `json:"created_at"`
+}
+
+// AuthorResponse is the user embedded in posts and leaderboards. Anyone who can read a
+// post sees it, including signed-link viewers and partners, so it holds display fields only.
+type AuthorResponse struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// AuthState is the cached subset of user data verified on every authenticated request
+type AuthState struct {
+	TokenVersion uint   `json:"token_version"`
+	Plan         string `json:"plan"`
+	Role         string `json:"role"`
+	Active       bool   `json:"active"`
+}
+
+// NormalizeEmail trims and lowercases an email so lookups and uniqueness ignore case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// BeforeSave keeps the email blind index in sync with the email
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	// Column updates run the hook on an empty model; they never touch the email
+	if u.Email == "" {
+		return nil
+	}
+	index, err := pii.BlindIndex(NormalizeEmail(u.Email))
 	if err != nil {
 		return err
 	}
-	u.Password = string(hash)
+	u.EmailIndex = index
 	return nil
 }
 
-// CheckPassword compares password with hash
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// HashPassword replaces the plaintext password with its hash
+func (u *User) HashPassword(hasher *password.Hasher) error {
+	hash, err := hasher.Hash(u.Password)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	return nil
+}
+
+// CheckPassword compares plain with the stored hash. needsRehash reports a hash made
+// with an outdated algorithm or parameters.
+func (u *User) CheckPassword(hasher *password.Hasher, plain string) (ok, needsRehash bool) {
+	return hasher.Verify(plain, u.Password)
 }
 
 // ToResponse converts User to UserResponse (hides sensitive data)
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Username:  u.Username,
-		FullName:  u.FullName,
-		Role:      u.Role,
-		Active:    u.Active,
-		CreatedAt: u.CreatedAt,
+		ID:           u.ID,
+		Email:        u.Email,
+		Username:     u.Username,
+		FullName:     u.FullName,
+		Role:         u.Role,
+		Plan:         u.Plan,
+		Active:       u.Active,
+		ReviewStatus: u.ReviewStatus,
+		Version:      u.Version,
+		LastLoginAt:  u.LastLoginAt,
+		CreatedAt:    u.CreatedAt,
+
+		DeletionScheduledAt: u.DeletionScheduledAt,
+	}
+}
+
+// Public returns the view of r shown to viewers who are neither admins nor the user
+func (r UserResponse) Public() PublicUserResponse {
+	return PublicUserResponse{
+		ID:        r.ID,
+		Email:     MaskEmail(r.Email),
+		Username:  r.Username,
+		FullName:  r.FullName,
+		AvatarURL: r.AvatarURL,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+// MaskEmail keeps the first character of the local part and the domain: j***@example.com
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return "***"
+	}
+	if local == "" {
+		return "***@" + domain
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+// ToAuthor returns u as shown next to their posts; the avatar is resolved by the caller
+func (u *User) ToAuthor() AuthorResponse {
+	return AuthorResponse{ID: u.ID, Username: u.Username}
+}
+
+// ToAuthState extracts the data JWTAuth needs to validate a request
+func (u *User) ToAuthState() AuthState {
+	return AuthState{
+		TokenVersion: u.TokenVersion,
+		Plan:         u.Plan,
+		Role:         u.Role,
+		Active:       u.Active,
 	}
 }
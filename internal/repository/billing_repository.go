@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BillingRepository interface {
+	GetPlans(ctx context.Context) ([]models.Plan, error)
+	GetPlanByCode(ctx context.Context, code string) (*models.Plan, error)
+	GetPlanByStripePriceID(ctx context.Context, priceID string) (*models.Plan, error)
+	UpsertPlan(ctx context.Context, plan *models.Plan) error
+	GetSubscriptionByUserID(ctx context.Context, userID uint, opts ...QueryOption) (*models.Subscription, error)
+	GetSubscriptionByStripeID(ctx context.Context, stripeSubscriptionID string, opts ...QueryOption) (*models.Subscription, error)
+	GetSubscriptionByCustomerID(ctx context.Context, customerID string, opts ...QueryOption) (*models.Subscription, error)
+	SaveSubscription(ctx context.Context, sub *models.Subscription) error
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type billingRepository struct {
+	db *gorm.DB
+}
+
+func NewBillingRepository(db *gorm.DB) BillingRepository {
+	return &billingRepository{db: db}
+}
+
+func (r *billingRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return utils.RunInTransaction(ctx, r.db, fn)
+}
+
+func (r *billingRepository) GetPlans(ctx context.Context) ([]models.Plan, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var plans []models.Plan
+	if err := db.Order("id ASC").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (r *billingRepository) GetPlanByCode(ctx context.Context, code string) (*models.Plan, error) {
+	return r.firstPlan(ctx, "code = ?", code)
+}
+
+func (r *billingRepository) GetPlanByStripePriceID(ctx context.Context, priceID string) (*models.Plan, error) {
+	return r.firstPlan(ctx, "stripe_price_id = ?", priceID)
+}
+
+func (r *billingRepository) firstPlan(ctx context.Context, query string, arg interface{}) (*models.Plan, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var plan models.Plan
+	if err := db.Where(query, arg).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// UpsertPlan inserts the plan or updates the existing one with the same code
+func (r *billingRepository) UpsertPlan(ctx context.Context, plan *models.Plan) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "stripe_price_id", "updated_at"}),
+	}).Create(plan).Error
+}
+
+func (r *billingRepository) GetSubscriptionByUserID(ctx context.Context, userID uint, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(ctx, "user_id = ?", userID, opts)
+}
+
+func (r *billingRepository) GetSubscriptionByStripeID(ctx context.Context, stripeSubscriptionID string, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(ctx, "stripe_subscription_id = ?", stripeSubscriptionID, opts)
+}
+
+func (r *billingRepository) GetSubscriptionByCustomerID(ctx context.Context, customerID string, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(ctx, "stripe_customer_id = ?", customerID, opts)
+}
+
+func (r *billingRepository) firstSubscription(ctx context.Context, query string, arg interface{}, opts []QueryOption) (*models.Subscription, error) {
+	db := applyQueryOptions(utils.GetDBFromContext(ctx, r.db), opts)
+	var sub models.Subscription
+	if err := db.Where(query, arg).Order("updated_at DESC").First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *billingRepository) SaveSubscription(ctx context.Context, sub *models.Subscription) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Save(sub).Error
+}
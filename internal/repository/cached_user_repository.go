@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+)
+
+// CacheInvalidator is implemented by caching repository decorators. Their own writes
+// invalidate what they change; code that changes rows another way, such as raw SQL in
+// a job, calls Invalidate with the IDs it touched.
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, ids ...uint) error
+}
+
+// usersTag marks cached reads whose result any user write can change, such as lists
+const usersTag = "repo:users"
+
+func cachedUserTag(id uint) string {
+	return fmt.Sprintf("repo:user:%d", id)
+}
+
+// CachedUserRepository caches the reads of a UserRepository in Redis, keyed by method
+// and arguments, and invalidates them on writes. Reads inside a transaction or with
+// QueryOptions go to the database, as does GetByEmail, which would put emails in keys.
+// Rows are gob-encoded, not stored through their json tags, so the password hash and
+// token version survive the round trip; the cache is as sensitive as the users table.
+type CachedUserRepository struct {
+	repo   UserRepository
+	cache  *cache.Cache
+	policy cache.Policy
+}
+
+var _ CacheInvalidator = (*CachedUserRepository)(nil)
+
+// NewCachedUserRepository wraps repo. policy.NotFound is set to ErrUserNotFound, so
+// lookups of unknown IDs are cached as missing too.
+func NewCachedUserRepository(repo UserRepository, cacheStore *cache.Cache, policy cache.Policy) UserRepository {
+	policy.NotFound = ErrUserNotFound
+	return &CachedUserRepository{repo: repo, cache: cacheStore, policy: policy}
+}
+
+// pendingInvalidation collects the IDs written inside WithTransaction, invalidated once
+// it returns so no concurrent read can cache a row the transaction has not committed
+type pendingInvalidation struct {
+	ids []uint
+}
+
+type pendingInvalidationKey struct{}
+
+// cachedRead returns the result of load cached under the method and its arguments.
+// tags are the cache tags its result is stored under.
+func cachedRead[T any](ctx context.Context, r *CachedUserRepository, tags []string, load func(ctx context.Context) (T, error), method string, args ...any) (T, error) {
+	if utils.InTransaction(ctx) || ctx.Value(pendingInvalidationKey{}) != nil {
+		return load(ctx)
+	}
+
+	parts := []string{"repo:user", method}
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+
+	var (
+		loaded T
+		fresh  bool
+	)
+	data, err := cache.ReadThroughTagged(ctx, r.cache, strings.Join(parts, ":"), r.policy, func(ctx context.Context) ([]byte, []string, error) {
+		value, err := load(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		loaded, fresh = value, true
+
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+			return nil, nil, err
+		}
+		return buf.Bytes(), tags, nil
+	})
+	if fresh {
+		return loaded, nil
+	}
+	if err != nil {
+		return loaded, err
+	}
+
+	var value T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return load(ctx)
+	}
+	return value, nil
+}
+
+// Invalidate drops every cached read that includes users ids, and every list
+func (r *CachedUserRepository) Invalidate(ctx context.Context, ids ...uint) error {
+	inv := cache.Invalidation{Tags: []string{usersTag}}
+	for _, id := range ids {
+		// The GetByID key is listed too: a not-found tombstone carries no tags
+		inv.Keys = append(inv.Keys, fmt.Sprintf("repo:user:GetByID:%d", id))
+		inv.Tags = append(inv.Tags, cachedUserTag(id))
+	}
+	return r.cache.Invalidate(ctx, inv)
+}
+
+// changed invalidates ids after a successful write, or once the enclosing transaction
+// returns. A failed invalidation only leaves stale reads until they expire, so it is
+// logged rather than failing the write.
+func (r *CachedUserRepository) changed(ctx context.Context, err error, ids ...uint) error {
+	if err != nil {
+		return err
+	}
+	if pending, ok := ctx.Value(pendingInvalidationKey{}).(*pendingInvalidation); ok {
+		pending.ids = append(pending.ids, ids...)
+		return nil
+	}
+	if err := r.Invalidate(ctx, ids...); err != nil {
+		logger.FromContext(ctx).Warn("Failed to invalidate cached users", "user_ids", ids, "error", err)
+	}
+	return nil
+}
+
+func (r *CachedUserRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(pendingInvalidationKey{}) != nil {
+		return r.repo.WithTransaction(ctx, fn)
+	}
+
+	// Rolled back writes are invalidated as well; dropping unchanged entries is harmless
+	pending := &pendingInvalidation{}
+	err := r.repo.WithTransaction(context.WithValue(ctx, pendingInvalidationKey{}, pending), fn)
+	if len(pending.ids) > 0 {
+		r.changed(ctx, nil, pending.ids...)
+	}
+	return err
+}
+
+func (r *CachedUserRepository) Create(ctx context.Context, user *models.User) error {
+	err := r.repo.Create(ctx, user)
+	return r.changed(ctx, err, user.ID)
+}
+
+func (r *CachedUserRepository) GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	if len(opts) > 0 {
+		return r.repo.GetByID(ctx, id, opts...)
+	}
+	return cachedRead(ctx, r, []string{cachedUserTag(id)}, func(ctx context.Context) (*models.User, error) {
+		return r.repo.GetByID(ctx, id)
+	}, "GetByID", id)
+}
+
+func (r *CachedUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.repo.GetByEmail(ctx, email)
+}
+
+func (r *CachedUserRepository) GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.User, models.PageInfo, error) {
+	return r.repo.GetAll(ctx, query)
+}
+
+func (r *CachedUserRepository) GetByReviewStatus(ctx context.Context, status string) ([]models.User, error) {
+	return cachedRead(ctx, r, []string{usersTag}, func(ctx context.Context) ([]models.User, error) {
+		return r.repo.GetByReviewStatus(ctx, status)
+	}, "GetByReviewStatus", status)
+}
+
+func (r *CachedUserRepository) GetRecentlyActive(ctx context.Context, limit int) ([]models.User, error) {
+	return cachedRead(ctx, r, []string{usersTag}, func(ctx context.Context) ([]models.User, error) {
+		return r.repo.GetRecentlyActive(ctx, limit)
+	}, "GetRecentlyActive", limit)
+}
+
+func (r *CachedUserRepository) GetScheduledDeletions(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.User, error) {
+	return r.repo.GetScheduledDeletions(ctx, before, afterID, limit)
+}
+
+func (r *CachedUserRepository) GetDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	return r.repo.GetDeleted(ctx, page)
+}
+
+func (r *CachedUserRepository) GetAllWithDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	return r.repo.GetAllWithDeleted(ctx, page)
+}
+
+func (r *CachedUserRepository) GetDeletedByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	return r.repo.GetDeletedByID(ctx, id, opts...)
+}
+
+func (r *CachedUserRepository) Each(ctx context.Context, fn func(user *models.User) error) error {
+	return r.repo.Each(ctx, fn)
+}
+
+// GetUsersByIDs is cached per set of IDs, tagged with each of them, so a missing user
+// that is restored later drops the entry too
+func (r *CachedUserRepository) GetUsersByIDs(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	tags := make([]string, len(sorted))
+	keys := make([]string, len(sorted))
+	for i, id := range sorted {
+		tags[i] = cachedUserTag(id)
+		keys[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return cachedRead(ctx, r, tags, func(ctx context.Context) (map[uint]*models.User, error) {
+		return r.repo.GetUsersByIDs(ctx, ids)
+	}, "GetUsersByIDs", strings.Join(keys, ","))
+}
+
+func (r *CachedUserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error) {
+	sorted := slices.Clone(usernames)
+	slices.Sort(sorted)
+	return cachedRead(ctx, r, []string{usersTag}, func(ctx context.Context) ([]models.User, error) {
+		return r.repo.GetByUsernames(ctx, usernames)
+	}, "GetByUsernames", strings.Join(slices.Compact(sorted), ","))
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, user *models.User) error {
+	err := r.repo.Update(ctx, user)
+	return r.changed(ctx, err, user.ID)
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id uint) error {
+	err := r.repo.Delete(ctx, id)
+	return r.changed(ctx, err, id)
+}
+
+func (r *CachedUserRepository) Restore(ctx context.Context, id uint) error {
+	err := r.repo.Restore(ctx, id)
+	return r.changed(ctx, err, id)
+}
+
+func (r *CachedUserRepository) IncrementTokenVersion(ctx context.Context, id uint) error {
+	err := r.repo.IncrementTokenVersion(ctx, id)
+	return r.changed(ctx, err, id)
+}
+
+func (r *CachedUserRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	err := r.repo.UpdatePassword(ctx, id, hash)
+	return r.changed(ctx, err, id)
+}
+
+func (r *CachedUserRepository) UpdateLastLogin(ctx context.Context, id uint, at time.Time) error {
+	err := r.repo.UpdateLastLogin(ctx, id, at)
+	return r.changed(ctx, err, id)
+}
+
+// PurgeDeleted needs no invalidation: purged users were already soft-deleted, and the
+// cached reads only return live users
+func (r *CachedUserRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	return r.repo.PurgeDeleted(ctx, cutoff, limit)
+}
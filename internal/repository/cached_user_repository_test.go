@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newCachedUsers(t *testing.T) (UserRepository, UserRepository) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	inner := NewInMemoryUserRepository()
+	cacheStore := cache.New(client, cache.Options{Namespace: "test", NegativeTTL: time.Minute})
+	return inner, NewCachedUserRepository(inner, cacheStore, cache.Policy{TTL: time.Minute})
+}
+
+func TestCachedUserRepositoryInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	inner, cached := newCachedUsers(t)
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", Password: "hash", TokenVersion: 3}
+	if err := cached.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cached.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Columns hidden from JSON must survive the cache
+	if got.Password != "hash" || got.TokenVersion != 3 {
+		t.Fatalf("got %+v, want the full row", got)
+	}
+
+	if _, err := cached.GetUsersByIDs(ctx, []uint{user.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write that bypasses the decorator is not seen until the entry is invalidated
+	stale := *got
+	stale.FullName = "Jane Doe"
+	if err := inner.Update(ctx, &stale); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cached.GetByID(ctx, user.ID); got.FullName != "" {
+		t.Fatalf("got %q, want the cached row", got.FullName)
+	}
+	if byID, _ := cached.GetUsersByIDs(ctx, []uint{user.ID}); byID[user.ID].FullName != "" {
+		t.Fatalf("got %q, want the cached row", byID[user.ID].FullName)
+	}
+
+	if err := cached.IncrementTokenVersion(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = cached.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FullName != "Jane Doe" || got.TokenVersion != 4 {
+		t.Fatalf("got %+v, want the row as written", got)
+	}
+	if byID, _ := cached.GetUsersByIDs(ctx, []uint{user.ID}); byID[user.ID].FullName != "Jane Doe" {
+		t.Fatalf("got %q, want the row as written", byID[user.ID].FullName)
+	}
+
+	if err := cached.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestCachedUserRepositoryInvalidatesAfterTransaction(t *testing.T) {
+	ctx := context.Background()
+	_, cached := newCachedUsers(t)
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", Password: "hash"}
+	if err := cached.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.GetByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cached.WithTransaction(ctx, func(txCtx context.Context) error {
+		current, err := cached.GetByID(txCtx, user.ID)
+		if err != nil {
+			return err
+		}
+		current.Active = false
+		if err := cached.Update(txCtx, current); err != nil {
+			return err
+		}
+		// Reads inside the transaction bypass the cache and see its writes
+		if current, _ := cached.GetByID(txCtx, user.ID); current.Active {
+			t.Error("read inside the transaction came from the cache")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := cached.GetByID(ctx, user.ID); got.Active {
+		t.Fatal("cached row was not invalidated after the transaction")
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	Create(ctx context.Context, category *models.Category) error
+	GetByID(ctx context.Context, id uint) (*models.Category, error)
+	// GetByName returns the category named name, ignoring case
+	GetByName(ctx context.Context, name string) (*models.Category, error)
+	// List returns every category ordered by name
+	List(ctx context.Context) ([]models.Category, error)
+	Update(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, id uint) error
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return utils.RunInTransaction(ctx, r.db, fn)
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Create(category).Error
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id uint) (*models.Category, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return findCategory(db.Where("id = ?", id))
+}
+
+func (r *categoryRepository) GetByName(ctx context.Context, name string) (*models.Category, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return findCategory(db.Where("lower(name) = lower(?)", name))
+}
+
+func findCategory(query *gorm.DB) (*models.Category, error) {
+	var category models.Category
+	if err := query.First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var categories []models.Category
+	if err := db.Order("name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Model(category).Select("name", "description").Updates(category)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrCategoryNotFound
+	}
+	return result.Error
+}
+
+// Delete removes the category. Posts still in it must be moved out first (see
+// PostRepository.ClearCategory); the foreign key would only clear them without telling
+// the caller which posts changed.
+func (r *categoryRepository) Delete(ctx context.Context, id uint) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Delete(&models.Category{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrCategoryNotFound
+	}
+	return result.Error
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is returned by Update when the row changed since it was read
+var ErrVersionConflict = errors.New("resource was modified by another request")
+
+// ErrNotFound is wrapped by every "record not found" error; match it with errors.Is
+var ErrNotFound = errors.New("not found")
+
+var (
+	ErrUserNotFound         = fmt.Errorf("user %w", ErrNotFound)
+	ErrPostNotFound         = fmt.Errorf("post %w", ErrNotFound)
+	ErrDraftNotFound        = fmt.Errorf("draft %w", ErrNotFound)
+	ErrPlanNotFound         = fmt.Errorf("plan %w", ErrNotFound)
+	ErrSubscriptionNotFound = fmt.Errorf("subscription %w", ErrNotFound)
+	ErrCategoryNotFound     = fmt.Errorf("category %w", ErrNotFound)
+	ErrPasskeyNotFound      = fmt.Errorf("passkey %w", ErrNotFound)
+)
+
+// ErrInvalidCursor is returned when ?after= names no post, not even a deleted one
+var ErrInvalidCursor = errors.New("cursor does not name a post")
+
+// ErrResetTokenInvalid is returned for password reset tokens that are unknown, expired or already used
+var ErrResetTokenInvalid = errors.New("reset token is invalid or expired")
+
+// ErrMagicLinkInvalid is returned for login link tokens that are unknown, expired or already used
+var ErrMagicLinkInvalid = errors.New("login link is invalid or expired")
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"html"
+	"strings"
+)
+
+// Sentinels wrapping matched words in search fragments. They are removed from the text
+// before it is highlighted, so after escaping they can only be ours.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+var highlightMarkup = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// markHighlights HTML-escapes a fragment and turns its sentinels into <mark> tags
+func markHighlights(fragment string) string {
+	return highlightMarkup.Replace(html.EscapeString(fragment))
+}
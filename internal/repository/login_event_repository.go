@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *models.LoginEvent) error
+	GetRecentByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error)
+}
+
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{db: db}
+}
+
+func (r *loginEventRepository) Create(ctx context.Context, event *models.LoginEvent) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Create(event).Error
+}
+
+func (r *loginEventRepository) GetRecentByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var events []models.LoginEvent
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
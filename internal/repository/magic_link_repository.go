@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, token *models.MagicLinkToken) error
+	// Consume marks the unused, unexpired token with hash as used and returns it
+	Consume(ctx context.Context, hash string, now time.Time) (*models.MagicLinkToken, error)
+	// InvalidateUser marks every unused token of userID as used
+	InvalidateUser(ctx context.Context, userID uint, now time.Time) error
+}
+
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, token *models.MagicLinkToken) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Create(token).Error
+}
+
+// Consume redeems the token in one UPDATE, so concurrent requests cannot both use it
+func (r *magicLinkRepository) Consume(ctx context.Context, hash string, now time.Time) (*models.MagicLinkToken, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var token models.MagicLinkToken
+	result := db.Model(&token).Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMagicLinkInvalid
+	}
+	return &token, nil
+}
+
+func (r *magicLinkRepository) InvalidateUser(ctx context.Context, userID uint, now time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", now).Error
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// memoryTable is a concurrency-safe, process-local table backing the in-memory repositories
+type memoryTable[T any] struct {
+	mu     sync.RWMutex
+	txMu   sync.Mutex
+	rows   map[uint]T
+	lastID uint
+}
+
+// memoryTxKey marks a context as running inside a transaction of one table
+type memoryTxKey struct {
+	table any
+}
+
+func newMemoryTable[T any]() *memoryTable[T] {
+	return &memoryTable[T]{rows: make(map[uint]T)}
+}
+
+// withTransaction emulates a database transaction: transactions are serialized and the
+// table is restored to its snapshot when fn returns an error. Nested calls join the outer one.
+func (t *memoryTable[T]) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if active, _ := ctx.Value(memoryTxKey{t}).(bool); active {
+		return fn(ctx)
+	}
+
+	t.txMu.Lock()
+	defer t.txMu.Unlock()
+
+	t.mu.RLock()
+	snapshot := maps.Clone(t.rows)
+	t.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{t}, true)); err != nil {
+		t.mu.Lock()
+		t.rows = snapshot
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (t *memoryTable[T]) get(id uint) (T, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	row, ok := t.rows[id]
+	return row, ok
+}
+
+// filter returns copies of all rows for which match returns true
+func (t *memoryTable[T]) filter(match func(row T) bool) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rows := make([]T, 0)
+	for _, row := range t.rows {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// write runs fn while holding the write lock so check-then-set sequences are atomic
+func (t *memoryTable[T]) write(fn func(rows map[uint]T) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fn(t.rows)
+}
+
+// nextID allocates a primary key; like a database sequence it is never reused after a rollback
+func (t *memoryTable[T]) nextID() uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastID++
+	return t.lastID
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryBillingRepository struct {
+	plans         *memoryTable[models.Plan]
+	subscriptions *memoryTable[models.Subscription]
+}
+
+// NewInMemoryBillingRepository returns a BillingRepository that keeps plans and subscriptions in process memory
+func NewInMemoryBillingRepository() BillingRepository {
+	return &memoryBillingRepository{
+		plans:         newMemoryTable[models.Plan](),
+		subscriptions: newMemoryTable[models.Subscription](),
+	}
+}
+
+func (r *memoryBillingRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.plans.withTransaction(ctx, func(ctx context.Context) error {
+		return r.subscriptions.withTransaction(ctx, fn)
+	})
+}
+
+func (r *memoryBillingRepository) GetPlans(ctx context.Context) ([]models.Plan, error) {
+	plans := r.plans.filter(func(models.Plan) bool { return true })
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ID < plans[j].ID })
+	return plans, nil
+}
+
+func (r *memoryBillingRepository) GetPlanByCode(ctx context.Context, code string) (*models.Plan, error) {
+	return r.firstPlan(func(p models.Plan) bool { return p.Code == code })
+}
+
+func (r *memoryBillingRepository) GetPlanByStripePriceID(ctx context.Context, priceID string) (*models.Plan, error) {
+	return r.firstPlan(func(p models.Plan) bool { return p.StripePriceID == priceID })
+}
+
+func (r *memoryBillingRepository) firstPlan(match func(models.Plan) bool) (*models.Plan, error) {
+	plans := r.plans.filter(match)
+	if len(plans) == 0 {
+		return nil, ErrPlanNotFound
+	}
+	return &plans[0], nil
+}
+
+// UpsertPlan inserts the plan or updates name and price of the existing one with the same code
+func (r *memoryBillingRepository) UpsertPlan(ctx context.Context, plan *models.Plan) error {
+	id := r.plans.nextID()
+	return r.plans.write(func(rows map[uint]models.Plan) error {
+		now := utctime.Now()
+		for existingID, existing := range rows {
+			if existing.Code == plan.Code {
+				existing.Name, existing.StripePriceID, existing.UpdatedAt = plan.Name, plan.StripePriceID, now
+				rows[existingID] = existing
+				plan.ID = existingID
+				return nil
+			}
+		}
+
+		if plan.RequestsPerMinute == 0 {
+			plan.RequestsPerMinute = 100
+		}
+		plan.ID, plan.CreatedAt, plan.UpdatedAt = id, now, now
+		rows[id] = *plan
+		return nil
+	})
+}
+
+func (r *memoryBillingRepository) GetSubscriptionByUserID(ctx context.Context, userID uint, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(func(s models.Subscription) bool { return s.UserID == userID })
+}
+
+func (r *memoryBillingRepository) GetSubscriptionByStripeID(ctx context.Context, stripeSubscriptionID string, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(func(s models.Subscription) bool { return s.StripeSubscriptionID == stripeSubscriptionID })
+}
+
+func (r *memoryBillingRepository) GetSubscriptionByCustomerID(ctx context.Context, customerID string, opts ...QueryOption) (*models.Subscription, error) {
+	return r.firstSubscription(func(s models.Subscription) bool { return s.StripeCustomerID == customerID })
+}
+
+func (r *memoryBillingRepository) firstSubscription(match func(models.Subscription) bool) (*models.Subscription, error) {
+	subs := r.subscriptions.filter(match)
+	if len(subs) == 0 {
+		return nil, ErrSubscriptionNotFound
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].UpdatedAt.After(subs[j].UpdatedAt.Time) })
+	return &subs[0], nil
+}
+
+func (r *memoryBillingRepository) SaveSubscription(ctx context.Context, sub *models.Subscription) error {
+	if sub.ID == 0 {
+		sub.ID = r.subscriptions.nextID()
+		sub.CreatedAt = utctime.Now()
+	}
+	return r.subscriptions.write(func(rows map[uint]models.Subscription) error {
+		sub.UpdatedAt = utctime.Now()
+		rows[sub.ID] = *sub
+		return nil
+	})
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryCategoryRepository struct {
+	categories *memoryTable[models.Category]
+}
+
+// NewInMemoryCategoryRepository returns a CategoryRepository that keeps categories in process memory
+func NewInMemoryCategoryRepository() CategoryRepository {
+	return &memoryCategoryRepository{categories: newMemoryTable[models.Category]()}
+}
+
+func (r *memoryCategoryRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.categories.withTransaction(ctx, fn)
+}
+
+func (r *memoryCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	id := r.categories.nextID()
+	return r.categories.write(func(rows map[uint]models.Category) error {
+		for _, existing := range rows {
+			if existing.Name == category.Name {
+				return errors.New("duplicate key value violates unique constraint")
+			}
+		}
+		now := utctime.Now()
+		category.ID, category.CreatedAt, category.UpdatedAt = id, now, now
+		rows[id] = *category
+		return nil
+	})
+}
+
+func (r *memoryCategoryRepository) GetByID(ctx context.Context, id uint) (*models.Category, error) {
+	category, ok := r.categories.get(id)
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return &category, nil
+}
+
+func (r *memoryCategoryRepository) GetByName(ctx context.Context, name string) (*models.Category, error) {
+	categories := r.categories.filter(func(c models.Category) bool { return strings.EqualFold(c.Name, name) })
+	if len(categories) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+	return &categories[0], nil
+}
+
+func (r *memoryCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	categories := r.categories.filter(func(models.Category) bool { return true })
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (r *memoryCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	return r.categories.write(func(rows map[uint]models.Category) error {
+		current, ok := rows[category.ID]
+		if !ok {
+			return ErrCategoryNotFound
+		}
+		current.Name, current.Description, current.UpdatedAt = category.Name, category.Description, utctime.Now()
+		rows[category.ID] = current
+		*category = current
+		return nil
+	})
+}
+
+func (r *memoryCategoryRepository) Delete(ctx context.Context, id uint) error {
+	return r.categories.write(func(rows map[uint]models.Category) error {
+		if _, ok := rows[id]; !ok {
+			return ErrCategoryNotFound
+		}
+		delete(rows, id)
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryLoginEventRepository struct {
+	events *memoryTable[models.LoginEvent]
+}
+
+// NewInMemoryLoginEventRepository returns a LoginEventRepository that keeps events in process memory
+func NewInMemoryLoginEventRepository() LoginEventRepository {
+	return &memoryLoginEventRepository{events: newMemoryTable[models.LoginEvent]()}
+}
+
+func (r *memoryLoginEventRepository) Create(ctx context.Context, event *models.LoginEvent) error {
+	id := r.events.nextID()
+	return r.events.write(func(rows map[uint]models.LoginEvent) error {
+		event.ID, event.CreatedAt = id, utctime.Now()
+		rows[id] = *event
+		return nil
+	})
+}
+
+func (r *memoryLoginEventRepository) GetRecentByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error) {
+	events := r.events.filter(func(e models.LoginEvent) bool { return e.UserID == userID })
+	sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
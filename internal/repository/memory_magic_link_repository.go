@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryMagicLinkRepository struct {
+	tokens *memoryTable[models.MagicLinkToken]
+}
+
+// NewInMemoryMagicLinkRepository returns a MagicLinkRepository that keeps tokens in process memory
+func NewInMemoryMagicLinkRepository() MagicLinkRepository {
+	return &memoryMagicLinkRepository{tokens: newMemoryTable[models.MagicLinkToken]()}
+}
+
+func (r *memoryMagicLinkRepository) Create(ctx context.Context, token *models.MagicLinkToken) error {
+	id := r.tokens.nextID()
+	return r.tokens.write(func(rows map[uint]models.MagicLinkToken) error {
+		token.ID, token.CreatedAt = id, utctime.Now()
+		rows[id] = *token
+		return nil
+	})
+}
+
+func (r *memoryMagicLinkRepository) Consume(ctx context.Context, hash string, now time.Time) (*models.MagicLinkToken, error) {
+	var consumed *models.MagicLinkToken
+	err := r.tokens.write(func(rows map[uint]models.MagicLinkToken) error {
+		for id, token := range rows {
+			if token.TokenHash != hash || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+				continue
+			}
+			token.UsedAt = utctime.Ptr(now)
+			rows[id] = token
+			consumed = &token
+			return nil
+		}
+		return ErrMagicLinkInvalid
+	})
+	return consumed, err
+}
+
+func (r *memoryMagicLinkRepository) InvalidateUser(ctx context.Context, userID uint, now time.Time) error {
+	return r.tokens.write(func(rows map[uint]models.MagicLinkToken) error {
+		for id, token := range rows {
+			if token.UserID == userID && token.UsedAt == nil {
+				token.UsedAt = utctime.Ptr(now)
+				rows[id] = token
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPasskeyRepository struct {
+	passkeys *memoryTable[models.Passkey]
+}
+
+// NewInMemoryPasskeyRepository returns a PasskeyRepository that keeps passkeys in process memory
+func NewInMemoryPasskeyRepository() PasskeyRepository {
+	return &memoryPasskeyRepository{passkeys: newMemoryTable[models.Passkey]()}
+}
+
+func (r *memoryPasskeyRepository) Create(ctx context.Context, passkey *models.Passkey) error {
+	id := r.passkeys.nextID()
+	return r.passkeys.write(func(rows map[uint]models.Passkey) error {
+		for _, existing := range rows {
+			if bytes.Equal(existing.CredentialID, passkey.CredentialID) {
+				return errors.New("duplicate key value violates unique constraint")
+			}
+		}
+		passkey.ID, passkey.CreatedAt = id, utctime.Now()
+		rows[id] = *passkey
+		return nil
+	})
+}
+
+func (r *memoryPasskeyRepository) GetByUserID(ctx context.Context, userID uint) ([]models.Passkey, error) {
+	passkeys := r.passkeys.filter(func(p models.Passkey) bool { return p.UserID == userID })
+	sort.Slice(passkeys, func(i, j int) bool { return passkeys[i].ID < passkeys[j].ID })
+	return passkeys, nil
+}
+
+func (r *memoryPasskeyRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*models.Passkey, error) {
+	passkeys := r.passkeys.filter(func(p models.Passkey) bool { return bytes.Equal(p.CredentialID, credentialID) })
+	if len(passkeys) == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+	return &passkeys[0], nil
+}
+
+func (r *memoryPasskeyRepository) RecordUse(ctx context.Context, id uint, signCount uint32, backedUp bool, at time.Time) error {
+	return r.passkeys.write(func(rows map[uint]models.Passkey) error {
+		if passkey, ok := rows[id]; ok {
+			passkey.SignCount, passkey.BackedUp, passkey.LastUsedAt = signCount, backedUp, utctime.Ptr(at)
+			rows[id] = passkey
+		}
+		return nil
+	})
+}
+
+func (r *memoryPasskeyRepository) Delete(ctx context.Context, userID, id uint) error {
+	return r.passkeys.write(func(rows map[uint]models.Passkey) error {
+		if passkey, ok := rows[id]; !ok || passkey.UserID != userID {
+			return ErrPasskeyNotFound
+		}
+		delete(rows, id)
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPasswordResetRepository struct {
+	tokens *memoryTable[models.PasswordResetToken]
+}
+
+// NewInMemoryPasswordResetRepository returns a PasswordResetRepository that keeps tokens in process memory
+func NewInMemoryPasswordResetRepository() PasswordResetRepository {
+	return &memoryPasswordResetRepository{tokens: newMemoryTable[models.PasswordResetToken]()}
+}
+
+func (r *memoryPasswordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	id := r.tokens.nextID()
+	return r.tokens.write(func(rows map[uint]models.PasswordResetToken) error {
+		token.ID, token.CreatedAt = id, utctime.Now()
+		rows[id] = *token
+		return nil
+	})
+}
+
+func (r *memoryPasswordResetRepository) Consume(ctx context.Context, hash string, now time.Time) (*models.PasswordResetToken, error) {
+	var consumed *models.PasswordResetToken
+	err := r.tokens.write(func(rows map[uint]models.PasswordResetToken) error {
+		for id, token := range rows {
+			if token.TokenHash != hash || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+				continue
+			}
+			token.UsedAt = utctime.Ptr(now)
+			rows[id] = token
+			consumed = &token
+			return nil
+		}
+		return ErrResetTokenInvalid
+	})
+	return consumed, err
+}
+
+func (r *memoryPasswordResetRepository) InvalidateUser(ctx context.Context, userID uint, now time.Time) error {
+	return r.tokens.write(func(rows map[uint]models.PasswordResetToken) error {
+		for id, token := range rows {
+			if token.UserID == userID && token.UsedAt == nil {
+				token.UsedAt = utctime.Ptr(now)
+				rows[id] = token
+			}
+		}
+		return nil
+	})
+}
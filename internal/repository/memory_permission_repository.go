@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPermissionRepository struct {
+	permissions *memoryTable[models.Permission]
+	grants      *memoryTable[models.RolePermission]
+}
+
+// NewInMemoryPermissionRepository returns a PermissionRepository that keeps permissions and grants in process memory
+func NewInMemoryPermissionRepository() PermissionRepository {
+	return &memoryPermissionRepository{
+		permissions: newMemoryTable[models.Permission](),
+		grants:      newMemoryTable[models.RolePermission](),
+	}
+}
+
+func (r *memoryPermissionRepository) CreatePermission(ctx context.Context, permission *models.Permission) (bool, error) {
+	id := r.permissions.nextID()
+	created := false
+	err := r.permissions.write(func(rows map[uint]models.Permission) error {
+		for _, existing := range rows {
+			if existing.Name == permission.Name {
+				*permission = existing
+				return nil
+			}
+		}
+		permission.ID, permission.CreatedAt = id, utctime.Now()
+		rows[id] = *permission
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *memoryPermissionRepository) Grant(ctx context.Context, role string, permissionID uint) error {
+	id := r.grants.nextID()
+	return r.grants.write(func(rows map[uint]models.RolePermission) error {
+		for _, existing := range rows {
+			if existing.Role == role && existing.PermissionID == permissionID {
+				return nil
+			}
+		}
+		rows[id] = models.RolePermission{ID: id, Role: role, PermissionID: permissionID, CreatedAt: utctime.Now()}
+		return nil
+	})
+}
+
+func (r *memoryPermissionRepository) NamesByRole(ctx context.Context, role string) ([]string, error) {
+	names := make([]string, 0)
+	for _, grant := range r.grants.filter(func(g models.RolePermission) bool { return g.Role == role }) {
+		if permission, ok := r.permissions.get(grant.PermissionID); ok {
+			names = append(names, permission.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPostDraftRepository struct {
+	drafts *memoryTable[models.PostDraft]
+}
+
+// NewInMemoryPostDraftRepository returns a PostDraftRepository that keeps drafts in process memory
+func NewInMemoryPostDraftRepository() PostDraftRepository {
+	return &memoryPostDraftRepository{drafts: newMemoryTable[models.PostDraft]()}
+}
+
+func (r *memoryPostDraftRepository) Save(ctx context.Context, draft *models.PostDraft) (bool, error) {
+	id := r.drafts.nextID()
+	saved := false
+	err := r.drafts.write(func(rows map[uint]models.PostDraft) error {
+		draft.UpdatedAt = utctime.Now()
+		for existingID, existing := range rows {
+			if existing.PostID == draft.PostID {
+				if !existing.Revision.Before(draft.Revision.Time) {
+					return nil
+				}
+				draft.ID = existingID
+				rows[existingID] = *draft
+				saved = true
+				return nil
+			}
+		}
+
+		draft.ID = id
+		rows[id] = *draft
+		saved = true
+		return nil
+	})
+	return saved, err
+}
+
+func (r *memoryPostDraftRepository) GetByPostID(ctx context.Context, postID uint) (*models.PostDraft, error) {
+	drafts := r.drafts.filter(func(d models.PostDraft) bool { return d.PostID == postID })
+	if len(drafts) == 0 {
+		return nil, ErrDraftNotFound
+	}
+	return &drafts[0], nil
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPostRepository struct {
+	posts *memoryTable[models.Post]
+}
+
+// NewInMemoryPostRepository returns a PostRepository that keeps posts in process memory
+func NewInMemoryPostRepository() PostRepository {
+	return &memoryPostRepository{posts: newMemoryTable[models.Post]()}
+}
+
+func (r *memoryPostRepository) Create(ctx context.Context, post *models.Post) error {
+	id := r.posts.nextID()
+	return r.posts.write(func(rows map[uint]models.Post) error {
+		now := utctime.Now()
+		post.ID, post.CreatedAt, post.UpdatedAt = id, now, now
+		post.Version = 1
+		rows[id] = *post
+		return nil
+	})
+}
+
+func (r *memoryPostRepository) GetByID(ctx context.Context, id uint) (*models.Post, error) {
+	post, ok := r.posts.get(id)
+	if !ok {
+		return nil, ErrPostNotFound
+	}
+	return &post, nil
+}
+
+func (r *memoryPostRepository) GetAll(ctx context.Context, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
+	return r.page(r.posts.filter(func(p models.Post) bool { return p.ArchivedAt == nil && listed(p, req) }), req)
+}
+
+func (r *memoryPostRepository) GetByUserID(ctx context.Context, userID uint, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
+	return r.page(r.posts.filter(func(p models.Post) bool {
+		return p.UserID == userID && (req.IncludeArchived || p.ArchivedAt == nil) && listed(p, req)
+	}), req)
+}
+
+// page mirrors postRepository.findPosts. Deleted posts are gone from memory, so a
+// deleted cursor is invalid here.
+func (r *memoryPostRepository) page(posts []models.Post, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
+	posts = sortPosts(posts, req.Sort)
+	page := req.PageRequest
+	if req.After != 0 {
+		cursor, ok := r.posts.get(req.After)
+		if !ok {
+			return nil, models.PageInfo{}, ErrInvalidCursor
+		}
+		start := sort.Search(len(posts), func(i int) bool {
+			return posts[i].CreatedAt.Before(cursor.CreatedAt.Time) ||
+				(posts[i].CreatedAt.Equal(cursor.CreatedAt.Time) && posts[i].ID < cursor.ID)
+		})
+		posts, page = posts[start:], keysetPage(req)
+	}
+
+	posts, info, err := paginate(posts, page)
+	return posts, nextCursor(posts, info, req), err
+}
+
+func (r *memoryPostRepository) Each(ctx context.Context, userID uint, fn func(post *models.Post) error) error {
+	posts := newestFirst(r.posts.filter(func(p models.Post) bool { return userID == 0 || p.UserID == userID }))
+	for i := range posts {
+		if err := fn(&posts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryPostRepository) EachListed(ctx context.Context, userID uint, req models.PostListRequest, fn func(post *models.Post) error) error {
+	posts := sortPosts(r.posts.filter(func(p models.Post) bool {
+		if (userID != 0 && p.UserID != userID) || !listed(p, req) {
+			return false
+		}
+		return (userID != 0 && req.IncludeArchived) || p.ArchivedAt == nil
+	}), req.Sort)
+	for i := range posts {
+		if err := fn(&posts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryPostRepository) ExistsByHashSince(ctx context.Context, userID uint, hash string, since time.Time) (bool, error) {
+	matches := r.posts.filter(func(p models.Post) bool {
+		return p.UserID == userID && p.ContentHash == hash && !p.CreatedAt.Before(since)
+	})
+	return len(matches) > 0, nil
+}
+
+func (r *memoryPostRepository) Update(ctx context.Context, post *models.Post) error {
+	return r.posts.write(func(rows map[uint]models.Post) error {
+		if current, ok := rows[post.ID]; !ok || current.Version != post.Version {
+			return ErrVersionConflict
+		}
+		post.Version++
+		post.UpdatedAt = utctime.Now()
+		rows[post.ID] = *post
+		return nil
+	})
+}
+
+func (r *memoryPostRepository) Delete(ctx context.Context, id uint) error {
+	return r.posts.write(func(rows map[uint]models.Post) error {
+		delete(rows, id)
+		return nil
+	})
+}
+
+func (r *memoryPostRepository) DeleteByUserID(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.posts.write(func(rows map[uint]models.Post) error {
+		for id, post := range rows {
+			if post.UserID == userID {
+				ids = append(ids, id)
+				delete(rows, id)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func (r *memoryPostRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint) ([]uint, error) {
+	var ids []uint
+	err := r.posts.write(func(rows map[uint]models.Post) error {
+		for id, post := range rows {
+			if post.UserID == fromUserID {
+				post.UserID = toUserID
+				post.Version++
+				post.UpdatedAt = utctime.Now()
+				rows[id] = post
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func (r *memoryPostRepository) ClearCategory(ctx context.Context, categoryID uint) ([]uint, error) {
+	var ids []uint
+	err := r.posts.write(func(rows map[uint]models.Post) error {
+		for id, post := range rows {
+			if post.CategoryID != nil && *post.CategoryID == categoryID {
+				post.CategoryID = nil
+				post.Version++
+				post.UpdatedAt = utctime.Now()
+				rows[id] = post
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func (r *memoryPostRepository) CountByUserIDs(ctx context.Context, userIDs []uint) (map[uint]int64, error) {
+	wanted := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+	counts := make(map[uint]int64)
+	for _, post := range r.posts.filter(func(p models.Post) bool { return wanted[p.UserID] && p.ArchivedAt == nil }) {
+		counts[post.UserID]++
+	}
+	return counts, nil
+}
+
+// listed mirrors listFilter: post is in req.CategoryID and carries req.Tag, when set
+func listed(post models.Post, req models.PostListRequest) bool {
+	if req.CategoryID != 0 && (post.CategoryID == nil || *post.CategoryID != req.CategoryID) {
+		return false
+	}
+	return req.Tag == "" || slices.ContainsFunc(post.Tags, func(t models.Tag) bool { return t.Name == req.Tag })
+}
+
+func (r *memoryPostRepository) CountTags(ctx context.Context, limit int) ([]models.TagCount, error) {
+	posts := make(map[string]int64)
+	for _, post := range r.posts.filter(func(p models.Post) bool { return p.ArchivedAt == nil }) {
+		for _, tag := range post.Tags {
+			posts[tag.Name]++
+		}
+	}
+
+	counts := make([]models.TagCount, 0, len(posts))
+	for name, n := range posts {
+		counts = append(counts, models.TagCount{Name: name, Posts: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Posts != counts[j].Posts {
+			return counts[i].Posts > counts[j].Posts
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts[:min(limit, len(counts))], nil
+}
+
+// GetRelated ranks other posts by the number of distinct words (3+ letters) they share
+// with post, a rough stand-in for the SQL repository's full-text ranking
+func (r *memoryPostRepository) GetRelated(ctx context.Context, post *models.Post, limit int) ([]models.Post, error) {
+	words := postWords(post)
+	scores := make(map[uint]int)
+	candidates := newestFirst(r.posts.filter(func(p models.Post) bool {
+		if p.ID == post.ID || p.ArchivedAt != nil {
+			return false
+		}
+		for word := range postWords(&p) {
+			if words[word] {
+				scores[p.ID]++
+			}
+		}
+		return scores[p.ID] > 0
+	}))
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].ID] > scores[candidates[j].ID]
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// Search approximates the SQL full-text search: every word of q must occur in the title
+// or content, case-insensitively, and more occurrences rank higher, those in the title
+// counting double. Stemming, phrases and operators are left to Postgres.
+func (r *memoryPostRepository) Search(ctx context.Context, query models.PostSearchQuery) ([]models.PostSearchHit, models.PageInfo, error) {
+	terms := searchTerms(query.Q)
+	ranks := make(map[uint]int)
+	posts := newestFirst(r.posts.filter(func(p models.Post) bool {
+		if p.ArchivedAt != nil || len(terms) == 0 {
+			return false
+		}
+		title, content := searchTerms(p.Title), searchTerms(p.Content)
+		for _, term := range terms {
+			n := 2*countTerm(title, term) + countTerm(content, term)
+			if n == 0 {
+				return false
+			}
+			ranks[p.ID] += n
+		}
+		return true
+	}))
+	sort.SliceStable(posts, func(i, j int) bool {
+		return ranks[posts[i].ID] > ranks[posts[j].ID]
+	})
+
+	posts, info, err := paginate(posts, query.PageRequest)
+	hits := make([]models.PostSearchHit, len(posts))
+	for i, post := range posts {
+		hits[i] = models.PostSearchHit{
+			Post: post,
+			Rank: float64(ranks[post.ID]),
+			Highlights: models.PostHighlights{
+				Title:   highlightTerms(post.Title, terms, 0),
+				Content: highlightTerms(post.Content, terms, 35),
+			},
+		}
+	}
+	return hits, info, err
+}
+
+// highlightTerms wraps the words of text matching terms in highlight sentinels. A
+// non-zero limit keeps that many words, starting a few before the first match, like
+// the MaxWords of ts_headline.
+func highlightTerms(text string, terms []string, limit int) string {
+	matches := func(field string) bool {
+		for _, word := range searchTerms(field) {
+			if slices.Contains(terms, word) {
+				return true
+			}
+		}
+		return false
+	}
+
+	text = strings.NewReplacer(highlightStart, "", highlightStop, "").Replace(text)
+	fields := strings.Fields(text)
+	if limit > 0 {
+		start := 0
+		for i, field := range fields {
+			if matches(field) {
+				start = max(0, i-10)
+				break
+			}
+		}
+		fields = fields[start:min(len(fields), start+limit)]
+	}
+	for i, field := range fields {
+		if matches(field) {
+			fields[i] = highlightStart + field + highlightStop
+		}
+	}
+	return markHighlights(strings.Join(fields, " "))
+}
+
+func countTerm(words []string, term string) int {
+	n := 0
+	for _, word := range words {
+		if word == term {
+			n++
+		}
+	}
+	return n
+}
+
+// searchTerms splits s into lowercase words
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func postWords(post *models.Post) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(post.Title+" "+post.Content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 3 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// newestFirst orders posts like the SQL repository (created_at DESC)
+func newestFirst(posts []models.Post) []models.Post {
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt.Time) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt.Time)
+	})
+	return posts
+}
+
+// sortPosts orders posts like PostListRequest.OrderBy: by the sort key, then newest first
+func sortPosts(posts []models.Post, sortBy string) []models.Post {
+	key := func(p models.Post) int { return 0 }
+	desc := strings.HasPrefix(sortBy, "-")
+	switch strings.TrimPrefix(sortBy, "-") {
+	case models.PostSortReadingTime:
+		key = func(p models.Post) int { return p.ReadingTimeMinutes }
+	case models.PostSortWordCount:
+		key = func(p models.Post) int { return p.WordCount }
+	}
+
+	posts = newestFirst(posts)
+	sort.SliceStable(posts, func(i, j int) bool {
+		if desc {
+			return key(posts[i]) > key(posts[j])
+		}
+		return key(posts[i]) < key(posts[j])
+	})
+	return posts
+}
+
+// PurgeDeleted is a no-op: Delete already removes rows from memory, so nothing is soft-deleted
+func (r *memoryPostRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	return 0, nil
+}
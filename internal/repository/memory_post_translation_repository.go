@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryPostTranslationRepository struct {
+	translations *memoryTable[models.PostTranslation]
+}
+
+// NewInMemoryPostTranslationRepository returns a PostTranslationRepository that keeps translations in process memory
+func NewInMemoryPostTranslationRepository() PostTranslationRepository {
+	return &memoryPostTranslationRepository{translations: newMemoryTable[models.PostTranslation]()}
+}
+
+func (r *memoryPostTranslationRepository) Upsert(ctx context.Context, translation *models.PostTranslation) error {
+	id := r.translations.nextID()
+	return r.translations.write(func(rows map[uint]models.PostTranslation) error {
+		now := utctime.Now()
+		for existingID, existing := range rows {
+			if existing.PostID == translation.PostID && existing.Locale == translation.Locale {
+				translation.ID, translation.CreatedAt, translation.UpdatedAt = existingID, existing.CreatedAt, now
+				rows[existingID] = *translation
+				return nil
+			}
+		}
+
+		translation.ID, translation.CreatedAt, translation.UpdatedAt = id, now, now
+		rows[id] = *translation
+		return nil
+	})
+}
+
+func (r *memoryPostTranslationRepository) GetByPostID(ctx context.Context, postID uint) ([]models.PostTranslation, error) {
+	translations := r.translations.filter(func(t models.PostTranslation) bool { return t.PostID == postID })
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Locale < translations[j].Locale })
+	return translations, nil
+}
+
+func (r *memoryPostTranslationRepository) GetByPostIDs(ctx context.Context, postIDs []uint, locales []string) ([]models.PostTranslation, error) {
+	return r.translations.filter(func(t models.PostTranslation) bool {
+		return slices.Contains(postIDs, t.PostID) && slices.Contains(locales, t.Locale)
+	}), nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryUsageRepository struct {
+	events  *memoryTable[models.UsageEvent]
+	rollups *memoryTable[models.UsageRollup]
+}
+
+// NewInMemoryUsageRepository returns a UsageRepository that keeps events and rollups in process memory
+func NewInMemoryUsageRepository() UsageRepository {
+	return &memoryUsageRepository{
+		events:  newMemoryTable[models.UsageEvent](),
+		rollups: newMemoryTable[models.UsageRollup](),
+	}
+}
+
+func (r *memoryUsageRepository) CreateEvents(ctx context.Context, events []models.UsageEvent) error {
+	for i := range events {
+		events[i].ID = r.events.nextID()
+	}
+	return r.events.write(func(rows map[uint]models.UsageEvent) error {
+		for _, event := range events {
+			rows[event.ID] = event
+		}
+		return nil
+	})
+}
+
+func (r *memoryUsageRepository) RollupDay(ctx context.Context, dayStart time.Time) error {
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	totals := make(map[usageKey]int64)
+	for _, e := range r.events.filter(func(e models.UsageEvent) bool {
+		return !e.CreatedAt.Before(dayStart) && e.CreatedAt.Before(dayEnd)
+	}) {
+		totals[usageKey{e.UserID, e.Metric}] += e.Quantity
+	}
+	return r.upsertRollups(models.PeriodDay, dayStart, totals)
+}
+
+func (r *memoryUsageRepository) RollupMonth(ctx context.Context, monthStart time.Time) error {
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	totals := make(map[usageKey]int64)
+	for _, ru := range r.rollups.filter(func(ru models.UsageRollup) bool {
+		return ru.Period == models.PeriodDay && !ru.PeriodStart.Before(monthStart) && ru.PeriodStart.Before(monthEnd)
+	}) {
+		totals[usageKey{ru.UserID, ru.Metric}] += ru.Quantity
+	}
+	return r.upsertRollups(models.PeriodMonth, monthStart, totals)
+}
+
+type usageKey struct {
+	userID uint
+	metric string
+}
+
+// upsertRollups mirrors INSERT ... ON CONFLICT (user_id, metric, period, period_start) DO UPDATE
+func (r *memoryUsageRepository) upsertRollups(period string, periodStart time.Time, totals map[usageKey]int64) error {
+	for key, quantity := range totals {
+		newID := r.rollups.nextID()
+		if err := r.rollups.write(func(rows map[uint]models.UsageRollup) error {
+			id := newID
+			for existingID, ru := range rows {
+				if ru.UserID == key.userID && ru.Metric == key.metric && ru.Period == period && ru.PeriodStart.Equal(periodStart) {
+					id = existingID
+					break
+				}
+			}
+			rows[id] = models.UsageRollup{
+				ID: id, UserID: key.userID, Metric: key.metric, Period: period,
+				PeriodStart: utctime.From(periodStart), Quantity: quantity, UpdatedAt: utctime.Now(),
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryUsageRepository) GetRollups(ctx context.Context, userID uint, period string, since time.Time) ([]models.UsageRollup, error) {
+	rollups := r.rollups.filter(func(ru models.UsageRollup) bool {
+		return ru.UserID == userID && ru.Period == period && !ru.PeriodStart.Before(since)
+	})
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].PeriodStart.Equal(rollups[j].PeriodStart.Time) {
+			return rollups[i].Metric < rollups[j].Metric
+		}
+		return rollups[i].PeriodStart.After(rollups[j].PeriodStart.Time)
+	})
+	return rollups, nil
+}
+
+func (r *memoryUsageRepository) GetReport(ctx context.Context, period string, periodStart time.Time) ([]models.UsageReportRow, error) {
+	rows := make([]models.UsageReportRow, 0)
+	for _, ru := range r.rollups.filter(func(ru models.UsageRollup) bool {
+		return ru.Period == period && ru.PeriodStart.Equal(periodStart)
+	}) {
+		rows = append(rows, models.UsageReportRow{UserID: ru.UserID, Metric: ru.Metric, Quantity: ru.Quantity})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Quantity > rows[j].Quantity })
+	return rows, nil
+}
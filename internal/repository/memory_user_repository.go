@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utctime"
+)
+
+type memoryUserRepository struct {
+	users *memoryTable[models.User]
+}
+
+// NewInMemoryUserRepository returns a UserRepository that keeps users in process memory
+func NewInMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{users: newMemoryTable[models.User]()}
+}
+
+func (r *memoryUserRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.users.withTransaction(ctx, fn)
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	id := r.users.nextID()
+	return r.users.write(func(rows map[uint]models.User) error {
+		for _, existing := range rows {
+			if strings.EqualFold(existing.Email, user.Email) || existing.Username == user.Username {
+				return errors.New("duplicate key value violates unique constraint")
+			}
+		}
+
+		// Mirror the column defaults applied by the database
+		if user.Role == "" {
+			user.Role = "user"
+		}
+		if user.Plan == "" {
+			user.Plan = models.PlanFree
+		}
+		user.Active = true
+		user.Version = 1
+
+		now := utctime.Now()
+		user.ID, user.CreatedAt, user.UpdatedAt = id, now, now
+		rows[id] = *user
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	user, ok := r.users.get(id)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	users := r.users.filter(func(u models.User) bool { return strings.EqualFold(u.Email, email) })
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return &users[0], nil
+}
+
+func (r *memoryUserRepository) GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.User, models.PageInfo, error) {
+	q := strings.ToLower(query.Q)
+	users := r.users.filter(func(u models.User) bool {
+		if query.Role != "" && u.Role != query.Role {
+			return false
+		}
+		if query.Active != nil && u.Active != *query.Active {
+			return false
+		}
+		return q == "" || strings.Contains(strings.ToLower(u.Username), q) ||
+			strings.Contains(strings.ToLower(u.FullName), q) || strings.EqualFold(u.Email, q)
+	})
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return paginate(users, query.PageRequest)
+}
+
+func (r *memoryUserRepository) Each(ctx context.Context, fn func(user *models.User) error) error {
+	users := r.users.filter(func(models.User) bool { return true })
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	for i := range users {
+		if err := fn(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetByReviewStatus(ctx context.Context, status string) ([]models.User, error) {
+	users := r.users.filter(func(u models.User) bool { return u.ReviewStatus == status })
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt.Time) })
+	return users, nil
+}
+
+func (r *memoryUserRepository) GetRecentlyActive(ctx context.Context, limit int) ([]models.User, error) {
+	users := r.users.filter(func(u models.User) bool { return u.LastLoginAt != nil })
+	sort.Slice(users, func(i, j int) bool { return users[i].LastLoginAt.After(users[j].LastLoginAt.Time) })
+	return users[:min(limit, len(users))], nil
+}
+
+func (r *memoryUserRepository) GetScheduledDeletions(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.User, error) {
+	users := r.users.filter(func(u models.User) bool {
+		return u.ID > afterID && u.DeletionScheduledAt != nil && !u.DeletionScheduledAt.After(before)
+	})
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users[:min(limit, len(users))], nil
+}
+
+// GetDeleted finds nothing: the memory store deletes users outright
+func (r *memoryUserRepository) GetDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	return paginate([]models.User{}, page)
+}
+
+func (r *memoryUserRepository) GetAllWithDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	return r.GetAll(ctx, models.ListUsersQuery{PageRequest: page})
+}
+
+// GetDeletedByID and Restore find nothing to undo, for the same reason as GetDeleted
+func (r *memoryUserRepository) GetDeletedByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	return nil, ErrUserNotFound
+}
+
+func (r *memoryUserRepository) Restore(ctx context.Context, id uint) error {
+	return ErrUserNotFound
+}
+
+func (r *memoryUserRepository) GetUsersByIDs(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
+	userMap := make(map[uint]*models.User, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users.get(id); ok {
+			userMap[id] = &user
+		}
+	}
+	return userMap, nil
+}
+
+func (r *memoryUserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error) {
+	return r.users.filter(func(u models.User) bool { return slices.Contains(usernames, u.Username) }), nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	return r.users.write(func(rows map[uint]models.User) error {
+		if current, ok := rows[user.ID]; !ok || current.Version != user.Version {
+			return ErrVersionConflict
+		}
+		user.Version++
+		user.UpdatedAt = utctime.Now()
+		rows[user.ID] = *user
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id uint) error {
+	return r.users.write(func(rows map[uint]models.User) error {
+		delete(rows, id)
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	return r.users.write(func(rows map[uint]models.User) error {
+		if user, ok := rows[id]; ok {
+			user.Password = hash
+			rows[id] = user
+		}
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) UpdateLastLogin(ctx context.Context, id uint, at time.Time) error {
+	return r.users.write(func(rows map[uint]models.User) error {
+		if user, ok := rows[id]; ok {
+			user.LastLoginAt = utctime.Ptr(at)
+			rows[id] = user
+		}
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) IncrementTokenVersion(ctx context.Context, id uint) error {
+	return r.users.write(func(rows map[uint]models.User) error {
+		if user, ok := rows[id]; ok {
+			user.TokenVersion++
+			user.Version++
+			rows[id] = user
+		}
+		return nil
+	})
+}
+
+// PurgeDeleted is a no-op: Delete already removes rows from memory, so nothing is soft-deleted
+func (r *memoryUserRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	return 0, nil
+}
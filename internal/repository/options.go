@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOption tweaks how a repository read is executed
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	lockForUpdate bool
+}
+
+// LockForUpdate reads with SELECT ... FOR UPDATE so the rows stay locked until the
+// surrounding WithTransaction commits. Use it for read-modify-write flows; outside a
+// transaction the lock is released as soon as the statement finishes.
+// In-memory repositories ignore it because their transactions are already serialized.
+func LockForUpdate() QueryOption {
+	return func(o *queryOptions) { o.lockForUpdate = true }
+}
+
+// applyQueryOptions adds the clauses requested by opts to db
+func applyQueryOptions(db *gorm.DB, opts []QueryOption) *gorm.DB {
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.lockForUpdate {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	return db
+}
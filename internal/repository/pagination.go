@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"goapi/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// findPage loads the requested page of query. In has_more mode it fetches one extra
+// row to detect a next page instead of running COUNT(*), which is costly on large tables.
+func findPage[T any](query *gorm.DB, order string, page models.PageRequest) ([]T, models.PageInfo, error) {
+	var info models.PageInfo
+	limit := page.Limit
+	if page.CountTotal() {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, info, err
+		}
+		info.Total = &total
+	} else {
+		limit++
+	}
+
+	var rows []T
+	if err := query.Order(order).Offset(page.Offset()).Limit(limit).Find(&rows).Error; err != nil {
+		return nil, info, err
+	}
+	return trimPage(rows, page, info)
+}
+
+// paginate returns the requested page of already sorted in-memory rows, mirroring findPage
+func paginate[T any](rows []T, page models.PageRequest) ([]T, models.PageInfo, error) {
+	var info models.PageInfo
+	if page.CountTotal() {
+		total := int64(len(rows))
+		info.Total = &total
+	}
+
+	start := min(page.Offset(), len(rows))
+	end := min(start+page.Limit+1, len(rows))
+	return trimPage(rows[start:end], page, info)
+}
+
+// keysetPage is the PageRequest of a keyset page: one look-ahead row, no count
+func keysetPage(req models.PostListRequest) models.PageRequest {
+	return models.PageRequest{Page: 1, Limit: req.Limit, Mode: models.PageModeHasMore}
+}
+
+// nextCursor sets info.NextCursor to the last post when a newest-first list continues
+func nextCursor(posts []models.Post, info models.PageInfo, req models.PostListRequest) models.PageInfo {
+	if req.Keyset() && info.HasMore && len(posts) > 0 {
+		info.NextCursor = posts[len(posts)-1].ID
+	}
+	return info
+}
+
+// trimPage drops the look-ahead row and sets HasMore
+func trimPage[T any](rows []T, page models.PageRequest, info models.PageInfo) ([]T, models.PageInfo, error) {
+	if len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+		info.HasMore = true
+	} else if info.Total != nil {
+		info.HasMore = int64(page.Offset()+len(rows)) < *info.Total
+	}
+	return rows, info, nil
+}
+
+// eachRow iterates query with a cursor instead of loading the whole result set
+func eachRow[T any](db, query *gorm.DB, fn func(row *T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
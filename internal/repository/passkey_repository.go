@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type PasskeyRepository interface {
+	Create(ctx context.Context, passkey *models.Passkey) error
+	// GetByUserID returns the user's passkeys, oldest first
+	GetByUserID(ctx context.Context, userID uint) ([]models.Passkey, error)
+	// GetByCredentialID returns the passkey with the WebAuthn credential ID
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*models.Passkey, error)
+	// RecordUse stores the signature counter and backup state reported by a login at at
+	RecordUse(ctx context.Context, id uint, signCount uint32, backedUp bool, at time.Time) error
+	// Delete removes passkey id if it belongs to userID
+	Delete(ctx context.Context, userID, id uint) error
+}
+
+type passkeyRepository struct {
+	db *gorm.DB
+}
+
+func NewPasskeyRepository(db *gorm.DB) PasskeyRepository {
+	return &passkeyRepository{db: db}
+}
+
+func (r *passkeyRepository) Create(ctx context.Context, passkey *models.Passkey) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Create(passkey).Error
+}
+
+func (r *passkeyRepository) GetByUserID(ctx context.Context, userID uint) ([]models.Passkey, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var passkeys []models.Passkey
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&passkeys).Error; err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+func (r *passkeyRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*models.Passkey, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var passkey models.Passkey
+	if err := db.Where("credential_id = ?", credentialID).First(&passkey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPasskeyNotFound
+		}
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+func (r *passkeyRepository) RecordUse(ctx context.Context, id uint, signCount uint32, backedUp bool, at time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.Passkey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"backed_up":    backedUp,
+		"last_used_at": at,
+	}).Error
+}
+
+func (r *passkeyRepository) Delete(ctx context.Context, userID, id uint) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Where("user_id = ?", userID).Delete(&models.Passkey{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	return result.Error
+}
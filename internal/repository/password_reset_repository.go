@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	// Consume marks the unused, unexpired token with hash as used and returns it
+	Consume(ctx context.Context, hash string, now time.Time) (*models.PasswordResetToken, error)
+	// InvalidateUser marks every unused token of userID as used
+	InvalidateUser(ctx context.Context, userID uint, now time.Time) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Create(token).Error
+}
+
+// Consume redeems the token in one UPDATE, so concurrent requests cannot both use it
+func (r *passwordResetRepository) Consume(ctx context.Context, hash string, now time.Time) (*models.PasswordResetToken, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var token models.PasswordResetToken
+	result := db.Model(&token).Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrResetTokenInvalid
+	}
+	return &token, nil
+}
+
+func (r *passwordResetRepository) InvalidateUser(ctx context.Context, userID uint, now time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", now).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PermissionRepository interface {
+	// CreatePermission inserts the permission unless one with its name exists, and
+	// reports whether it was created. Either way permission.ID is set.
+	CreatePermission(ctx context.Context, permission *models.Permission) (bool, error)
+	// Grant gives role the permission; granting it twice is a no-op
+	Grant(ctx context.Context, role string, permissionID uint) error
+	// NamesByRole returns the names of the permissions granted to role
+	NamesByRole(ctx context.Context, role string) ([]string, error)
+}
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) CreatePermission(ctx context.Context, permission *models.Permission) (bool, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(permission)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+	return false, db.Where("name = ?", permission.Name).First(permission).Error
+}
+
+func (r *permissionRepository) Grant(ctx context.Context, role string, permissionID uint) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RolePermission{Role: role, PermissionID: permissionID}).Error
+}
+
+func (r *permissionRepository) NamesByRole(ctx context.Context, role string) ([]string, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var names []string
+	err := db.Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role = ?", role).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
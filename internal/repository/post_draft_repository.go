@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostDraftRepository interface {
+	Save(ctx context.Context, draft *models.PostDraft) (bool, error)
+	GetByPostID(ctx context.Context, postID uint) (*models.PostDraft, error)
+}
+
+type postDraftRepository struct {
+	db *gorm.DB
+}
+
+func NewPostDraftRepository(db *gorm.DB) PostDraftRepository {
+	return &postDraftRepository{db: db}
+}
+
+// Save inserts the post's draft or overwrites it when draft.Revision is newer than the
+// stored one, in a single statement. It reports whether draft was written.
+func (r *postDraftRepository) Save(ctx context.Context, draft *models.PostDraft) (bool, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "content", "revision", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "post_drafts.revision < excluded.revision"},
+		}},
+	}).Create(draft)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *postDraftRepository) GetByPostID(ctx context.Context, postID uint) (*models.PostDraft, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var draft models.PostDraft
+	if err := db.Where("post_id = ?", postID).First(&draft).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDraftNotFound
+		}
+		return nil, err
+	}
+	return &draft, nil
+}
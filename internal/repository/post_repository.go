@@ -3,22 +3,58 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"goapi/internal/models"
 	"goapi/pkg/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostRepository interface {
 	Create(ctx context.Context, post *models.Post) error
 	GetByID(ctx context.Context, id uint) (*models.Post, error)
-	GetAll(ctx context.Context) ([]models.Post, error)
-	GetByUserID(ctx context.Context, userID uint) ([]models.Post, error)
+	GetAll(ctx context.Context, req models.PostListRequest) ([]models.Post, models.PageInfo, error)
+	GetByUserID(ctx context.Context, userID uint, req models.PostListRequest) ([]models.Post, models.PageInfo, error)
+	Each(ctx context.Context, userID uint, fn func(post *models.Post) error) error
+	EachListed(ctx context.Context, userID uint, req models.PostListRequest, fn func(post *models.Post) error) error
+	ExistsByHashSince(ctx context.Context, userID uint, hash string, since time.Time) (bool, error)
+	GetRelated(ctx context.Context, post *models.Post, limit int) ([]models.Post, error)
+	Search(ctx context.Context, query models.PostSearchQuery) ([]models.PostSearchHit, models.PageInfo, error)
+	CountByUserIDs(ctx context.Context, userIDs []uint) (map[uint]int64, error)
+	// CountTags returns up to limit tags by how many live, unarchived posts carry them
+	CountTags(ctx context.Context, limit int) ([]models.TagCount, error)
 	Update(ctx context.Context, post *models.Post) error
 	Delete(ctx context.Context, id uint) error
+	DeleteByUserID(ctx context.Context, userID uint) ([]uint, error)
+	ReassignUser(ctx context.Context, fromUserID, toUserID uint) ([]uint, error)
+	// ClearCategory takes every post, deleted ones included, out of category categoryID
+	// and returns the IDs of the live ones
+	ClearCategory(ctx context.Context, categoryID uint) ([]uint, error)
+	PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error)
 }
 
+// notArchived limits a query to posts visible in public lists
+const notArchived = "archived_at IS NULL"
+
+// PostSearchDocument is the full-text vector of a post, title words weighted above
+// content words. config.Migrate stores it in the generated, GIN-indexed column
+// search_vector, which queries match against instead of repeating the expression.
+const PostSearchDocument = "setweight(to_tsvector('english', coalesce(title, '')), 'A') || " +
+	"setweight(to_tsvector('english', coalesce(content, '')), 'B')"
+
+// webSearchQuery parses user input such as `go "connection pool" -mysql`; unlike
+// to_tsquery it never fails on malformed syntax
+const webSearchQuery = "websearch_to_tsquery('english', ?)"
+
+// ts_headline options. Matches are wrapped in sentinels rather than tags so the
+// fragment can be HTML-escaped before the <mark> tags go in (see markHighlights).
+var (
+	titleHeadline   = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+	contentHeadline = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
 type postRepository struct {
 	db *gorm.DB
 }
@@ -27,48 +63,376 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
+// Create inserts post. Its Tags only need names: missing tags are created and the
+// rows, with IDs, replace them.
 func (r *postRepository) Create(ctx context.Context, post *models.Post) error {
 	db := utils.GetDBFromContext(ctx, r.db)
-	return db.Create(post).Error
+	if len(post.Tags) == 0 {
+		return db.Create(post).Error
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		names := models.TagNames(post.Tags)
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&post.Tags).Error; err != nil {
+			return err
+		}
+		post.Tags = nil
+		if err := tx.Where("name IN ?", names).Order("name").Find(&post.Tags).Error; err != nil {
+			return err
+		}
+		// The tags exist now, so only the post_tags rows are inserted
+		return tx.Omit("Tags.*").Create(post).Error
+	})
 }
 
 func (r *postRepository) GetByID(ctx context.Context, id uint) (*models.Post, error) {
 	db := utils.GetDBFromContext(ctx, r.db)
-	var post models.Post
-	if err := db.First(&post, id).Error; err != nil {
+	posts := make([]models.Post, 1)
+	if err := db.First(&posts[0], id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("post not found")
+			return nil, ErrPostNotFound
 		}
 		return nil, err
 	}
-	return &post, nil
+	if err := loadTags(db, posts); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// loadTags fills the Tags of posts in one query, sorted by name. It is used instead of
+// Preload, which cannot be combined with the COUNT of findPage.
+func loadTags(db *gorm.DB, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	var rows []struct {
+		PostID uint
+		models.Tag
+	}
+	err := db.Table("post_tags").
+		Select("post_tags.post_id, tags.*").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("post_tags.post_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[uint][]models.Tag)
+	for _, row := range rows {
+		tags[row.PostID] = append(tags[row.PostID], row.Tag)
+	}
+	for i := range posts {
+		posts[i].Tags = tags[posts[i].ID]
+	}
+	return nil
+}
+
+// listFilter limits query to the posts in req.CategoryID carrying req.Tag; unset
+// filters leave it unchanged
+func listFilter(query *gorm.DB, req models.PostListRequest) *gorm.DB {
+	if req.Tag != "" {
+		query = query.Where("id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.name = ?)", req.Tag)
+	}
+	if req.CategoryID != 0 {
+		query = query.Where("category_id = ?", req.CategoryID)
+	}
+	return query
 }
 
-func (r *postRepository) GetAll(ctx context.Context) ([]models.Post, error) {
+func (r *postRepository) GetAll(ctx context.Context, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
 	db := utils.GetDBFromContext(ctx, r.db)
-	var posts []models.Post
 	// Without Preload - this is where N+1 would happen if we load users individually
-	if err := db.Order("created_at DESC").Find(&posts).Error; err != nil {
-		return nil, err
+	return r.findPosts(db, listFilter(db.Model(&models.Post{}).Where(notArchived), req), req)
+}
+
+func (r *postRepository) GetByUserID(ctx context.Context, userID uint, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	query := listFilter(db.Model(&models.Post{}).Where("user_id = ?", userID), req)
+	if !req.IncludeArchived {
+		query = query.Where(notArchived)
+	}
+	return r.findPosts(db, query, req)
+}
+
+// findPosts loads one page of query: after req.After by keyset, otherwise by offset
+func (r *postRepository) findPosts(db, query *gorm.DB, req models.PostListRequest) ([]models.Post, models.PageInfo, error) {
+	if req.After == 0 {
+		posts, info, err := findPage[models.Post](query, req.OrderBy(), req.PageRequest)
+		if err == nil {
+			err = loadTags(db, posts)
+		}
+		return posts, nextCursor(posts, info, req), err
+	}
+
+	// The cursor post may have been deleted or archived since; its position still holds
+	var cursor models.Post
+	if err := db.Unscoped().Select("id", "created_at").First(&cursor, req.After).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.PageInfo{}, ErrInvalidCursor
+		}
+		return nil, models.PageInfo{}, err
 	}
-	return posts, nil
+
+	// The row comparison reads one range of idx_posts_feed, however deep the page is
+	var posts []models.Post
+	if err := query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID).
+		Order("created_at DESC, id DESC").Limit(req.Limit + 1).Find(&posts).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	posts, info, err := trimPage(posts, keysetPage(req), models.PageInfo{})
+	if err == nil {
+		err = loadTags(db, posts)
+	}
+	return posts, nextCursor(posts, info, req), err
 }
 
-func (r *postRepository) GetByUserID(ctx context.Context, userID uint) ([]models.Post, error) {
+// Each streams posts newest first through a database cursor, one row at a time.
+// A non-zero userID limits the stream to that user's posts.
+func (r *postRepository) Each(ctx context.Context, userID uint, fn func(post *models.Post) error) error {
 	db := utils.GetDBFromContext(ctx, r.db)
+	query := db.Model(&models.Post{}).Order("created_at DESC")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	return eachRow(db, query, fn)
+}
+
+// EachListed streams the posts GetAll (or, with a non-zero userID, GetByUserID) would
+// list, in the same order, through a database cursor. Paging fields of req are ignored,
+// and tags are not loaded, which would cost a query per row.
+func (r *postRepository) EachListed(ctx context.Context, userID uint, req models.PostListRequest, fn func(post *models.Post) error) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	query := listFilter(db.Model(&models.Post{}).Order(req.OrderBy()), req)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if userID == 0 || !req.IncludeArchived {
+		query = query.Where(notArchived)
+	}
+	return eachRow(db, query, fn)
+}
+
+func (r *postRepository) ExistsByHashSince(ctx context.Context, userID uint, hash string, since time.Time) (bool, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var count int64
+	err := db.Model(&models.Post{}).
+		Where("user_id = ? AND content_hash = ? AND created_at >= ?", userID, hash, since).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// GetRelated returns up to limit other posts ranked by full-text similarity to post.
+// The words of post become an OR query, so a candidate matching any of them qualifies
+// and ts_rank orders those sharing more, and rarer, words first.
+func (r *postRepository) GetRelated(ctx context.Context, post *models.Post, limit int) ([]models.Post, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	query := gorm.Expr("replace(plainto_tsquery('english', ?)::text, '&', '|')::tsquery", post.Title+" "+post.Content)
+
 	var posts []models.Post
-	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&posts).Error; err != nil {
+	err := db.Model(&models.Post{}).
+		Where("id <> ?", post.ID).
+		Where(notArchived).
+		Where("search_vector @@ ?", query).
+		Order(clause.Expr{SQL: "ts_rank(search_vector, ?) DESC, created_at DESC", Vars: []interface{}{query}}).
+		Limit(limit).
+		Find(&posts).Error
+	if err == nil {
+		err = loadTags(db, posts)
+	}
+	return posts, err
+}
+
+// Search returns one page of unarchived posts matching query.Q, best ranked first.
+// Highlights are computed by a second query over the page only: ts_headline reparses
+// each document, which is too slow to run on every match.
+func (r *postRepository) Search(ctx context.Context, query models.PostSearchQuery) ([]models.PostSearchHit, models.PageInfo, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	tsquery := gorm.Expr(webSearchQuery, query.Q)
+
+	matches := db.Model(&models.Post{}).
+		Where(notArchived).
+		Where("search_vector @@ ?", tsquery).
+		Order(clause.Expr{SQL: "ts_rank(search_vector, ?) DESC", Vars: []interface{}{tsquery}})
+	posts, info, err := findPage[models.Post](matches, "created_at DESC, id DESC", query.PageRequest)
+	if err != nil || len(posts) == 0 {
+		return nil, info, err
+	}
+	if err := loadTags(db, posts); err != nil {
+		return nil, info, err
+	}
+
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	// The sentinels are stripped from the text first, so only ts_headline's can remain
+	type headline struct {
+		ID      uint
+		Rank    float64
+		Title   string
+		Content string
+	}
+	var rows []headline
+	err = db.Model(&models.Post{}).
+		Select("id, ts_rank(search_vector, ?) AS rank, "+
+			"ts_headline('english', translate(title, ?, ''), ?, ?) AS title, "+
+			"ts_headline('english', translate(content, ?, ''), ?, ?) AS content",
+			tsquery, highlightStart+highlightStop, tsquery, titleHeadline, highlightStart+highlightStop, tsquery, contentHeadline).
+		Where("id IN ?", ids).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, info, err
+	}
+
+	headlines := make(map[uint]headline, len(rows))
+	for _, row := range rows {
+		headlines[row.ID] = row
+	}
+	hits := make([]models.PostSearchHit, len(posts))
+	for i, post := range posts {
+		row := headlines[post.ID]
+		hits[i] = models.PostSearchHit{
+			Post:       post,
+			Rank:       row.Rank,
+			Highlights: models.PostHighlights{Title: markHighlights(row.Title), Content: markHighlights(row.Content)},
+		}
+	}
+	return hits, info, nil
+}
+
+// CountByUserIDs counts the live, unarchived posts of each user in a single query (for the stats
+// DataLoader). Users without posts are absent from the map.
+func (r *postRepository) CountByUserIDs(ctx context.Context, userIDs []uint) (map[uint]int64, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var rows []struct {
+		UserID uint
+		Count  int64
+	}
+	err := db.Model(&models.Post{}).
+		Select("user_id, count(*) AS count").
+		Where("user_id IN ?", userIDs).
+		Where(notArchived).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts, nil
 }
 
+// CountTags ranks tags by the number of live, unarchived posts carrying them, then by
+// name; unused tags are left out
+func (r *postRepository) CountTags(ctx context.Context, limit int) ([]models.TagCount, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	counts := []models.TagCount{}
+	err := db.Table("tags").
+		Select("tags.name, count(*) AS post_count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("posts.deleted_at IS NULL AND posts.archived_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("post_count DESC, tags.name").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// Update saves all fields only if the row still has the version that was read,
+// then bumps it. Returns ErrVersionConflict when another update won the race.
+// Associations are not saved: tags are only set on create.
 func (r *postRepository) Update(ctx context.Context, post *models.Post) error {
 	db := utils.GetDBFromContext(ctx, r.db)
-	return db.Save(post).Error
+
+	expected := post.Version
+	post.Version++
+	result := db.Model(post).Where("version = ?", expected).Select("*").Omit(clause.Associations).Updates(post)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		post.Version = expected
+	}
+	return result.Error
 }
 
 func (r *postRepository) Delete(ctx context.Context, id uint) error {
 	db := utils.GetDBFromContext(ctx, r.db)
 	return db.Delete(&models.Post{}, id).Error
 }
+
+// DeleteByUserID soft-deletes every post by userID and returns their IDs
+func (r *postRepository) DeleteByUserID(ctx context.Context, userID uint) ([]uint, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var ids []uint
+	if err := db.Model(&models.Post{}).Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids, db.Delete(&models.Post{}, ids).Error
+}
+
+// ReassignUser moves every post by fromUserID to toUserID and returns their IDs.
+// Versions are bumped so edits based on the old author fail.
+func (r *postRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint) ([]uint, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var ids []uint
+	if err := db.Model(&models.Post{}).Where("user_id = ?", fromUserID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids, db.Model(&models.Post{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"user_id": toUserID,
+		"version": gorm.Expr("version + 1"),
+	}).Error
+}
+
+func (r *postRepository) ClearCategory(ctx context.Context, categoryID uint) ([]uint, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var ids []uint
+	if err := db.Model(&models.Post{}).Where("category_id = ?", categoryID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	// Deleted posts keep their row until the purge, so they would still reference the category
+	return ids, db.Unscoped().Model(&models.Post{}).Where("category_id = ?", categoryID).Updates(map[string]interface{}{
+		"category_id": nil,
+		"version":     gorm.Expr("version + 1"),
+	}).Error
+}
+
+// PurgeDeleted permanently removes up to limit posts soft-deleted before cutoff
+func (r *postRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var ids []uint
+	if err := db.Unscoped().Model(&models.Post{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := db.Unscoped().Delete(&models.Post{}, ids)
+	return result.RowsAffected, result.Error
+}
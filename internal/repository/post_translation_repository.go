@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostTranslationRepository interface {
+	Upsert(ctx context.Context, translation *models.PostTranslation) error
+	GetByPostID(ctx context.Context, postID uint) ([]models.PostTranslation, error)
+	GetByPostIDs(ctx context.Context, postIDs []uint, locales []string) ([]models.PostTranslation, error)
+}
+
+type postTranslationRepository struct {
+	db *gorm.DB
+}
+
+func NewPostTranslationRepository(db *gorm.DB) PostTranslationRepository {
+	return &postTranslationRepository{db: db}
+}
+
+// Upsert inserts the translation or replaces the existing one for the same post and locale
+func (r *postTranslationRepository) Upsert(ctx context.Context, translation *models.PostTranslation) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "content", "updated_at"}),
+	}).Create(translation).Error
+}
+
+func (r *postTranslationRepository) GetByPostID(ctx context.Context, postID uint) ([]models.PostTranslation, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var translations []models.PostTranslation
+	err := db.Where("post_id = ?", postID).Order("locale").Find(&translations).Error
+	return translations, err
+}
+
+// GetByPostIDs loads the translations of several posts into any of locales in one query
+func (r *postTranslationRepository) GetByPostIDs(ctx context.Context, postIDs []uint, locales []string) ([]models.PostTranslation, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var translations []models.PostTranslation
+	err := db.Where("post_id IN ? AND locale IN ?", postIDs, locales).Find(&translations).Error
+	return translations, err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type UsageRepository interface {
+	CreateEvents(ctx context.Context, events []models.UsageEvent) error
+	RollupDay(ctx context.Context, dayStart time.Time) error
+	RollupMonth(ctx context.Context, monthStart time.Time) error
+	GetRollups(ctx context.Context, userID uint, period string, since time.Time) ([]models.UsageRollup, error)
+	GetReport(ctx context.Context, period string, periodStart time.Time) ([]models.UsageReportRow, error)
+}
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) CreateEvents(ctx context.Context, events []models.UsageEvent) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.CreateInBatches(events, 500).Error
+}
+
+// RollupDay (re)computes the daily rollups for the day starting at dayStart from raw events
+func (r *usageRepository) RollupDay(ctx context.Context, dayStart time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Exec(`
+		INSERT INTO usage_rollups (user_id, metric, period, period_start, quantity, updated_at)
+		SELECT user_id, metric, ?, ?, SUM(quantity), NOW()
+		FROM usage_events
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY user_id, metric
+		ON CONFLICT (user_id, metric, period, period_start)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()`,
+		models.PeriodDay, dayStart, dayStart, dayStart.AddDate(0, 0, 1),
+	).Error
+}
+
+// RollupMonth (re)computes the monthly rollups for the month starting at monthStart from daily rollups
+func (r *usageRepository) RollupMonth(ctx context.Context, monthStart time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Exec(`
+		INSERT INTO usage_rollups (user_id, metric, period, period_start, quantity, updated_at)
+		SELECT user_id, metric, ?, ?, SUM(quantity), NOW()
+		FROM usage_rollups
+		WHERE period = ? AND period_start >= ? AND period_start < ?
+		GROUP BY user_id, metric
+		ON CONFLICT (user_id, metric, period, period_start)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()`,
+		models.PeriodMonth, monthStart, models.PeriodDay, monthStart, monthStart.AddDate(0, 1, 0),
+	).Error
+}
+
+func (r *usageRepository) GetRollups(ctx context.Context, userID uint, period string, since time.Time) ([]models.UsageRollup, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var rollups []models.UsageRollup
+	if err := db.Where("user_id = ? AND period = ? AND period_start >= ?", userID, period, since).
+		Order("period_start DESC, metric ASC").Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+func (r *usageRepository) GetReport(ctx context.Context, period string, periodStart time.Time) ([]models.UsageReportRow, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var rows []models.UsageReportRow
+	if err := db.Model(&models.UsageRollup{}).
+		Select("user_id, metric, quantity").
+		Where("period = ? AND period_start = ?", period, periodStart).
+		Order("quantity DESC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -3,7 +3,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
+
 	"goapi/internal/models"
+	"goapi/pkg/pii"
 	"goapi/pkg/utils"
 
 	"gorm.io/gorm"
@@ -11,12 +15,34 @@ import (
 
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
-	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
-	GetAll(ctx context.Context) ([]models.User, error)
+	// GetAll returns one page of the users matching query, ordered by ID
+	GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.User, models.PageInfo, error)
+	GetByReviewStatus(ctx context.Context, status string) ([]models.User, error)
+	// GetRecentlyActive returns up to limit users who logged in most recently
+	GetRecentlyActive(ctx context.Context, limit int) ([]models.User, error)
+	// GetScheduledDeletions returns up to limit users, in ID order after afterID, whose
+	// scheduled deletion is due by before
+	GetScheduledDeletions(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.User, error)
+	// GetDeleted pages through soft-deleted users not yet purged, most recently deleted first
+	GetDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error)
+	// GetAllWithDeleted pages through live and soft-deleted users alike, ordered by ID
+	GetAllWithDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error)
+	// GetDeletedByID returns a soft-deleted user; live users are reported as not found
+	GetDeletedByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error)
+	Each(ctx context.Context, fn func(user *models.User) error) error
 	GetUsersByIDs(ctx context.Context, ids []uint) (map[uint]*models.User, error)
+	// GetByUsernames returns the users with any of usernames, in no particular order
+	GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uint) error
+	// Restore undoes the soft delete of user id
+	Restore(ctx context.Context, id uint) error
+	IncrementTokenVersion(ctx context.Context, id uint) error
+	UpdatePassword(ctx context.Context, id uint, hash string) error
+	UpdateLastLogin(ctx context.Context, id uint, at time.Time) error
+	PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error)
 	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
@@ -37,12 +63,12 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	return db.Create(user).Error
 }
 
-func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
-	db := utils.GetDBFromContext(ctx, r.db)
+func (r *userRepository) GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	db := applyQueryOptions(utils.GetDBFromContext(ctx, r.db), opts)
 	var user models.User
 	if err := db.First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -51,31 +77,134 @@ func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, er
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	db := utils.GetDBFromContext(ctx, r.db)
+	// Emails are encrypted with a random nonce, so they are looked up by their blind index
+	index, err := pii.BlindIndex(models.NormalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
 	var user models.User
-	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := db.Where("email_index = ?", index).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetAll(ctx context.Context) ([]models.User, error) {
+func (r *userRepository) GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.User, models.PageInfo, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	users := db.Model(&models.User{})
+	if query.Role != "" {
+		users = users.Where("role = ?", query.Role)
+	}
+	if query.Active != nil {
+		users = users.Where("active = ?", *query.Active)
+	}
+	if query.Q != "" {
+		// The trigram indexes from config.Migrate serve these ILIKE patterns
+		pattern := "%" + escapeLike(query.Q) + "%"
+		match := db.Where("username ILIKE ?", pattern).Or("full_name ILIKE ?", pattern)
+		// Emails are encrypted, so only a whole address matches, through the blind index
+		if strings.Contains(query.Q, "@") {
+			index, err := pii.BlindIndex(models.NormalizeEmail(query.Q))
+			if err != nil {
+				return nil, models.PageInfo{}, err
+			}
+			match = match.Or("email_index = ?", index)
+		}
+		users = users.Where(match)
+	}
+	return findPage[models.User](users, "id", query.PageRequest)
+}
+
+// escapeLike escapes the LIKE wildcards in s, so user input only matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *userRepository) GetAllWithDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return findPage[models.User](db.Unscoped().Model(&models.User{}), "id", page)
+}
+
+// Each streams all users in ID order through a database cursor, one row at a time
+func (r *userRepository) Each(ctx context.Context, fn func(user *models.User) error) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return eachRow(db, db.Model(&models.User{}).Order("id"), fn)
+}
+
+func (r *userRepository) GetByReviewStatus(ctx context.Context, status string) ([]models.User, error) {
 	db := utils.GetDBFromContext(ctx, r.db)
 	var users []models.User
-	if err := db.Find(&users).Error; err != nil {
+	if err := db.Where("review_status = ?", status).Order("created_at DESC").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
+func (r *userRepository) GetRecentlyActive(ctx context.Context, limit int) ([]models.User, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var users []models.User
+	if err := db.Where("last_login_at IS NOT NULL").Order("last_login_at DESC").Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) GetScheduledDeletions(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.User, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var users []models.User
+	if err := db.Where("deletion_scheduled_at <= ? AND id > ?", before, afterID).Order("id").Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) GetDeleted(ctx context.Context, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return findPage[models.User](db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL"), "deleted_at DESC, id DESC", page)
+}
+
+// Update saves all fields only if the row still has the version that was read,
+// then bumps it. Returns ErrVersionConflict when another update won the race.
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	db := utils.GetDBFromContext(ctx, r.db)
-	return db.Save(user).Error
+
+	expected := user.Version
+	user.Version++
+	result := db.Model(user).Where("version = ?", expected).Select("*").Updates(user)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		user.Version = expected
+	}
+	return result.Error
+}
+
+func (r *userRepository) GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+	var users []models.User
+	if err := db.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
 }
 
 // GetUsersByIDs retrieves multiple users by their IDs in a single query (for DataLoader)
+func (r *userRepository) GetDeletedByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
+	db := applyQueryOptions(utils.GetDBFromContext(ctx, r.db), opts)
+	var user models.User
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) GetUsersByIDs(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
 	db := utils.GetDBFromContext(ctx, r.db)
 
@@ -97,3 +226,67 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 	db := utils.GetDBFromContext(ctx, r.db)
 	return db.Delete(&models.User{}, id).Error
 }
+
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	result := db.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+			// The account was erased on schedule; it must not be erased again on the next run
+			"deletion_scheduled_at": nil,
+			"deletion_reminded_at":  nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdatePassword stores a re-computed hash of the same password. It leaves version
+// alone: nothing visible to clients changes, so concurrent edits must not conflict.
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.User{}).Where("id = ?", id).UpdateColumn("password", hash).Error
+}
+
+// UpdateLastLogin records a successful login without bumping version, like UpdatePassword
+func (r *userRepository) UpdateLastLogin(ctx context.Context, id uint, at time.Time) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.User{}).Where("id = ?", id).UpdateColumn("last_login_at", at).Error
+}
+
+// IncrementTokenVersion bumps the user's token version, invalidating all previously issued tokens.
+// The row version is bumped too, so an update based on an earlier read cannot undo the revocation.
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id uint) error {
+	db := utils.GetDBFromContext(ctx, r.db)
+	return db.Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"token_version": gorm.Expr("token_version + 1"),
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+}
+
+// PurgeDeleted permanently removes up to limit users soft-deleted before cutoff.
+// Users still referenced by posts are skipped so the foreign key is never violated.
+func (r *userRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	db := utils.GetDBFromContext(ctx, r.db)
+
+	var ids []uint
+	if err := db.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM posts WHERE posts.user_id = users.id)").
+		Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := db.Unscoped().Delete(&models.User{}, ids)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stalledDB returns a GORM handle whose server accepts connections and never answers,
+// so every query blocks until its context ends
+func stalledDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+
+	addr := listener.Addr().(*net.TCPAddr)
+	dsn := "host=127.0.0.1 user=goapi dbname=goapi sslmode=disable connect_timeout=30 port=" + strconv.Itoa(addr.Port)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// cancelDuring runs call and cancels its context once it is in flight
+func cancelDuring(t *testing.T, call func(ctx context.Context) error) error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- call(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("query kept running after its context was canceled")
+		return nil
+	}
+}
+
+func TestCanceledRequestAbortsQuery(t *testing.T) {
+	repo := NewUserRepository(stalledDB(t))
+
+	err := cancelDuring(t, func(ctx context.Context) error {
+		_, err := repo.GetByID(ctx, 1)
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestCanceledRequestAbortsTransaction(t *testing.T) {
+	repo := NewUserRepository(stalledDB(t))
+
+	err := cancelDuring(t, func(ctx context.Context) error {
+		return repo.WithTransaction(ctx, func(txCtx context.Context) error {
+			_, err := repo.GetByID(txCtx, 1)
+			return err
+		})
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"jane":      "jane",
+		"100%":      `100\%`,
+		"j_doe":     `j\_doe`,
+		`back\name`: `back\\name`,
+	}
+	for in, want := range tests {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"errors"
+
+	"goapi/internal/app"
+	"goapi/internal/config"
+	"goapi/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// New builds the HTTP router with the full middleware and handler stack from deps.
+// It has no side effects beyond route registration, so tests can serve it with httptest.
+func New(cfg *config.Config, deps *app.Container) (*gin.Engine, error) {
+	if cfg == nil || deps == nil {
+		return nil, errors.New("server: config and dependencies are required")
+	}
+	if cfg.App.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	h, mw := deps.Handlers, deps.Middlewares
+
+	// Setup Gin router (Use New() to avoid default Logger)
+	router := gin.New()
+	router.Use(middleware.CustomRecovery(!cfg.App.IsProduction()))
+
+	// Global middleware
+	router.Use(middleware.RequestID()) // Add Request ID first
+	router.Use(middleware.Logger())    // Add Custom Logger
+	router.Use(middleware.CORS())
+	router.Use(mw.Language)
+	router.Use(mw.DataLoader) // Add DataLoader for N+1 prevention
+
+	// Global Rate Limiter (RATE_LIMIT_GLOBAL per RATE_LIMIT_PERIOD, default 100/min),
+	// counting anonymous clients by device token where they have one
+	router.Use(mw.DeviceToken)
+	router.Use(mw.GlobalLimiter)
+
+	// Health check
+	router.GET("/health", h.Health.Check)
+	router.GET("/readyz", h.Health.Ready)
+	router.GET("/version", mw.Coalesce, h.Health.Version)
+
+	// Development tools
+	if !cfg.App.IsProduction() {
+		dev := router.Group("/dev")
+		dev.GET("/emails", h.EmailPreview.List)
+		dev.GET("/emails/:name", h.EmailPreview.Preview)
+	}
+
+	// API routes v1
+	v1 := router.Group("/api/v1")
+	{
+		// Public routes
+		// Strict Rate Limiter for Auth (RATE_LIMIT_AUTH, default 5/min)
+		v1.GET("/register/form-token", h.User.FormToken)
+		v1.POST("/register", mw.AuthLimiter, h.User.Register)
+		v1.POST("/login", mw.AuthLimiter, h.User.Login)
+		v1.POST("/login/passkey/begin", mw.AuthLimiter, h.Passkeys.BeginLogin)
+		v1.POST("/login/passkey/finish", mw.AuthLimiter, h.Passkeys.FinishLogin)
+		v1.POST("/login/magic-link", mw.AuthLimiter, h.MagicLink.Request)
+		v1.GET("/login/magic", mw.AuthLimiter, h.MagicLink.Login)
+		v1.POST("/password/forgot", mw.AuthLimiter, h.Password.ForgotPassword)
+		v1.POST("/password/reset", mw.AuthLimiter, h.Password.ResetPassword)
+
+		// Billing (webhook is authenticated by the Stripe signature)
+		v1.GET("/billing/plans", mw.Coalesce, h.Billing.GetPlans)
+		v1.POST("/billing/webhook", h.Billing.Webhook)
+
+		// Partner routes (HMAC-signed requests, no JWT)
+		partner := v1.Group("/partner")
+		partner.Use(mw.Partner)
+		{
+			partner.GET("/posts", h.Post.GetAllPosts)
+		}
+
+		// Streaming routes (EventSource/WebSocket clients may pass ?access_token=)
+		streaming := v1.Group("/stream")
+		streaming.Use(mw.StreamAuth)
+		streaming.Use(mw.PlanLimiter)
+		streaming.Use(mw.UsageMeter)
+		{
+			streaming.GET("/posts", h.Post.ExportPosts)
+		}
+
+		// Protected routes
+		authorized := v1.Group("")
+		// Signed share links (?exp=&sig=) pass Auth without a token
+		authorized.Use(mw.SignedURL)
+		authorized.Use(mw.Auth)
+		authorized.Use(mw.CSRF)
+		// Per-plan limits: requests per minute come from the user's plan
+		authorized.Use(mw.PlanLimiter)
+		authorized.Use(mw.UsageMeter)
+		{
+			// User routes
+			authorized.GET("/users", mw.AdminOnly, h.User.GetAllUsers)
+			authorized.POST("/users/lookup", h.User.LookupUsers)
+			authorized.GET("/users/:id", h.User.GetUserByID)
+			authorized.GET("/users/:id/stats", h.User.GetUserStats)
+			authorized.PUT("/users/:id", h.User.UpdateUser)
+			authorized.DELETE("/users/:id", mw.AdminOnly, h.User.DeleteUser)
+			authorized.GET("/me", h.User.GetCurrentUser)
+			authorized.DELETE("/me", mw.AuthLimiter, h.User.DeleteAccount)
+			authorized.POST("/logout", h.User.Logout)
+			authorized.PUT("/me/password", h.User.ChangePassword)
+			authorized.POST("/me/logout-all", mw.AuthLimiter, h.User.LogoutAll)
+			authorized.GET("/me/security/logins", h.User.GetLoginHistory)
+			authorized.GET("/me/features", h.Features.GetMyFeatures)
+			authorized.GET("/me/sessions", h.User.GetSessions)
+			authorized.DELETE("/me/sessions/:id", h.User.RevokeSession)
+			authorized.GET("/me/passkeys", h.Passkeys.List)
+			authorized.POST("/me/passkeys/register/begin", h.Passkeys.BeginRegistration)
+			authorized.POST("/me/passkeys/register/finish", h.Passkeys.FinishRegistration)
+			authorized.DELETE("/me/passkeys/:id", h.Passkeys.Delete)
+
+			// Billing routes
+			authorized.POST("/billing/checkout", h.Billing.CreateCheckout)
+			authorized.GET("/me/usage", h.Usage.GetMyUsage)
+
+			// Post routes (demonstrates DataLoader usage)
+			authorized.POST("/posts", h.Post.CreatePost)
+			authorized.GET("/posts", h.Post.GetAllPosts) // Batches user loading, supports ?user_id=X
+			authorized.GET("/posts/search", h.Post.SearchPosts)
+			authorized.GET("/posts/:id", h.Post.GetPost)
+			authorized.GET("/posts/:id/related", h.Post.GetRelatedPosts)
+			authorized.DELETE("/posts/:id", h.Post.DeletePost)
+			authorized.POST("/posts/:id/archive", h.Post.ArchivePost)
+			authorized.POST("/posts/:id/unarchive", h.Post.UnarchivePost)
+			authorized.POST("/posts/:id/share", h.Post.SharePost)
+			authorized.GET("/posts/:id/draft", h.Post.GetDraft)
+			authorized.PUT("/posts/:id/draft", mw.DraftLimiter, h.Post.SaveDraft)
+			authorized.GET("/posts/:id/translations", h.Post.GetTranslations)
+			authorized.PUT("/posts/:id/translations/:locale", h.Post.TranslatePost)
+			authorized.GET("/tags", h.Post.ListTags)
+			authorized.GET("/categories", h.Categories.List)
+			authorized.GET("/categories/:id", h.Categories.Get)
+			authorized.POST("/categories", mw.AdminOnly, h.Categories.Create)
+			authorized.PUT("/categories/:id", mw.AdminOnly, h.Categories.Update)
+			authorized.DELETE("/categories/:id", mw.AdminOnly, h.Categories.Delete)
+			authorized.GET("/leaderboard/authors", h.Leaderboard.GetAuthors)
+
+			// Admin routes
+			admin := authorized.Group("/admin")
+			admin.Use(mw.AdminOnly)
+			{
+				admin.GET("/usage", h.Usage.GetReport)
+				admin.GET("/config", h.Config.Get)
+				admin.GET("/health/details", h.Health.Details)
+				admin.POST("/cache/flush", h.Cache.Flush)
+				admin.GET("/cache/keys", h.Cache.ListKeys)
+				admin.DELETE("/cache/keys/:key", h.Cache.EvictKey)
+				admin.GET("/cache/usage", h.Cache.Usage)
+				admin.GET("/signups/flagged", h.User.ListFlaggedSignups)
+				admin.POST("/signups/:id/approve", h.User.ApproveSignup)
+				admin.GET("/exports/users", h.User.ExportUsers)
+				admin.GET("/exports/posts", h.Post.ExportPosts)
+				admin.GET("/jobs/failed", h.Jobs.ListFailed)
+				admin.POST("/jobs/:id/retry", h.Jobs.Retry)
+
+				admin.GET("/users", h.AdminUsers.List)
+				admin.GET("/users/deleted", h.AdminUsers.GetDeleted)
+				admin.POST("/users/:id/restore", h.AdminUsers.Restore)
+				admin.POST("/users/:id/ban", h.AdminUsers.Ban)
+				admin.POST("/users/:id/unban", h.AdminUsers.Unban)
+				admin.POST("/users/:id/force-password-reset", h.AdminUsers.ForcePasswordReset)
+				admin.PUT("/users/:id/role", h.AdminUsers.ChangeRole)
+			}
+		}
+	}
+
+	return router, nil
+}
@@ -0,0 +1,1049 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"goapi/internal/app"
+	"goapi/internal/config"
+	"goapi/internal/models"
+	"goapi/internal/services"
+	"goapi/pkg/logger"
+	"goapi/pkg/mailer"
+	"goapi/pkg/utctime"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Init()
+	os.Exit(m.Run())
+}
+
+// testServer is the full router over in-memory repositories and a miniredis instance
+type testServer struct {
+	router    *gin.Engine
+	container *app.Container
+}
+
+func newTestServer(t *testing.T, opts ...app.Option) *testServer {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	opts = append([]app.Option{app.WithRepositories(app.InMemoryRepositories())}, opts...)
+	container, err := app.New(context.Background(), cfg, nil, redisClient, opts...)
+	if err != nil {
+		t.Fatalf("build container: %v", err)
+	}
+	router, err := New(cfg, container)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	return &testServer{router: router, container: container}
+}
+
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// login registers a user with role and returns their ID and an access token
+func (s *testServer) login(t *testing.T, email, role string) (uint, string) {
+	t.Helper()
+	ctx := context.Background()
+	users := s.container.Services.User
+	username, _, _ := strings.Cut(email, "@")
+	registered, err := users.Register(ctx, &models.RegisterRequest{Email: email, Username: username, Password: "secret123", FullName: "Test User"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if role != "user" {
+		if _, err := users.ChangeRole(ctx, registered.ID, role); err != nil {
+			t.Fatalf("set role: %v", err)
+		}
+	}
+	token, _, err := users.Login(ctx, &models.LoginRequest{Email: email, Password: "secret123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return registered.ID, token
+}
+
+func TestRoleChangeRevokesOldToken(t *testing.T) {
+	s := newTestServer(t)
+	id, token := s.login(t, "alice@example.com", "admin")
+
+	if rec := s.do(http.MethodGet, "/api/v1/admin/users/deleted", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("admin route before demotion: got %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	if _, err := s.container.Services.User.ChangeRole(context.Background(), id, "user"); err != nil {
+		t.Fatalf("change role: %v", err)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/admin/users/deleted", token, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("admin route with pre-demotion token: got %d, want 401: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestLookupUsers(t *testing.T) {
+	s := newTestServer(t)
+	janeID, _ := s.login(t, "jane@example.com", "user")
+	johnID, _ := s.login(t, "john@example.com", "user")
+	_, token := s.login(t, "viewer@example.com", "user")
+
+	rec := s.do(http.MethodPost, "/api/v1/users/lookup", token, gin.H{"ids": []uint{johnID, 999}, "usernames": []string{"jane", "john", "nobody"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data []struct {
+			ID    uint   `json:"id"`
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.Data[0].ID != johnID || body.Data[1].ID != janeID {
+		t.Fatalf("got %+v, want john then jane", body.Data)
+	}
+	for _, user := range body.Data {
+		if !strings.Contains(user.Email, "***") || user.Role != "" {
+			t.Errorf("user %d is not shown in the public view: %+v", user.ID, user)
+		}
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/users/lookup", token, gin.H{}); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("empty lookup: got %d, want 422", rec.Code)
+	}
+}
+
+func TestPublicUserViewHidesVersion(t *testing.T) {
+	s := newTestServer(t)
+	janeID, janeToken := s.login(t, "jane@example.com", "user")
+	_, viewerToken := s.login(t, "viewer@example.com", "user")
+	path := "/api/v1/users/" + strconv.FormatUint(uint64(janeID), 10)
+
+	tests := []struct {
+		name        string
+		token       string
+		wantVersion bool
+	}{
+		{"self", janeToken, true},
+		{"other user", viewerToken, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, path, tt.token, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %d, want 200: %s", rec.Code, rec.Body)
+			}
+			var body struct {
+				Data map[string]any `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			_, hasVersion := body.Data["version"]
+			hasETag := rec.Header().Get("ETag") != ""
+			if hasVersion != tt.wantVersion || hasETag != tt.wantVersion {
+				t.Fatalf("got version %v and ETag %v, want %v", hasVersion, hasETag, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestAdminUserListingAndRestore(t *testing.T) {
+	s := newTestServer(t)
+	janeID, _ := s.login(t, "jane@example.com", "user")
+	_, token := s.login(t, "alice@example.com", "admin")
+
+	rec := s.do(http.MethodGet, "/api/v1/admin/users", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: got %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 {
+		t.Fatalf("got %d users, want 2", len(body.Data))
+	}
+	for _, user := range body.Data {
+		if deletedAt, ok := user["deleted_at"]; !ok || deletedAt != nil {
+			t.Errorf("live user %v: want deleted_at null", user["id"])
+		}
+	}
+
+	// Only soft-deleted users can be restored
+	if rec := s.do(http.MethodPost, "/api/v1/admin/users/"+strconv.FormatUint(uint64(janeID), 10)+"/restore", token, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("restore live user: got %d, want 404: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPostIncludes(t *testing.T) {
+	s := newTestServer(t)
+	id, token := s.login(t, "jane@example.com", "user")
+	if _, err := s.container.Services.Post.Create(context.Background(), &models.CreatePostRequest{Title: "Hello", Content: "Hello world, this is a post."}, id); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantAuthor bool
+	}{
+		{"default", "", http.StatusOK, true},
+		{"author", "?include=author", http.StatusOK, true},
+		{"nothing", "?include=", http.StatusOK, false},
+		{"unknown relation", "?include=comments.author", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/api/v1/posts"+tt.query, token, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data []struct {
+					Author *models.AuthorResponse `json:"author"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 || (body.Data[0].Author != nil) != tt.wantAuthor {
+				t.Fatalf("got %s, want author embedded: %v", rec.Body, tt.wantAuthor)
+			}
+		})
+	}
+}
+
+func TestListUsersPaginates(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "jane@example.com", "user")
+	_, token := s.login(t, "alice@example.com", "admin")
+
+	tests := []struct {
+		query       string
+		wantUsers   int
+		wantHasMore bool
+	}{
+		{"?page=1&limit=1", 1, true},
+		{"?page=2&limit=1", 1, false},
+		{"?page=3&limit=1", 0, false},
+	}
+	for _, tt := range tests {
+		rec := s.do(http.MethodGet, "/api/v1/users"+tt.query, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d, want 200: %s", tt.query, rec.Code, rec.Body)
+		}
+		var body struct {
+			Data []json.RawMessage `json:"data"`
+			Meta struct {
+				Total   *int64 `json:"total"`
+				HasMore bool   `json:"has_more"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data == nil || len(body.Data) != tt.wantUsers || body.Meta.HasMore != tt.wantHasMore {
+			t.Errorf("%s: got %s", tt.query, rec.Body)
+		}
+		if body.Meta.Total == nil || *body.Meta.Total != 2 {
+			t.Errorf("%s: got total %v, want 2", tt.query, body.Meta.Total)
+		}
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/users?limit=101", token, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("limit over the maximum: got %d, want 400", rec.Code)
+	}
+}
+
+func TestPostFeedCursor(t *testing.T) {
+	s := newTestServer(t)
+	id, token := s.login(t, "jane@example.com", "user")
+	var created []uint
+	for i := range 5 {
+		post, err := s.container.Services.Post.Create(context.Background(), &models.CreatePostRequest{
+			Title:   "Post " + strconv.Itoa(i),
+			Content: "Entry number " + strconv.Itoa(i) + " of the feed.",
+		}, id)
+		if err != nil {
+			t.Fatalf("create post: %v", err)
+		}
+		created = append(created, post.ID)
+	}
+
+	// Walk the feed by cursor, newest first, two posts at a time
+	var seen []uint
+	path := "/api/v1/posts?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("feed did not end")
+		}
+		rec := s.do(http.MethodGet, path, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d, want 200: %s", path, rec.Code, rec.Body)
+		}
+		var body struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+			Meta struct {
+				NextCursor uint `json:"next_cursor"`
+				HasMore    bool `json:"has_more"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		for _, post := range body.Data {
+			seen = append(seen, post.ID)
+		}
+		if body.Meta.HasMore != (body.Meta.NextCursor != 0) {
+			t.Fatalf("%s: has_more %v with next_cursor %d", path, body.Meta.HasMore, body.Meta.NextCursor)
+		}
+		path = ""
+		if body.Meta.NextCursor != 0 {
+			if link := rec.Header().Get("Link"); !strings.Contains(link, "after="+strconv.FormatUint(uint64(body.Meta.NextCursor), 10)) {
+				t.Errorf("next link does not carry the cursor: %s", link)
+			}
+			path = "/api/v1/posts?limit=2&after=" + strconv.FormatUint(uint64(body.Meta.NextCursor), 10)
+		}
+	}
+	for i, postID := range seen {
+		if postID != created[len(created)-1-i] {
+			t.Fatalf("got %v, want %v newest first", seen, created)
+		}
+	}
+	if len(seen) != len(created) {
+		t.Fatalf("got %v, want all of %v", seen, created)
+	}
+
+	for _, query := range []string{"?after=999", "?after=1&sort=word_count"} {
+		if rec := s.do(http.MethodGet, "/api/v1/posts"+query, token, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestAdminConfigRedactsSecrets(t *testing.T) {
+	const secret = "a-long-enough-jwt-secret-for-testing-only"
+	t.Setenv("JWT_SECRET", secret)
+	s := newTestServer(t)
+	_, userToken := s.login(t, "jane@example.com", "user")
+	_, token := s.login(t, "alice@example.com", "admin")
+
+	if rec := s.do(http.MethodGet, "/api/v1/admin/config", userToken, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: got %d, want 403", rec.Code)
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/admin/config", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), secret) {
+		t.Fatal("response contains the JWT secret")
+	}
+	var body struct {
+		Data config.Report `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	auth, _ := body.Data.Config["auth"].(map[string]any)
+	if auth["jwt_secret"] != "[REDACTED]" || auth["token_ttl"] != "24h0m0s" {
+		t.Errorf("auth: got %v", auth)
+	}
+	if body.Data.Sources["JWT_SECRET"] != config.SourceEnvironment || body.Data.Sources["JWT_TTL"] != config.SourceDefault {
+		t.Errorf("sources: got JWT_SECRET=%q JWT_TTL=%q", body.Data.Sources["JWT_SECRET"], body.Data.Sources["JWT_TTL"])
+	}
+	if !slices.Contains(body.Data.Overrides, "JWT_SECRET") || slices.Contains(body.Data.Overrides, "JWT_TTL") {
+		t.Errorf("overrides: got %v", body.Data.Overrides)
+	}
+}
+
+func TestListUsersFilters(t *testing.T) {
+	s := newTestServer(t)
+	janeID, _ := s.login(t, "jane@example.com", "user")
+	johnID, _ := s.login(t, "john@example.com", "user")
+	adminID, token := s.login(t, "alice@example.com", "admin")
+	if _, err := s.container.Services.User.Ban(context.Background(), johnID, ""); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"?q=JA", []uint{janeID}},
+		{"?q=test", []uint{janeID, johnID, adminID}},
+		{"?q=john@example.com", []uint{johnID}},
+		{"?role=admin", []uint{adminID}},
+		{"?active=false", []uint{johnID}},
+		{"?q=ja&role=user&active=true", []uint{janeID}},
+	}
+	for _, tt := range tests {
+		rec := s.do(http.MethodGet, "/api/v1/users"+tt.query, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d, want 200: %s", tt.query, rec.Code, rec.Body)
+		}
+		var body struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		var got []uint
+		for _, user := range body.Data {
+			got = append(got, user.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/users?role=owner", token, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown role: got %d, want 400", rec.Code)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	s := newTestServer(t)
+	id, token := s.login(t, "jane@example.com", "user")
+	for _, req := range []models.CreatePostRequest{
+		{Title: "Tuning the connection pool", Content: "A pool that is too small queues requests. Size the pool from measured latency."},
+		{Title: "Release notes", Content: "This release adds a connection timeout & retry fixes."},
+		{Title: "Gardening", Content: "Nothing about databases here at all, only tomatoes."},
+	} {
+		if _, err := s.container.Services.Post.Create(context.Background(), &req, id); err != nil {
+			t.Fatalf("create post: %v", err)
+		}
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/posts/search?q=connection", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data []models.PostSearchResult `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.Meta.Total != 2 {
+		t.Fatalf("got %s, want the two posts about connections", rec.Body)
+	}
+	if got := body.Data[0].Highlights.Title; got != "Tuning the <mark>connection</mark> pool" {
+		t.Errorf("title highlight = %q", got)
+	}
+	if got := body.Data[1].Highlights.Content; !strings.Contains(got, "<mark>connection</mark> timeout &amp; retry") {
+		t.Errorf("content highlight = %q, want the match marked and the rest escaped", got)
+	}
+	if body.Data[0].Author == nil || body.Data[0].Rank <= 0 {
+		t.Errorf("got %+v, want an author and a positive rank", body.Data[0])
+	}
+
+	rec = s.do(http.MethodGet, "/api/v1/posts/search?q=pool+latency", token, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 1 || body.Data[0].Title != "Tuning the connection pool" {
+		t.Fatalf("got %s, want only the post with both words", rec.Body)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/posts/search", token, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing q: got %d, want 400", rec.Code)
+	}
+}
+
+func TestPostTags(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t, "jane@example.com", "user")
+
+	for _, post := range []map[string]any{
+		{"title": "Generics in Go", "content": "Type parameters, constraints and inference.", "tags": []string{"Golang", " generics", "golang"}},
+		{"title": "Go modules", "content": "Versioning dependencies with go.mod.", "tags": []string{"golang"}},
+		{"title": "Untagged", "content": "A post without any tags at all."},
+	} {
+		if rec := s.do(http.MethodPost, "/api/v1/posts", token, post); rec.Code != http.StatusCreated {
+			t.Fatalf("create post: got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/posts?tag=GoLang", token, nil)
+	var posts struct {
+		Data []models.PostListItem `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &posts); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts.Data) != 2 || posts.Data[0].Title != "Go modules" {
+		t.Fatalf("got %s, want the two golang posts", rec.Body)
+	}
+	if got := posts.Data[1].Tags; !slices.Equal(got, []string{"generics", "golang"}) {
+		t.Errorf("tags = %v, want normalized, deduplicated and sorted", got)
+	}
+
+	rec = s.do(http.MethodGet, "/api/v1/tags", token, nil)
+	var tags struct {
+		Data []models.TagCount `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &tags); err != nil {
+		t.Fatal(err)
+	}
+	want := []models.TagCount{{Name: "golang", Posts: 2}, {Name: "generics", Posts: 1}}
+	if !slices.Equal(tags.Data, want) {
+		t.Fatalf("got %+v, want %+v", tags.Data, want)
+	}
+
+	for _, bad := range [][]string{{"two words"}, {"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}} {
+		rec := s.do(http.MethodPost, "/api/v1/posts", token, map[string]any{"title": "Bad tags", "content": "This post has invalid tags.", "tags": bad})
+		if rec.Code != http.StatusBadRequest && rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("tags %q: got %d, want a rejection", bad, rec.Code)
+		}
+	}
+}
+
+func TestAccountDeletion(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	id, token := s.login(t, "jane@example.com", "user")
+	credentials := map[string]any{"email": "jane@example.com", "password": "secret123"}
+
+	if rec := s.do(http.MethodDelete, "/api/v1/me", token, map[string]string{"password": "wrong"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: got %d, want 401: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodDelete, "/api/v1/me", token, map[string]string{"password": "secret123"}); rec.Code != http.StatusAccepted {
+		t.Fatalf("delete: got %d, want 202: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/me", token, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("old token: got %d, want 401", rec.Code)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/login", "", credentials)
+	var failed struct {
+		Code  string `json:"code"`
+		Error struct {
+			DeletionScheduledAt string `json:"deletion_scheduled_at"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failed); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusForbidden || failed.Code != "account_pending_deletion" || failed.Error.DeletionScheduledAt == "" {
+		t.Fatalf("login: got %d %s, want 403 account_pending_deletion", rec.Code, rec.Body)
+	}
+
+	credentials["restore"] = true
+	if rec := s.do(http.MethodPost, "/api/v1/login", "", credentials); rec.Code != http.StatusOK {
+		t.Fatalf("restore: got %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if user, _ := s.container.Repositories.User.GetByID(ctx, id); user.DeletionScheduledAt != nil {
+		t.Fatal("restore left the deletion scheduled")
+	}
+
+	// The job reminds users whose deletion is near and erases the ones past it
+	users := s.container.Repositories.User
+	schedule := func(at time.Time) {
+		t.Helper()
+		user, err := users.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.DeletionScheduledAt = utctime.Ptr(at)
+		if err := users.Update(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schedule(time.Now().Add(time.Hour))
+	for _, want := range []services.DeletionReport{{Reminded: 1}, {}} {
+		report, err := s.container.Services.AccountDeletion.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *report != want {
+			t.Fatalf("before the deadline: got %+v, want %+v", *report, want)
+		}
+	}
+
+	schedule(time.Now().Add(-time.Minute))
+	report, err := s.container.Services.AccountDeletion.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Erased != 1 {
+		t.Fatalf("after the deadline: got %+v, want 1 erased", *report)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/login", "", credentials); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login after erasure: got %d, want 401", rec.Code)
+	}
+}
+
+func TestPostCategories(t *testing.T) {
+	s := newTestServer(t)
+	_, userToken := s.login(t, "jane@example.com", "user")
+	_, adminToken := s.login(t, "alice@example.com", "admin")
+
+	if rec := s.do(http.MethodPost, "/api/v1/categories", userToken, map[string]string{"name": "Tutorials"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("create as user: got %d, want 403", rec.Code)
+	}
+	rec := s.do(http.MethodPost, "/api/v1/categories", adminToken, map[string]string{"name": " Tutorials ", "description": "Step by step guides"})
+	var created struct {
+		Data models.Category `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || created.Data.Name != "Tutorials" {
+		t.Fatalf("create: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/categories", adminToken, map[string]string{"name": "tutorials"}); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate name: got %d, want 409", rec.Code)
+	}
+	category := strconv.FormatUint(uint64(created.Data.ID), 10)
+
+	if rec := s.do(http.MethodPost, "/api/v1/posts", userToken, map[string]any{"title": "Lost post", "content": "In a category that does not exist.", "category_id": 999}); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown category: got %d, want 422: %s", rec.Code, rec.Body)
+	}
+	rec = s.do(http.MethodPost, "/api/v1/posts", userToken, map[string]any{"title": "Channels in Go", "content": "Buffered and unbuffered channels.", "category_id": created.Data.ID})
+	var post struct {
+		Data models.PostResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || post.Data.CategoryID == nil || *post.Data.CategoryID != created.Data.ID {
+		t.Fatalf("create post: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/posts", userToken, map[string]any{"title": "Uncategorized", "content": "A post outside every category."}); rec.Code != http.StatusCreated {
+		t.Fatalf("create post: got %d: %s", rec.Code, rec.Body)
+	}
+
+	listed := func() []models.PostListItem {
+		t.Helper()
+		rec := s.do(http.MethodGet, "/api/v1/posts?category_id="+category, userToken, nil)
+		var posts struct {
+			Data []models.PostListItem `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &posts); err != nil {
+			t.Fatal(err)
+		}
+		return posts.Data
+	}
+	if posts := listed(); len(posts) != 1 || posts[0].ID != post.Data.ID {
+		t.Fatalf("got %+v, want only the categorized post", posts)
+	}
+
+	if rec := s.do(http.MethodPut, "/api/v1/categories/"+category, adminToken, map[string]string{"name": "Guides"}); rec.Code != http.StatusOK {
+		t.Fatalf("rename: got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Deleting the category keeps its posts, outside any category
+	if rec := s.do(http.MethodDelete, "/api/v1/categories/"+category, adminToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("delete: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/categories/"+category, userToken, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("get deleted: got %d, want 404", rec.Code)
+	}
+	if posts := listed(); len(posts) != 0 {
+		t.Fatalf("got %d posts in the deleted category", len(posts))
+	}
+	rec = s.do(http.MethodGet, "/api/v1/posts/"+strconv.FormatUint(uint64(post.Data.ID), 10), userToken, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Data.CategoryID != nil {
+		t.Fatalf("post still in category %d", *post.Data.CategoryID)
+	}
+}
+
+// softAuthenticator is a software passkey with a P-256 key, answering ceremonies from
+// the origin and relying party the default config allows
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	counter      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &softAuthenticator{t: t, key: key, credentialID: credentialID}
+}
+
+func (a *softAuthenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": "http://localhost:3000"})
+	return data
+}
+
+// authData is the authenticator data with user presence and verification, plus rest
+func (a *softAuthenticator) authData(flags byte, rest []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte("localhost"))
+	data := append(rpIDHash[:], flags|0x01|0x04)
+	data = binary.BigEndian.AppendUint32(data, a.counter)
+	return append(data, rest...)
+}
+
+// create answers the data of POST /me/passkeys/register/begin
+func (a *softAuthenticator) create(body []byte) map[string]any {
+	a.t.Helper()
+	var begin struct {
+		Data struct {
+			PublicKey struct {
+				Challenge string `json:"challenge"`
+				User      struct {
+					ID string `json:"id"`
+				} `json:"user"`
+			} `json:"publicKey"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &begin); err != nil {
+		a.t.Fatal(err)
+	}
+	a.userHandle, _ = base64.RawURLEncoding.DecodeString(begin.Data.PublicKey.User.ID)
+
+	point, err := a.key.PublicKey.ECDH()
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	xy := point.Bytes()[1:]
+	publicKey, err := webauthncbor.Marshal(map[int]any{1: 2, 3: -7, -1: 1, -2: xy[:32], -3: xy[32:]})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(append(attested, a.credentialID...), publicKey...)
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(0x40, attested),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	return map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(a.clientData("webauthn.create", begin.Data.PublicKey.Challenge)),
+			"attestationObject": encode(attestation),
+		},
+	}
+}
+
+// get answers the data of POST /login/passkey/begin
+func (a *softAuthenticator) get(body []byte) map[string]any {
+	a.t.Helper()
+	var begin struct {
+		Data struct {
+			PublicKey struct {
+				Challenge string `json:"challenge"`
+			} `json:"publicKey"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &begin); err != nil {
+		a.t.Fatal(err)
+	}
+
+	a.counter++
+	authData := a.authData(0, nil)
+	clientData := a.clientData("webauthn.get", begin.Data.PublicKey.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(slices.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	return map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.userHandle),
+		},
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.login(t, "jane@example.com", "user")
+	authenticator := newSoftAuthenticator(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/me/passkeys/register/begin", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("begin registration: got %d: %s", rec.Code, rec.Body)
+	}
+	registration := map[string]any{"name": " Laptop ", "credential": authenticator.create(rec.Body.Bytes())}
+	rec = s.do(http.MethodPost, "/api/v1/me/passkeys/register/finish", token, registration)
+	var registered struct {
+		Data models.Passkey `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &registered); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || registered.Data.Name != "Laptop" {
+		t.Fatalf("finish registration: got %d %s", rec.Code, rec.Body)
+	}
+	// The challenge is spent
+	if rec := s.do(http.MethodPost, "/api/v1/me/passkeys/register/finish", token, registration); rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed registration: got %d, want 400", rec.Code)
+	}
+
+	loginWith := func(a *softAuthenticator) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := s.do(http.MethodPost, "/api/v1/login/passkey/begin", "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("begin login: got %d: %s", rec.Code, rec.Body)
+		}
+		return s.do(http.MethodPost, "/api/v1/login/passkey/finish", "", map[string]any{"credential": a.get(rec.Body.Bytes())})
+	}
+
+	rec = loginWith(authenticator)
+	var loggedIn struct {
+		Data struct {
+			Token string              `json:"token"`
+			User  models.UserResponse `json:"user"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &loggedIn); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || loggedIn.Data.User.ID != userID {
+		t.Fatalf("passkey login: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/me", loggedIn.Data.Token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("token from passkey login: got %d", rec.Code)
+	}
+
+	// Another key claiming the same credential ID fails the signature check
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID, impostor.userHandle = authenticator.credentialID, authenticator.userHandle
+	impostor.counter = authenticator.counter
+	if rec := loginWith(impostor); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong key: got %d, want 401", rec.Code)
+	}
+
+	rec = s.do(http.MethodGet, "/api/v1/me/passkeys", token, nil)
+	var passkeys struct {
+		Data []models.Passkey `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &passkeys); err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys.Data) != 1 || passkeys.Data[0].LastUsedAt == nil {
+		t.Fatalf("got %s, want the used passkey", rec.Body)
+	}
+
+	id := strconv.FormatUint(uint64(registered.Data.ID), 10)
+	if rec := s.do(http.MethodDelete, "/api/v1/me/passkeys/"+id, token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("delete: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := loginWith(authenticator); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("deleted passkey: got %d, want 401", rec.Code)
+	}
+}
+
+// outbox is a mailer.Sender that hands every message to the test
+type outbox chan mailer.Message
+
+func (o outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o <- msg
+	return nil
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	mail := make(outbox, 10)
+	s := newTestServer(t, app.WithMailer(mail))
+	userID, _ := s.login(t, "jane@example.com", "user")
+
+	request := func() int {
+		return s.do(http.MethodPost, "/api/v1/login/magic-link", "", map[string]string{"email": "jane@example.com"}).Code
+	}
+	if code := request(); code != http.StatusAccepted {
+		t.Fatalf("request link: got %d, want 202", code)
+	}
+	var msg mailer.Message
+	select {
+	case msg = <-mail:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no login link was sent")
+	}
+	match := regexp.MustCompile(`/login/magic\?token=([A-Za-z0-9_-]+)`).FindStringSubmatch(msg.HTML)
+	if msg.To != "jane@example.com" || match == nil {
+		t.Fatalf("got %+v, want a login link for jane", msg)
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/login/magic?token="+match[1], "", nil)
+	var loggedIn struct {
+		Data struct {
+			Token string              `json:"token"`
+			User  models.UserResponse `json:"user"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &loggedIn); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || loggedIn.Data.User.ID != userID {
+		t.Fatalf("magic link login: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/me", loggedIn.Data.Token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("token from magic link login: got %d", rec.Code)
+	}
+	// The link works once
+	if rec := s.do(http.MethodGet, "/api/v1/login/magic?token="+match[1], "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed link: got %d, want 401", rec.Code)
+	}
+
+	// MAGIC_LINK_MAX_PER_EMAIL defaults to 3 per window
+	for range 2 {
+		if code := request(); code != http.StatusAccepted {
+			t.Fatalf("request link: got %d, want 202", code)
+		}
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("request past the limit: got %d, want 429", code)
+	}
+}
+
+func TestArchivedPostTranslationsHidden(t *testing.T) {
+	s := newTestServer(t)
+	_, author := s.login(t, "jane@example.com", "user")
+	_, other := s.login(t, "john@example.com", "user")
+
+	rec := s.do(http.MethodPost, "/api/v1/posts", author, map[string]any{"title": "Hello world", "content": "A post that gets translated."})
+	var created struct {
+		Data models.PostResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/posts/" + strconv.FormatUint(uint64(created.Data.ID), 10)
+	if rec := s.do(http.MethodPut, path+"/translations/id", author, map[string]any{"title": "Halo dunia", "content": "Tulisan yang diterjemahkan."}); rec.Code != http.StatusOK {
+		t.Fatalf("translate: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, path+"/translations", other, nil); rec.Code != http.StatusOK {
+		t.Fatalf("translations of a live post: got %d", rec.Code)
+	}
+
+	if rec := s.do(http.MethodPost, path+"/archive", author, nil); rec.Code != http.StatusOK {
+		t.Fatalf("archive: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, path+"/translations", other, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("translations of an archived post for another user: got %d, want 404", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, path+"/translations", author, nil); rec.Code != http.StatusOK {
+		t.Fatalf("translations of an archived post for its author: got %d, want 200", rec.Code)
+	}
+}
+
+func TestShareLinkBoundToItsRoute(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t, "jane@example.com", "user")
+
+	var ids []string
+	for _, title := range []string{"Shared post", "Other post"} {
+		rec := s.do(http.MethodPost, "/api/v1/posts", token, map[string]any{"title": title, "content": "Some content for the post."})
+		var created struct {
+			Data models.PostResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, strconv.FormatUint(uint64(created.Data.ID), 10))
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/posts/"+ids[0]+"/share", token, nil)
+	var shared struct {
+		Data models.SharePostResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &shared); err != nil {
+		t.Fatal(err)
+	}
+	_, query, ok := strings.Cut(shared.Data.URL, "?")
+	if rec.Code != http.StatusOK || !ok {
+		t.Fatalf("share: got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := s.do(http.MethodGet, shared.Data.URL, "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("shared link: got %d, want 200", rec.Code)
+	}
+	for _, replay := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/posts/" + ids[1]},
+		{http.MethodGet, "/api/v1/posts/" + ids[0] + "/translations"},
+		{http.MethodDelete, "/api/v1/posts/" + ids[0]},
+	} {
+		if rec := s.do(replay.method, replay.path+"?"+query, "", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s with the share signature: got %d, want 403", replay.method, replay.path, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/posts/"+ids[0], token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("post after replays: got %d, want it still there", rec.Code)
+	}
+}
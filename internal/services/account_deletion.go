@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/internal/templates"
+	"goapi/pkg/cache"
+	"goapi/pkg/i18n"
+	"goapi/pkg/logger"
+	"goapi/pkg/mailer"
+	"goapi/pkg/password"
+	"goapi/pkg/utctime"
+)
+
+// ErrInvalidPassword is returned when the password confirming an account deletion is wrong
+var ErrInvalidPassword = errors.New("password is incorrect")
+
+// ErrDeletionNotDue is returned by DeleteScheduled for users whose deletion was canceled
+// or moved past the cutoff
+var ErrDeletionNotDue = errors.New("account deletion is not due")
+
+// PendingDeletionError is returned by Login for an account pending deletion when the
+// request does not ask to restore it
+type PendingDeletionError struct {
+	ScheduledAt utctime.Time `json:"deletion_scheduled_at"`
+}
+
+func (e *PendingDeletionError) Error() string {
+	return fmt.Sprintf("account is scheduled for deletion at %s", e.ScheduledAt.Format(time.RFC3339))
+}
+
+func (e *PendingDeletionError) ErrorCode() string { return "account_pending_deletion" }
+
+// deletionBatchSize caps the scheduled users loaded per query by a run
+const deletionBatchSize = 100
+
+// AccountDeletionService runs the deletions users ask for themselves. DELETE /me schedules
+// one after a grace period; a job reminds the user before it and then erases the account
+// with the cascade of UserService.Delete.
+type AccountDeletionService interface {
+	// Schedule re-confirms the user's password, schedules the deletion and logs the user
+	// out everywhere. An account already pending deletion keeps its date.
+	Schedule(ctx context.Context, id uint, password string) (utctime.Time, error)
+	// Run sends the reminders that are due and erases the accounts whose grace period is over
+	Run(ctx context.Context) (*DeletionReport, error)
+}
+
+// AccountDeletionOptions configures the grace period and the reminder
+type AccountDeletionOptions struct {
+	// Grace is how long an account stays pending deletion
+	Grace time.Duration
+	// ReminderBefore is how long before the deletion the reminder goes out; 0 sends none
+	ReminderBefore time.Duration
+	// RestoreURL is the login page the reminder links to
+	RestoreURL string
+}
+
+// DeletionReport counts what one run of the deletion job did
+type DeletionReport struct {
+	Reminded int `json:"reminded"`
+	Erased   int `json:"erased"`
+}
+
+type accountDeletionService struct {
+	repo      repository.UserRepository
+	users     UserService
+	cache     *cache.Cache
+	passwords *password.Hasher
+	emails    *templates.Renderer
+	mail      mailer.Sender
+	opts      AccountDeletionOptions
+}
+
+func NewAccountDeletionService(repo repository.UserRepository, users UserService, cacheStore *cache.Cache, passwords *password.Hasher, emails *templates.Renderer, mail mailer.Sender, opts AccountDeletionOptions) AccountDeletionService {
+	return &accountDeletionService{
+		repo:      repo,
+		users:     users,
+		cache:     cacheStore,
+		passwords: passwords,
+		emails:    emails,
+		mail:      mail,
+		opts:      opts,
+	}
+}
+
+func (s *accountDeletionService) Schedule(ctx context.Context, id uint, password string) (utctime.Time, error) {
+	var scheduled utctime.Time
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.repo.GetByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+		if ok, _ := user.CheckPassword(s.passwords, password); !ok {
+			return ErrInvalidPassword
+		}
+
+		if user.DeletionScheduledAt == nil {
+			user.DeletionScheduledAt = utctime.Ptr(time.Now().Add(s.opts.Grace))
+			user.DeletionRemindedAt = nil
+			if err := s.repo.Update(txCtx, user); err != nil {
+				return err
+			}
+		}
+		scheduled = *user.DeletionScheduledAt
+		return nil
+	})
+	if err != nil {
+		return utctime.Time{}, err
+	}
+
+	logger.FromContext(ctx).Info("Account deletion scheduled", "user_id", id, "scheduled_at", scheduled.Format(time.RFC3339))
+	return scheduled, s.users.RevokeTokens(ctx, id)
+}
+
+// Run pages through the users whose deletion is due by the end of the reminder window.
+// A failure for one user is reported once the others are done, so it cannot hold up the
+// rest of the batch.
+func (s *accountDeletionService) Run(ctx context.Context) (*DeletionReport, error) {
+	now := time.Now()
+	report := &DeletionReport{}
+	var errs []error
+
+	for afterID := uint(0); ; {
+		users, err := s.repo.GetScheduledDeletions(ctx, now.Add(s.opts.ReminderBefore), afterID, deletionBatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		for i := range users {
+			user := &users[i]
+			afterID = user.ID
+
+			switch {
+			case !user.DeletionScheduledAt.After(now):
+				err := s.users.DeleteScheduled(ctx, user.ID, now)
+				// Restored or deleted by an admin since the query
+				if errors.Is(err, ErrDeletionNotDue) || errors.Is(err, ErrUserNotFound) {
+					continue
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("erase user %d: %w", user.ID, err))
+					continue
+				}
+				logger.FromContext(ctx).Info("Account erased after scheduled deletion", "user_id", user.ID)
+				report.Erased++
+
+			case user.DeletionRemindedAt == nil:
+				if err := s.remind(ctx, user); err != nil {
+					errs = append(errs, fmt.Errorf("remind user %d: %w", user.ID, err))
+					continue
+				}
+				report.Reminded++
+			}
+		}
+
+		if len(users) < deletionBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	logger.FromContext(ctx).Info("Processed scheduled account deletions", "erased", report.Erased, "reminded", report.Reminded, "failed", len(errs))
+	return report, errors.Join(append(errs, ctx.Err())...)
+}
+
+// remind emails user that their account is about to be erased. Users have no language
+// preference stored, so the email is in the default language. The reminder is recorded
+// only once the email is sent, so a failed send is retried on the next run.
+func (s *accountDeletionService) remind(ctx context.Context, user *models.User) error {
+	message, err := s.emails.Render(templates.Deletion, i18n.DefaultLanguage, templates.DeletionData{
+		Name: user.FullName,
+		Link: s.opts.RestoreURL,
+		Date: user.DeletionScheduledAt.Format("2 January 2006 15:04 MST"),
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.mail.Send(ctx, mailer.Message{To: user.Email, Subject: message.Subject, HTML: message.HTML}); err != nil {
+		return fmt.Errorf("send deletion reminder: %w", err)
+	}
+
+	user.DeletionRemindedAt = utctime.Ptr(time.Now())
+	if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	return userChanged(ctx, s.cache, user.ID)
+}
+
+// cancelDeletion takes account id out of pending deletion and returns it as updated
+func (s *userService) cancelDeletion(ctx context.Context, id uint) (*models.User, error) {
+	var user *models.User
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		user, err = s.repo.GetByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+		user.DeletionScheduledAt, user.DeletionRemindedAt = nil, nil
+		return s.repo.Update(txCtx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Account deletion canceled", "user_id", id)
+	return user, userChanged(ctx, s.cache, id)
+}
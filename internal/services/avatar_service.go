@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/httpclient"
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const gravatarBaseURL = "https://www.gravatar.com/avatar/"
+
+// gravatarStyles are fallbacks Gravatar generates itself, so no existence check is needed
+var gravatarStyles = map[string]bool{
+	"identicon": true, "monsterid": true, "wavatar": true, "retro": true, "robohash": true, "mp": true,
+}
+
+// AvatarOptions configures avatar fallbacks
+type AvatarOptions struct {
+	Size int
+	// Fallback is a Gravatar style (e.g. "identicon") or a URL template where {hash} is
+	// replaced by the email hash, used when the user has no Gravatar
+	Fallback string
+	CacheTTL time.Duration
+}
+
+type AvatarService interface {
+	Resolve(ctx context.Context, user *models.User) string
+}
+
+type avatarService struct {
+	redis *redis.Client
+	http  *httpclient.Client
+	opts  AvatarOptions
+}
+
+func NewAvatarService(redisClient *redis.Client, httpClient *httpclient.Client, opts AvatarOptions) AvatarService {
+	return &avatarService{redis: redisClient, http: httpClient, opts: opts}
+}
+
+// Resolve returns the user's own avatar, else their Gravatar, else the configured fallback
+func (s *avatarService) Resolve(ctx context.Context, user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.AvatarURL != "" {
+		return user.AvatarURL
+	}
+
+	hash := gravatarHash(user.Email)
+	if gravatarStyles[s.opts.Fallback] {
+		return s.gravatarURL(hash, s.opts.Fallback)
+	}
+	if s.hasGravatar(ctx, hash) {
+		return s.gravatarURL(hash, "404")
+	}
+	return strings.ReplaceAll(s.opts.Fallback, "{hash}", hash)
+}
+
+// userResponse converts user to its public view with the resolved avatar_url
+func userResponse(ctx context.Context, avatars AvatarService, user *models.User) models.UserResponse {
+	response := user.ToResponse()
+	response.AvatarURL = avatars.Resolve(ctx, user)
+	return response
+}
+
+// authorResponse converts user for embedding in posts and leaderboards with their resolved avatar
+func authorResponse(ctx context.Context, avatars AvatarService, user *models.User) models.AuthorResponse {
+	author := user.ToAuthor()
+	author.AvatarURL = avatars.Resolve(ctx, user)
+	return author
+}
+
+// hasGravatar asks Gravatar whether the hash has an image; answers are cached in Redis
+func (s *avatarService) hasGravatar(ctx context.Context, hash string) bool {
+	cacheKey := "avatar:gravatar:" + hash
+	if val, err := s.redis.Get(ctx, cacheKey).Result(); err == nil {
+		return val == "1"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.gravatarURL(hash, "404"), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Gravatar lookup failed", "error", err)
+		return false
+	}
+	resp.Body.Close()
+
+	exists := resp.StatusCode == http.StatusOK
+	if exists || resp.StatusCode == http.StatusNotFound {
+		value := "0"
+		if exists {
+			value = "1"
+		}
+		s.redis.Set(ctx, cacheKey, value, s.opts.CacheTTL)
+	}
+	return exists
+}
+
+func (s *avatarService) gravatarURL(hash, fallback string) string {
+	return fmt.Sprintf("%s%s?s=%d&d=%s", gravatarBaseURL, hash, s.opts.Size, fallback)
+}
+
+// gravatarHash is the SHA-256 of the trimmed, lowercased email, as Gravatar expects
+func gravatarHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+	"goapi/pkg/stripe"
+	"goapi/pkg/utctime"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type BillingService interface {
+	GetPlans(ctx context.Context) ([]models.Plan, error)
+	EnsureDefaultPlans(ctx context.Context, proPriceID string) error
+	RateLimits(ctx context.Context) (map[string]int, error)
+	CreateCheckout(ctx context.Context, userID uint, planCode string) (*models.CheckoutResponse, error)
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+// BillingOptions holds the Stripe settings the billing service needs
+type BillingOptions struct {
+	WebhookSecret string
+	SuccessURL    string
+	CancelURL     string
+}
+
+type billingService struct {
+	repo     repository.BillingRepository
+	userRepo repository.UserRepository
+	stripe   *stripe.Client
+	redis    *redis.Client
+	cache    *cache.Cache
+	opts     BillingOptions
+}
+
+func NewBillingService(repo repository.BillingRepository, userRepo repository.UserRepository, stripeClient *stripe.Client, redisClient *redis.Client, cacheStore *cache.Cache, opts BillingOptions) BillingService {
+	return &billingService{
+		repo:     repo,
+		userRepo: userRepo,
+		stripe:   stripeClient,
+		redis:    redisClient,
+		cache:    cacheStore,
+		opts:     opts,
+	}
+}
+
+func (s *billingService) GetPlans(ctx context.Context) ([]models.Plan, error) {
+	return s.repo.GetPlans(ctx)
+}
+
+// EnsureDefaultPlans seeds the free and pro plans; existing limits are left untouched
+func (s *billingService) EnsureDefaultPlans(ctx context.Context, proPriceID string) error {
+	defaults := []models.Plan{
+		{Code: models.PlanFree, Name: "Free", RequestsPerMinute: 100, MaxPostsPerDay: 10},
+		{Code: models.PlanPro, Name: "Pro", StripePriceID: proPriceID, RequestsPerMinute: 1000, MaxPostsPerDay: 0},
+	}
+
+	for i := range defaults {
+		if err := s.repo.UpsertPlan(ctx, &defaults[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RateLimits returns the requests-per-minute limit of every plan, keyed by plan code
+func (s *billingService) RateLimits(ctx context.Context) (map[string]int, error) {
+	plans, err := s.repo.GetPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]int, len(plans))
+	for _, plan := range plans {
+		limits[plan.Code] = plan.RequestsPerMinute
+	}
+	return limits, nil
+}
+
+func (s *billingService) CreateCheckout(ctx context.Context, userID uint, planCode string) (*models.CheckoutResponse, error) {
+	plan, err := s.repo.GetPlanByCode(ctx, planCode)
+	if err != nil {
+		return nil, err
+	}
+	if plan.StripePriceID == "" {
+		return nil, errors.New("plan is not purchasable")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := stripe.CheckoutSessionParams{
+		PriceID:           plan.StripePriceID,
+		CustomerEmail:     user.Email,
+		ClientReferenceID: strconv.FormatUint(uint64(user.ID), 10),
+		SuccessURL:        s.opts.SuccessURL,
+		CancelURL:         s.opts.CancelURL,
+		Metadata:          map[string]string{"plan": plan.Code},
+	}
+
+	// Reuse the Stripe customer from a previous subscription if any
+	if sub, err := s.repo.GetSubscriptionByUserID(ctx, userID); err == nil {
+		params.CustomerID = sub.StripeCustomerID
+	}
+
+	session, err := s.stripe.CreateCheckoutSession(ctx, params)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create checkout session", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return &models.CheckoutResponse{SessionID: session.ID, URL: session.URL}, nil
+}
+
+// HandleWebhook verifies and applies a Stripe event. Events are processed at most once.
+func (s *billingService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := stripe.ConstructEvent(payload, signature, s.opts.WebhookSecret, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	// Stripe retries deliveries, so skip events we've already processed
+	eventKey := fmt.Sprintf("stripe:event:%s", event.ID)
+	fresh, err := s.redis.SetNX(ctx, eventKey, 1, 72*time.Hour).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return nil
+	}
+
+	if err := s.applyEvent(ctx, event); err != nil {
+		// Allow Stripe's retry to reprocess the event
+		s.redis.Del(ctx, eventKey)
+		logger.FromContext(ctx).Error("Failed to process Stripe event", "event_id", event.ID, "type", event.Type, "error", err)
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Stripe event processed", "event_id", event.ID, "type", event.Type)
+	return nil
+}
+
+func (s *billingService) applyEvent(ctx context.Context, event *stripe.Event) error {
+	switch event.Type {
+	case "checkout.session.completed":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return err
+		}
+		return s.handleCheckoutCompleted(ctx, &session)
+
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var stripeSub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Object, &stripeSub); err != nil {
+			return err
+		}
+		if event.Type == "customer.subscription.deleted" {
+			stripeSub.Status = "canceled"
+		}
+		return s.handleSubscriptionChange(ctx, &stripeSub)
+
+	case "invoice.paid", "invoice.payment_failed":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
+			return err
+		}
+		status := "active"
+		if event.Type == "invoice.payment_failed" {
+			status = "past_due"
+		}
+		return s.handleInvoice(ctx, &invoice, status)
+	}
+
+	// Unhandled event types are acknowledged and ignored
+	return nil
+}
+
+func (s *billingService) handleCheckoutCompleted(ctx context.Context, session *stripe.CheckoutSession) error {
+	userID, err := strconv.ParseUint(session.ClientReferenceID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid client_reference_id %q", session.ClientReferenceID)
+	}
+
+	return s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		sub, err := s.repo.GetSubscriptionByStripeID(txCtx, session.Subscription, repository.LockForUpdate())
+		if err != nil {
+			sub = &models.Subscription{StripeSubscriptionID: session.Subscription}
+		}
+
+		sub.UserID = uint(userID)
+		sub.StripeCustomerID = session.Customer
+		sub.PlanCode = session.Metadata["plan"]
+		sub.Status = "active"
+
+		if err := s.repo.SaveSubscription(txCtx, sub); err != nil {
+			return err
+		}
+		return s.syncUserPlan(txCtx, sub)
+	})
+}
+
+func (s *billingService) handleSubscriptionChange(ctx context.Context, stripeSub *stripe.Subscription) error {
+	return s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		sub, err := s.findSubscription(txCtx, stripeSub.ID, stripeSub.Customer)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Stripe subscription without local record", "subscription", stripeSub.ID)
+			return nil
+		}
+
+		sub.StripeSubscriptionID = stripeSub.ID
+		sub.Status = stripeSub.Status
+		sub.CurrentPeriodEnd = utctime.From(time.Unix(stripeSub.CurrentPeriodEnd, 0))
+		if plan, err := s.repo.GetPlanByStripePriceID(txCtx, stripeSub.PriceID()); err == nil {
+			sub.PlanCode = plan.Code
+		}
+
+		if err := s.repo.SaveSubscription(txCtx, sub); err != nil {
+			return err
+		}
+		return s.syncUserPlan(txCtx, sub)
+	})
+}
+
+func (s *billingService) handleInvoice(ctx context.Context, invoice *stripe.Invoice, status string) error {
+	if invoice.Subscription == "" {
+		return nil
+	}
+
+	return s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		sub, err := s.findSubscription(txCtx, invoice.Subscription, invoice.Customer)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Stripe invoice without local subscription", "subscription", invoice.Subscription)
+			return nil
+		}
+
+		sub.Status = status
+		if err := s.repo.SaveSubscription(txCtx, sub); err != nil {
+			return err
+		}
+		return s.syncUserPlan(txCtx, sub)
+	})
+}
+
+func (s *billingService) findSubscription(ctx context.Context, stripeSubscriptionID, customerID string) (*models.Subscription, error) {
+	if sub, err := s.repo.GetSubscriptionByStripeID(ctx, stripeSubscriptionID, repository.LockForUpdate()); err == nil {
+		return sub, nil
+	}
+	return s.repo.GetSubscriptionByCustomerID(ctx, customerID, repository.LockForUpdate())
+}
+
+// syncUserPlan sets the user's plan from the subscription state and invalidates cached user data
+func (s *billingService) syncUserPlan(ctx context.Context, sub *models.Subscription) error {
+	plan := models.PlanFree
+	if sub.IsActive() && sub.PlanCode != "" {
+		plan = sub.PlanCode
+	}
+
+	user, err := s.userRepo.GetByID(ctx, sub.UserID, repository.LockForUpdate())
+	if err != nil {
+		return err
+	}
+	if user.Plan == plan {
+		return nil
+	}
+
+	user.Plan = plan
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("User plan changed", "user_id", user.ID, "plan", plan)
+	return userChanged(ctx, s.cache, user.ID)
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"goapi/internal/models"
+	"goapi/pkg/cache"
+	"goapi/pkg/utils"
+)
+
+// userChanged drops everything cached from a user: the user, their auth state and the
+// post lists showing them as an author. It also clears a not-found tombstone for the ID
+// and the request's dataloader entry, so later loads in the request see the change.
+func userChanged(ctx context.Context, c *cache.Cache, id uint) error {
+	utils.ClearUser(ctx, id)
+	return c.Invalidate(ctx, cache.Invalidation{
+		Keys: []string{fmt.Sprintf("user:%d", id), authStateCacheKey(id)},
+		Tags: []string{userTag(id)},
+	})
+}
+
+// postChanged drops everything cached from a post: the post, the lists showing it and
+// the lists whose contents or totals it changes. It also clears a not-found tombstone
+// and the author's post count in the request's dataloader.
+func postChanged(ctx context.Context, c *cache.Cache, id, userID uint) error {
+	utils.ClearAuthorStats(ctx, userID)
+	return c.Invalidate(ctx, cache.Invalidation{
+		Keys: []string{fmt.Sprintf("post:%d", id)},
+		Tags: []string{postTag(id), allPostsTag, userPostsTag(userID)},
+	})
+}
+
+// postsChanged drops the cached posts in ids after a bulk change, plus the lists of
+// the authors whose posts were removed or gained
+func postsChanged(ctx context.Context, c *cache.Cache, ids []uint, userIDs ...uint) error {
+	inv := cache.Invalidation{Tags: []string{allPostsTag}}
+	for _, id := range ids {
+		inv.Keys = append(inv.Keys, fmt.Sprintf("post:%d", id))
+		inv.Tags = append(inv.Tags, postTag(id))
+	}
+	for _, userID := range userIDs {
+		utils.ClearAuthorStats(ctx, userID)
+		inv.Tags = append(inv.Tags, userPostsTag(userID))
+	}
+	return c.Invalidate(ctx, inv)
+}
+
+// Cache tags group cached lists by the entities they show (see cache.SetTagged).
+// Invalidating a tag drops every list tagged with it.
+const allPostsTag = "posts"
+
+func postTag(id uint) string {
+	return fmt.Sprintf("post:%d", id)
+}
+
+func userTag(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func userPostsTag(userID uint) string {
+	return fmt.Sprintf("user:%d:posts", userID)
+}
+
+// postListTags tags a cached post list with its own list tag plus every post and
+// author on it, so creating, deleting or editing any of them invalidates the list
+func postListTags(listTag string, posts []models.PostListItem) []string {
+	tags := []string{listTag}
+	authors := make(map[uint]bool)
+	for _, post := range posts {
+		tags = append(tags, postTag(post.ID))
+		if !authors[post.UserID] {
+			authors[post.UserID] = true
+			tags = append(tags, userTag(post.UserID))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+)
+
+// ReportCacheUsage returns a job that logs the memory held by each cache namespace as a
+// "Cache memory" metric line (namespace, keys, bytes, current) for log-based dashboards.
+// It warns when the current namespace exceeds budget bytes; 0 disables the warning.
+func ReportCacheUsage(store *cache.Cache, budget int64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		usage, err := store.Usage(ctx)
+		if err != nil {
+			return err
+		}
+
+		log := logger.FromContext(ctx)
+		for _, u := range usage {
+			log.Info("Cache memory", "namespace", u.Namespace, "keys", u.Keys, "bytes", u.Bytes, "current", u.Current)
+			if u.Current && budget > 0 && u.Bytes > budget {
+				log.Warn("Cache memory over budget", "namespace", u.Namespace, "bytes", u.Bytes, "budget", budget)
+			}
+		}
+		return nil
+	}
+}
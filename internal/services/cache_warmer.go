@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+)
+
+// CacheWarmer preloads hot cache entries after a deploy moved to a fresh cache namespace,
+// so the first requests do not all miss at once
+type CacheWarmer interface {
+	// Start warms the cache in the background; only the first call does anything
+	Start(ctx context.Context)
+	// Warmed reports whether warm-up has finished, failed or timed out, or is disabled.
+	// The readiness probe waits for it.
+	Warmed() bool
+}
+
+// CacheWarmerOptions configures the startup warm-up
+type CacheWarmerOptions struct {
+	Enabled bool
+	// Timeout bounds the warm-up; the instance reports ready when it expires regardless
+	Timeout time.Duration
+	// Users is how many recently active users get their profile and auth state cached
+	Users int
+}
+
+type cacheWarmer struct {
+	posts       PostService
+	users       UserService
+	leaderboard LeaderboardService
+	userRepo    repository.UserRepository
+	// withLoaders stores the dataloaders post lists need to embed authors
+	withLoaders func(ctx context.Context) context.Context
+	opts        CacheWarmerOptions
+
+	once sync.Once
+	done atomic.Bool
+}
+
+func NewCacheWarmer(posts PostService, users UserService, leaderboard LeaderboardService, userRepo repository.UserRepository, withLoaders func(ctx context.Context) context.Context, opts CacheWarmerOptions) CacheWarmer {
+	w := &cacheWarmer{
+		posts:       posts,
+		users:       users,
+		leaderboard: leaderboard,
+		userRepo:    userRepo,
+		withLoaders: withLoaders,
+		opts:        opts,
+	}
+	w.done.Store(!opts.Enabled)
+	return w
+}
+
+func (w *cacheWarmer) Start(ctx context.Context) {
+	if !w.opts.Enabled {
+		return
+	}
+	w.once.Do(func() {
+		go func() {
+			defer w.done.Store(true)
+			ctx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+			defer cancel()
+			w.warm(ctx)
+		}()
+	})
+}
+
+func (w *cacheWarmer) Warmed() bool {
+	return w.done.Load()
+}
+
+// warm fills the entries every client hits first. Steps run concurrently and each
+// failure is only logged: a cold entry is filled by the first request as usual.
+func (w *cacheWarmer) warm(ctx context.Context) {
+	start := time.Now()
+	log := logger.FromContext(ctx)
+
+	steps := map[string]func(ctx context.Context) error{
+		// The first page as requested without parameters (GET /posts)
+		"posts": func(ctx context.Context) error {
+			_, _, err := w.posts.GetAll(w.withLoaders(ctx), models.PostListRequest{
+				PageRequest: models.PageRequest{Page: 1, Limit: 20, Mode: models.PageModeCount},
+				Sort:        models.PostSortNewest,
+			})
+			return err
+		},
+		// The default board size of GET /leaderboard/authors
+		"leaderboard": func(ctx context.Context) error {
+			_, err := w.leaderboard.TopAuthors(ctx, 10)
+			return err
+		},
+		// Auth state is read on every authenticated request
+		"users": func(ctx context.Context) error {
+			if w.opts.Users == 0 {
+				return nil
+			}
+			users, err := w.userRepo.GetRecentlyActive(ctx, w.opts.Users)
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				if _, err := w.users.GetAuthState(ctx, user.ID); err != nil {
+					return err
+				}
+				if _, err := w.users.GetByID(ctx, user.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	for name, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := step(ctx); err != nil {
+				log.Warn("Cache warm-up step failed", "step", name, "error", err)
+			}
+		}()
+	}
+
+	// A step stuck on a call that ignores ctx must not hold readiness past the timeout
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+
+	log.Info("Cache warmed", "duration", time.Since(start).String(), "timed_out", ctx.Err() != nil)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+)
+
+var errWarmup = errors.New("backend down")
+
+// Only the methods the warmer calls are implemented; the embedded nil interfaces panic
+// if anything else is reached.
+type failingPosts struct{ PostService }
+
+func (failingPosts) GetAll(ctx context.Context, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error) {
+	return nil, models.PageInfo{}, errWarmup
+}
+
+type failingLeaderboard struct{ LeaderboardService }
+
+func (failingLeaderboard) TopAuthors(ctx context.Context, limit int) (*models.Leaderboard, error) {
+	return nil, errWarmup
+}
+
+type failingUsers struct{ repository.UserRepository }
+
+func (failingUsers) GetRecentlyActive(ctx context.Context, limit int) ([]models.User, error) {
+	return nil, errWarmup
+}
+
+// stuckPosts blocks until released, ignoring ctx like a hung driver call would
+type stuckPosts struct {
+	PostService
+	release chan struct{}
+}
+
+func (p stuckPosts) GetAll(ctx context.Context, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error) {
+	<-p.release
+	return nil, models.PageInfo{}, nil
+}
+
+// quietContext carries a discarding logger, so warm-up failures stay out of test output
+func quietContext() context.Context {
+	return logger.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func noLoaders(ctx context.Context) context.Context { return ctx }
+
+func waitWarmed(t *testing.T, w CacheWarmer, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for !w.Warmed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("warm-up not finished after %s", within)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheWarmerDisabledIsWarmed(t *testing.T) {
+	w := NewCacheWarmer(nil, nil, nil, nil, noLoaders, CacheWarmerOptions{})
+	w.Start(quietContext())
+	if !w.Warmed() {
+		t.Fatal("disabled warmer must report warmed immediately")
+	}
+}
+
+func TestCacheWarmerFailuresDoNotBlockBoot(t *testing.T) {
+	w := NewCacheWarmer(failingPosts{}, nil, failingLeaderboard{}, failingUsers{}, noLoaders, CacheWarmerOptions{
+		Enabled: true,
+		Timeout: time.Minute,
+		Users:   10,
+	})
+
+	start := time.Now()
+	w.Start(quietContext())
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Start blocked for %s", elapsed)
+	}
+	waitWarmed(t, w, time.Second)
+}
+
+func TestCacheWarmerTimeoutReleasesReadiness(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	w := NewCacheWarmer(stuckPosts{release: release}, nil, failingLeaderboard{}, failingUsers{}, noLoaders, CacheWarmerOptions{
+		Enabled: true,
+		Timeout: 50 * time.Millisecond,
+		Users:   10,
+	})
+
+	w.Start(quietContext())
+	if w.Warmed() {
+		t.Fatal("warmer reported warmed while a step is still running")
+	}
+	waitWarmed(t, w, time.Second)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+)
+
+// ErrCategoryNotFound is returned when a category does not exist
+var ErrCategoryNotFound = repository.ErrCategoryNotFound
+
+// ErrCategoryExists is returned when another category already has the name, ignoring case
+var ErrCategoryExists = errors.New("a category with this name already exists")
+
+// CategoryService manages post categories. Reads are not cached: there are few
+// categories, and posts carry only their category ID, so renaming one changes no post.
+type CategoryService interface {
+	// List returns every category ordered by name
+	List(ctx context.Context) ([]models.Category, error)
+	GetByID(ctx context.Context, id uint) (*models.Category, error)
+	Create(ctx context.Context, req *models.CategoryRequest) (*models.Category, error)
+	Update(ctx context.Context, id uint, req *models.CategoryRequest) (*models.Category, error)
+	// Delete removes the category; its posts are kept without a category
+	Delete(ctx context.Context, id uint) error
+}
+
+type categoryService struct {
+	repo  repository.CategoryRepository
+	posts repository.PostRepository
+	cache *cache.Cache
+}
+
+func NewCategoryService(repo repository.CategoryRepository, posts repository.PostRepository, cacheStore *cache.Cache) CategoryService {
+	return &categoryService{repo: repo, posts: posts, cache: cacheStore}
+}
+
+func (s *categoryService) List(ctx context.Context) ([]models.Category, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *categoryService) GetByID(ctx context.Context, id uint) (*models.Category, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *categoryService) Create(ctx context.Context, req *models.CategoryRequest) (*models.Category, error) {
+	req.Normalize()
+	category := &models.Category{Name: req.Name, Description: req.Description}
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.checkName(txCtx, 0, req.Name); err != nil {
+			return err
+		}
+		return s.repo.Create(txCtx, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Category created", "category_id", category.ID, "name", category.Name)
+	return category, nil
+}
+
+func (s *categoryService) Update(ctx context.Context, id uint, req *models.CategoryRequest) (*models.Category, error) {
+	req.Normalize()
+	var category *models.Category
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		if category, err = s.repo.GetByID(txCtx, id); err != nil {
+			return err
+		}
+		if err := s.checkName(txCtx, id, req.Name); err != nil {
+			return err
+		}
+		category.Name, category.Description = req.Name, req.Description
+		return s.repo.Update(txCtx, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Category updated", "category_id", id)
+	return category, nil
+}
+
+// checkName returns ErrCategoryExists when a category other than id is named name. The
+// unique index still rejects a concurrent create that passes the check.
+func (s *categoryService) checkName(ctx context.Context, id uint, name string) error {
+	existing, err := s.repo.GetByName(ctx, name)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return ErrCategoryExists
+	}
+	return nil
+}
+
+// Delete takes the posts out of the category in the same transaction, then drops the
+// cached posts that changed
+func (s *categoryService) Delete(ctx context.Context, id uint) error {
+	var postIDs []uint
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		if postIDs, err = s.posts.ClearCategory(txCtx, id); err != nil {
+			return err
+		}
+		return s.repo.Delete(txCtx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Category deleted", "category_id", id, "posts", len(postIDs))
+	return postsChanged(ctx, s.cache, postIDs)
+}
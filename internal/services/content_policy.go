@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// HTML policies for user-submitted text
+const (
+	HTMLStrip = "strip" // remove tags and keep the text
+	HTMLDeny  = "deny"  // reject input containing tags
+)
+
+var (
+	// scriptOrStyle drops executable/styling blocks together with their bodies
+	scriptOrStyle = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(script|style)\s*>`)
+	htmlComment   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlTag       = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// ContentPolicy bounds and sanitizes post input
+type ContentPolicy struct {
+	MinTitleLength   int
+	MaxTitleLength   int
+	MaxContentLength int
+	HTML             string
+	// ExcerptWords is how many words of the content a post excerpt keeps
+	ExcerptWords int
+}
+
+// FieldError describes one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when input breaks the content policy
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) ErrorCode() string { return "validation_failed" }
+
+// Apply sanitizes title and content in place and checks their lengths.
+// Lengths are counted in characters after sanitizing.
+func (p ContentPolicy) Apply(title, content *string) error {
+	return p.apply(title, content, p.MinTitleLength, 1)
+}
+
+// ApplyDraft is Apply for work in progress: only maximum lengths are checked
+func (p ContentPolicy) ApplyDraft(title, content *string) error {
+	return p.apply(title, content, 0, 0)
+}
+
+func (p ContentPolicy) apply(title, content *string, minTitle, minContent int) error {
+	var fields []FieldError
+
+	check := func(field string, value *string, min, max int) {
+		if p.HTML == HTMLDeny && containsHTML(*value) {
+			fields = append(fields, FieldError{Field: field, Message: "must not contain HTML"})
+			return
+		}
+		*value = strings.TrimSpace(stripHTML(*value))
+
+		length := utf8.RuneCountInString(*value)
+		switch {
+		case length < min:
+			fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf("must be at least %d characters", min)})
+		case max > 0 && length > max:
+			fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", max)})
+		}
+	}
+
+	check("title", title, minTitle, p.MaxTitleLength)
+	check("content", content, minContent, p.MaxContentLength)
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Excerpt returns the first ExcerptWords words of content without markup, ending in
+// "…" when words were cut
+func (p ContentPolicy) Excerpt(content string) string {
+	words := strings.Fields(stripHTML(content))
+	if len(words) <= p.ExcerptWords {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:p.ExcerptWords], " ") + "…"
+}
+
+func containsHTML(s string) bool {
+	return htmlTag.MatchString(s) || htmlComment.MatchString(s)
+}
+
+// stripHTML removes markup, repeating until nothing changes so removals cannot
+// splice a new tag together ("<<b>script>"). Entities are left escaped.
+func stripHTML(s string) string {
+	for {
+		stripped := scriptOrStyle.ReplaceAllString(s, "")
+		stripped = htmlComment.ReplaceAllString(stripped, "")
+		stripped = htmlTag.ReplaceAllString(stripped, "")
+		if stripped == s {
+			return s
+		}
+		s = stripped
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Actions taken on a near-duplicate post
+const (
+	DuplicateReject = "reject"
+	DuplicateWarn   = "warn"
+)
+
+// DuplicatePolicy controls near-duplicate detection of posts by the same user
+type DuplicatePolicy struct {
+	Window time.Duration
+	Action string
+}
+
+// DuplicatePostError is returned when a user reposts the same content within the window
+type DuplicatePostError struct {
+	Window string `json:"window"`
+}
+
+func (e *DuplicatePostError) Error() string {
+	return fmt.Sprintf("an identical post was created in the last %s", e.Window)
+}
+
+func (e *DuplicatePostError) ErrorCode() string { return "duplicate_post" }
+
+// contentHash hashes title and content after lowercasing, dropping punctuation and
+// collapsing whitespace, so trivially edited reposts hash the same
+func contentHash(title, content string) string {
+	sum := sha256.Sum256([]byte(normalizeText(title) + "\n" + normalizeText(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeText(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// isDuplicate checks the user's recent-hash set in Redis, falling back to the
+// indexed content_hash column when Redis is unavailable
+func (s *postService) isDuplicate(ctx context.Context, userID uint, hash string) (bool, error) {
+	since := time.Now().Add(-s.duplicates.Window)
+
+	score, err := s.redis.ZScore(ctx, recentHashesKey(userID), hash).Result()
+	if err == nil {
+		return int64(score) >= since.Unix(), nil
+	}
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+
+	logger.FromContext(ctx).Warn("Recent post hashes unavailable, checking database", "error", err)
+	return s.repo.ExistsByHashSince(ctx, userID, hash, since)
+}
+
+// rememberHash records the hash and trims entries older than the window
+func (s *postService) rememberHash(ctx context.Context, userID uint, hash string) {
+	key := recentHashesKey(userID)
+	now := time.Now()
+
+	pipe := s.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Unix()), Member: hash})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(now.Add(-s.duplicates.Window).Unix(), 10))
+	pipe.Expire(ctx, key, s.duplicates.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx).Warn("Failed to record post hash", "user_id", userID, "error", err)
+	}
+}
+
+func recentHashesKey(userID uint) string {
+	return fmt.Sprintf("post:hashes:%d", userID)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"goapi/internal/repository"
+	"goapi/internal/templates"
+	"goapi/pkg/logger"
+	"goapi/pkg/mailer"
+	"goapi/pkg/utctime"
+)
+
+// linkTokenRepository is what emailing a single-use link needs from its token store,
+// such as PasswordResetRepository or MagicLinkRepository
+type linkTokenRepository[T any] interface {
+	Create(ctx context.Context, token *T) error
+	InvalidateUser(ctx context.Context, userID uint, now time.Time) error
+}
+
+// emailLink describes one kind of single-use link emailed to users
+type emailLink[T any] struct {
+	// name starts the log messages, as in "Password reset link sent"
+	name     string
+	tokens   linkTokenRepository[T]
+	newToken func(userID uint, hash string, expiresAt utctime.Time) *T
+	template string
+	// url receives the token as ?token=
+	url string
+	ttl time.Duration
+}
+
+// sendEmailLink emails link to the account with email, in language lang. Unknown and
+// inactive accounts are skipped silently so callers cannot probe for accounts. The new
+// token supersedes any earlier one of the same kind still in the user's inbox.
+func sendEmailLink[T any](ctx context.Context, users repository.UserRepository, emails *templates.Renderer, mail mailer.Sender, link emailLink[T], email, lang string) error {
+	user, err := users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		logger.FromContext(ctx).Info(link.name + " requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.Active {
+		logger.FromContext(ctx).Info(link.name+" requested for inactive user", "user_id", user.ID)
+		return nil
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = users.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := link.tokens.InvalidateUser(txCtx, user.ID, now); err != nil {
+			return err
+		}
+		return link.tokens.Create(txCtx, link.newToken(user.ID, hashToken(token), utctime.From(now.Add(link.ttl))))
+	})
+	if err != nil {
+		return err
+	}
+
+	target, err := url.Parse(link.url)
+	if err != nil {
+		return err
+	}
+	query := target.Query()
+	query.Set("token", token)
+	target.RawQuery = query.Encode()
+
+	message, err := emails.Render(link.template, lang, templates.LinkData{
+		Name:      user.FullName,
+		Link:      target.String(),
+		ExpiresIn: link.ttl.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := mail.Send(ctx, mailer.Message{To: user.Email, Subject: message.Subject, HTML: message.HTML}); err != nil {
+		return fmt.Errorf("send %s email: %w", link.template, err)
+	}
+
+	logger.FromContext(ctx).Info(link.name+" link sent", "user_id", user.ID)
+	return nil
+}
+
+// newRandomToken returns 256 random bits, URL-safe so the token can go in a link as is
+func newRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is the stored form of a token. Tokens are random, so an unsalted fast
+// hash is enough: there is nothing to brute-force.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
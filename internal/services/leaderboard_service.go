@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+	"goapi/pkg/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PostEvents is notified after a post change is stored. Listeners cannot fail the
+// change; they log their own errors.
+type PostEvents interface {
+	PostCreated(ctx context.Context, post *models.Post)
+	PostDeleted(ctx context.Context, post *models.Post)
+}
+
+// LeaderboardService ranks authors by posts published this week. Counts live in one
+// Redis sorted set per ISO week, updated from post events; a week's set expires a day
+// after the week ends, so the board resets every Monday 00:00 UTC.
+type LeaderboardService interface {
+	PostEvents
+	TopAuthors(ctx context.Context, limit int) (*models.Leaderboard, error)
+}
+
+type leaderboardService struct {
+	redis   *redis.Client
+	cache   *cache.Cache
+	avatars AvatarService
+	// policy caches the rendered board; counts change on every post, so keep it short
+	policy cache.Policy
+}
+
+func NewLeaderboardService(redisClient *redis.Client, cacheStore *cache.Cache, avatars AvatarService, policy cache.Policy) LeaderboardService {
+	return &leaderboardService{redis: redisClient, cache: cacheStore, avatars: avatars, policy: policy}
+}
+
+func (s *leaderboardService) PostCreated(ctx context.Context, post *models.Post) {
+	week, end := leaderboardWeek(post.CreatedAt.Time)
+	key := leaderboardKey(week)
+
+	pipe := s.redis.TxPipeline()
+	pipe.ZIncrBy(ctx, key, 1, strconv.FormatUint(uint64(post.UserID), 10))
+	pipe.ExpireAt(ctx, key, end.Add(24*time.Hour))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx).Warn("Failed to update author leaderboard", "user_id", post.UserID, "error", err)
+	}
+}
+
+// PostDeleted takes back a post created in the current week; older posts were never
+// on the current board
+func (s *leaderboardService) PostDeleted(ctx context.Context, post *models.Post) {
+	week, _ := leaderboardWeek(post.CreatedAt.Time)
+	if current, _ := leaderboardWeek(time.Now()); week != current {
+		return
+	}
+	key := leaderboardKey(week)
+
+	pipe := s.redis.TxPipeline()
+	pipe.ZIncrBy(ctx, key, -1, strconv.FormatUint(uint64(post.UserID), 10))
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "0")
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.FromContext(ctx).Warn("Failed to update author leaderboard", "user_id", post.UserID, "error", err)
+	}
+}
+
+// TopAuthors returns the current week's top limit authors. Deleted authors are left
+// out, so a board can be shorter than limit.
+func (s *leaderboardService) TopAuthors(ctx context.Context, limit int) (*models.Leaderboard, error) {
+	week, _ := leaderboardWeek(time.Now())
+	key := fmt.Sprintf("leaderboard:authors:%s:%d", week, limit)
+	board, err := cache.ReadThrough(ctx, s.cache, key, s.policy, func(ctx context.Context) (models.Leaderboard, error) {
+		return s.render(ctx, week, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+func (s *leaderboardService) render(ctx context.Context, week string, limit int) (models.Leaderboard, error) {
+	board := models.Leaderboard{Week: week, Entries: []models.LeaderboardEntry{}}
+	ranked, err := s.redis.ZRevRangeWithScores(ctx, leaderboardKey(week), 0, int64(limit-1)).Result()
+	if err != nil {
+		return board, err
+	}
+
+	userIDs := make([]uint, 0, len(ranked))
+	for _, z := range ranked {
+		id, err := strconv.ParseUint(z.Member.(string), 10, 64)
+		if err != nil {
+			return board, fmt.Errorf("leaderboard member %q: %w", z.Member, err)
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+	users, errs := utils.LoadUsers(ctx, userIDs)
+
+	rank, previous := 0, -1.0
+	for i, z := range ranked {
+		if errs[i] != nil || users[i] == nil {
+			continue
+		}
+		if z.Score != previous {
+			rank, previous = i+1, z.Score
+		}
+		board.Entries = append(board.Entries, models.LeaderboardEntry{
+			Rank:   rank,
+			Author: authorResponse(ctx, s.avatars, users[i]),
+			Posts:  int64(z.Score),
+		})
+	}
+	return board, nil
+}
+
+func leaderboardKey(week string) string {
+	return "leaderboard:authors:" + week
+}
+
+// leaderboardWeek returns the ISO week of t in UTC ("2026-W42") and when it ends
+func leaderboardWeek(t time.Time) (string, time.Time) {
+	t = t.UTC()
+	year, week := t.ISOWeek()
+	daysToMonday := (8 - int(t.Weekday())) % 7
+	if daysToMonday == 0 {
+		daysToMonday = 7
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day()+daysToMonday, 0, 0, 0, 0, time.UTC)
+	return fmt.Sprintf("%d-W%02d", year, week), end
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/internal/templates"
+	"goapi/pkg/logger"
+	"goapi/pkg/mailer"
+	"goapi/pkg/utctime"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMagicLinkInvalid is returned for login links that are unknown, expired or already used
+var ErrMagicLinkInvalid = repository.ErrMagicLinkInvalid
+
+// ErrMagicLinkThrottled is returned by Throttle once an email has asked for too many links
+var ErrMagicLinkThrottled = errors.New("too many login links requested for this email, try again later")
+
+// MagicLinkService logs users in with a single-use link emailed to them
+type MagicLinkService interface {
+	// Throttle counts a link request for email and returns ErrMagicLinkThrottled past
+	// the limit. Every address is counted alike, so it reveals nothing about accounts.
+	Throttle(ctx context.Context, email string) error
+	// Send emails a login link to the account with email, in language lang. Unknown and
+	// inactive accounts are skipped silently so callers cannot probe for accounts.
+	Send(ctx context.Context, email, lang string) error
+	// Login redeems a link from Send and logs its user in like UserService.Login
+	Login(ctx context.Context, req *models.MagicLinkLoginRequest) (string, *models.UserResponse, error)
+}
+
+// MagicLinkOptions configures login links and how often they can be requested
+type MagicLinkOptions struct {
+	// TTL is how long a link stays valid
+	TTL time.Duration
+	// URL is the page links point to; the token is added as ?token=
+	URL string
+	// MaxPerEmail links can be requested for one email within Window
+	MaxPerEmail int
+	Window      time.Duration
+}
+
+type magicLinkService struct {
+	users  repository.UserRepository
+	tokens repository.MagicLinkRepository
+	login  UserService
+	redis  *redis.Client
+	emails *templates.Renderer
+	mail   mailer.Sender
+	opts   MagicLinkOptions
+}
+
+func NewMagicLinkService(users repository.UserRepository, tokens repository.MagicLinkRepository, login UserService, redisClient *redis.Client, emails *templates.Renderer, mail mailer.Sender, opts MagicLinkOptions) MagicLinkService {
+	return &magicLinkService{
+		users:  users,
+		tokens: tokens,
+		login:  login,
+		redis:  redisClient,
+		emails: emails,
+		mail:   mail,
+		opts:   opts,
+	}
+}
+
+// Throttle keys the counter by a hash of the email, so addresses do not end up in
+// Redis. A Redis failure lets the request through; the auth rate limiter still applies.
+func (s *magicLinkService) Throttle(ctx context.Context, email string) error {
+	key := fmt.Sprintf("magic-link:email:%s", hashToken(models.NormalizeEmail(email)))
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		logger.FromContext(ctx).Warn("Magic link throttle check failed", "error", err)
+		return nil
+	}
+	if count == 1 {
+		s.redis.Expire(ctx, key, s.opts.Window)
+	}
+	if count > int64(s.opts.MaxPerEmail) {
+		return ErrMagicLinkThrottled
+	}
+	return nil
+}
+
+func (s *magicLinkService) Send(ctx context.Context, email, lang string) error {
+	return sendEmailLink(ctx, s.users, s.emails, s.mail, emailLink[models.MagicLinkToken]{
+		name:   "Magic link",
+		tokens: s.tokens,
+		newToken: func(userID uint, hash string, expiresAt utctime.Time) *models.MagicLinkToken {
+			return &models.MagicLinkToken{UserID: userID, TokenHash: hash, ExpiresAt: expiresAt}
+		},
+		template: templates.MagicLink,
+		url:      s.opts.URL,
+		ttl:      s.opts.TTL,
+	}, email, lang)
+}
+
+// Login spends the link before the account checks, so a link that fails them, such as
+// for an account pending deletion without restore, cannot be retried.
+func (s *magicLinkService) Login(ctx context.Context, req *models.MagicLinkLoginRequest) (string, *models.UserResponse, error) {
+	token, err := s.tokens.Consume(ctx, hashToken(req.Token), time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	user, err := s.users.GetByID(ctx, token.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return "", nil, ErrMagicLinkInvalid
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s.login.LoginVerified(ctx, user, &models.LoginRequest{
+		Device:    req.Device,
+		Restore:   req.Restore,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+	})
+}
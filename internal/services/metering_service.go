@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+	"goapi/pkg/utctime"
+)
+
+type MeteringService interface {
+	Record(ctx context.Context, userID uint, metric string, quantity int64)
+	Start(ctx context.Context)
+	Rollup(ctx context.Context) error
+	GetUsage(ctx context.Context, userID uint, period string) ([]models.UsageRollup, error)
+	GetReport(ctx context.Context, period string, periodStart time.Time) ([]models.UsageReportRow, error)
+}
+
+const (
+	meteringBufferSize    = 10000
+	meteringBatchSize     = 500
+	meteringFlushInterval = 5 * time.Second
+	// meteringFlushTimeout bounds one batch insert, including the final flush at shutdown
+	meteringFlushTimeout = 10 * time.Second
+)
+
+type meteringService struct {
+	repo   repository.UsageRepository
+	events chan models.UsageEvent
+}
+
+func NewMeteringService(repo repository.UsageRepository) MeteringService {
+	return &meteringService{
+		repo:   repo,
+		events: make(chan models.UsageEvent, meteringBufferSize),
+	}
+}
+
+// Record queues a billable event; events are written to Postgres in batches by the flusher.
+// It never blocks the request path: when the buffer is full the event is dropped and logged.
+func (s *meteringService) Record(ctx context.Context, userID uint, metric string, quantity int64) {
+	event := models.UsageEvent{UserID: userID, Metric: metric, Quantity: quantity, CreatedAt: utctime.Now()}
+
+	select {
+	case s.events <- event:
+	default:
+		logger.FromContext(ctx).Warn("Usage buffer full, dropping event", "user_id", userID, "metric", metric)
+	}
+}
+
+// Start runs the background flusher until ctx is canceled
+func (s *meteringService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(meteringFlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]models.UsageEvent, 0, meteringBatchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			// Detached from ctx so the flush on cancellation still runs
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), meteringFlushTimeout)
+			defer cancel()
+			if err := s.repo.CreateEvents(flushCtx, batch); err != nil {
+				logger.Error("Failed to flush usage events", "count", len(batch), "error", err)
+			}
+			batch = batch[:0]
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				flush()
+				return
+			case event := <-s.events:
+				batch = append(batch, event)
+				if len(batch) >= meteringBatchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+}
+
+// Rollup refreshes daily rollups for yesterday and today, then the monthly rollups they belong to
+func (s *meteringService) Rollup(ctx context.Context) error {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	for _, day := range []time.Time{yesterday, today} {
+		if err := s.repo.RollupDay(ctx, day); err != nil {
+			return err
+		}
+	}
+
+	months := []time.Time{monthStart(today)}
+	if monthStart(yesterday) != months[0] {
+		months = append(months, monthStart(yesterday))
+	}
+	for _, month := range months {
+		if err := s.repo.RollupMonth(ctx, month); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUsage returns the user's rollups: the last 31 days or the last 12 months
+func (s *meteringService) GetUsage(ctx context.Context, userID uint, period string) ([]models.UsageRollup, error) {
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -31)
+	if period == models.PeriodMonth {
+		since = monthStart(now).AddDate(0, -11, 0)
+	}
+	return s.repo.GetRollups(ctx, userID, period, since)
+}
+
+func (s *meteringService) GetReport(ctx context.Context, period string, periodStart time.Time) ([]models.UsageReportRow, error) {
+	return s.repo.GetReport(ctx, period, periodStart)
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrPasskeyNotFound is returned when deleting a passkey the user does not have
+var ErrPasskeyNotFound = repository.ErrPasskeyNotFound
+
+// ErrPasskeyLimit is returned when registering a passkey beyond models.MaxPasskeys
+var ErrPasskeyLimit = fmt.Errorf("at most %d passkeys can be registered", models.MaxPasskeys)
+
+// ErrPasskeyInvalid is returned when a registration or login cannot be verified: its
+// challenge expired or was already used, or the authenticator's response does not check out
+var ErrPasskeyInvalid = errors.New("passkey could not be verified")
+
+// PasskeyService registers WebAuthn passkeys and logs users in with them, alongside
+// email and password. Each ceremony is a begin call, whose options the browser passes to
+// navigator.credentials, and a finish call with the credential it returns. The challenge
+// in between is kept in Redis and can be finished once.
+type PasskeyService interface {
+	// BeginRegistration returns the options for navigator.credentials.create. A user has
+	// one registration pending at a time; beginning another replaces it.
+	BeginRegistration(ctx context.Context, userID uint) (*protocol.CredentialCreation, error)
+	// FinishRegistration verifies the new credential and stores it as a passkey
+	FinishRegistration(ctx context.Context, userID uint, req *models.PasskeyRegistrationRequest) (*models.Passkey, error)
+	List(ctx context.Context, userID uint) ([]models.Passkey, error)
+	Delete(ctx context.Context, userID, id uint) error
+	// BeginLogin returns the options for navigator.credentials.get. No account is named:
+	// the authenticator offers the passkeys it holds for the site.
+	BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, error)
+	// FinishLogin verifies the assertion and logs its owner in like UserService.Login
+	FinishLogin(ctx context.Context, req *models.PasskeyLoginRequest) (string, *models.UserResponse, error)
+}
+
+// PasskeyOptions configures the relying party, as WebAuthn calls the site
+type PasskeyOptions struct {
+	// RPID is the domain passkeys are bound to, without scheme or port
+	RPID string
+	// RPName is the site name authenticators show
+	RPName string
+	// Origins are the frontend origins allowed to run the ceremonies
+	Origins []string
+	// Timeout is how long a ceremony may take between begin and finish
+	Timeout time.Duration
+}
+
+type passkeyService struct {
+	repo     repository.PasskeyRepository
+	users    repository.UserRepository
+	login    UserService
+	redis    *redis.Client
+	webauthn *webauthn.WebAuthn
+	timeout  time.Duration
+}
+
+// NewPasskeyService requires a user-verified, discoverable credential: the passkey alone
+// must identify and authenticate the user, standing in for both email and password.
+func NewPasskeyService(repo repository.PasskeyRepository, users repository.UserRepository, login UserService, redisClient *redis.Client, opts PasskeyOptions) PasskeyService {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: opts.Timeout, TimeoutUVD: opts.Timeout}
+	return &passkeyService{
+		repo:  repo,
+		users: users,
+		login: login,
+		redis: redisClient,
+		// Validated by config.Load; the library checks it again on first use
+		webauthn: &webauthn.WebAuthn{Config: &webauthn.Config{
+			RPID:          opts.RPID,
+			RPDisplayName: opts.RPName,
+			RPOrigins:     opts.Origins,
+			AuthenticatorSelection: protocol.AuthenticatorSelection{
+				ResidentKey:      protocol.ResidentKeyRequirementRequired,
+				UserVerification: protocol.VerificationRequired,
+			},
+			AttestationPreference: protocol.PreferNoAttestation,
+			Timeouts:              webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+		}},
+		timeout: opts.Timeout,
+	}
+}
+
+func (s *passkeyService) BeginRegistration(ctx context.Context, userID uint) (*protocol.CredentialCreation, error) {
+	owner, err := s.owner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(owner.passkeys) >= models.MaxPasskeys {
+		return nil, ErrPasskeyLimit
+	}
+
+	// Authenticators that already hold one of the user's passkeys decline to make another
+	exclude := webauthn.Credentials(owner.WebAuthnCredentials()).CredentialDescriptors()
+	creation, session, err := s.webauthn.BeginRegistration(owner, webauthn.WithExclusions(exclude))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveSession(ctx, passkeyRegistrationKey(userID), session); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+func (s *passkeyService) FinishRegistration(ctx context.Context, userID uint, req *models.PasskeyRegistrationRequest) (*models.Passkey, error) {
+	session, err := s.takeSession(ctx, passkeyRegistrationKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	owner, err := s.owner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(owner.passkeys) >= models.MaxPasskeys {
+		return nil, ErrPasskeyLimit
+	}
+	credential, err := s.webauthn.CreateCredential(owner, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+	passkey := &models.Passkey{
+		UserID:          userID,
+		Name:            req.Name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackedUp:        credential.Flags.BackupState,
+		Transports:      strings.Join(transports, ","),
+	}
+	if err := s.repo.Create(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Passkey registered", "user_id", userID, "passkey_id", passkey.ID)
+	return passkey, nil
+}
+
+func (s *passkeyService) List(ctx context.Context, userID uint) ([]models.Passkey, error) {
+	return s.repo.GetByUserID(ctx, userID)
+}
+
+func (s *passkeyService) Delete(ctx context.Context, userID, id uint) error {
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("Passkey deleted", "user_id", userID, "passkey_id", id)
+	return nil
+}
+
+func (s *passkeyService) BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, error) {
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, err
+	}
+	// No user is known yet, so the session is found again by its challenge, which the
+	// browser signs into the assertion
+	if err := s.saveSession(ctx, passkeyLoginKey(session.Challenge), session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishLogin answers every verification failure with ErrPasskeyInvalid, like a wrong
+// password, so the response tells nothing about which passkeys exist; the cause is logged
+func (s *passkeyService) FinishLogin(ctx context.Context, req *models.PasskeyLoginRequest) (string, *models.UserResponse, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+	session, err := s.takeSession(ctx, passkeyLoginKey(parsed.Response.CollectedClientData.Challenge))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var owner *passkeyUser
+	credential, err := s.webauthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		passkey, err := s.repo.GetByCredentialID(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.users.GetByID(ctx, passkey.UserID)
+		if err != nil {
+			return nil, err
+		}
+		owner = &passkeyUser{user: user, passkeys: []models.Passkey{*passkey}}
+		return owner, nil
+	}, *session, parsed)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Passkey login rejected", "error", err)
+		return "", nil, ErrPasskeyInvalid
+	}
+
+	passkey := owner.passkeys[0]
+	// The counter did not move forward: the passkey may have been copied off its authenticator
+	if credential.Authenticator.CloneWarning {
+		logger.FromContext(ctx).Warn("Passkey login rejected: signature counter went backwards", "user_id", owner.user.ID, "passkey_id", passkey.ID)
+		return "", nil, ErrPasskeyInvalid
+	}
+	if err := s.repo.RecordUse(ctx, passkey.ID, credential.Authenticator.SignCount, credential.Flags.BackupState, time.Now()); err != nil {
+		return "", nil, err
+	}
+
+	return s.login.LoginVerified(ctx, owner.user, &models.LoginRequest{
+		Device:    req.Device,
+		Restore:   req.Restore,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+	})
+}
+
+// owner loads user id with their passkeys
+func (s *passkeyService) owner(ctx context.Context, id uint) (*passkeyUser, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.repo.GetByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &passkeyUser{user: user, passkeys: passkeys}, nil
+}
+
+func (s *passkeyService) saveSession(ctx context.Context, key string, session *webauthn.SessionData) error {
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, key, value, s.timeout).Err()
+}
+
+// takeSession reads and deletes the ceremony stored under key in one command, so a
+// challenge can only be answered once
+func (s *passkeyService) takeSession(ctx context.Context, key string) (*webauthn.SessionData, error) {
+	value, err := s.redis.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrPasskeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(value, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func passkeyRegistrationKey(userID uint) string {
+	return fmt.Sprintf("passkey:register:%d", userID)
+}
+
+func passkeyLoginKey(challenge string) string {
+	return "passkey:login:" + challenge
+}
+
+// passkeyUser adapts a user and their passkeys to webauthn.User. The user handle is the
+// decimal user ID: stable, and it reveals nothing about the person.
+type passkeyUser struct {
+	user     *models.User
+	passkeys []models.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.user.ID), 10))
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if u.user.FullName != "" {
+		return u.user.FullName
+	}
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+	for i, passkey := range u.passkeys {
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(passkey.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackedUp,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		}
+	}
+	return credentials
+}
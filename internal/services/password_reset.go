@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/internal/templates"
+	"goapi/pkg/cache"
+	"goapi/pkg/logger"
+	"goapi/pkg/mailer"
+	"goapi/pkg/password"
+	"goapi/pkg/utctime"
+)
+
+// ErrResetTokenInvalid is returned for reset tokens that are unknown, expired or already used
+var ErrResetTokenInvalid = repository.ErrResetTokenInvalid
+
+// PasswordResetService runs the forgot/reset password flow
+type PasswordResetService interface {
+	// Forgot emails a reset link to the account with email, in language lang. Unknown
+	// and inactive accounts are skipped silently so callers cannot probe for accounts.
+	Forgot(ctx context.Context, email, lang string) error
+	// Reset redeems a token from Forgot, sets the new password and revokes all sessions
+	Reset(ctx context.Context, req *models.ResetPasswordRequest) error
+}
+
+// PasswordResetOptions configures reset links
+type PasswordResetOptions struct {
+	// TTL is how long a link stays valid
+	TTL time.Duration
+	// URL is the frontend page that receives ?token= and posts it to /password/reset
+	URL string
+}
+
+type passwordResetService struct {
+	users     repository.UserRepository
+	tokens    repository.PasswordResetRepository
+	cache     *cache.Cache
+	passwords *password.Hasher
+	emails    *templates.Renderer
+	mail      mailer.Sender
+	opts      PasswordResetOptions
+}
+
+func NewPasswordResetService(users repository.UserRepository, tokens repository.PasswordResetRepository, cacheStore *cache.Cache, passwords *password.Hasher, emails *templates.Renderer, mail mailer.Sender, opts PasswordResetOptions) PasswordResetService {
+	return &passwordResetService{
+		users:     users,
+		tokens:    tokens,
+		cache:     cacheStore,
+		passwords: passwords,
+		emails:    emails,
+		mail:      mail,
+		opts:      opts,
+	}
+}
+
+func (s *passwordResetService) Forgot(ctx context.Context, email, lang string) error {
+	return sendEmailLink(ctx, s.users, s.emails, s.mail, emailLink[models.PasswordResetToken]{
+		name:   "Password reset",
+		tokens: s.tokens,
+		newToken: func(userID uint, hash string, expiresAt utctime.Time) *models.PasswordResetToken {
+			return &models.PasswordResetToken{UserID: userID, TokenHash: hash, ExpiresAt: expiresAt}
+		},
+		template: templates.PasswordReset,
+		url:      s.opts.URL,
+		ttl:      s.opts.TTL,
+	}, email, lang)
+}
+
+func (s *passwordResetService) Reset(ctx context.Context, req *models.ResetPasswordRequest) error {
+	hash, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	var userID uint
+	err = s.users.WithTransaction(ctx, func(txCtx context.Context) error {
+		now := time.Now()
+		token, err := s.tokens.Consume(txCtx, hashToken(req.Token), now)
+		if err != nil {
+			return err
+		}
+		user, err := s.users.GetByID(txCtx, token.UserID, repository.LockForUpdate())
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrResetTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.users.UpdatePassword(txCtx, user.ID, hash); err != nil {
+			return err
+		}
+		if err := s.users.IncrementTokenVersion(txCtx, user.ID); err != nil {
+			return err
+		}
+		userID = user.ID
+		return s.tokens.InvalidateUser(txCtx, user.ID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Password reset", "user_id", userID)
+	return userChanged(ctx, s.cache, userID)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"goapi/internal/models"
+	"goapi/internal/repository"
+	"goapi/pkg/cache"
+)
+
+// ErrForbidden is returned when the caller's role lacks the permission for an action
+var ErrForbidden = errors.New("permission denied")
+
+// defaultPermissions are created at startup with the roles they are granted to
+var defaultPermissions = []struct {
+	models.Permission
+	Roles []string
+}{
+	{models.Permission{Name: models.PermPostsDeleteOwn, Description: "Delete your own posts"}, []string{"user", "admin"}},
+	{models.Permission{Name: models.PermPostsDeleteAny, Description: "Delete any user's posts"}, []string{"admin"}},
+}
+
+// PermissionService evaluates role permissions stored in permissions/role_permissions
+type PermissionService interface {
+	// EnsureDefaults creates the built-in permissions. Their default grants are only added
+	// when a permission is first created, so revoking one in the database sticks.
+	EnsureDefaults(ctx context.Context) error
+	// Permissions returns the names of the permissions granted to role
+	Permissions(ctx context.Context, role string) ([]string, error)
+	// Can reports whether role holds permission
+	Can(ctx context.Context, role, permission string) (bool, error)
+	// Authorize checks action ("posts:delete") on a resource owned by ownerID: "<action>:any"
+	// allows it on every resource, "<action>:own" only when userID is the owner.
+	// It returns ErrForbidden otherwise.
+	Authorize(ctx context.Context, role string, userID, ownerID uint, action string) error
+}
+
+type permissionService struct {
+	repo  repository.PermissionRepository
+	cache *cache.Cache
+	// policy caches each role's permissions; grants only change through the database
+	policy cache.Policy
+}
+
+func NewPermissionService(repo repository.PermissionRepository, cacheStore *cache.Cache, policy cache.Policy) PermissionService {
+	return &permissionService{repo: repo, cache: cacheStore, policy: policy}
+}
+
+func (s *permissionService) EnsureDefaults(ctx context.Context) error {
+	for _, def := range defaultPermissions {
+		permission := def.Permission
+		created, err := s.repo.CreatePermission(ctx, &permission)
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+		for _, role := range def.Roles {
+			if err := s.repo.Grant(ctx, role, permission.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *permissionService) Permissions(ctx context.Context, role string) ([]string, error) {
+	granted, err := cache.ReadThrough(ctx, s.cache, "permissions:"+role, s.policy, func(ctx context.Context) (models.RolePermissions, error) {
+		names, err := s.repo.NamesByRole(ctx, role)
+		return models.RolePermissions{Role: role, Permissions: names}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return granted.Permissions, nil
+}
+
+func (s *permissionService) Can(ctx context.Context, role, permission string) (bool, error) {
+	if role == "" {
+		return false, nil
+	}
+	granted, err := s.Permissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(granted, permission), nil
+}
+
+func (s *permissionService) Authorize(ctx context.Context, role string, userID, ownerID uint, action string) error {
+	if role == "" {
+		return ErrForbidden
+	}
+	granted, err := s.Permissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(granted, action+":any") {
+		return nil
+	}
+	if userID == ownerID && slices.Contains(granted, action+":own") {
+		return nil
+	}
+	return ErrForbidden
+}
@@ -4,108 +4,304 @@ import (
 	"context"
 	"errors"
 
-	"encoding/json"
 	"fmt"
 	"goapi/internal/models"
 	"goapi/internal/repository"
+	"goapi/pkg/cache"
 	"goapi/pkg/logger"
+	"goapi/pkg/utctime"
 	"goapi/pkg/utils"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// streamBatch posts are read from the cursor before their authors are loaded and they
+// are emitted, bounding both memory use and the number of user queries
+const streamBatch = 100
+
+// ErrPostNotFound is returned when a post does not exist
+var ErrPostNotFound = repository.ErrPostNotFound
+
+// ErrInvalidCursor is returned when a list's ?after= names no post
+var ErrInvalidCursor = repository.ErrInvalidCursor
+
+// ErrDraftNotFound is returned when a post has no saved draft
+var ErrDraftNotFound = repository.ErrDraftNotFound
+
+// ErrNotPostAuthor is returned when a user edits a post they did not write
+var ErrNotPostAuthor = errors.New("only the author can edit this post")
+
+// StaleDraftError is returned when a draft save carries a revision no newer than the
+// stored draft, which is kept and returned in Current
+type StaleDraftError struct {
+	Current *models.PostDraft
+}
+
+func (e *StaleDraftError) Error() string {
+	return "a newer revision of this draft is already saved"
+}
+
 type PostService interface {
 	Create(ctx context.Context, req *models.CreatePostRequest, userID uint) (*models.PostResponse, error)
 	GetByID(ctx context.Context, id uint) (*models.PostResponse, error)
-	GetAll(ctx context.Context) ([]models.PostResponse, error)
-	GetByUserID(ctx context.Context, userID uint) ([]models.PostResponse, error)
-	Delete(ctx context.Context, id uint, userID uint) error
+	GetAll(ctx context.Context, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error)
+	GetByUserID(ctx context.Context, userID uint, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error)
+	GetRelated(ctx context.Context, id uint, limit int) ([]models.PostListItem, error)
+	Search(ctx context.Context, query models.PostSearchQuery) ([]models.PostSearchResult, models.PageInfo, error)
+	ListTags(ctx context.Context, limit int) ([]models.TagCount, error)
+	// Delete removes post id if role may delete it: posts:delete:any, or posts:delete:own for the author
+	Delete(ctx context.Context, id uint, userID uint, role string) error
+	Archive(ctx context.Context, id uint, userID uint) (*models.PostResponse, error)
+	Unarchive(ctx context.Context, id uint, userID uint) (*models.PostResponse, error)
+	SaveDraft(ctx context.Context, id uint, userID uint, req *models.SaveDraftRequest) (*models.PostDraft, error)
+	GetDraft(ctx context.Context, id uint, userID uint) (*models.PostDraft, error)
+	TranslatePost(ctx context.Context, id uint, userID uint, locale string, req *models.TranslatePostRequest) (*models.PostTranslation, error)
+	// GetTranslations lists the translations of post id. An archived post's are only
+	// listed for its author or a viewer with a signed link, like the post itself.
+	GetTranslations(ctx context.Context, id uint, viewerID uint, signedLink bool) ([]models.PostTranslation, error)
+	Localize(ctx context.Context, posts []models.PostResponse, acceptLanguage string) error
+	// LocalizeList is Localize for list items, swapping in the translation's excerpt
+	LocalizeList(ctx context.Context, items []models.PostListItem, acceptLanguage string) error
+	Export(ctx context.Context, userID uint, emit func(post models.PostResponse) error) error
+	StreamList(ctx context.Context, userID uint, req models.PostListRequest, emit func(posts []models.PostListItem) error) error
 }
 
 type postService struct {
-	repo  repository.PostRepository
-	redis *redis.Client
+	repo         repository.PostRepository
+	drafts       repository.PostDraftRepository
+	translations repository.PostTranslationRepository
+	categories   repository.CategoryRepository
+	redis        *redis.Client
+	cache        *cache.Cache
+	quotas       QuotaService
+	metering     MeteringService
+	avatars      AvatarService
+	events       PostEvents
+	counter      PostCounter
+	permissions  PermissionService
+	policy       ContentPolicy
+	duplicates   DuplicatePolicy
+	cachePolicy  cache.Policy
+	listPolicy   cache.Policy
 }
 
-func NewPostService(repo repository.PostRepository, redisClient *redis.Client) PostService {
+func NewPostService(repo repository.PostRepository, drafts repository.PostDraftRepository, translations repository.PostTranslationRepository, categories repository.CategoryRepository, redisClient *redis.Client, cacheStore *cache.Cache, quotas QuotaService, metering MeteringService, avatars AvatarService, events PostEvents, counter PostCounter, permissions PermissionService, policy ContentPolicy, duplicates DuplicatePolicy, cachePolicy, listPolicy cache.Policy) PostService {
+	cachePolicy.NotFound = repository.ErrPostNotFound
+	listPolicy.NotFound = repository.ErrPostNotFound // related posts of a missing post
 	return &postService{
-		repo:  repo,
-		redis: redisClient,
+		repo:         repo,
+		drafts:       drafts,
+		translations: translations,
+		categories:   categories,
+		redis:        redisClient,
+		cache:        cacheStore,
+		quotas:       quotas,
+		metering:     metering,
+		avatars:      avatars,
+		events:       events,
+		counter:      counter,
+		permissions:  permissions,
+		policy:       policy,
+		duplicates:   duplicates,
+		cachePolicy:  cachePolicy,
+		listPolicy:   listPolicy,
 	}
 }
 
 func (s *postService) Create(ctx context.Context, req *models.CreatePostRequest, userID uint) (*models.PostResponse, error) {
+	// Sanitize before anything is stored or counted against the quota
+	title, content := req.Title, req.Content
+	if err := s.policy.Apply(&title, &content); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if req.CategoryID != nil {
+		if _, err := s.categories.GetByID(ctx, *req.CategoryID); errors.Is(err, repository.ErrCategoryNotFound) {
+			return nil, &ValidationError{Fields: []FieldError{{Field: "category_id", Message: "category does not exist"}}}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Reject or flag reposts of the same content within the window
+	hash := contentHash(title, content)
+	var warnings []string
+	duplicate, err := s.isDuplicate(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		dupErr := &DuplicatePostError{Window: s.duplicates.Window.String()}
+		if s.duplicates.Action != DuplicateWarn {
+			return nil, dupErr
+		}
+		logger.FromContext(ctx).Warn("Duplicate post created", "user_id", userID, "hash", hash)
+		warnings = append(warnings, dupErr.Error())
+	}
+
+	// Enforce the plan's daily post quota
+	release, err := s.quotas.ConsumePostQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	post := &models.Post{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  userID,
+		Title:       title,
+		Content:     content,
+		UserID:      userID,
+		ContentHash: hash,
+		Locale:      postLocale(req.Locale),
+		Tags:        tags,
+		CategoryID:  req.CategoryID,
 	}
+	applyReadingStats(post)
+	post.Excerpt = s.policy.Excerpt(content)
 
 	if err := s.repo.Create(ctx, post); err != nil {
-		logger.WithContext(ctx).Error("Failed to create post", "error", err)
+		release()
+		logger.FromContext(ctx).Error("Failed to create post", "error", err)
 		return nil, err
 	}
 
+	s.rememberHash(ctx, userID, hash)
+	postChanged(ctx, s.cache, post.ID, userID)
+	s.counter.Add(ctx, userID, 1)
+	s.events.PostCreated(ctx, post)
+
+	// Meter billable usage
+	s.metering.Record(ctx, userID, models.MetricPostsCreated, 1)
+	s.metering.Record(ctx, userID, models.MetricStorageBytes, int64(len(post.Title)+len(post.Content)))
+
 	// Load author using DataLoader
 	user, err := utils.LoadUser(ctx, post.UserID)
 	if err != nil {
-		logger.WithContext(ctx).Warn("Failed to load post author", "user_id", post.UserID, "error", err)
+		logger.FromContext(ctx).Warn("Failed to load post author", "user_id", post.UserID, "error", err)
 	}
 
 	post.User = user
-	response := post.ToResponse()
+	response := s.toResponse(ctx, post)
+	response.Warnings = warnings
 	return &response, nil
 }
 
 func (s *postService) GetByID(ctx context.Context, id uint) (*models.PostResponse, error) {
-	cacheKey := fmt.Sprintf("post:%d", id)
+	response, err := cache.ReadThrough(ctx, s.cache, fmt.Sprintf("post:%d", id), s.cachePolicy, func(ctx context.Context) (models.PostResponse, error) {
+		post, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return models.PostResponse{}, err
+		}
 
-	// 1. Try Cache
-	val, err := s.redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var cachedPost models.PostResponse
-		if err := json.Unmarshal([]byte(val), &cachedPost); err == nil {
-			return &cachedPost, nil
+		// Load author using DataLoader to avoid N+1
+		user, err := utils.LoadUser(ctx, post.UserID)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to load post author", "user_id", post.UserID, "error", err)
 		}
-	}
 
-	// 2. Cache Miss - Query DB
-	post, err := s.repo.GetByID(ctx, id)
+		post.User = user
+		return s.toResponse(ctx, post), nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &response, nil
+}
 
-	// Load author using DataLoader to avoid N+1
-	user, err := utils.LoadUser(ctx, post.UserID)
-	if err != nil {
-		logger.WithContext(ctx).Warn("Failed to load post author", "user_id", post.UserID, "error", err)
-	}
+func (s *postService) GetAll(ctx context.Context, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error) {
+	return s.listPage(ctx, allPostsTag, req, func(ctx context.Context) (models.PostPage, error) {
+		posts, info, err := s.repo.GetAll(ctx, req)
+		if err != nil {
+			return models.PostPage{Info: info}, err
+		}
+		return models.PostPage{Posts: s.withAuthors(ctx, posts), Info: info}, nil
+	})
+}
 
-	post.User = user
-	response := post.ToResponse()
+func (s *postService) GetByUserID(ctx context.Context, userID uint, req models.PostListRequest) ([]models.PostListItem, models.PageInfo, error) {
+	return s.listPage(ctx, userPostsTag(userID), req, func(ctx context.Context) (models.PostPage, error) {
+		posts, info, err := s.repo.GetByUserID(ctx, userID, req)
+		if err != nil {
+			return models.PostPage{Info: info}, err
+		}
 
-	// 3. Set Cache (TTL 10 mins)
-	if data, err := json.Marshal(response); err == nil {
-		s.redis.Set(ctx, cacheKey, data, 10*time.Minute)
-	}
+		// Load author once using DataLoader
+		user, err := utils.LoadUser(ctx, userID)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to load post author", "user_id", userID, "error", err)
+		}
 
-	return &response, nil
+		// Build list items
+		items := make([]models.PostListItem, len(posts))
+		for i, post := range posts {
+			post.User = user
+			items[i] = s.toListItem(ctx, &post)
+		}
+
+		return models.PostPage{Posts: items, Info: info}, nil
+	})
 }
 
-func (s *postService) GetAll(ctx context.Context) ([]models.PostResponse, error) {
-	posts, err := s.repo.GetAll(ctx)
+// GetRelated returns up to limit posts similar to post id. The result is computed on
+// first request and cached under the post's tag and those of every post in it, so
+// editing or deleting any of them recomputes it. Newer posts appear once it expires.
+func (s *postService) GetRelated(ctx context.Context, id uint, limit int) ([]models.PostListItem, error) {
+	key := fmt.Sprintf("post:%d:related:%d", id, limit)
+	return cache.ReadThroughTagged(ctx, s.cache, key, s.listPolicy, func(ctx context.Context) ([]models.PostListItem, []string, error) {
+		post, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		posts, err := s.repo.GetRelated(ctx, post, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items := s.withAuthors(ctx, posts)
+		return items, postListTags(postTag(id), items), nil
+	})
+}
+
+// Search returns one page of posts matching query.Q with their authors. Results are not
+// cached: queries rarely repeat, and any post write could change them.
+func (s *postService) Search(ctx context.Context, query models.PostSearchQuery) ([]models.PostSearchResult, models.PageInfo, error) {
+	hits, info, err := s.repo.Search(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, info, err
+	}
+
+	posts := make([]models.Post, len(hits))
+	for i, hit := range hits {
+		posts[i] = hit.Post
 	}
+	items := s.withAuthors(ctx, posts)
 
+	results := make([]models.PostSearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = models.PostSearchResult{PostListItem: items[i], Rank: hit.Rank, Highlights: hit.Highlights}
+	}
+	return results, info, nil
+}
+
+// ListTags returns up to limit tags, most used first. Counts are read live: they change
+// with every post write, and the query only touches the tag tables and their posts.
+func (s *postService) ListTags(ctx context.Context, limit int) ([]models.TagCount, error) {
+	return s.repo.CountTags(ctx, limit)
+}
+
+// withAuthors converts posts to list items with their authors batch-loaded through the
+// DataLoader (solves the N+1 problem)
+func (s *postService) withAuthors(ctx context.Context, posts []models.Post) []models.PostListItem {
 	// Collect all user IDs
 	userIDs := make([]uint, 0, len(posts))
 	for _, post := range posts {
 		userIDs = append(userIDs, post.UserID)
 	}
 
-	// Batch load all users at once using DataLoader (solves N+1 problem)
+	// Batch load all users at once using DataLoader
 	users, errs := utils.LoadUsers(ctx, userIDs)
 
 	// Create a map for quick lookup
@@ -116,53 +312,200 @@ func (s *postService) GetAll(ctx context.Context) ([]models.PostResponse, error)
 		}
 	}
 
-	// Build responses with loaded users
-	responses := make([]models.PostResponse, len(posts))
+	// Build list items with loaded users
+	items := make([]models.PostListItem, len(posts))
 	for i, post := range posts {
 		post.User = userMap[post.UserID]
-		responses[i] = post.ToResponse()
+		items[i] = s.toListItem(ctx, &post)
+	}
+	return items
+}
+
+// listPage caches the first page of the list identified by listTag, tagged with the
+// posts and authors on it. Later pages, by offset or cursor, are requested rarely and
+// always hit the database.
+func (s *postService) listPage(ctx context.Context, listTag string, req models.PostListRequest, load func(ctx context.Context) (models.PostPage, error)) ([]models.PostListItem, models.PageInfo, error) {
+	if req.Page != 1 || req.After != 0 {
+		result, err := load(ctx)
+		return result.Posts, result.Info, err
 	}
 
-	return responses, nil
+	key := fmt.Sprintf("%s:page1:%d:%s:%s:%t:%s:%d", listTag, req.Limit, req.Mode, req.Sort, req.IncludeArchived, req.Tag, req.CategoryID)
+	result, err := cache.ReadThroughTagged(ctx, s.cache, key, s.listPolicy, func(ctx context.Context) (models.PostPage, []string, error) {
+		result, err := load(ctx)
+		if err != nil {
+			return result, nil, err
+		}
+		return result, postListTags(listTag, result.Posts), nil
+	})
+	return result.Posts, result.Info, err
 }
 
-func (s *postService) GetByUserID(ctx context.Context, userID uint) ([]models.PostResponse, error) {
-	posts, err := s.repo.GetByUserID(ctx, userID)
+func (s *postService) Delete(ctx context.Context, id uint, userID uint, role string) error {
+	post, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Load author once using DataLoader
-	user, err := utils.LoadUser(ctx, userID)
+	if err := s.permissions.Authorize(ctx, role, userID, post.UserID, "posts:delete"); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if post.ArchivedAt == nil {
+		s.counter.Add(ctx, post.UserID, -1)
+	}
+	s.events.PostDeleted(ctx, post)
+
+	// Invalidate cache
+	return postChanged(ctx, s.cache, id, post.UserID)
+}
+
+// Archive hides post id from public lists, related posts and author stats until it is
+// unarchived. Only the author may archive; the author still sees it.
+func (s *postService) Archive(ctx context.Context, id uint, userID uint) (*models.PostResponse, error) {
+	return s.setArchived(ctx, id, userID, true)
+}
+
+// Unarchive restores an archived post
+func (s *postService) Unarchive(ctx context.Context, id uint, userID uint) (*models.PostResponse, error) {
+	return s.setArchived(ctx, id, userID, false)
+}
+
+func (s *postService) setArchived(ctx context.Context, id uint, userID uint, archived bool) (*models.PostResponse, error) {
+	post, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		logger.WithContext(ctx).Warn("Failed to load post author", "user_id", userID, "error", err)
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, ErrNotPostAuthor
 	}
 
-	// Build responses
-	responses := make([]models.PostResponse, len(posts))
-	for i, post := range posts {
-		post.User = user
-		responses[i] = post.ToResponse()
+	// Repeating the current state is a no-op, so retries are safe
+	if (post.ArchivedAt != nil) != archived {
+		post.ArchivedAt = nil
+		if archived {
+			post.ArchivedAt = utctime.Ptr(time.Now())
+		}
+		if err := s.repo.Update(ctx, post); err != nil {
+			return nil, err
+		}
+		// Archived posts are not counted
+		delta := int64(1)
+		if archived {
+			delta = -1
+		}
+		s.counter.Add(ctx, post.UserID, delta)
+		if err := postChanged(ctx, s.cache, id, post.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	response := s.toResponse(ctx, post)
+	return &response, nil
+}
+
+// SaveDraft autosaves the author's unpublished edits to post id, leaving the published
+// post untouched. Saves are last-write-wins by req.Revision: an older or repeated
+// revision is rejected with a StaleDraftError holding the stored draft.
+func (s *postService) SaveDraft(ctx context.Context, id uint, userID uint, req *models.SaveDraftRequest) (*models.PostDraft, error) {
+	if err := s.checkAuthor(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	title, content := req.Title, req.Content
+	if err := s.policy.ApplyDraft(&title, &content); err != nil {
+		return nil, err
 	}
 
-	return responses, nil
+	draft := &models.PostDraft{PostID: id, Title: title, Content: content, Revision: utctime.From(req.Revision)}
+	saved, err := s.drafts.Save(ctx, draft)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		current, err := s.drafts.GetByPostID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &StaleDraftError{Current: current}
+	}
+	return draft, nil
+}
+
+// GetDraft returns the author's saved draft of post id
+func (s *postService) GetDraft(ctx context.Context, id uint, userID uint) (*models.PostDraft, error) {
+	if err := s.checkAuthor(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.drafts.GetByPostID(ctx, id)
 }
 
-func (s *postService) Delete(ctx context.Context, id uint, userID uint) error {
+func (s *postService) checkAuthor(ctx context.Context, id uint, userID uint) error {
 	post, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
-
-	// Check ownership
 	if post.UserID != userID {
-		return errors.New("unauthorized to delete this post")
+		return ErrNotPostAuthor
 	}
+	return nil
+}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+// Export streams posts (optionally only userID's) without loading them all into memory.
+// Authors are not embedded; records carry user_id.
+func (s *postService) Export(ctx context.Context, userID uint, emit func(post models.PostResponse) error) error {
+	return s.repo.Each(ctx, userID, func(post *models.Post) error {
+		// Exports are public lists; Each itself includes archived posts for internal callers
+		if post.ArchivedAt != nil {
+			return nil
+		}
+		return emit(post.ToResponse())
+	})
+}
+
+// StreamList emits every post the list selected by userID and req would page through,
+// in batches of streamBatch with authors embedded. Nothing is cached.
+func (s *postService) StreamList(ctx context.Context, userID uint, req models.PostListRequest, emit func(posts []models.PostListItem) error) error {
+	batch := make([]models.Post, 0, streamBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := emit(s.withAuthors(ctx, batch))
+		batch = batch[:0]
 		return err
 	}
 
-	// Invalidate cache
-	return s.redis.Del(ctx, fmt.Sprintf("post:%d", id)).Err()
+	err := s.repo.EachListed(ctx, userID, req, func(post *models.Post) error {
+		batch = append(batch, *post)
+		if len(batch) == streamBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// toResponse converts post and sets its author's resolved avatar
+func (s *postService) toResponse(ctx context.Context, post *models.Post) models.PostResponse {
+	response := post.ToResponse()
+	if response.Author != nil {
+		response.Author.AvatarURL = s.avatars.Resolve(ctx, post.User)
+	}
+	return response
+}
+
+// toListItem converts post to a list item and sets its author's resolved avatar
+func (s *postService) toListItem(ctx context.Context, post *models.Post) models.PostListItem {
+	item := post.ToListItem()
+	if item.Author != nil {
+		item.Author.AvatarURL = s.avatars.Resolve(ctx, post.User)
+	}
+	return item
 }
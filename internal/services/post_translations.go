@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"regexp"
+
+	"goapi/internal/models"
+	"goapi/pkg/i18n"
+)
+
+// localePattern accepts ISO 639 base languages, as stored after i18n.Base
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// postLocale normalizes a requested locale to its base language, defaulting to English
+func postLocale(tag string) string {
+	if tag == "" {
+		return i18n.DefaultLanguage
+	}
+	return i18n.Base(tag)
+}
+
+// TranslatePost adds or replaces the author's translation of post id into locale
+func (s *postService) TranslatePost(ctx context.Context, id uint, userID uint, locale string, req *models.TranslatePostRequest) (*models.PostTranslation, error) {
+	post, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, ErrNotPostAuthor
+	}
+
+	locale = i18n.Base(locale)
+	switch {
+	case !localePattern.MatchString(locale):
+		return nil, &ValidationError{Fields: []FieldError{{Field: "locale", Message: "must be a language code such as \"en\" or \"id\""}}}
+	case locale == post.Locale:
+		return nil, &ValidationError{Fields: []FieldError{{Field: "locale", Message: "is the post's original locale"}}}
+	}
+
+	title, content := req.Title, req.Content
+	if err := s.policy.Apply(&title, &content); err != nil {
+		return nil, err
+	}
+
+	translation := &models.PostTranslation{PostID: id, Locale: locale, Title: title, Content: content}
+	if err := s.translations.Upsert(ctx, translation); err != nil {
+		return nil, err
+	}
+	return translation, nil
+}
+
+// GetTranslations answers ErrPostNotFound for an archived post the viewer may not see
+func (s *postService) GetTranslations(ctx context.Context, id uint, viewerID uint, signedLink bool) ([]models.PostTranslation, error) {
+	post, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.ArchivedAt != nil && post.UserID != viewerID && !signedLink {
+		return nil, ErrPostNotFound
+	}
+	return s.translations.GetByPostID(ctx, id)
+}
+
+// Localize replaces the title and content of posts with the translation best matching
+// acceptLanguage, in place. A post keeps its original text when its original locale is
+// preferred over every translation, or when no translation matches. Responses are
+// cached untranslated, so this runs after the cache on every request.
+func (s *postService) Localize(ctx context.Context, posts []models.PostResponse, acceptLanguage string) error {
+	originals := make(map[uint]string, len(posts))
+	for _, post := range posts {
+		originals[post.ID] = post.OriginalLocale
+	}
+	best, err := s.translationsFor(ctx, originals, acceptLanguage)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		if t, ok := best[posts[i].ID]; ok {
+			posts[i].Title, posts[i].Content, posts[i].Locale = t.Title, t.Content, t.Locale
+			posts[i].Excerpt = s.policy.Excerpt(t.Content)
+		}
+	}
+	return nil
+}
+
+func (s *postService) LocalizeList(ctx context.Context, items []models.PostListItem, acceptLanguage string) error {
+	originals := make(map[uint]string, len(items))
+	for _, item := range items {
+		originals[item.ID] = item.OriginalLocale
+	}
+	best, err := s.translationsFor(ctx, originals, acceptLanguage)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if t, ok := best[items[i].ID]; ok {
+			items[i].Title, items[i].Excerpt, items[i].Locale = t.Title, s.policy.Excerpt(t.Content), t.Locale
+		}
+	}
+	return nil
+}
+
+// translationsFor picks, for each post ID in originals (mapped to its original locale),
+// the translation acceptLanguage prefers over the original. Posts best read in their
+// original language are left out.
+func (s *postService) translationsFor(ctx context.Context, originals map[uint]string, acceptLanguage string) (map[uint]models.PostTranslation, error) {
+	prefs := i18n.Preferences(acceptLanguage)
+	if len(prefs) == 0 {
+		return nil, nil
+	}
+	rank := func(locale string) int {
+		for i, lang := range prefs {
+			if lang == locale {
+				return i
+			}
+		}
+		return len(prefs)
+	}
+
+	// Posts already written in the most preferred language need no lookup
+	var ids []uint
+	for id, original := range originals {
+		if original != prefs[0] {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	translations, err := s.translations.GetByPostIDs(ctx, ids, prefs)
+	if err != nil {
+		return nil, err
+	}
+	best := make(map[uint]models.PostTranslation)
+	for _, t := range translations {
+		if current, ok := best[t.PostID]; !ok || rank(t.Locale) < rank(current.Locale) {
+			best[t.PostID] = t
+		}
+	}
+	for id, t := range best {
+		if rank(t.Locale) >= rank(originals[id]) {
+			delete(best, id)
+		}
+	}
+	return best, nil
+}
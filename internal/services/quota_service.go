@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	QuotaPostsPerDay = "posts_per_day"
+	QuotaPosts       = "posts"
+)
+
+// QuotaExceededError is returned when a user has used up a plan quota
+type QuotaExceededError struct {
+	Quota string `json:"quota"`
+	Limit int    `json:"limit"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded (limit %d)", e.Quota, e.Limit)
+}
+
+func (e *QuotaExceededError) ErrorCode() string { return "quota_exceeded" }
+
+type QuotaService interface {
+	ConsumePostQuota(ctx context.Context, userID uint) (release func(), err error)
+}
+
+type quotaService struct {
+	userRepo    repository.UserRepository
+	billingRepo repository.BillingRepository
+	counter     PostCounter
+	redis       *redis.Client
+}
+
+func NewQuotaService(userRepo repository.UserRepository, billingRepo repository.BillingRepository, counter PostCounter, redisClient *redis.Client) QuotaService {
+	return &quotaService{
+		userRepo:    userRepo,
+		billingRepo: billingRepo,
+		counter:     counter,
+		redis:       redisClient,
+	}
+}
+
+// ConsumePostQuota checks the plan's total post limit and reserves one post from the
+// user's daily quota. The returned release func gives the reservation back if the post
+// isn't created. Admins are not subject to quotas.
+//
+// The total limit reads the post counter without reserving, so concurrent creates by
+// one user can pass it together and overshoot by the number in flight.
+func (s *quotaService) ConsumePostQuota(ctx context.Context, userID uint) (func(), error) {
+	noop := func() {}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return noop, err
+	}
+	if user.Role == "admin" {
+		return noop, nil
+	}
+
+	plan, err := s.billingRepo.GetPlanByCode(ctx, user.Plan)
+	if err != nil {
+		return noop, err
+	}
+	if plan.MaxPosts > 0 {
+		counts, err := s.counter.Counts(ctx, []uint{userID})
+		if err != nil {
+			return noop, err
+		}
+		if counts[userID] >= int64(plan.MaxPosts) {
+			logger.FromContext(ctx).Info("Post quota exceeded", "user_id", userID, "plan", plan.Code, "quota", QuotaPosts, "limit", plan.MaxPosts)
+			return noop, &QuotaExceededError{Quota: QuotaPosts, Limit: plan.MaxPosts}
+		}
+	}
+	if plan.MaxPostsPerDay <= 0 {
+		return noop, nil
+	}
+
+	key := postQuotaKey(userID)
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return noop, err
+	}
+	if count == 1 {
+		s.redis.Expire(ctx, key, 48*time.Hour)
+	}
+
+	if count > int64(plan.MaxPostsPerDay) {
+		s.redis.Decr(ctx, key)
+		logger.FromContext(ctx).Info("Post quota exceeded", "user_id", userID, "plan", plan.Code, "limit", plan.MaxPostsPerDay)
+		return noop, &QuotaExceededError{Quota: QuotaPostsPerDay, Limit: plan.MaxPostsPerDay}
+	}
+
+	return func() { s.redis.Decr(ctx, key) }, nil
+}
+
+// postQuotaKey buckets the counter per UTC day
+func postQuotaKey(userID uint) string {
+	return fmt.Sprintf("quota:%s:%d:%s", QuotaPostsPerDay, userID, time.Now().UTC().Format("2006-01-02"))
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"strings"
+
+	"goapi/internal/models"
+)
+
+// wordsPerMinute is the average adult silent reading speed used for reading time
+const wordsPerMinute = 200
+
+// applyReadingStats recomputes post's word count and reading time from its content.
+// Call it whenever content is written so sorting by either stays accurate.
+func applyReadingStats(post *models.Post) {
+	post.WordCount = len(strings.Fields(post.Content))
+	post.ReadingTimeMinutes = (post.WordCount + wordsPerMinute - 1) / wordsPerMinute
+}
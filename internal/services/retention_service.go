@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"goapi/internal/repository"
+	"goapi/pkg/logger"
+)
+
+// PurgeReport counts the rows permanently deleted by one purge run
+type PurgeReport struct {
+	Users int64 `json:"users"`
+	Posts int64 `json:"posts"`
+}
+
+type RetentionService interface {
+	PurgeSoftDeleted(ctx context.Context) (*PurgeReport, error)
+}
+
+type retentionService struct {
+	userRepo  repository.UserRepository
+	postRepo  repository.PostRepository
+	retention time.Duration
+	batchSize int
+}
+
+func NewRetentionService(userRepo repository.UserRepository, postRepo repository.PostRepository, retention time.Duration, batchSize int) RetentionService {
+	return &retentionService{
+		userRepo:  userRepo,
+		postRepo:  postRepo,
+		retention: retention,
+		batchSize: batchSize,
+	}
+}
+
+// PurgeSoftDeleted permanently deletes posts, then users, soft-deleted longer than the retention period.
+// Each batch runs in its own transaction so a long purge never holds locks for the whole run.
+func (s *retentionService) PurgeSoftDeleted(ctx context.Context) (*PurgeReport, error) {
+	cutoff := time.Now().Add(-s.retention)
+	report := &PurgeReport{}
+
+	// Posts first: purged users must no longer be referenced
+	if err := s.purgeInBatches(ctx, func(txCtx context.Context) (int64, error) {
+		return s.postRepo.PurgeDeleted(txCtx, cutoff, s.batchSize)
+	}, &report.Posts); err != nil {
+		return report, err
+	}
+	if err := s.purgeInBatches(ctx, func(txCtx context.Context) (int64, error) {
+		return s.userRepo.PurgeDeleted(txCtx, cutoff, s.batchSize)
+	}, &report.Users); err != nil {
+		return report, err
+	}
+
+	logger.FromContext(ctx).Info("Purged soft-deleted records", "users", report.Users, "posts", report.Posts, "cutoff", cutoff.Format(time.RFC3339))
+	return report, nil
+}
+
+func (s *retentionService) purgeInBatches(ctx context.Context, purge func(ctx context.Context) (int64, error), total *int64) error {
+	for {
+		var purged int64
+		err := s.userRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+			var err error
+			purged, err = purge(txCtx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		*total += purged
+		if purged < int64(s.batchSize) || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
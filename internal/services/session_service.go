@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrSessionNotFound is returned when revoking a session that does not exist or has ended
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService tracks issued access tokens per user in the Redis hash session:<user_id>,
+// keyed by the token's jti. A token whose session is gone is rejected by JWTAuth.
+type SessionService interface {
+	// Create records a session for a token issued with tokenVersion
+	Create(ctx context.Context, userID uint, session *models.Session, tokenVersion uint) error
+	// List returns the user's live sessions, newest first. Expired sessions and those
+	// revoked by a token version bump (password change, logout-all) are dropped.
+	List(ctx context.Context, userID uint, tokenVersion uint) ([]models.Session, error)
+	// Active reports whether session id of the user has not been revoked
+	Active(ctx context.Context, userID uint, id string) (bool, error)
+	Revoke(ctx context.Context, userID uint, id string) error
+	RevokeAll(ctx context.Context, userID uint) error
+}
+
+// sessionRecord is the stored form of a session
+type sessionRecord struct {
+	models.Session
+	TokenVersion uint `json:"token_version"`
+}
+
+type sessionService struct {
+	redis *redis.Client
+}
+
+func NewSessionService(redisClient *redis.Client) SessionService {
+	return &sessionService{redis: redisClient}
+}
+
+func (s *sessionService) Create(ctx context.Context, userID uint, session *models.Session, tokenVersion uint) error {
+	value, err := json.Marshal(sessionRecord{Session: *session, TokenVersion: tokenVersion})
+	if err != nil {
+		return err
+	}
+
+	// Tokens share one TTL, so the newest session always expires last and the hash can
+	// expire with it
+	key := sessionKey(userID)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, session.ID, value)
+	pipe.ExpireAt(ctx, key, session.ExpiresAt.Time)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *sessionService) List(ctx context.Context, userID uint, tokenVersion uint) ([]models.Session, error) {
+	key := sessionKey(userID)
+	values, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]models.Session, 0, len(values))
+	var ended []string
+	for id, value := range values {
+		var record sessionRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil || record.TokenVersion != tokenVersion || !record.ExpiresAt.After(now) {
+			ended = append(ended, id)
+			continue
+		}
+		sessions = append(sessions, record.Session)
+	}
+	if len(ended) > 0 {
+		if err := s.redis.HDel(ctx, key, ended...).Err(); err != nil {
+			logger.FromContext(ctx).Warn("Failed to prune ended sessions", "user_id", userID, "error", err)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].IssuedAt.After(sessions[j].IssuedAt.Time) })
+	return sessions, nil
+}
+
+func (s *sessionService) Active(ctx context.Context, userID uint, id string) (bool, error) {
+	return s.redis.HExists(ctx, sessionKey(userID), id).Result()
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userID uint, id string) error {
+	removed, err := s.redis.HDel(ctx, sessionKey(userID), id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrSessionNotFound
+	}
+	logger.FromContext(ctx).Info("Session revoked", "user_id", userID, "session_id", id)
+	return nil
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID uint) error {
+	return s.redis.Del(ctx, sessionKey(userID)).Err()
+}
+
+func sessionKey(userID uint) string {
+	return fmt.Sprintf("session:%d", userID)
+}
+
+// newSessionID returns a random token ID (jti)
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/internal/models"
+	"goapi/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Signup review flags
+const (
+	FlagHoneypot         = "honeypot"
+	FlagMissingFormToken = "missing_form_token"
+	FlagInvalidFormToken = "invalid_form_token"
+	FlagTooFast          = "too_fast"
+	FlagIPVelocity       = "ip_velocity"
+)
+
+// SignupGuardOptions tunes bot detection on registration
+type SignupGuardOptions struct {
+	Secret string
+	// MinFillTime is the minimum time between issuing the form token and submitting
+	MinFillTime time.Duration
+	// MaxPerIP signups are allowed per IP within Window before new ones are flagged
+	MaxPerIP int
+	Window   time.Duration
+}
+
+// SignupGuard detects likely bots on the public registration form. It never rejects:
+// it returns flags so suspicious signups can be held for review.
+type SignupGuard interface {
+	IssueFormToken() string
+	Inspect(ctx context.Context, ip string, req *models.RegisterRequest) []string
+}
+
+type signupGuard struct {
+	redis *redis.Client
+	opts  SignupGuardOptions
+}
+
+func NewSignupGuard(redisClient *redis.Client, opts SignupGuardOptions) SignupGuard {
+	return &signupGuard{redis: redisClient, opts: opts}
+}
+
+// IssueFormToken returns "<unix_millis>.<signature>" to embed in the form when it is rendered
+func (g *signupGuard) IssueFormToken() string {
+	issuedAt := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return issuedAt + "." + g.sign(issuedAt)
+}
+
+func (g *signupGuard) Inspect(ctx context.Context, ip string, req *models.RegisterRequest) []string {
+	var flags []string
+
+	if strings.TrimSpace(req.Website) != "" {
+		flags = append(flags, FlagHoneypot)
+	}
+
+	if flag := g.checkFormToken(req.FormToken); flag != "" {
+		flags = append(flags, flag)
+	}
+
+	key := fmt.Sprintf("signup:ip:%s", ip)
+	count, err := g.redis.Incr(ctx, key).Result()
+	if err != nil {
+		logger.FromContext(ctx).Warn("Signup velocity check failed", "error", err)
+	} else {
+		if count == 1 {
+			g.redis.Expire(ctx, key, g.opts.Window)
+		}
+		if count > int64(g.opts.MaxPerIP) {
+			flags = append(flags, FlagIPVelocity)
+		}
+	}
+
+	if len(flags) > 0 {
+		logger.FromContext(ctx).Warn("Suspicious signup", "ip", ip, "email", req.Email, "flags", flags)
+	}
+	return flags
+}
+
+func (g *signupGuard) checkFormToken(token string) string {
+	if token == "" {
+		return FlagMissingFormToken
+	}
+
+	issuedAt, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(g.sign(issuedAt))) {
+		return FlagInvalidFormToken
+	}
+	millis, err := strconv.ParseInt(issuedAt, 10, 64)
+	if err != nil {
+		return FlagInvalidFormToken
+	}
+	if time.Since(time.UnixMilli(millis)) < g.opts.MinFillTime {
+		return FlagTooFast
+	}
+	return ""
+}
+
+func (g *signupGuard) sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(g.opts.Secret))
+	mac.Write([]byte("signup-form:" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+
+	"goapi/internal/models"
+)
+
+// tagPattern is a normalized tag: lowercase letters and digits, with "-", "+", "#" and
+// "." allowed after the first character ("c++", "c#", "node.js")
+var tagPattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}+#.-]*$`)
+
+// normalizeTags normalizes the tags of a new post, dropping duplicates, and returns them
+// sorted by name as the repository stores them
+func normalizeTags(names []string) ([]models.Tag, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		tag := models.NormalizeTag(name)
+		if utf8.RuneCountInString(tag) > models.MaxTagLength || !tagPattern.MatchString(tag) {
+			return nil, &ValidationError{Fields: []FieldError{{Field: "tags", Message: fmt.Sprintf("%q is not a valid tag: use letters, digits and - + # .", name)}}}
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > models.MaxPostTags {
+		return nil, &ValidationError{Fields: []FieldError{{Field: "tags", Message: fmt.Sprintf("at most %d tags", models.MaxPostTags)}}}
+	}
+
+	tags := make([]models.Tag, len(normalized))
+	for i, name := range normalized {
+		tags[i] = models.Tag{Name: name}
+	}
+	return tags, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenClaims are the identity claims carried by an access token
+type TokenClaims struct {
+	UserID       uint
+	Email        string
+	Role         string
+	TokenVersion uint
+	// SessionID is the token's jti; empty for tokens issued before sessions were tracked
+	SessionID string
+}
+
+// TokenService issues and verifies access tokens with the configured secret
+type TokenService interface {
+	// Issue signs a token for user carrying sessionID as its jti
+	Issue(user *models.User, sessionID string) (string, error)
+	Parse(tokenString string) (*TokenClaims, error)
+	TTL() time.Duration
+}
+
+// TokenOptions configures token issuance and verification
+type TokenOptions struct {
+	Secret string
+	TTL    time.Duration
+	// Issuer and Audience are set on issued tokens and required on verified ones
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew between servers when checking exp, nbf and iat
+	Leeway time.Duration
+	// MaxLifetime rejects tokens whose exp is further than this after their iat
+	MaxLifetime time.Duration
+}
+
+type tokenService struct {
+	secret []byte
+	opts   TokenOptions
+	parser *jwt.Parser
+}
+
+func NewTokenService(opts TokenOptions) TokenService {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(opts.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &tokenService{
+		secret: []byte(opts.Secret),
+		opts:   opts,
+		parser: jwt.NewParser(parserOpts...),
+	}
+}
+
+func (s *tokenService) Issue(user *models.User, sessionID string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti":     sessionID,
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"ver":     user.TokenVersion,
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+		"exp":     now.Add(s.opts.TTL).Unix(),
+	}
+	if s.opts.Issuer != "" {
+		claims["iss"] = s.opts.Issuer
+	}
+	if s.opts.Audience != "" {
+		claims["aud"] = s.opts.Audience
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secret)
+}
+
+func (s *tokenService) Parse(tokenString string) (*TokenClaims, error) {
+	token, err := s.parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+	if err := s.checkLifetime(claims); err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+	version, _ := claims["ver"].(float64)
+	sessionID, _ := claims["jti"].(string)
+
+	return &TokenClaims{
+		UserID:       uint(userID),
+		Email:        email,
+		Role:         role,
+		TokenVersion: uint(version),
+		SessionID:    sessionID,
+	}, nil
+}
+
+// checkLifetime requires iat and nbf (the parser only validates them when present)
+// and rejects tokens valid for longer than MaxLifetime
+func (s *tokenService) checkLifetime(claims jwt.MapClaims) error {
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return errors.New("token has no issued-at time")
+	}
+	nbf, err := claims.GetNotBefore()
+	if err != nil || nbf == nil {
+		return errors.New("token has no not-before time")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("token has no expiry")
+	}
+
+	if s.opts.MaxLifetime > 0 && exp.Sub(iat.Time) > s.opts.MaxLifetime {
+		return errors.New("token lifetime exceeds the allowed maximum")
+	}
+	return nil
+}
+
+func (s *tokenService) TTL() time.Duration {
+	return s.opts.TTL
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goapi/internal/models"
+)
+
+// What happens to a deleted user's posts
+const (
+	DeletePosts     = "delete"
+	ReassignPosts   = "reassign"
+	AnonymizeAuthor = "anonymize"
+)
+
+// DeletionPolicy controls the cascade of userService.Delete
+type DeletionPolicy struct {
+	// PostAction is DeletePosts, ReassignPosts or AnonymizeAuthor
+	PostAction string
+	// ReassignTo is the system account receiving posts under ReassignPosts
+	ReassignTo uint
+}
+
+// ErrReassignTarget is returned when deleting the account posts are reassigned to
+var ErrReassignTarget = errors.New("cannot delete the account that deleted users' posts are reassigned to")
+
+// cascade applies the deletion policy to user inside the delete transaction and returns
+// the IDs of the posts whose author changed or that were deleted
+func (s *userService) cascade(ctx context.Context, user *models.User) ([]uint, error) {
+	switch s.deletion.PostAction {
+	case ReassignPosts:
+		if user.ID == s.deletion.ReassignTo {
+			return nil, ErrReassignTarget
+		}
+		if _, err := s.repo.GetByID(ctx, s.deletion.ReassignTo); err != nil {
+			return nil, fmt.Errorf("reassign target %d: %w", s.deletion.ReassignTo, err)
+		}
+		ids, err := s.posts.ReassignUser(ctx, user.ID, s.deletion.ReassignTo)
+		if err != nil {
+			return nil, err
+		}
+		return ids, s.repo.Delete(ctx, user.ID)
+
+	case AnonymizeAuthor:
+		// The row stays so posts keep an author; it only loses what identifies the person
+		var ids []uint
+		if err := s.posts.Each(ctx, user.ID, func(post *models.Post) error {
+			ids = append(ids, post.ID)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		anonymize(user)
+		if err := s.repo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		return ids, s.repo.IncrementTokenVersion(ctx, user.ID)
+
+	default:
+		ids, err := s.posts.DeleteByUserID(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return ids, s.repo.Delete(ctx, user.ID)
+	}
+}
+
+// anonymize scrubs personal data from user and disables the account. The placeholder
+// email and username are unique per ID so they never collide with live users.
+func anonymize(user *models.User) {
+	user.Email = fmt.Sprintf("deleted-%d@users.invalid", user.ID)
+	user.Username = fmt.Sprintf("deleted-%d", user.ID)
+	user.FullName = "Deleted user"
+	user.AvatarURL = ""
+	user.Password = ""
+	user.Role = "user"
+	user.Active = false
+	user.LastLoginAt = nil
+	user.DeletionScheduledAt = nil
+	user.DeletionRemindedAt = nil
+}
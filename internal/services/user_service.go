@@ -5,41 +5,119 @@ import (
 	"errors"
 	"goapi/internal/models"
 	"goapi/internal/repository"
+	"goapi/pkg/cache"
 	"goapi/pkg/logger"
+	"goapi/pkg/password"
+	"goapi/pkg/utctime"
+	"goapi/pkg/utils"
+	"strings"
 	"time"
 
-	"encoding/json"
 	"fmt"
-
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 )
 
+// ErrVersionConflict is returned when an update is based on a stale version
+var ErrVersionConflict = repository.ErrVersionConflict
+
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = repository.ErrUserNotFound
+
+// ErrEmailInUse is returned when restoring a user whose email now belongs to another account
+var ErrEmailInUse = errors.New("email is registered to another account")
+
+// ErrUsernameInUse is returned when restoring a user whose username now belongs to another account
+var ErrUsernameInUse = errors.New("username is taken by another account")
+
 type UserService interface {
 	Register(ctx context.Context, req *models.RegisterRequest) (*models.UserResponse, error)
 	Login(ctx context.Context, req *models.LoginRequest) (string, *models.UserResponse, error)
+	// LoginVerified logs in user, whose identity the caller has already verified (with a
+	// passkey, say), with the account checks and session of Login. req.Email and
+	// req.Password are ignored.
+	LoginVerified(ctx context.Context, user *models.User, req *models.LoginRequest) (string, *models.UserResponse, error)
 	GetByID(ctx context.Context, id uint) (*models.UserResponse, error)
-	GetAll(ctx context.Context) ([]models.UserResponse, error)
-	Update(ctx context.Context, id uint, updates *models.User) (*models.UserResponse, error)
+	GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.UserResponse, models.PageInfo, error)
+	Update(ctx context.Context, id uint, version uint, updates *models.User) (*models.UserResponse, error)
 	Delete(ctx context.Context, id uint) error
+	// DeleteScheduled deletes user id like Delete if their scheduled deletion is due by
+	// before, and returns ErrDeletionNotDue if it was canceled or is not due
+	DeleteScheduled(ctx context.Context, id uint, before time.Time) error
+	ChangePassword(ctx context.Context, id uint, req *models.ChangePasswordRequest) error
+	RevokeTokens(ctx context.Context, id uint) error
+	LogoutAll(ctx context.Context, id uint, password string) error
+	GetAuthState(ctx context.Context, id uint) (*models.AuthState, error)
+	GetPendingReview(ctx context.Context) ([]models.UserResponse, error)
+	Export(ctx context.Context, emit func(user models.UserResponse) error) error
+	ApproveSignup(ctx context.Context, id uint) (*models.UserResponse, error)
+	GetLoginHistory(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error)
+	// ListSessions returns the user's live sessions, marking currentID as current
+	ListSessions(ctx context.Context, userID uint, currentID string) ([]models.Session, error)
+	// RevokeSession logs out one session; its token stops working immediately
+	RevokeSession(ctx context.Context, userID uint, id string) error
+	GetStats(ctx context.Context, id uint) (*models.AuthorStats, error)
+	// Lookup returns the users matching any of ids or usernames in two queries: the ID
+	// matches in request order, then the username matches. Unknown entries are skipped.
+	Lookup(ctx context.Context, req models.UserLookupRequest) ([]models.UserResponse, error)
+
+	// Ban deactivates a user and logs them out everywhere; they can no longer log in
+	Ban(ctx context.Context, id uint, reason string) (*models.UserResponse, error)
+	Unban(ctx context.Context, id uint) (*models.UserResponse, error)
+	// ForcePasswordReset replaces the password with a random one and logs the user out
+	// everywhere, so they must go through the reset flow to log in again
+	ForcePasswordReset(ctx context.Context, id uint) (*models.UserResponse, error)
+	// ChangeRole sets the user's role and logs them out everywhere
+	ChangeRole(ctx context.Context, id uint, role string) (*models.UserResponse, error)
+	GetDeleted(ctx context.Context, page models.PageRequest) ([]models.DeletedUserResponse, models.PageInfo, error)
+	// ListAll pages through every user not yet purged, soft-deleted ones included
+	ListAll(ctx context.Context, page models.PageRequest) ([]models.AdminUserResponse, models.PageInfo, error)
+	// Restore undoes a soft delete. Tokens issued before the delete stay revoked, and
+	// posts removed by the deletion cascade are not brought back.
+	Restore(ctx context.Context, id uint) (*models.UserResponse, error)
 }
 
 type userService struct {
-	repo      repository.UserRepository
-	redis     *redis.Client
-	jwtSecret string
+	repo        repository.UserRepository
+	logins      repository.LoginEventRepository
+	posts       repository.PostRepository
+	cache       *cache.Cache
+	tokens      TokenService
+	avatars     AvatarService
+	counter     PostCounter
+	sessions    SessionService
+	usernames   UsernamePolicy
+	passwords   *password.Hasher
+	deletion    DeletionPolicy
+	cachePolicy cache.Policy
+	// authPolicy caches auth state briefly so role and active changes apply quickly
+	authPolicy cache.Policy
 }
 
-func NewUserService(repo repository.UserRepository, redisClient *redis.Client) UserService {
+func NewUserService(repo repository.UserRepository, logins repository.LoginEventRepository, posts repository.PostRepository, cacheStore *cache.Cache, tokens TokenService, avatars AvatarService, counter PostCounter, sessions SessionService, usernames UsernamePolicy, passwords *password.Hasher, deletion DeletionPolicy, cachePolicy, authPolicy cache.Policy) UserService {
+	cachePolicy.NotFound = repository.ErrUserNotFound
+	authPolicy.NotFound = repository.ErrUserNotFound
 	return &userService{
-		repo:      repo,
-		redis:     redisClient,
-		jwtSecret: "your-secret-key-change-in-production",
+		repo:        repo,
+		logins:      logins,
+		posts:       posts,
+		cache:       cacheStore,
+		tokens:      tokens,
+		avatars:     avatars,
+		counter:     counter,
+		sessions:    sessions,
+		usernames:   usernames,
+		passwords:   passwords,
+		deletion:    deletion,
+		cachePolicy: cachePolicy,
+		authPolicy:  authPolicy,
 	}
 }
 
 func (s *userService) Register(ctx context.Context, req *models.RegisterRequest) (*models.UserResponse, error) {
 	var response models.UserResponse
+	req.Email = models.NormalizeEmail(req.Email)
+	if err := s.usernames.Check(req.Username); err != nil {
+		return nil, err
+	}
 
 	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
 		// Check if email exists
@@ -54,8 +132,14 @@ func (s *userService) Register(ctx context.Context, req *models.RegisterRequest)
 			FullName: req.FullName,
 		}
 
+		// Suspicious signups are created but held for review
+		if len(req.ReviewFlags) > 0 {
+			user.ReviewStatus = models.ReviewPending
+			user.ReviewFlags = strings.Join(req.ReviewFlags, ",")
+		}
+
 		// Hash password
-		if err := user.HashPassword(); err != nil {
+		if err := user.HashPassword(s.passwords); err != nil {
 			return err
 		}
 
@@ -63,114 +147,220 @@ func (s *userService) Register(ctx context.Context, req *models.RegisterRequest)
 			return err
 		}
 
-		response = user.ToResponse()
+		response = userResponse(ctx, s.avatars, user)
 		return nil
 	})
 
 	if err != nil {
-		logger.WithContext(ctx).Error("Failed to register user", "email", req.Email, "error", err)
+		logger.FromContext(ctx).Error("Failed to register user", "email", req.Email, "error", err)
 		return nil, err
 	}
 
-	logger.WithContext(ctx).Info("User registered successfully", "user_id", response.ID, "email", response.Email)
+	// Clear any tombstone left by a lookup of this ID before it existed
+	userChanged(ctx, s.cache, response.ID)
+
+	logger.FromContext(ctx).Info("User registered successfully", "user_id", response.ID, "email", response.Email)
 	return &response, nil
 }
 
 func (s *userService) Login(ctx context.Context, req *models.LoginRequest) (string, *models.UserResponse, error) {
-	user, err := s.repo.GetByEmail(ctx, req.Email)
+	user, err := s.repo.GetByEmail(ctx, models.NormalizeEmail(req.Email))
 	if err != nil {
 		return "", nil, errors.New("invalid credentials")
 	}
 
-	if !user.CheckPassword(req.Password) {
+	ok, needsRehash := user.CheckPassword(s.passwords, req.Password)
+	if !ok {
+		s.recordLogin(ctx, user.ID, req, models.LoginFailedPassword)
 		return "", nil, errors.New("invalid credentials")
 	}
+	if needsRehash {
+		s.rehashPassword(ctx, user.ID, req.Password)
+	}
+	return s.LoginVerified(ctx, user, req)
+}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24 hours
-	})
+func (s *userService) LoginVerified(ctx context.Context, user *models.User, req *models.LoginRequest) (string, *models.UserResponse, error) {
+	var err error
+	if user.ReviewStatus == models.ReviewPending {
+		s.recordLogin(ctx, user.ID, req, models.LoginFailedPending)
+		return "", nil, errors.New("account is pending review")
+	}
+	if !user.Active {
+		s.recordLogin(ctx, user.ID, req, models.LoginFailedInactive)
+		return "", nil, errors.New("account is deactivated")
+	}
+	if user.DeletionScheduledAt != nil {
+		if !req.Restore {
+			s.recordLogin(ctx, user.ID, req, models.LoginFailedPendingDeletion)
+			return "", nil, &PendingDeletionError{ScheduledAt: *user.DeletionScheduledAt}
+		}
+		if user, err = s.cancelDeletion(ctx, user.ID); err != nil {
+			return "", nil, err
+		}
+	}
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	// Generate JWT, tracked as a session so it can be listed and revoked
+	sessionID, err := newSessionID()
 	if err != nil {
-		logger.WithContext(ctx).Error("Failed to sign token", "error", err)
 		return "", nil, err
 	}
+	tokenString, err := s.tokens.Issue(user, sessionID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to sign token", "error", err)
+		return "", nil, err
+	}
+
+	now := time.Now()
+	err = s.sessions.Create(ctx, user.ID, &models.Session{
+		ID:        sessionID,
+		Device:    req.Device,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+		IssuedAt:  utctime.From(now),
+		ExpiresAt: utctime.From(now.Add(s.tokens.TTL())),
+	}, user.TokenVersion)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to record session", "user_id", user.ID, "error", err)
+		return "", nil, err
+	}
+	user.LastLoginAt = utctime.Ptr(now)
+	s.recordLogin(ctx, user.ID, req, "")
+	if err := s.repo.UpdateLastLogin(ctx, user.ID, now); err != nil {
+		logger.FromContext(ctx).Warn("Failed to update last login", "user_id", user.ID, "error", err)
+	}
+	s.cache.Delete(ctx, fmt.Sprintf("user:%d", user.ID))
 
-	logger.WithContext(ctx).Info("User logged in", "user_id", user.ID)
-	response := user.ToResponse()
+	logger.FromContext(ctx).Info("User logged in", "user_id", user.ID)
+	response := userResponse(ctx, s.avatars, user)
 	return tokenString, &response, nil
 }
 
 func (s *userService) GetByID(ctx context.Context, id uint) (*models.UserResponse, error) {
-	cacheKey := fmt.Sprintf("user:%d", id)
-
-	// 1. Try Cache
-	val, err := s.redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var cachedUser models.UserResponse
-		if err := json.Unmarshal([]byte(val), &cachedUser); err == nil {
-			return &cachedUser, nil
+	response, err := cache.ReadThrough(ctx, s.cache, fmt.Sprintf("user:%d", id), s.cachePolicy, func(ctx context.Context) (models.UserResponse, error) {
+		user, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return models.UserResponse{}, err
 		}
+		return userResponse(ctx, s.avatars, user), nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &response, nil
+}
 
-	// 2. Cache Miss - Query DB
-	user, err := s.repo.GetByID(ctx, id)
+func (s *userService) GetAll(ctx context.Context, query models.ListUsersQuery) ([]models.UserResponse, models.PageInfo, error) {
+	users, info, err := s.repo.GetAll(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
-	response := user.ToResponse()
 
-	// 3. Set Cache (TTL 10 mins)
-	if data, err := json.Marshal(response); err == nil {
-		s.redis.Set(ctx, cacheKey, data, 10*time.Minute)
+	// A page past the end is an empty list, not null
+	responses := make([]models.UserResponse, 0, len(users))
+	userIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, userResponse(ctx, s.avatars, &user))
+		userIDs = append(userIDs, user.ID)
 	}
 
-	return &response, nil
+	// Batch load post counts for the whole page (one query instead of one per user)
+	stats, errs := utils.LoadManyAuthorStats(ctx, userIDs)
+	for i := range stats {
+		if errs[i] != nil {
+			continue
+		}
+		responses[i].PostCount = &stats[i].PostCount
+	}
+	return responses, info, nil
 }
 
-func (s *userService) GetAll(ctx context.Context) ([]models.UserResponse, error) {
-	users, err := s.repo.GetAll(ctx)
-	if err != nil {
-		return nil, err
+func (s *userService) Lookup(ctx context.Context, req models.UserLookupRequest) ([]models.UserResponse, error) {
+	if len(req.IDs) == 0 && len(req.Usernames) == 0 {
+		return nil, &ValidationError{Fields: []FieldError{{Field: "ids", Message: "ids or usernames is required"}}}
+	}
+	if len(req.IDs)+len(req.Usernames) > models.MaxUserLookup {
+		return nil, &ValidationError{Fields: []FieldError{{Field: "ids", Message: fmt.Sprintf("at most %d ids and usernames in total", models.MaxUserLookup)}}}
 	}
 
-	var responses []models.UserResponse
-	for _, user := range users {
-		responses = append(responses, user.ToResponse())
+	responses := make([]models.UserResponse, 0, len(req.IDs)+len(req.Usernames))
+	seen := make(map[uint]bool, cap(responses))
+	if len(req.IDs) > 0 {
+		byID, err := s.repo.GetUsersByIDs(ctx, req.IDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range req.IDs {
+			if user, ok := byID[id]; ok && !seen[id] {
+				seen[id] = true
+				responses = append(responses, userResponse(ctx, s.avatars, user))
+			}
+		}
+	}
+	if len(req.Usernames) > 0 {
+		users, err := s.repo.GetByUsernames(ctx, req.Usernames)
+		if err != nil {
+			return nil, err
+		}
+		byName := make(map[string]*models.User, len(users))
+		for i := range users {
+			byName[users[i].Username] = &users[i]
+		}
+		for _, name := range req.Usernames {
+			if user, ok := byName[name]; ok && !seen[user.ID] {
+				seen[user.ID] = true
+				responses = append(responses, userResponse(ctx, s.avatars, user))
+			}
+		}
 	}
 	return responses, nil
 }
 
-func (s *userService) Update(ctx context.Context, id uint, updates *models.User) (*models.UserResponse, error) {
+// GetStats returns a user's author statistics
+func (s *userService) GetStats(ctx context.Context, id uint) (*models.AuthorStats, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	stats, err := utils.LoadAuthorStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Update applies updates only if the user is still at version, the one the client last read
+func (s *userService) Update(ctx context.Context, id uint, version uint, updates *models.User) (*models.UserResponse, error) {
 	// Start a transaction for update (even though it's single record, good practice)
 	var response models.UserResponse
 	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
-		user, err := s.repo.GetByID(txCtx, id)
+		user, err := s.repo.GetByID(txCtx, id, repository.LockForUpdate())
 		if err != nil {
 			return err
 		}
+		if user.Version != version {
+			return ErrVersionConflict
+		}
 
 		// Update fields
 		if updates.FullName != "" {
 			user.FullName = updates.FullName
 		}
-		if updates.Username != "" {
+		if updates.Username != "" && updates.Username != user.Username {
+			if err := s.usernames.Check(updates.Username); err != nil {
+				return err
+			}
 			user.Username = updates.Username
 		}
+		if updates.AvatarURL != "" {
+			user.AvatarURL = updates.AvatarURL
+		}
 
 		if err := s.repo.Update(txCtx, user); err != nil {
 			return err
 		}
 
-		// Invalidate cache
-		cacheKey := fmt.Sprintf("user:%d", id)
-		s.redis.Del(ctx, cacheKey)
-
-		response = user.ToResponse()
+		response = userResponse(ctx, s.avatars, user)
 		return nil
 	})
 
@@ -178,13 +368,398 @@ func (s *userService) Update(ctx context.Context, id uint, updates *models.User)
 		return nil, err
 	}
 
+	// Invalidate cache once the update is committed
+	userChanged(ctx, s.cache, id)
+
 	return &response, nil
 }
 
+// Delete removes a user and, in the same transaction, deletes, reassigns or keeps their
+// posts according to the deletion policy
 func (s *userService) Delete(ctx context.Context, id uint) error {
-	if err := s.repo.Delete(ctx, id); err != nil {
+	return s.delete(ctx, id, func(*models.User) error { return nil })
+}
+
+func (s *userService) DeleteScheduled(ctx context.Context, id uint, before time.Time) error {
+	// The check runs on the locked row, so a restore that commits first always wins
+	return s.delete(ctx, id, func(user *models.User) error {
+		if user.DeletionScheduledAt == nil || user.DeletionScheduledAt.After(before) {
+			return ErrDeletionNotDue
+		}
+		return nil
+	})
+}
+
+// delete runs the deletion cascade for user id if check accepts the locked row
+func (s *userService) delete(ctx context.Context, id uint, check func(user *models.User) error) error {
+	var postIDs []uint
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.repo.GetByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+		if err := check(user); err != nil {
+			return err
+		}
+		postIDs, err = s.cascade(txCtx, user)
 		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Invalidate cache once the cascade is committed
+	authors := []uint{id}
+	if s.deletion.PostAction == ReassignPosts {
+		authors = append(authors, s.deletion.ReassignTo)
+	}
+	s.counter.Forget(ctx, authors...)
+	if err := postsChanged(ctx, s.cache, postIDs, authors...); err != nil {
+		return err
+	}
+	return userChanged(ctx, s.cache, id)
+}
+
+// ChangePassword verifies the current password, stores the new hash and revokes all issued tokens
+func (s *userService) ChangePassword(ctx context.Context, id uint, req *models.ChangePasswordRequest) error {
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.repo.GetByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+
+		if ok, _ := user.CheckPassword(s.passwords, req.CurrentPassword); !ok {
+			return errors.New("current password is incorrect")
+		}
+
+		user.Password = req.NewPassword
+		if err := user.HashPassword(s.passwords); err != nil {
+			return err
+		}
+
+		if err := s.repo.Update(txCtx, user); err != nil {
+			return err
+		}
+
+		return s.repo.IncrementTokenVersion(txCtx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("User password changed", "user_id", id)
+	return userChanged(ctx, s.cache, id)
+}
+
+// RevokeTokens forces a logout everywhere by bumping the user's token version
+func (s *userService) RevokeTokens(ctx context.Context, id uint) error {
+	if err := s.repo.IncrementTokenVersion(ctx, id); err != nil {
+		return err
+	}
+
+	// The version bump already rejects every token; this only clears the session list
+	if err := s.sessions.RevokeAll(ctx, id); err != nil {
+		logger.FromContext(ctx).Warn("Failed to clear sessions", "user_id", id, "error", err)
+	}
+
+	logger.FromContext(ctx).Info("User tokens revoked", "user_id", id)
+	return userChanged(ctx, s.cache, id)
+}
+
+func (s *userService) ListSessions(ctx context.Context, userID uint, currentID string) ([]models.Session, error) {
+	state, err := s.GetAuthState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.List(ctx, userID, state.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+func (s *userService) RevokeSession(ctx context.Context, userID uint, id string) error {
+	return s.sessions.Revoke(ctx, userID, id)
+}
+
+// LogoutAll re-confirms the user's password and revokes every token issued to them
+func (s *userService) LogoutAll(ctx context.Context, id uint, password string) error {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := user.CheckPassword(s.passwords, password); !ok {
+		return errors.New("invalid credentials")
+	}
+
+	return s.RevokeTokens(ctx, id)
+}
+
+// recordLogin appends to the user's login history; an empty reason means success.
+// History is best effort and never blocks a login.
+func (s *userService) recordLogin(ctx context.Context, userID uint, req *models.LoginRequest, reason string) {
+	userAgent := req.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = strings.ToValidUTF8(userAgent[:512], "")
 	}
-	// Invalidate cache
-	return s.redis.Del(ctx, fmt.Sprintf("user:%d", id)).Err()
+
+	event := &models.LoginEvent{
+		UserID:    userID,
+		IP:        req.IP,
+		UserAgent: userAgent,
+		Success:   reason == "",
+		Reason:    reason,
+	}
+	if err := s.logins.Create(ctx, event); err != nil {
+		logger.FromContext(ctx).Warn("Failed to record login event", "user_id", userID, "error", err)
+	}
+}
+
+// GetLoginHistory returns the user's most recent login attempts, newest first
+func (s *userService) GetLoginHistory(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error) {
+	return s.logins.GetRecentByUserID(ctx, userID, limit)
+}
+
+// rehashPassword upgrades a stored hash to the configured algorithm and parameters
+// while the plaintext is at hand. Failure only delays the upgrade to the next login.
+func (s *userService) rehashPassword(ctx context.Context, id uint, plain string) {
+	hash, err := s.passwords.Hash(plain)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, id, hash)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to upgrade password hash", "user_id", id, "error", err)
+		return
+	}
+	logger.FromContext(ctx).Info("Password hash upgraded", "user_id", id)
+}
+
+// GetAuthState returns the security-relevant user state (token version, plan, role, active) checked by JWTAuth on every request
+func (s *userService) GetAuthState(ctx context.Context, id uint) (*models.AuthState, error) {
+	state, err := cache.ReadThrough(ctx, s.cache, authStateCacheKey(id), s.authPolicy, func(ctx context.Context) (models.AuthState, error) {
+		user, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return models.AuthState{}, err
+		}
+		return user.ToAuthState(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func authStateCacheKey(id uint) string {
+	return fmt.Sprintf("user:%d:auth", id)
+}
+
+// GetPendingReview lists signups flagged by bot detection
+func (s *userService) GetPendingReview(ctx context.Context) ([]models.UserResponse, error) {
+	users, err := s.repo.GetByReviewStatus(ctx, models.ReviewPending)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, userResponse(ctx, s.avatars, &user))
+	}
+	return responses, nil
+}
+
+// ApproveSignup releases a flagged signup so the user can log in
+func (s *userService) ApproveSignup(ctx context.Context, id uint) (*models.UserResponse, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ReviewStatus != models.ReviewPending {
+		return nil, errors.New("user is not pending review")
+	}
+
+	user.ReviewStatus = ""
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	userChanged(ctx, s.cache, id)
+
+	logger.FromContext(ctx).Info("Signup approved", "user_id", id, "flags", user.ReviewFlags)
+	response := userResponse(ctx, s.avatars, user)
+	return &response, nil
+}
+
+// Export streams every user without loading them all into memory. Avatars are not
+// resolved, so exports never call Gravatar.
+func (s *userService) Export(ctx context.Context, emit func(user models.UserResponse) error) error {
+	return s.repo.Each(ctx, func(user *models.User) error {
+		return emit(user.ToResponse())
+	})
+}
+
+func (s *userService) Ban(ctx context.Context, id uint, reason string) (*models.UserResponse, error) {
+	response, err := s.adminUpdate(ctx, id, true, func(user *models.User) error {
+		user.Active = false
+		return nil
+	})
+	if err == nil {
+		logger.FromContext(ctx).Info("User banned", "user_id", id, "reason", reason)
+	}
+	return response, err
+}
+
+func (s *userService) Unban(ctx context.Context, id uint) (*models.UserResponse, error) {
+	response, err := s.adminUpdate(ctx, id, false, func(user *models.User) error {
+		user.Active = true
+		return nil
+	})
+	if err == nil {
+		logger.FromContext(ctx).Info("User unbanned", "user_id", id)
+	}
+	return response, err
+}
+
+func (s *userService) ForcePasswordReset(ctx context.Context, id uint) (*models.UserResponse, error) {
+	response, err := s.adminUpdate(ctx, id, true, func(user *models.User) error {
+		// Nobody knows the random password, so only a reset link gets the user back in
+		random, err := newRandomToken()
+		if err != nil {
+			return err
+		}
+		user.Password = random
+		return user.HashPassword(s.passwords)
+	})
+	if err == nil {
+		logger.FromContext(ctx).Info("Password reset forced", "user_id", id)
+	}
+	return response, err
+}
+
+func (s *userService) ChangeRole(ctx context.Context, id uint, role string) (*models.UserResponse, error) {
+	// Tokens carry the role, so they are revoked like on any other privilege change
+	response, err := s.adminUpdate(ctx, id, true, func(user *models.User) error {
+		user.Role = role
+		return nil
+	})
+	if err == nil {
+		logger.FromContext(ctx).Info("User role changed", "user_id", id, "role", role)
+	}
+	return response, err
+}
+
+// adminUpdate applies change to user id under a row lock. With revoke, every token and
+// session of the user is revoked in the same transaction.
+func (s *userService) adminUpdate(ctx context.Context, id uint, revoke bool, change func(user *models.User) error) (*models.UserResponse, error) {
+	var response models.UserResponse
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.repo.GetByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+		if err := change(user); err != nil {
+			return err
+		}
+		if err := s.repo.Update(txCtx, user); err != nil {
+			return err
+		}
+		if revoke {
+			if err := s.repo.IncrementTokenVersion(txCtx, id); err != nil {
+				return err
+			}
+		}
+
+		response = userResponse(ctx, s.avatars, user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if revoke {
+		if err := s.sessions.RevokeAll(ctx, id); err != nil {
+			logger.FromContext(ctx).Warn("Failed to clear sessions", "user_id", id, "error", err)
+		}
+	}
+	// Dropping the cached auth state makes the change apply on the user's next request
+	userChanged(ctx, s.cache, id)
+	return &response, nil
+}
+
+// GetDeleted lists soft-deleted users until the retention job purges them
+func (s *userService) GetDeleted(ctx context.Context, page models.PageRequest) ([]models.DeletedUserResponse, models.PageInfo, error) {
+	users, info, err := s.repo.GetDeleted(ctx, page)
+	if err != nil {
+		return nil, info, err
+	}
+
+	responses := make([]models.DeletedUserResponse, len(users))
+	for i, user := range users {
+		responses[i] = models.DeletedUserResponse{
+			UserResponse: user.ToResponse(),
+			DeletedAt:    utctime.From(user.DeletedAt.Time),
+		}
+	}
+	return responses, info, nil
+}
+
+func (s *userService) ListAll(ctx context.Context, page models.PageRequest) ([]models.AdminUserResponse, models.PageInfo, error) {
+	users, info, err := s.repo.GetAllWithDeleted(ctx, page)
+	if err != nil {
+		return nil, info, err
+	}
+
+	responses := make([]models.AdminUserResponse, len(users))
+	for i, user := range users {
+		responses[i] = models.AdminUserResponse{UserResponse: user.ToResponse()}
+		if user.DeletedAt.Valid {
+			responses[i].DeletedAt = utctime.Ptr(user.DeletedAt.Time)
+		}
+	}
+	return responses, info, nil
+}
+
+func (s *userService) Restore(ctx context.Context, id uint) (*models.UserResponse, error) {
+	var response models.UserResponse
+	err := s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.repo.GetDeletedByID(txCtx, id, repository.LockForUpdate())
+		if err != nil {
+			return err
+		}
+		// Someone may have signed up with the email since; two live accounts must not share it
+		if _, err := s.repo.GetByEmail(txCtx, user.Email); err == nil {
+			return ErrEmailInUse
+		} else if !errors.Is(err, repository.ErrUserNotFound) {
+			return err
+		}
+		// Likewise for the username, whose unique index would otherwise fail the restore with a raw error
+		if taken, err := s.repo.GetByUsernames(txCtx, []string{user.Username}); err != nil {
+			return err
+		} else if len(taken) > 0 {
+			return ErrUsernameInUse
+		}
+		if err := s.repo.Restore(txCtx, id); err != nil {
+			return err
+		}
+		// Tokens from before the delete must not come back to life with the account
+		if err := s.repo.IncrementTokenVersion(txCtx, id); err != nil {
+			return err
+		}
+
+		restored, err := s.repo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+		response = userResponse(ctx, s.avatars, restored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("User restored", "user_id", id)
+	userChanged(ctx, s.cache, id)
+	return &response, nil
 }
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UsernamePolicy rejects malformed usernames and names reserved for the system
+type UsernamePolicy struct {
+	// Reserved names are matched case-insensitively, ignoring '.', '_' and '-'
+	// so "Ad_min" cannot stand in for "admin"
+	Reserved []string
+	// Pattern must match the whole username; nil accepts any characters
+	Pattern *regexp.Regexp
+}
+
+// Check returns a *ValidationError for the username field, or nil
+func (p UsernamePolicy) Check(username string) error {
+	if p.Pattern != nil && !p.Pattern.MatchString(username) {
+		return &ValidationError{Fields: []FieldError{{Field: "username", Message: "contains characters that are not allowed"}}}
+	}
+
+	key := reservedKey(username)
+	for _, reserved := range p.Reserved {
+		if key == reservedKey(reserved) {
+			return &ValidationError{Fields: []FieldError{{Field: "username", Message: "is reserved"}}}
+		}
+	}
+	return nil
+}
+
+func reservedKey(name string) string {
+	return strings.NewReplacer(".", "", "_", "", "-", "").Replace(strings.ToLower(name))
+}
@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"goapi/pkg/i18n"
+)
+
+//go:embed html/*.html
+var files embed.FS
+
+// Email template names
+const (
+	Welcome       = "welcome"
+	Verification  = "verification"
+	PasswordReset = "reset"
+	MagicLink     = "magic_link"
+	Digest        = "digest"
+	Deletion      = "deletion"
+)
+
+// WelcomeData is rendered by the welcome email
+type WelcomeData struct {
+	Name   string
+	AppURL string
+}
+
+// LinkData is rendered by emails built around a single time-limited link (verification, reset, magic link)
+type LinkData struct {
+	Name      string
+	Link      string
+	ExpiresIn string
+}
+
+// DeletionData is rendered by the reminder before a scheduled account deletion
+type DeletionData struct {
+	Name string
+	Link string
+	Date string // When the account is erased
+}
+
+// DigestData is rendered by the notification digest email
+type DigestData struct {
+	Name   string
+	Period string // "daily" or "weekly"
+	Items  []DigestItem
+}
+
+type DigestItem struct {
+	Title   string
+	Summary string
+	URL     string
+}
+
+// Email is a rendered, localized message ready to hand to a mailer
+type Email struct {
+	Subject string
+	HTML    string
+}
+
+type button struct {
+	URL   string
+	Label string
+}
+
+// Renderer renders the embedded email templates inside the shared layout
+type Renderer struct {
+	bundle *i18n.Bundle
+	pages  map[string]*template.Template
+}
+
+func NewRenderer(bundle *i18n.Bundle) (*Renderer, error) {
+	// Placeholders so templates parse; they are rebound per language in Render
+	funcs := template.FuncMap{
+		"t":       func(key string, args ...any) string { return key },
+		"lang":    func() string { return i18n.DefaultLanguage },
+		"subject": func() string { return "" },
+		"button":  func(url, label string) button { return button{URL: url, Label: label} },
+	}
+
+	r := &Renderer{bundle: bundle, pages: make(map[string]*template.Template)}
+	for _, name := range []string{Welcome, Verification, PasswordReset, MagicLink, Digest, Deletion} {
+		tmpl, err := template.New(name).Funcs(funcs).ParseFS(files, "html/layout.html", "html/button.html", "html/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("templates: parse %s: %w", name, err)
+		}
+		r.pages[name] = tmpl
+	}
+	return r, nil
+}
+
+// Names lists the available email templates
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.pages))
+	for name := range r.pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render produces the localized subject and HTML body of an email
+func (r *Renderer) Render(name, lang string, data any) (*Email, error) {
+	page, ok := r.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("templates: unknown email %q", name)
+	}
+
+	subject := r.subject(name, lang, data)
+	tmpl, err := page.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(template.FuncMap{
+		"t":       func(key string, args ...any) string { return r.bundle.T(lang, key, args...) },
+		"lang":    func() string { return lang },
+		"subject": func() string { return subject },
+	})
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return nil, fmt.Errorf("templates: render %s: %w", name, err)
+	}
+	return &Email{Subject: subject, HTML: buf.String()}, nil
+}
+
+func (r *Renderer) subject(name, lang string, data any) string {
+	if digest, ok := data.(DigestData); ok {
+		period := r.bundle.T(lang, "email.digest.period."+digest.Period)
+		return r.bundle.T(lang, "email.digest.subject", period)
+	}
+	return r.bundle.T(lang, "email."+name+".subject")
+}
+
+// SampleData returns placeholder data for previewing a template
+func SampleData(name string) any {
+	switch name {
+	case Welcome:
+		return WelcomeData{Name: "Jane Doe", AppURL: "http://localhost:3000"}
+	case Verification:
+		return LinkData{Name: "Jane Doe", Link: "http://localhost:3000/verify?token=sample", ExpiresIn: "24h"}
+	case PasswordReset:
+		return LinkData{Name: "Jane Doe", Link: "http://localhost:3000/reset?token=sample", ExpiresIn: "1h"}
+	case MagicLink:
+		return LinkData{Name: "Jane Doe", Link: "http://localhost:8080/api/v1/login/magic?token=sample", ExpiresIn: "15m"}
+	case Deletion:
+		return DeletionData{Name: "Jane Doe", Link: "http://localhost:3000/login", Date: "2 January 2026 15:04 UTC"}
+	case Digest:
+		return DigestData{Name: "Jane Doe", Period: "daily", Items: []DigestItem{
+			{Title: "Your post got a new comment", Summary: "\"Great write-up!\"", URL: "http://localhost:3000/posts/1"},
+			{Title: "John Smith published a new post", URL: "http://localhost:3000/posts/2"},
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ugorji/go/codec"
+)
+
+// Every stored value starts with a format byte so the encoding can evolve: entries in
+// an unknown format (including pre-msgpack JSON) are treated as misses and rewritten.
+const (
+	formatMsgpack     byte = 1
+	formatMsgpackGzip byte = 2
+	formatMissing     byte = 3 // negative-cache tombstone, no payload
+)
+
+// ErrMissing is returned by Get when the key holds a tombstone written by SetMissing
+var ErrMissing = errors.New("cache: cached as missing")
+
+const flushBatchSize = 500
+
+// msgpack is safe for concurrent use. Struct fields use their json tags; WriteExt
+// enables the timestamp extension so time.Time round-trips.
+var msgpack = &codec.MsgpackHandle{WriteExt: true}
+
+// Options tunes how values are stored
+type Options struct {
+	// Namespace prefixes every key (see Namespace); changing it orphans all old entries
+	Namespace string
+	// CompressThreshold is the encoded size in bytes above which values are gzipped; 0 disables
+	CompressThreshold int
+	// NegativeTTL is how long SetMissing remembers that a record does not exist
+	NegativeTTL time.Duration
+}
+
+// Cache stores msgpack-encoded values in Redis, compressing large ones transparently
+type Cache struct {
+	client *redis.Client
+	opts   Options
+}
+
+func New(client *redis.Client, opts Options) *Cache {
+	return &Cache{client: client, opts: opts}
+}
+
+// Get decodes the value at key into dest. found is false on a miss; err is ErrMissing
+// when the record is known not to exist.
+func (c *Cache) Get(ctx context.Context, key string, dest any) (found bool, err error) {
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return decode(data, dest)
+}
+
+// Set encodes value and stores it at key for ttl
+func (c *Cache) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.key(key), data, ttl).Err()
+}
+
+// SetMissing caches that the record at key does not exist, so repeated lookups of
+// unknown IDs skip the database for NegativeTTL
+func (c *Cache) SetMissing(ctx context.Context, key string) error {
+	if c.opts.NegativeTTL <= 0 {
+		return nil
+	}
+	return c.client.Set(ctx, c.key(key), []byte{formatMissing}, c.opts.NegativeTTL).Err()
+}
+
+// Delete removes keys
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = c.key(key)
+	}
+	return c.client.Del(ctx, full...).Err()
+}
+
+// Flush deletes every key of the current namespace matching pattern (a Redis glob such
+// as "post:*"; empty means all). It scans incrementally, so Redis is never blocked.
+func (c *Cache) Flush(ctx context.Context, pattern string) (int64, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var deleted int64
+	iter := c.client.Scan(ctx, 0, c.key(pattern), flushBatchSize).Iterator()
+	batch := make([]string, 0, flushBatchSize)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == flushBatchSize {
+			n, err := c.client.Unlink(ctx, batch...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if len(batch) > 0 {
+		n, err := c.client.Unlink(ctx, batch...).Result()
+		deleted += n
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// Namespace returns the key prefix in use
+func (c *Cache) Namespace() string {
+	return c.opts.Namespace
+}
+
+func (c *Cache) key(key string) string {
+	if c.opts.Namespace == "" {
+		return key
+	}
+	return c.opts.Namespace + ":" + key
+}
+
+func (c *Cache) encode(value any) ([]byte, error) {
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, msgpack).Encode(value); err != nil {
+		return nil, err
+	}
+	if c.opts.CompressThreshold <= 0 || len(encoded) <= c.opts.CompressThreshold {
+		return append([]byte{formatMsgpack}, encoded...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(formatMsgpackGzip)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(encoded); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte, dest any) (bool, error) {
+	if len(data) == 0 {
+		return false, nil
+	}
+
+	payload := data[1:]
+	switch data[0] {
+	case formatMissing:
+		return false, ErrMissing
+	case formatMsgpack:
+	case formatMsgpackGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return false, err
+		}
+		defer zr.Close()
+		if payload, err = io.ReadAll(zr); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+
+	if err := codec.NewDecoderBytes(payload, msgpack).Decode(dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
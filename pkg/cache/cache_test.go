@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type entry struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newTestCache(t *testing.T, opts Options) (*Cache, *redis.Client) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, opts), client
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	small := entry{ID: 1, Name: "a", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	large := entry{ID: 2, Name: strings.Repeat("x", 2048), Tags: []string{"go", "redis"}, CreatedAt: small.CreatedAt}
+
+	tests := []struct {
+		name       string
+		value      entry
+		wantFormat byte
+	}{
+		{"below threshold stored plain", small, formatMsgpack},
+		{"above threshold gzipped", large, formatMsgpackGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c, client := newTestCache(t, Options{Namespace: "app", CompressThreshold: 256})
+			if err := c.Set(ctx, "k", tt.value, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, _ := client.Get(ctx, "app:k").Bytes()
+			if raw[0] != tt.wantFormat {
+				t.Fatalf("got format %d, want %d", raw[0], tt.wantFormat)
+			}
+			var got entry
+			found, err := c.Get(ctx, "k", &got)
+			if !found || err != nil {
+				t.Fatalf("got %v %v, want a hit", found, err)
+			}
+			if got.ID != tt.value.ID || got.Name != tt.value.Name || len(got.Tags) != len(tt.value.Tags) || !got.CreatedAt.Equal(tt.value.CreatedAt) {
+				t.Fatalf("got %+v, want %+v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestOldFormatIsAMiss(t *testing.T) {
+	ctx := context.Background()
+	c, client := newTestCache(t, Options{})
+
+	// Entries written before the format byte were plain JSON
+	old, _ := json.Marshal(entry{ID: 1, Name: "old"})
+	client.Set(ctx, "k", old, time.Minute)
+	var got entry
+	if found, err := c.Get(ctx, "k", &got); found || err != nil {
+		t.Fatalf("got %v %v, want a miss", found, err)
+	}
+
+	// ReadThrough treats it as a miss and rewrites it in the current format
+	loads := 0
+	load := func(context.Context) (entry, error) {
+		loads++
+		return entry{ID: 1, Name: "new"}, nil
+	}
+	for range 2 {
+		if got, err := ReadThrough(ctx, c, "k", Policy{TTL: time.Minute}, load); err != nil || got.Name != "new" {
+			t.Fatalf("got %+v %v, want the loaded value", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("got %d loads, want 1", loads)
+	}
+	if raw, _ := client.Get(ctx, "k").Bytes(); raw[0] != formatMsgpack {
+		t.Fatalf("got format %d, want the entry rewritten as msgpack", raw[0])
+	}
+}
+
+func TestReadThroughCachesNotFound(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(t, Options{NegativeTTL: time.Minute})
+	errNotFound := errors.New("not found")
+
+	loads := 0
+	load := func(context.Context) (entry, error) {
+		loads++
+		return entry{}, errNotFound
+	}
+	for range 2 {
+		if _, err := ReadThrough(ctx, c, "k", Policy{TTL: time.Minute, NotFound: errNotFound}, load); !errors.Is(err, errNotFound) {
+			t.Fatalf("got %v, want errNotFound", err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("got %d loads, want the tombstone to answer the second lookup", loads)
+	}
+	var got entry
+	if _, err := c.Get(ctx, "k", &got); !errors.Is(err, ErrMissing) {
+		t.Fatalf("got %v, want ErrMissing", err)
+	}
+}
+
+func TestInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	c, client := newTestCache(t, Options{Namespace: "app"})
+
+	c.SetTagged(ctx, "list:1", []uint{1, 2}, time.Minute, "user:1", "user:2")
+	c.SetTagged(ctx, "list:2", []uint{2}, time.Hour, "user:2")
+	c.SetTagged(ctx, "list:3", []uint{3}, time.Minute, "user:3")
+	c.Set(ctx, "plain", 1, time.Minute)
+
+	// The tag set lives as long as its longest-lived entry
+	if ttl := client.TTL(ctx, "app:tag:user:2").Val(); ttl != time.Hour {
+		t.Fatalf("got tag TTL %v, want 1h", ttl)
+	}
+
+	if err := c.Invalidate(ctx, Invalidation{Keys: []string{"plain"}, Tags: []string{"user:2"}}); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"list:1": false, "list:2": false, "plain": false, "list:3": true} {
+		var got []uint
+		if found, _ := c.Get(ctx, key, &got); found != want {
+			t.Fatalf("%s: got found %v, want %v", key, found, want)
+		}
+	}
+	if client.Exists(ctx, "app:tag:user:2").Val() != 0 {
+		t.Fatal("tag set was not dropped")
+	}
+
+	// Entries tagged after the invalidation land in a fresh set
+	c.SetTagged(ctx, "list:1", []uint{1}, time.Minute, "user:2")
+	c.InvalidateTags(ctx, "user:2")
+	var got []uint
+	if found, _ := c.Get(ctx, "list:1", &got); found {
+		t.Fatal("entry tagged after the first invalidation survived the second")
+	}
+}
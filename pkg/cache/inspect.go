@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyInfo describes a cache entry for operators
+type KeyInfo struct {
+	// Key is relative to the namespace, as passed to Get and Delete
+	Key  string `json:"key"`
+	Type string `json:"type"` // "string" for values, "set" for tag indexes
+	// TTL is the remaining lifetime in seconds, -1 when the key does not expire
+	TTL int64 `json:"ttl"`
+	// Size is the stored size in bytes for values, the member count for sets
+	Size int64 `json:"size"`
+	// Missing marks a negative-cache tombstone written by SetMissing
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Keys lists entries of the current namespace matching pattern (a Redis glob; empty
+// means all), resuming the SCAN at cursor. It scans until it has at least limit keys or
+// the scan is complete, so a page can hold slightly more than limit. The returned
+// cursor is 0 once every key has been visited.
+func (c *Cache) Keys(ctx context.Context, pattern string, cursor uint64, limit int) ([]KeyInfo, uint64, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var keys []string
+	for {
+		page, next, err := c.client.Scan(ctx, cursor, c.key(pattern), int64(limit)).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		keys, cursor = append(keys, page...), next
+		if cursor == 0 || len(keys) >= limit {
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return []KeyInfo{}, cursor, nil
+	}
+
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	if _, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			types[i] = pipe.Type(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	// Sizes depend on the type; keys that expired since the scan report type "none"
+	sizes := make([]*redis.IntCmd, len(keys))
+	heads := make([]*redis.StringCmd, len(keys))
+	if _, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			switch types[i].Val() {
+			case "string":
+				sizes[i] = pipe.StrLen(ctx, key)
+				heads[i] = pipe.GetRange(ctx, key, 0, 0)
+			case "set":
+				sizes[i] = pipe.SCard(ctx, key)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	infos := make([]KeyInfo, 0, len(keys))
+	for i, key := range keys {
+		info := KeyInfo{
+			Key:  c.relative(key),
+			Type: types[i].Val(),
+			TTL:  -1,
+		}
+		if info.Type == "none" {
+			continue
+		}
+		if ttl := ttls[i].Val(); ttl >= 0 {
+			info.TTL = int64(ttl.Round(time.Second) / time.Second)
+		}
+		if sizes[i] != nil {
+			info.Size = sizes[i].Val()
+		}
+		if heads[i] != nil {
+			info.Missing = heads[i].Val() == string([]byte{formatMissing})
+		}
+		infos = append(infos, info)
+	}
+	return infos, cursor, nil
+}
+
+// Evict removes a single entry, reporting whether it existed
+func (c *Cache) Evict(ctx context.Context, key string) (bool, error) {
+	n, err := c.client.Unlink(ctx, c.key(key)).Result()
+	return n > 0, err
+}
+
+func (c *Cache) relative(key string) string {
+	if c.opts.Namespace == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, c.opts.Namespace+":")
+}
+
+// NamespaceUsage is the memory held by one cache namespace
+type NamespaceUsage struct {
+	Namespace string `json:"namespace"`
+	Keys      int64  `json:"keys"`
+	// Bytes is the sum of MEMORY USAGE over the keys, Redis overhead included
+	Bytes int64 `json:"bytes"`
+	// Current marks the namespace this build reads and writes. Others are left over
+	// from earlier deploys and shrink as their entries expire.
+	Current bool `json:"current"`
+}
+
+// Usage measures every namespace sharing the current one's app prefix (the part
+// before the first colon), largest first. It SCANs the whole prefix, so run it from a
+// background job or an admin request, not on a hot path.
+func (c *Cache) Usage(ctx context.Context) ([]NamespaceUsage, error) {
+	current := c.opts.Namespace
+	pattern, depth := "*", 1
+	if current != "" {
+		app, _, _ := strings.Cut(current, ":")
+		pattern, depth = app+":*", strings.Count(current, ":")+1
+	}
+
+	usage := make(map[string]*NamespaceUsage)
+	measure := func(keys []string) error {
+		sizes := make([]*redis.IntCmd, len(keys))
+		if _, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				sizes[i] = pipe.MemoryUsage(ctx, key)
+			}
+			return nil
+		}); err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		for i, key := range keys {
+			namespace := ""
+			if current != "" {
+				parts := strings.SplitN(key, ":", depth+1)
+				namespace = strings.Join(parts[:min(depth, len(parts))], ":")
+			}
+			u, ok := usage[namespace]
+			if !ok {
+				u = &NamespaceUsage{Namespace: namespace, Current: namespace == current}
+				usage[namespace] = u
+			}
+			// Keys that expired since the scan report redis.Nil and count as empty
+			u.Keys++
+			u.Bytes += sizes[i].Val()
+		}
+		return nil
+	}
+
+	iter := c.client.Scan(ctx, 0, pattern, flushBatchSize).Iterator()
+	batch := make([]string, 0, flushBatchSize)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == flushBatchSize {
+			if err := measure(batch); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if len(batch) > 0 {
+		if err := measure(batch); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]NamespaceUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Bytes > result[j].Bytes })
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Invalidation lists everything one change makes stale: plain keys and tags (see SetTagged)
+type Invalidation struct {
+	Keys []string
+	Tags []string
+}
+
+// Invalidate drops all keys and tagged entries of inv. Keys, tag sets and the lookup
+// of tagged entries go out in one transactional pipeline; the tagged entries found
+// are then unlinked in a second round trip.
+func (c *Cache) Invalidate(ctx context.Context, inv Invalidation) error {
+	if len(inv.Keys) == 0 && len(inv.Tags) == 0 {
+		return nil
+	}
+
+	pipe := c.client.TxPipeline()
+	members := make([]*redis.StringSliceCmd, len(inv.Tags))
+	for i, tag := range inv.Tags {
+		// Read and drop each set atomically so entries tagged concurrently land in a fresh set
+		members[i] = pipe.SMembers(ctx, c.tagKey(tag))
+	}
+	stale := make([]string, 0, len(inv.Keys)+len(inv.Tags))
+	for _, key := range inv.Keys {
+		stale = append(stale, c.key(key))
+	}
+	for _, tag := range inv.Tags {
+		stale = append(stale, c.tagKey(tag))
+	}
+	pipe.Unlink(ctx, stale...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	var tagged []string
+	for _, cmd := range members {
+		tagged = append(tagged, cmd.Val()...)
+	}
+	if len(tagged) == 0 {
+		return nil
+	}
+	return c.client.Unlink(ctx, tagged...).Err()
+}
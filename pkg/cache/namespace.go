@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Namespace builds "<app>:v<schemaVersion>:<shape>", where shape fingerprints the
+// field names, types and tags of the cached types. A deploy that changes a cached
+// response shape therefore starts a fresh namespace instead of decoding stale entries.
+func Namespace(app string, schemaVersion int, cached ...any) string {
+	var b strings.Builder
+	seen := make(map[reflect.Type]bool)
+	for _, v := range cached {
+		describe(&b, reflect.TypeOf(v), seen)
+		b.WriteByte(';')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return fmt.Sprintf("%s:v%d:%s", app, schemaVersion, hex.EncodeToString(sum[:4]))
+}
+
+// describe writes a canonical description of t, recursing into named struct types once
+func describe(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		b.WriteString(t.Kind().String() + "<")
+		describe(b, t.Elem(), seen)
+		b.WriteString(">")
+	case reflect.Map:
+		b.WriteString("map<")
+		describe(b, t.Key(), seen)
+		b.WriteString(",")
+		describe(b, t.Elem(), seen)
+		b.WriteString(">")
+	case reflect.Struct:
+		b.WriteString(t.String())
+		if seen[t] || t.PkgPath() == "time" {
+			return
+		}
+		seen[t] = true
+		b.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fmt.Fprintf(b, "%s %q ", f.Name, f.Tag)
+			describe(b, f.Type, seen)
+			b.WriteString(",")
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(t.String())
+	}
+}
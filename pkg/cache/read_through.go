@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Policy controls how ReadThrough caches one type of value
+type Policy struct {
+	TTL time.Duration
+	// Jitter randomizes each TTL by up to ±Jitter (a fraction, e.g. 0.1) so entries
+	// written together do not all expire together
+	Jitter float64
+	// NotFound is the loader error cached as a tombstone (see SetMissing) and returned
+	// while the tombstone is live; nil disables negative caching
+	NotFound error
+}
+
+// ttl returns TTL with jitter applied
+func (p Policy) ttl() time.Duration {
+	if p.Jitter <= 0 || p.TTL <= 0 {
+		return p.TTL
+	}
+	spread := float64(p.TTL) * p.Jitter
+	return p.TTL + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// ReadThrough returns the value cached at key, calling load and caching its result on
+// a miss. Cache errors are treated as misses so Redis outages only cost latency.
+func ReadThrough[T any](ctx context.Context, c *Cache, key string, policy Policy, load func(ctx context.Context) (T, error)) (T, error) {
+	return ReadThroughTagged(ctx, c, key, policy, func(ctx context.Context) (T, []string, error) {
+		value, err := load(ctx)
+		return value, nil, err
+	})
+}
+
+// ReadThroughTagged is ReadThrough for values derived from several entities, such as
+// lists: load also returns the tags to store the value under (see SetTagged)
+func ReadThroughTagged[T any](ctx context.Context, c *Cache, key string, policy Policy, load func(ctx context.Context) (T, []string, error)) (T, error) {
+	var cached T
+	found, err := c.Get(ctx, key, &cached)
+	if found {
+		return cached, nil
+	}
+	if errors.Is(err, ErrMissing) && policy.NotFound != nil {
+		return cached, policy.NotFound
+	}
+
+	value, tags, err := load(ctx)
+	if err != nil {
+		if policy.NotFound != nil && errors.Is(err, policy.NotFound) {
+			c.SetMissing(ctx, key)
+		}
+		return value, err
+	}
+
+	c.SetTagged(ctx, key, value, policy.ttl(), tags...)
+	return value, nil
+}
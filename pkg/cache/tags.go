@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// SetTagged stores value like Set and records key in a Redis set per tag, so
+// InvalidateTags can later drop every entry derived from a changed entity
+func (c *Cache) SetTagged(ctx context.Context, key string, value any, ttl time.Duration, tags ...string) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	full := c.key(key)
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, full, data, ttl)
+	for _, tag := range tags {
+		setKey := c.tagKey(tag)
+		pipe.SAdd(ctx, setKey, full)
+		// A tag set must outlive its longest-lived entry: NX gives a new set a TTL,
+		// GT only ever extends it
+		pipe.ExpireNX(ctx, setKey, ttl)
+		pipe.ExpireGT(ctx, setKey, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTags deletes every entry tagged with any of tags, along with the tag sets
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.Invalidate(ctx, Invalidation{Tags: tags})
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.key("tag:" + tag)
+}
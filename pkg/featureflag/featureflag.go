@@ -0,0 +1,106 @@
+// Package featureflag decides whether a feature is on for a user. A flag rolls out to a
+// percentage of users picked by a hash of the flag name and user ID, so each user stays
+// in the same cohort and raising the percentage only ever adds users.
+package featureflag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rollout is how far one flag is enabled
+type Rollout struct {
+	// Percent of users (0-100) the flag is on for
+	Percent int `json:"percent"`
+	// Users always get the flag, whatever the percentage, e.g. internal testers
+	Users []uint `json:"users,omitempty"`
+}
+
+// Set holds the rollout of every known flag. Unknown flags are off.
+type Set struct {
+	rollouts map[string]Rollout
+}
+
+// New returns a Set for rollouts; a nil map turns every flag off
+func New(rollouts map[string]Rollout) *Set {
+	if rollouts == nil {
+		rollouts = map[string]Rollout{}
+	}
+	return &Set{rollouts: rollouts}
+}
+
+// Enabled reports whether flag name is on for userID. Anonymous callers (userID 0)
+// have no stable cohort, so they only get flags rolled out to 100%.
+func (s *Set) Enabled(name string, userID uint) bool {
+	rollout, ok := s.rollouts[name]
+	if !ok {
+		return false
+	}
+	if rollout.Percent >= 100 {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+	for _, id := range rollout.Users {
+		if id == userID {
+			return true
+		}
+	}
+	return Bucket(name, userID) < rollout.Percent
+}
+
+// EnabledFor returns the flags that are on for userID, in name order
+func (s *Set) EnabledFor(userID uint) []string {
+	enabled := make([]string, 0, len(s.rollouts))
+	for name := range s.rollouts {
+		if s.Enabled(name, userID) {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// Bucket places userID in 0-99 for flag name. Hashing the name too keeps the 5% of
+// one flag from being the same users as the 5% of every other flag.
+func Bucket(name string, userID uint) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + strconv.FormatUint(uint64(userID), 10)))
+	return int(h.Sum32() % 100)
+}
+
+// Parse reads "name:percent[:id|id...]" entries separated by commas, e.g.
+// "related_v2:5,search_cache:25:1|42"
+func Parse(raw string) (map[string]Rollout, error) {
+	rollouts := make(map[string]Rollout)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid flag %q, want name:percent[:id|id...]", entry)
+		}
+		percent, err := strconv.Atoi(parts[1])
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("flag %q: percent must be an integer between 0 and 100", parts[0])
+		}
+		rollout := Rollout{Percent: percent}
+		if len(parts) == 3 {
+			for _, raw := range strings.Split(parts[2], "|") {
+				id, err := strconv.ParseUint(raw, 10, 64)
+				if err != nil || id == 0 {
+					return nil, fmt.Errorf("flag %q: invalid user ID %q", parts[0], raw)
+				}
+				rollout.Users = append(rollout.Users, uint(id))
+			}
+		}
+		rollouts[parts[0]] = rollout
+	}
+	return rollouts, nil
+}
@@ -0,0 +1,191 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"goapi/pkg/reqctx"
+)
+
+// ErrCircuitOpen is returned without calling the remote host while the breaker is open
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker open")
+
+// Config tunes timeouts, retries and circuit breaking of a Client
+type Config struct {
+	// Name identifies the integration in errors and logs (e.g. "stripe")
+	Name    string
+	Timeout time.Duration
+	// MaxRetries is the number of extra attempts for retryable failures
+	MaxRetries int
+	// RetryBackoff is the base delay, doubled per attempt with jitter
+	RetryBackoff time.Duration
+	// BreakerThreshold consecutive failures open the circuit for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultConfig returns conservative settings for an external integration
+func DefaultConfig(name string) Config {
+	return Config{
+		Name:             name,
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// Client wraps http.Client with retries, a circuit breaker and propagation of
+// X-Request-ID and trace headers from the request context
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+}
+
+func New(cfg Config) *Client {
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: cfg.Timeout},
+		breaker: &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown},
+	}
+}
+
+// Do sends the request. Network errors, 429 and 5xx responses are retried for idempotent
+// methods and for requests carrying an Idempotency-Key header; other requests run once.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	reqctx.Inject(ctx, req.Header)
+
+	attempts := 1
+	if retryable(req) {
+		attempts += c.cfg.MaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt); err != nil {
+				return nil, err
+			}
+			if req.Body != nil {
+				if req.GetBody == nil {
+					return nil, lastErr
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		if !c.breaker.allow() {
+			return nil, fmt.Errorf("%s: %w", c.cfg.Name, ErrCircuitOpen)
+		}
+
+		resp, err := c.http.Do(req)
+		if err == nil && !failed(resp.StatusCode) {
+			c.breaker.success()
+			return resp, nil
+		}
+		c.breaker.failure()
+
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", c.cfg.Name, err)
+			if ctx.Err() != nil {
+				return nil, lastErr
+			}
+			continue
+		}
+
+		// Hand the last failed response to the caller so it can read the error body
+		if attempt == attempts-1 {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		lastErr = fmt.Errorf("%s: status %d", c.cfg.Name, resp.StatusCode)
+	}
+	return nil, lastErr
+}
+
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	backoff := c.cfg.RetryBackoff << (attempt - 1)
+	if backoff > 0 {
+		backoff += time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func failed(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// breaker is a consecutive-failure circuit breaker. After cooldown it lets a single
+// trial request through (half-open); its outcome closes or re-opens the circuit.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures, b.probing = 0, false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goapi/pkg/reqctx"
+)
+
+// newServer answers with statuses in order, repeating the last one, and counts hits
+func newServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func testConfig() Config {
+	return Config{Name: "test", Timeout: time.Second, MaxRetries: 2, RetryBackoff: time.Millisecond}
+}
+
+func send(t *testing.T, client *Client, req *http.Request) (int, error) {
+	t.Helper()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		statuses   []int
+		wantStatus int
+		wantHits   int32
+	}{
+		{"5xx retried until success", http.MethodGet, "", []int{503, 502, 200}, 200, 3},
+		{"429 retried", http.MethodGet, "", []int{429, 200}, 200, 2},
+		{"last failure handed back", http.MethodGet, "", []int{500}, 500, 3},
+		{"4xx not retried", http.MethodGet, "", []int{404}, 404, 1},
+		{"POST not retried", http.MethodPost, "", []int{503, 200}, 503, 1},
+		{"PATCH not retried", http.MethodPatch, "", []int{503, 200}, 503, 1},
+		{"POST with Idempotency-Key retried", http.MethodPost, "key-1", []int{503, 200}, 200, 2},
+		{"PUT retried", http.MethodPut, "", []int{503, 200}, 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits := newServer(t, tt.statuses...)
+			req, _ := http.NewRequest(tt.method, server.URL, strings.NewReader("{}"))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+
+			status, err := send(t, New(testConfig()), req)
+			if err != nil || status != tt.wantStatus || hits.Load() != tt.wantHits {
+				t.Fatalf("got %d %v after %d hits, want %d after %d", status, err, hits.Load(), tt.wantStatus, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server, hits := newServer(t, 500, 500, 500, 200)
+	cfg := testConfig()
+	cfg.MaxRetries, cfg.BreakerThreshold, cfg.BreakerCooldown = 0, 2, 50*time.Millisecond
+	client := New(cfg)
+	get := func() (int, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		return send(t, client, req)
+	}
+
+	// Two consecutive failures open the circuit
+	for range 2 {
+		if status, err := get(); err != nil || status != 500 {
+			t.Fatalf("got %d %v, want 500", status, err)
+		}
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) || hits.Load() != 2 {
+		t.Fatalf("open circuit: got %v after %d hits, want ErrCircuitOpen without a call", err, hits.Load())
+	}
+
+	// A failed probe after the cooldown re-opens it
+	time.Sleep(cfg.BreakerCooldown)
+	if status, err := get(); err != nil || status != 500 {
+		t.Fatalf("probe: got %d %v, want 500", status, err)
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) || hits.Load() != 3 {
+		t.Fatalf("after failed probe: got %v after %d hits, want ErrCircuitOpen", err, hits.Load())
+	}
+
+	// A successful probe closes it
+	time.Sleep(cfg.BreakerCooldown)
+	for range 2 {
+		if status, err := get(); err != nil || status != 200 {
+			t.Fatalf("closed circuit: got %d %v, want 200", status, err)
+		}
+	}
+	if hits.Load() != 5 {
+		t.Fatalf("got %d hits, want 5", hits.Load())
+	}
+}
+
+func TestPropagatesRequestID(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	t.Cleanup(server.Close)
+
+	incoming := http.Header{"Traceparent": {"00-trace-span-01"}}
+	ctx := reqctx.WithTraceHeaders(reqctx.WithRequestID(context.Background(), "req-123"), incoming)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := send(t, New(testConfig()), req); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(reqctx.RequestIDHeader) != "req-123" || got.Get("traceparent") != "00-trace-span-01" {
+		t.Fatalf("got request ID %q and traceparent %q", got.Get(reqctx.RequestIDHeader), got.Get("traceparent"))
+	}
+}
@@ -0,0 +1,134 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// DefaultLanguage is used when a key or language is missing
+const DefaultLanguage = "en"
+
+// Bundle holds translated messages per language, keyed by dotted message IDs
+type Bundle struct {
+	messages map[string]map[string]string
+}
+
+// Load reads the embedded locales/<lang>.json files
+func Load() (*Bundle, error) {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{messages: make(map[string]map[string]string)}
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("i18n: parse %s: %w", entry.Name(), err)
+		}
+		b.messages[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	if _, ok := b.messages[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("i18n: missing default language %q", DefaultLanguage)
+	}
+	return b, nil
+}
+
+// Languages lists the available languages
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.messages))
+	for lang := range b.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T translates key into lang, formatting args with fmt verbs.
+// Missing translations fall back to the default language, then to the key itself.
+func (b *Bundle) T(lang, key string, args ...any) string {
+	msg, ok := b.messages[lang][key]
+	if !ok {
+		if msg, ok = b.messages[DefaultLanguage][key]; !ok {
+			return key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup returns the message for key in lang only, without falling back to another
+// language, so callers can keep their own default
+func (b *Bundle) Lookup(lang, key string) (string, bool) {
+	msg, ok := b.messages[lang][key]
+	return msg, ok
+}
+
+// Match picks the best available language for an Accept-Language header value
+// (e.g. "id-ID,id;q=0.9,en;q=0.8"), falling back to DefaultLanguage
+func (b *Bundle) Match(acceptLanguage string) string {
+	for _, lang := range Preferences(acceptLanguage) {
+		if _, ok := b.messages[lang]; ok {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// Base returns the lowercase primary language of a tag: "pt-BR" -> "pt"
+func Base(tag string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	return base
+}
+
+// Preferences lists the base languages of an Accept-Language header value, most
+// preferred first. Entries with q=0 and the "*" wildcard are dropped.
+func Preferences(acceptLanguage string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var prefs []weighted
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang := Base(tag)
+		if lang == "" || lang == "*" || seen[lang] {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		seen[lang] = true
+		prefs = append(prefs, weighted{lang: lang, q: q})
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	langs := make([]string, len(prefs))
+	for i, p := range prefs {
+		langs[i] = p.lang
+	}
+	return langs
+}
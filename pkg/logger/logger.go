@@ -4,6 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+
+	"goapi/pkg/buildinfo"
+	"goapi/pkg/reqctx"
 )
 
 var Log *slog.Logger
@@ -19,23 +22,38 @@ func Init() {
 
 	// Use JSON Handler for structured logging
 	handler := slog.NewJSONHandler(os.Stdout, opts)
-	Log = slog.New(handler)
+	Log = slog.New(handler).With(slog.String("version", buildinfo.Get().Version))
 
 	// Set as default logger
 	slog.SetDefault(Log)
 }
 
-// WithContext returns a logger with context attributes (like RequestID)
-func WithContext(ctx context.Context) *slog.Logger {
-	// Example: Extract RequestID from context if available
-	// Assuming middleware sets "RequestID" in context
-	reqID, ok := ctx.Value("RequestID").(string)
-	if ok {
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, so everything logged through
+// FromContext(ctx) downstream includes l's attributes
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request-scoped logger stored in ctx. The RequestID middleware
+// stores one with the request ID and route, and authentication adds the caller. Contexts
+// without one (startup, jobs) get the base logger, tagged with the request ID if ctx has one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	if reqID := reqctx.RequestID(ctx); reqID != "" {
 		return Log.With(slog.String("request_id", reqID))
 	}
 	return Log
 }
 
+// With returns a copy of ctx whose logger also carries args, e.g. the user ID once known
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
 // Info logs at Info level using standard logger
 func Info(msg string, args ...any) {
 	Log.Info(msg, args...)
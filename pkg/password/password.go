@@ -0,0 +1,132 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported hash algorithms
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var errMalformedHash = errors.New("password: malformed hash")
+
+// Argon2Params are the argon2id cost parameters (RFC 9106)
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Options selects the algorithm used for new hashes
+type Options struct {
+	Algorithm string
+	Argon2    Argon2Params
+	// BcryptCost is the bcrypt work factor; 0 means bcrypt.DefaultCost
+	BcryptCost int
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies hashes
+// produced by any supported one
+type Hasher struct {
+	opts Options
+}
+
+func NewHasher(opts Options) *Hasher {
+	return &Hasher{opts: opts}
+}
+
+// Hash returns an encoded hash of password that includes the algorithm and parameters
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.opts.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost())
+		return string(hash), err
+	}
+
+	p := h.opts.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches hash. needsRehash is true when the match
+// used another algorithm or weaker parameters than the configured ones, so the caller
+// can store a fresh Hash while it has the plaintext.
+func (h *Hasher) Verify(password, hash string) (ok, needsRehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false
+		}
+		return true, h.opts.Algorithm != Argon2id || params.weakerThan(h.opts.Argon2)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, false
+	}
+	if h.opts.Algorithm != Bcrypt {
+		return true, true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost < h.bcryptCost()
+}
+
+func (h *Hasher) bcryptCost() int {
+	if h.opts.BcryptCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return h.opts.BcryptCost
+}
+
+func (p Argon2Params) weakerThan(target Argon2Params) bool {
+	return p.Memory < target.Memory || p.Iterations < target.Iterations || p.Parallelism < target.Parallelism
+}
+
+// decodeArgon2id parses "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>"
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+
+	b64 := base64.RawStdEncoding
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast; only their relative strength matters here
+var (
+	weakArgon2   = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strongArgon2 = Argon2Params{Memory: 2048, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+)
+
+func mustHash(t *testing.T, h *Hasher, password string) string {
+	t.Helper()
+	hash, err := h.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashFormats(t *testing.T) {
+	argon := mustHash(t, NewHasher(Options{Algorithm: Argon2id, Argon2: weakArgon2}), "secret123")
+	if !strings.HasPrefix(argon, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("argon2id hash = %q, want the PHC format with its parameters", argon)
+	}
+	if again := mustHash(t, NewHasher(Options{Algorithm: Argon2id, Argon2: weakArgon2}), "secret123"); again == argon {
+		t.Error("two hashes of the same password share a salt")
+	}
+
+	bc := mustHash(t, NewHasher(Options{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}), "secret123")
+	if cost, err := bcrypt.Cost([]byte(bc)); err != nil || cost != bcrypt.MinCost {
+		t.Errorf("bcrypt cost = %d, %v; want %d", cost, err, bcrypt.MinCost)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	weakArgonHasher := NewHasher(Options{Algorithm: Argon2id, Argon2: weakArgon2})
+	strongArgonHasher := NewHasher(Options{Algorithm: Argon2id, Argon2: strongArgon2})
+	weakBcryptHasher := NewHasher(Options{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost})
+	strongBcryptHasher := NewHasher(Options{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1})
+
+	argonHash := mustHash(t, weakArgonHasher, "secret123")
+	bcryptHash := mustHash(t, weakBcryptHasher, "secret123")
+
+	tests := []struct {
+		name           string
+		hasher         *Hasher
+		password, hash string
+		wantOK, rehash bool
+	}{
+		{"argon2id match", weakArgonHasher, "secret123", argonHash, true, false},
+		{"argon2id wrong password", weakArgonHasher, "secret124", argonHash, false, false},
+		{"argon2id parameters raised", strongArgonHasher, "secret123", argonHash, true, true},
+		{"argon2id parameters lowered", NewHasher(Options{Algorithm: Argon2id, Argon2: Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1}}), "secret123", argonHash, true, false},
+		{"argon2id under bcrypt config", weakBcryptHasher, "secret123", argonHash, true, true},
+		{"bcrypt match", weakBcryptHasher, "secret123", bcryptHash, true, false},
+		{"bcrypt wrong password", weakBcryptHasher, "wrong", bcryptHash, false, false},
+		{"bcrypt cost raised", strongBcryptHasher, "secret123", bcryptHash, true, true},
+		{"bcrypt fallback under argon2id config", weakArgonHasher, "secret123", bcryptHash, true, true},
+		{"bcrypt fallback wrong password", weakArgonHasher, "wrong", bcryptHash, false, false},
+		{"empty hash", weakArgonHasher, "secret123", "", false, false},
+		{"plaintext stored as hash", weakArgonHasher, "secret123", "secret123", false, false},
+		{"argon2id missing key", weakArgonHasher, "secret123", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ", false, false},
+		{"argon2id empty key", weakArgonHasher, "secret123", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$", false, false},
+		{"argon2id wrong version", weakArgonHasher, "secret123", strings.Replace(argonHash, "v=19", "v=16", 1), false, false},
+		{"argon2id bad parameters", weakArgonHasher, "secret123", strings.Replace(argonHash, "m=1024", "m=lots", 1), false, false},
+		{"argon2id bad salt encoding", weakArgonHasher, "secret123", "$argon2id$v=19$m=1024,t=1,p=1$!!!$c2FsdHNhbHQ", false, false},
+		{"argon2id tampered parameters", weakArgonHasher, "secret123", strings.Replace(argonHash, "t=1", "t=2", 1), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := tt.hasher.Verify(tt.password, tt.hash)
+			if ok != tt.wantOK || rehash != tt.rehash {
+				t.Fatalf("Verify = %v, %v; want %v, %v", ok, rehash, tt.wantOK, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestRehashUpgradesParameters(t *testing.T) {
+	old := mustHash(t, NewHasher(Options{Algorithm: Argon2id, Argon2: weakArgon2}), "secret123")
+	current := NewHasher(Options{Algorithm: Argon2id, Argon2: strongArgon2})
+
+	ok, rehash := current.Verify("secret123", old)
+	if !ok || !rehash {
+		t.Fatalf("Verify = %v, %v; want a match that needs a rehash", ok, rehash)
+	}
+	upgraded := mustHash(t, current, "secret123")
+	if ok, rehash := current.Verify("secret123", upgraded); !ok || rehash {
+		t.Fatalf("Verify after rehash = %v, %v; want a match at the current parameters", ok, rehash)
+	}
+}
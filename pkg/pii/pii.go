@@ -0,0 +1,108 @@
+// Package pii encrypts personal data at rest and derives blind indexes so encrypted
+// values can still be looked up by equality.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// Encrypted values are stored as prefix + base64(nonce || ciphertext). There is one key
+// and no keyring: changing PII_ENCRYPTION_KEY makes existing values unreadable.
+const prefix = "enc:v1:"
+
+var (
+	ErrNotConfigured = errors.New("pii: keys not configured")
+	errCiphertext    = errors.New("pii: malformed ciphertext")
+)
+
+type keys struct {
+	aead  cipher.AEAD
+	index []byte
+}
+
+var current atomic.Pointer[keys]
+
+// Init installs the AES-256 encryption key and the HMAC key for blind indexes. Both
+// must be 32 bytes and must never change once data has been written with them.
+func Init(encryptionKey, indexKey []byte) error {
+	if len(encryptionKey) != 32 || len(indexKey) != 32 {
+		return errors.New("pii: encryption and index keys must be 32 bytes")
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	current.Store(&keys{aead: aead, index: indexKey})
+	return nil
+}
+
+// Encrypt seals plaintext with a random nonce; empty strings stay empty
+func Encrypt(plaintext string) (string, error) {
+	k := current.Load()
+	if k == nil {
+		return "", ErrNotConfigured
+	}
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without the prefix are returned
+// unchanged: they are plaintext written before encryption was enabled.
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	k := current.Load()
+	if k == nil {
+		return "", ErrNotConfigured
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return "", errCiphertext
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups. Callers normalize
+// value first (e.g. models.NormalizeEmail) so equivalent inputs match.
+func BlindIndex(value string) (string, error) {
+	k := current.Load()
+	if k == nil {
+		return "", ErrNotConfigured
+	}
+	mac := hmac.New(sha256.New, k.index)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,120 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func initKeys(t *testing.T, encryption, index byte) {
+	t.Helper()
+	if err := Init(bytes.Repeat([]byte{encryption}, 32), bytes.Repeat([]byte{index}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { current.Store(nil) })
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	initKeys(t, 1, 2)
+
+	for _, plaintext := range []string{"jane@example.com", "ünïcödé@例え.jp", ""} {
+		sealed, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext != "" && (!IsEncrypted(sealed) || strings.Contains(sealed, plaintext)) {
+			t.Fatalf("Encrypt(%q) = %q, want an opaque prefixed value", plaintext, sealed)
+		}
+		got, err := Decrypt(sealed)
+		if err != nil || got != plaintext {
+			t.Fatalf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+
+	// A random nonce makes equal plaintexts encrypt differently
+	a, _ := Encrypt("jane@example.com")
+	b, _ := Encrypt("jane@example.com")
+	if a == b {
+		t.Fatal("two encryptions of the same value are identical")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	initKeys(t, 1, 2)
+	sealed, err := Encrypt("jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, prefix))
+	flipped := bytes.Clone(raw)
+	flipped[len(flipped)-1] ^= 1
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"flipped ciphertext bit", prefix + base64.StdEncoding.EncodeToString(flipped)},
+		{"truncated", prefix + base64.StdEncoding.EncodeToString(raw[:8])},
+		{"not base64", prefix + "%%%"},
+		{"empty payload", prefix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decrypt(tt.value); !errors.Is(err, errCiphertext) {
+				t.Fatalf("got %q, %v; want errCiphertext", got, err)
+			}
+		})
+	}
+
+	// Another key cannot open it either
+	initKeys(t, 3, 2)
+	if _, err := Decrypt(sealed); !errors.Is(err, errCiphertext) {
+		t.Fatalf("decrypt with another key: got %v, want errCiphertext", err)
+	}
+}
+
+func TestDecryptPassesPlaintextThrough(t *testing.T) {
+	// Rows written before encryption was enabled hold plaintext, readable without keys
+	for _, value := range []string{"jane@example.com", "", "enc:v2:not-ours"} {
+		if got, err := Decrypt(value); err != nil || got != value {
+			t.Fatalf("Decrypt(%q) = %q, %v; want it unchanged", value, got, err)
+		}
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	current.Store(nil)
+	if _, err := Encrypt("jane@example.com"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Encrypt: got %v, want ErrNotConfigured", err)
+	}
+	if _, err := Decrypt(prefix + "AAAA"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Decrypt: got %v, want ErrNotConfigured", err)
+	}
+	if _, err := BlindIndex("jane@example.com"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("BlindIndex: got %v, want ErrNotConfigured", err)
+	}
+	if err := Init(make([]byte, 16), make([]byte, 32)); err == nil {
+		t.Error("Init accepted a 16-byte encryption key")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	initKeys(t, 1, 2)
+	a, err := BlindIndex("jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := BlindIndex("jane@example.com"); a != b || len(a) != 64 {
+		t.Fatalf("got %q and %q, want the same 64-character hex digest", a, b)
+	}
+	if other, _ := BlindIndex("john@example.com"); other == a {
+		t.Fatal("different values share an index")
+	}
+
+	initKeys(t, 1, 9)
+	if rekeyed, _ := BlindIndex("jane@example.com"); rekeyed == a {
+		t.Fatal("the index does not depend on the key")
+	}
+}
@@ -0,0 +1,58 @@
+package reqctx
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID between services
+const RequestIDHeader = "X-Request-ID"
+
+// TraceHeaders are the W3C trace context headers forwarded to downstream calls
+var TraceHeaders = []string{"traceparent", "tracestate", "baggage"}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceHeadersKey
+)
+
+// WithRequestID stores the request ID in ctx
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or "" when there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithTraceHeaders stores the trace headers present in incoming headers
+func WithTraceHeaders(ctx context.Context, incoming http.Header) context.Context {
+	trace := http.Header{}
+	for _, name := range TraceHeaders {
+		if value := incoming.Get(name); value != "" {
+			trace.Set(name, value)
+		}
+	}
+	if len(trace) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceHeadersKey, trace)
+}
+
+// Inject copies the request ID and trace headers from ctx onto outgoing headers
+func Inject(ctx context.Context, outgoing http.Header) {
+	if requestID := RequestID(ctx); requestID != "" && outgoing.Get(RequestIDHeader) == "" {
+		outgoing.Set(RequestIDHeader, requestID)
+	}
+	if trace, ok := ctx.Value(traceHeadersKey).(http.Header); ok {
+		for name, values := range trace {
+			if outgoing.Get(name) == "" {
+				outgoing[name] = values
+			}
+		}
+	}
+}
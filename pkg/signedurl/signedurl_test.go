@@ -0,0 +1,60 @@
+package signedurl
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signer := New("secret")
+	now := time.Unix(1_700_000_000, 0)
+	query := signer.Query(http.MethodGet, "/api/v1/posts/1", now.Add(time.Hour))
+	sig := query.Get(SigParam)
+
+	with := func(key, value string) url.Values {
+		changed := url.Values{ExpParam: {query.Get(ExpParam)}, SigParam: {sig}}
+		changed.Set(key, value)
+		return changed
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		query  url.Values
+		now    time.Time
+		want   error
+	}{
+		{"valid", http.MethodGet, "/api/v1/posts/1", query, now, nil},
+		{"different path", http.MethodGet, "/api/v1/posts/2", query, now, ErrInvalid},
+		{"path prefix", http.MethodGet, "/api/v1/posts/1/translations", query, now, ErrInvalid},
+		{"different method", http.MethodDelete, "/api/v1/posts/1", query, now, ErrInvalid},
+		{"expired", http.MethodGet, "/api/v1/posts/1", query, now.Add(time.Hour), ErrExpired},
+		{"extended exp", http.MethodGet, "/api/v1/posts/1", with(ExpParam, strconv.FormatInt(now.Add(48*time.Hour).Unix(), 10)), now, ErrInvalid},
+		{"forged sig", http.MethodGet, "/api/v1/posts/1", with(SigParam, New("other").Query(http.MethodGet, "/api/v1/posts/1", now.Add(time.Hour)).Get(SigParam)), now, ErrInvalid},
+		{"truncated sig", http.MethodGet, "/api/v1/posts/1", with(SigParam, sig[:len(sig)-4]), now, ErrInvalid},
+		{"sig not base64", http.MethodGet, "/api/v1/posts/1", with(SigParam, "!!!"), now, ErrInvalid},
+		{"missing sig", http.MethodGet, "/api/v1/posts/1", url.Values{ExpParam: {query.Get(ExpParam)}}, now, ErrInvalid},
+		{"missing exp", http.MethodGet, "/api/v1/posts/1", url.Values{SigParam: {sig}}, now, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := signer.Verify(tt.method, tt.path, tt.query, tt.now); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryNotRevealedForForgedLinks(t *testing.T) {
+	signer := New("secret")
+	now := time.Unix(1_700_000_000, 0)
+	forged := New("other").Query(http.MethodGet, "/api/v1/posts/1", now.Add(-time.Hour))
+	if err := signer.Verify(http.MethodGet, "/api/v1/posts/1", forged, now); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Verify = %v, want ErrInvalid before the expiry is looked at", err)
+	}
+}
@@ -0,0 +1,187 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapi/pkg/httpclient"
+)
+
+const apiBaseURL = "https://api.stripe.com/v1"
+
+// Client is a minimal Stripe REST client covering checkout and webhook verification
+type Client struct {
+	secretKey  string
+	httpClient *httpclient.Client
+}
+
+func NewClient(secretKey string, httpClient *httpclient.Client) *Client {
+	return &Client{
+		secretKey:  secretKey,
+		httpClient: httpClient,
+	}
+}
+
+// CheckoutSessionParams describes a subscription checkout for a single price
+type CheckoutSessionParams struct {
+	PriceID           string
+	CustomerID        string
+	CustomerEmail     string
+	ClientReferenceID string
+	SuccessURL        string
+	CancelURL         string
+	Metadata          map[string]string
+}
+
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Customer          string            `json:"customer"`
+	Subscription      string            `json:"subscription"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// CreateCheckoutSession creates a hosted checkout page for a subscription
+func (c *Client) CreateCheckoutSession(ctx context.Context, params CheckoutSessionParams) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("line_items[0][price]", params.PriceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("client_reference_id", params.ClientReferenceID)
+	if params.CustomerID != "" {
+		form.Set("customer", params.CustomerID)
+	} else if params.CustomerEmail != "" {
+		form.Set("customer_email", params.CustomerEmail)
+	}
+	for key, value := range params.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var session CheckoutSession
+	if err := c.post(ctx, "/checkout/sessions", form, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiBaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("stripe: %s (status %d)", apiErr.Error.Message, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Event is a webhook event; Data.Object is decoded by the caller based on Type
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type Subscription struct {
+	ID               string `json:"id"`
+	Customer         string `json:"customer"`
+	Status           string `json:"status"`
+	CurrentPeriodEnd int64  `json:"current_period_end"`
+	Items            struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PriceID returns the price of the first subscription item
+func (s *Subscription) PriceID() string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	return s.Items.Data[0].Price.ID
+}
+
+type Invoice struct {
+	ID           string `json:"id"`
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+}
+
+var ErrInvalidSignature = errors.New("stripe: invalid webhook signature")
+
+// ConstructEvent verifies the Stripe-Signature header and decodes the event payload
+func ConstructEvent(payload []byte, sigHeader, secret string, tolerance time.Duration) (*Event, error) {
+	var timestamp int64
+	var signatures [][]byte
+	for _, part := range strings.Split(sigHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if time.Since(time.Unix(timestamp, 0)) > tolerance {
+		return nil, errors.New("stripe: webhook timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			var event Event
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return nil, err
+			}
+			return &event, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
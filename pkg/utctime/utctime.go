@@ -0,0 +1,110 @@
+// Package utctime provides a time type that is always UTC: in JSON, where it is written
+// as RFC 3339 truncated to a configurable precision, and in the database, where it is
+// stored and scanned as UTC regardless of the server or session time zone.
+package utctime
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var precision atomic.Int64
+
+func init() {
+	precision.Store(int64(time.Millisecond))
+}
+
+// SetPrecision sets the unit JSON timestamps are truncated to; 0 keeps nanoseconds.
+// Values are stored at full precision either way.
+func SetPrecision(d time.Duration) {
+	precision.Store(int64(d))
+}
+
+// Precision returns the unit JSON timestamps are truncated to
+func Precision() time.Duration {
+	return time.Duration(precision.Load())
+}
+
+// Time is a time.Time normalized to UTC. Its methods are those of time.Time, so
+// comparisons take the embedded value: a.Before(b.Time).
+type Time struct {
+	time.Time
+}
+
+// Now returns the current time in UTC
+func Now() Time {
+	return Time{time.Now().UTC()}
+}
+
+// From converts t to UTC
+func From(t time.Time) Time {
+	return Time{t.UTC()}
+}
+
+// Ptr converts t to UTC and returns a pointer, for nullable fields
+func Ptr(t time.Time) *Time {
+	u := From(t)
+	return &u
+}
+
+// MarshalJSON writes t as an RFC 3339 UTC timestamp ("2006-01-02T15:04:05.123Z")
+func (t Time) MarshalJSON() ([]byte, error) {
+	text, err := t.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON accepts an RFC 3339 timestamp in any offset and converts it to UTC
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var parsed time.Time
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	t.Time = parsed.UTC()
+	return nil
+}
+
+func (t Time) MarshalText() ([]byte, error) {
+	return t.Time.UTC().Truncate(Precision()).AppendFormat(nil, time.RFC3339Nano), nil
+}
+
+func (t *Time) UnmarshalText(data []byte) error {
+	parsed, err := time.Parse(time.RFC3339, string(data))
+	if err != nil {
+		return err
+	}
+	t.Time = parsed.UTC()
+	return nil
+}
+
+func (t Time) String() string {
+	return t.Time.UTC().String()
+}
+
+// Scan implements sql.Scanner, converting database times to UTC
+func (t *Time) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v.UTC()
+	default:
+		return fmt.Errorf("utctime: cannot scan %T", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (t Time) Value() (driver.Value, error) {
+	return t.Time.UTC(), nil
+}
+
+// GormDataType keeps the column a timestamp, so GORM also fills CreatedAt and UpdatedAt
+func (Time) GormDataType() string {
+	return "time"
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AccessTokenCookie holds the JWT in cookie auth mode (httpOnly)
+	AccessTokenCookie = "access_token"
+	// CSRFTokenCookie holds the double-submit CSRF token (readable by JavaScript)
+	CSRFTokenCookie = "csrf_token"
+	// CSRFHeader must echo the CSRF cookie value on mutating requests
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// CookieConfig controls cookie-based token delivery for browser-first frontends
+type CookieConfig struct {
+	Enabled bool
+	Domain  string
+	Secure  bool
+	// MaxAge matches the access token lifetime so the cookie expires with the token
+	MaxAge time.Duration
+}
+
+// SetAuthCookies writes the access token as an httpOnly cookie together with a fresh CSRF token
+func SetAuthCookies(c *gin.Context, cfg CookieConfig, token string, maxAge int) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(AccessTokenCookie, token, maxAge, "/", cfg.Domain, cfg.Secure, true)
+	c.SetCookie(CSRFTokenCookie, hex.EncodeToString(b), maxAge, "/", cfg.Domain, cfg.Secure, false)
+	return nil
+}
+
+// ClearAuthCookies expires both auth cookies
+func ClearAuthCookies(c *gin.Context, cfg CookieConfig) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(AccessTokenCookie, "", -1, "/", cfg.Domain, cfg.Secure, true)
+	c.SetCookie(CSRFTokenCookie, "", -1, "/", cfg.Domain, cfg.Secure, false)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"goapi/internal/models"
 
@@ -18,7 +19,31 @@ const (
 
 // Loaders holds all dataloaders for the application
 type Loaders struct {
-	UserLoader *dataloader.Loader[uint, *models.User]
+	UserLoader  *dataloader.Loader[uint, *models.User]
+	StatsLoader *dataloader.Loader[uint, models.AuthorStats]
+}
+
+// LoaderOptions tunes the request-scoped dataloaders
+type LoaderOptions struct {
+	// Wait is how long a loader collects keys before dispatching a batch. It bounds the
+	// latency added to every load, so keep it small.
+	Wait          time.Duration
+	BatchCapacity int
+	// Cache memoizes results for the rest of the request. Clear keys after mutating
+	// them (see ClearUser) so later loads in the same request are not stale.
+	Cache bool
+}
+
+// loaderOptions converts opts for a loader of K to V
+func loaderOptions[K comparable, V any](opts LoaderOptions) []dataloader.Option[K, V] {
+	options := []dataloader.Option[K, V]{
+		dataloader.WithWait[K, V](opts.Wait),
+		dataloader.WithBatchCapacity[K, V](opts.BatchCapacity),
+	}
+	if !opts.Cache {
+		options = append(options, dataloader.WithCache[K, V](&dataloader.NoCache[K, V]{}))
+	}
+	return options
 }
 
 // GetLoadersFromContext retrieves the Loaders from the context
@@ -33,16 +58,19 @@ func GetLoadersFromContext(ctx context.Context) *Loaders {
 
 // NewLoaders creates a new instance of Loaders with configured dataloaders
 func NewLoaders(
+	opts LoaderOptions,
 	userBatchFn func(ctx context.Context, keys []uint) []*dataloader.Result[*models.User],
+	statsBatchFn func(ctx context.Context, keys []uint) []*dataloader.Result[models.AuthorStats],
 ) *Loaders {
 	// Configure batch function for user loader
-	userLoader := dataloader.NewBatchedLoader(
-		userBatchFn,
-		dataloader.WithBatchCapacity[uint, *models.User](100),
-	)
+	userLoader := dataloader.NewBatchedLoader(userBatchFn, loaderOptions[uint, *models.User](opts)...)
+
+	// Configure batch function for author stats loader
+	statsLoader := dataloader.NewBatchedLoader(statsBatchFn, loaderOptions[uint, models.AuthorStats](opts)...)
 
 	return &Loaders{
-		UserLoader: userLoader,
+		UserLoader:  userLoader,
+		StatsLoader: statsLoader,
 	}
 }
 
@@ -57,13 +85,72 @@ func LoadUser(ctx context.Context, userID uint) (*models.User, error) {
 	return thunk()
 }
 
-// LoadUsers loads multiple users by IDs using the dataloader
+// LoadUsers loads multiple users by IDs using the dataloader. Both results always have
+// one entry per ID, so callers can index them alike.
 func LoadUsers(ctx context.Context, userIDs []uint) ([]*models.User, []error) {
 	loaders := GetLoadersFromContext(ctx)
 	if loaders == nil {
-		return nil, []error{fmt.Errorf("loaders not found in context")}
+		return make([]*models.User, len(userIDs)), missingLoaders(len(userIDs))
 	}
 
 	thunk := loaders.UserLoader.LoadMany(ctx, userIDs)
+	users, errs := thunk()
+	return users, alignErrors(errs, len(userIDs))
+}
+
+// LoadAuthorStats loads the author stats of a single user using the dataloader
+func LoadAuthorStats(ctx context.Context, userID uint) (models.AuthorStats, error) {
+	loaders := GetLoadersFromContext(ctx)
+	if loaders == nil {
+		return models.AuthorStats{}, fmt.Errorf("loaders not found in context")
+	}
+
+	thunk := loaders.StatsLoader.Load(ctx, userID)
 	return thunk()
 }
+
+// LoadManyAuthorStats loads the author stats of multiple users using the dataloader,
+// with one result and one error per ID like LoadUsers
+func LoadManyAuthorStats(ctx context.Context, userIDs []uint) ([]models.AuthorStats, []error) {
+	loaders := GetLoadersFromContext(ctx)
+	if loaders == nil {
+		return make([]models.AuthorStats, len(userIDs)), missingLoaders(len(userIDs))
+	}
+
+	thunk := loaders.StatsLoader.LoadMany(ctx, userIDs)
+	stats, errs := thunk()
+	return stats, alignErrors(errs, len(userIDs))
+}
+
+// alignErrors pads the errors of a LoadMany to n entries: the loader returns nil
+// instead of a slice of nils when every key resolved
+func alignErrors(errs []error, n int) []error {
+	if errs == nil {
+		return make([]error, n)
+	}
+	return errs
+}
+
+// missingLoaders reports n keys that could not be loaded because the request has no loaders
+func missingLoaders(n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = fmt.Errorf("loaders not found in context")
+	}
+	return errs
+}
+
+// ClearUser drops a user from the request's loader cache after it was modified
+func ClearUser(ctx context.Context, userID uint) {
+	if loaders := GetLoadersFromContext(ctx); loaders != nil {
+		loaders.UserLoader.Clear(ctx, userID)
+	}
+}
+
+// ClearAuthorStats drops a user's author stats from the request's loader cache after
+// their posts changed
+func ClearAuthorStats(ctx context.Context, userID uint) {
+	if loaders := GetLoadersFromContext(ctx); loaders != nil {
+		loaders.StatsLoader.Clear(ctx, userID)
+	}
+}
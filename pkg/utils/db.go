@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 )
@@ -13,25 +15,65 @@ const (
 	TxKey dbContextKey = "tx_key"
 )
 
+// ErrQueryWithoutContext is returned by RequireContext for queries that would ignore
+// request cancellation
+var ErrQueryWithoutContext = errors.New("query without context: use utils.GetDBFromContext or WithContext")
+
 // GetDBFromContext returns the transaction from the context if it exists,
-// otherwise it returns the default db passed as argument.
+// otherwise the shard for the context's shard key (see SetShards), falling back
+// to the default db passed as argument. Either way queries run with ctx, so a
+// canceled request aborts them.
 func GetDBFromContext(ctx context.Context, defaultDB *gorm.DB) *gorm.DB {
 	tx, ok := ctx.Value(TxKey).(*gorm.DB)
 	if ok && tx != nil {
-		return tx
+		return tx.WithContext(ctx)
 	}
-	return defaultDB.WithContext(ctx)
+	return shardDB(ctx, defaultDB).WithContext(ctx)
+}
+
+// InTransaction reports whether ctx carries a transaction from RunInTransaction
+func InTransaction(ctx context.Context) bool {
+	tx, ok := ctx.Value(TxKey).(*gorm.DB)
+	return ok && tx != nil
 }
 
 // TransactionFunc is a function that runs within a transaction
 type TransactionFunc func(ctx context.Context) error
 
 // RunInTransaction runs the given function within a database transaction.
-// It handles commit and rollback automatically.
+// It handles commit and rollback automatically. The transaction is opened on the
+// shard for the context's shard key, so it cannot span shards, and is rolled
+// back if ctx is canceled before it commits.
 func RunInTransaction(ctx context.Context, db *gorm.DB, fn TransactionFunc) error {
-	return db.Transaction(func(tx *gorm.DB) error {
+	return shardDB(ctx, db).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Pass the transaction to the context
 		txCtx := context.WithValue(ctx, TxKey, tx)
 		return fn(txCtx)
 	})
 }
+
+// RequireContext makes every query on db fail with ErrQueryWithoutContext unless it
+// was given a context with WithContext. GORM defaults to context.Background, so such
+// queries keep running after the request is gone. Meant for development.
+func RequireContext(db *gorm.DB) error {
+	check := func(tx *gorm.DB) {
+		if ctx := tx.Statement.Context; ctx == context.Background() || ctx == context.TODO() {
+			_ = tx.AddError(fmt.Errorf("%w (table %q)", ErrQueryWithoutContext, tx.Statement.Table))
+		}
+	}
+
+	callbacks := db.Callback()
+	for name, register := range map[string]func(string, func(*gorm.DB)) error{
+		"create": callbacks.Create().Before("*").Register,
+		"query":  callbacks.Query().Before("*").Register,
+		"update": callbacks.Update().Before("*").Register,
+		"delete": callbacks.Delete().Before("*").Register,
+		"row":    callbacks.Row().Before("*").Register,
+		"raw":    callbacks.Raw().Before("*").Register,
+	} {
+		if err := register("goapi:require_context", check); err != nil {
+			return fmt.Errorf("require context on %s: %w", name, err)
+		}
+	}
+	return nil
+}
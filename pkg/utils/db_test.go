@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type contextRow struct {
+	ID uint
+}
+
+func TestRequireContext(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 sslmode=disable"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RequireContext(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []contextRow
+	if err := db.Find(&rows).Error; !errors.Is(err, ErrQueryWithoutContext) {
+		t.Fatalf("query without context: got %v, want ErrQueryWithoutContext", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := GetDBFromContext(ctx, db).Find(&rows).Error; err != nil {
+		t.Fatalf("query with context: %v", err)
+	}
+}
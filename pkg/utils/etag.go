@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetETag exposes a resource version as a strong ETag ("3") for use with If-Match
+func SetETag(c *gin.Context, version uint) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(uint64(version), 10)))
+}
+
+// IfMatchVersion parses the version from an If-Match header set by SetETag.
+// ok is false when the header is missing or not a version ETag.
+func IfMatchVersion(c *gin.Context) (version uint, ok bool) {
+	tag := strings.TrimPrefix(strings.TrimSpace(c.GetHeader("If-Match")), "W/")
+	value, err := strconv.ParseUint(strings.Trim(tag, `"`), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(value), true
+}
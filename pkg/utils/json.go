@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// jsonBufferSize is the initial capacity of a pooled response buffer; a post-list
+	// page of 20 fits without growing
+	jsonBufferSize = 16 << 10
+	// jsonBufferMax buffers that grew past this are dropped instead of pooled, so one
+	// large export does not pin memory for the life of the process
+	jsonBufferMax = 1 << 20
+)
+
+var jsonBuffers = sync.Pool{
+	New: func() any { return bytes.NewBuffer(make([]byte, 0, jsonBufferSize)) },
+}
+
+// writeJSON renders obj like c.JSON, but encodes into a pooled buffer with the
+// encoder selected by build tag (see json_*.go) instead of allocating per response
+func writeJSON(c *gin.Context, status int, obj any) {
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= jsonBufferMax {
+			buf.Reset()
+			jsonBuffers.Put(buf)
+		}
+	}()
+
+	if err := appendJSON(buf, obj); err != nil {
+		_ = c.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", buf.Bytes())
+}
+
+// appendJSON encodes v onto buf without the newline encoders terminate values with
+func appendJSON(buf *bytes.Buffer, v any) error {
+	if err := encodeJSON(buf, v); err != nil {
+		return err
+	}
+	if n := buf.Len(); n > 0 && buf.Bytes()[n-1] == '\n' {
+		buf.Truncate(n - 1)
+	}
+	return nil
+}
+
+// StreamJSON writes a success envelope whose data is a JSON array, encoding records
+// as produce emits them instead of collecting a slice first. Errors before the first
+// record become a normal error response. Later ones leave the array unterminated, so
+// clients see invalid JSON rather than a list that looks complete.
+func StreamJSON(c *gin.Context, message string, produce func(emit func(record any) error) error) {
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= jsonBufferMax {
+			buf.Reset()
+			jsonBuffers.Put(buf)
+		}
+	}()
+
+	extendDeadline := func() {}
+	start := func() error {
+		extendDeadline = streamDeadline(c)
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		buf.WriteString(`{"success":true,"message":`)
+		if err := appendJSON(buf, message); err != nil {
+			return err
+		}
+		buf.WriteString(`,"data":[`)
+		return nil
+	}
+	write := func() error {
+		_, err := c.Writer.Write(buf.Bytes())
+		buf.Reset()
+		c.Writer.Flush()
+		extendDeadline()
+		return err
+	}
+
+	written := 0
+	err := produce(func(record any) error {
+		if written == 0 {
+			if err := start(); err != nil {
+				return err
+			}
+		} else {
+			buf.WriteByte(',')
+		}
+		if err := appendJSON(buf, record); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushEvery == 0 {
+			return write()
+		}
+		return nil
+	})
+
+	if err != nil && written == 0 {
+		buf.Reset()
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to stream response", err.Error())
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		if buf.Len() > 0 {
+			_ = write()
+		}
+		return
+	}
+	if written == 0 {
+		if err := start(); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "Failed to stream response", err.Error())
+			return
+		}
+	}
+	buf.WriteString("]}")
+	_ = write()
+}
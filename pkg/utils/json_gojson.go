@@ -0,0 +1,15 @@
+//go:build go_json
+
+package utils
+
+import (
+	"bytes"
+
+	json "github.com/goccy/go-json"
+)
+
+const JSONEncoder = "go-json"
+
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	return json.NewEncoder(buf).Encode(v)
+}
@@ -0,0 +1,26 @@
+//go:build jsoniter
+
+package utils
+
+import (
+	"bytes"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const JSONEncoder = "jsoniter"
+
+var jsoniterAPI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// encodeJSON encodes with a pooled stream: NewEncoder allocates one per call. The stream
+// gets no writer, because writing through one drops buffer capacity on every Write.
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	stream := jsoniterAPI.BorrowStream(nil)
+	defer jsoniterAPI.ReturnStream(stream)
+	stream.WriteVal(v)
+	if stream.Error != nil {
+		return stream.Error
+	}
+	_, err := buf.Write(stream.Buffer())
+	return err
+}
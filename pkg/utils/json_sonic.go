@@ -0,0 +1,15 @@
+//go:build sonic && avx && (linux || windows || darwin) && amd64
+
+package utils
+
+import (
+	"bytes"
+
+	"github.com/bytedance/sonic"
+)
+
+const JSONEncoder = "sonic"
+
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	return sonic.ConfigStd.NewEncoder(buf).Encode(v)
+}
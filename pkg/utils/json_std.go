@@ -0,0 +1,16 @@
+//go:build !jsoniter && !go_json && !(sonic && avx && (linux || windows || darwin) && amd64)
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONEncoder names the encoder compiled in; the build tags match Gin's, so one
+// -tags flag switches both
+const JSONEncoder = "encoding/json"
+
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	return json.NewEncoder(buf).Encode(v)
+}
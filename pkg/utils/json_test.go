@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchPost mirrors a post list item, so the payload matches a real GET /posts page
+type benchPost struct {
+	ID                 uint      `json:"id"`
+	Title              string    `json:"title"`
+	Excerpt            string    `json:"excerpt"`
+	UserID             uint      `json:"user_id"`
+	Author             benchUser `json:"author"`
+	CreatedAt          time.Time `json:"created_at"`
+	WordCount          int       `json:"word_count"`
+	ReadingTimeMinutes int       `json:"reading_time_minutes"`
+	Locale             string    `json:"locale"`
+	OriginalLocale     string    `json:"original_locale"`
+}
+
+type benchUser struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+func benchPage() []benchPost {
+	posts := make([]benchPost, 20)
+	for i := range posts {
+		posts[i] = benchPost{
+			ID:                 uint(i + 1),
+			Title:              "Understanding request-scoped dataloaders in Go",
+			Excerpt:            strings.Repeat("lorem ipsum dolor sit amet ", 10),
+			UserID:             uint(i%5 + 1),
+			Author:             benchUser{ID: uint(i%5 + 1), Username: "author", AvatarURL: "https://www.gravatar.com/avatar/0123456789abcdef?d=identicon"},
+			CreatedAt:          time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+			WordCount:          540,
+			ReadingTimeMinutes: 3,
+			Locale:             "en",
+			OriginalLocale:     "en",
+		}
+	}
+	return posts
+}
+
+// discardWriter is a ResponseWriter that drops the body, so only encoding is measured
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchContext() (*gin.Context, *discardWriter) {
+	gin.SetMode(gin.TestMode)
+	w := &discardWriter{header: http.Header{}}
+	c, _ := gin.CreateTestContext(w)
+	return c, w
+}
+
+// BenchmarkResponseGinJSON is the path the helpers used before pooling: c.JSON marshals
+// into a fresh slice per response
+func BenchmarkResponseGinJSON(b *testing.B) {
+	page := benchPage()
+	c, w := benchContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clear(w.header)
+		c.JSON(http.StatusOK, Response{Success: true, Message: "Posts retrieved successfully", Data: page})
+	}
+}
+
+// BenchmarkResponsePooled is SuccessResponse, encoding into a pooled buffer
+func BenchmarkResponsePooled(b *testing.B) {
+	page := benchPage()
+	c, w := benchContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clear(w.header)
+		SuccessResponse(c, http.StatusOK, "Posts retrieved successfully", page)
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// streamFlushEvery records are buffered before a chunk is sent to the client
+	streamFlushEvery = 100
+	// streamWriteWindow replaces the server write timeout for streams: each flush
+	// extends the deadline, so long exports survive but stalled clients are dropped
+	streamWriteWindow = 30 * time.Second
+)
+
+// streamDeadline starts the first write window of a stream and returns the function
+// that extends it after each flush
+func streamDeadline(c *gin.Context) func() {
+	rc := http.NewResponseController(c.Writer)
+	extend := func() { _ = rc.SetWriteDeadline(time.Now().Add(streamWriteWindow)) }
+	extend()
+	return extend
+}
+
+// StreamNDJSON writes records as newline-delimited JSON with chunked transfer encoding.
+// produce calls emit once per record; nothing is accumulated in memory.
+// Errors before the first record become a normal error response; later ones end the stream.
+func StreamNDJSON(c *gin.Context, produce func(emit func(record any) error) error) {
+	extendDeadline := streamDeadline(c)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	enc := json.NewEncoder(c.Writer)
+	written := 0
+	err := produce(func(record any) error {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushEvery == 0 {
+			c.Writer.Flush()
+			extendDeadline()
+		}
+		return nil
+	})
+
+	if err != nil && written == 0 {
+		ErrorResponse(c, http.StatusInternalServerError, "Export failed", err.Error())
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+	}
+	if written == 0 {
+		c.Status(http.StatusOK)
+	}
+	c.Writer.Flush()
+}
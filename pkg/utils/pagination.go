@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setLinkHeader emits RFC 8288 (formerly RFC 5988) first/prev/next/last links so
+// generic clients can walk a collection without parsing the response envelope.
+// Links keep the request's other query params and are relative to the host.
+// "last" is omitted when the total was not counted. With a next cursor, "next" pages
+// by ?after= instead of by page number.
+func setLinkHeader(c *gin.Context, meta Meta) {
+	page, limit := meta.Page, meta.Limit
+	if limit <= 0 {
+		return
+	}
+
+	links := []string{pageLink(c, 1, limit, "first")}
+	if page > 1 {
+		prev := page - 1
+		if meta.Total != nil {
+			prev = min(prev, lastPage(*meta.Total, limit))
+		}
+		links = append(links, pageLink(c, prev, limit, "prev"))
+	}
+	if meta.NextCursor != 0 {
+		links = append(links, cursorLink(c, meta.NextCursor, limit, "next"))
+	} else if meta.HasMore {
+		links = append(links, pageLink(c, page+1, limit, "next"))
+	}
+	if meta.Total != nil {
+		links = append(links, pageLink(c, lastPage(*meta.Total, limit), limit, "last"))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+func lastPage(total int64, limit int) int {
+	return max(1, int((total+int64(limit)-1)/int64(limit)))
+}
+
+func pageLink(c *gin.Context, page, limit int, rel string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Del("after")
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
+
+func cursorLink(c *gin.Context, after uint, limit int, rel string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Del("page")
+	query.Set("after", strconv.FormatUint(uint64(after), 10))
+	query.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
@@ -1,13 +1,18 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Response is the envelope of every API response. Message is for people and may be
+// localized; Code is for programs and is the same in every language.
 type Response struct {
 	Success bool        `json:"success"`
+	Code    string      `json:"code,omitempty"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   interface{} `json:"error,omitempty"`
@@ -17,11 +22,15 @@ type Response struct {
 type Meta struct {
 	Page  int `json:"page,omitempty"`
 	Limit int `json:"limit,omitempty"`
-	Total int `json:"total,omitempty"`
+	// Total is nil when the endpoint skipped COUNT(*) (?pagination=has_more)
+	Total   *int64 `json:"total,omitempty"`
+	HasMore bool   `json:"has_more"`
+	// NextCursor is the ?after= value of the next page on keyset-paged lists
+	NextCursor uint `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(c *gin.Context, status int, message string, data interface{}) {
-	c.JSON(status, Response{
+	writeJSON(c, status, Response{
 		Success: true,
 		Message: message,
 		Data:    data,
@@ -43,22 +52,75 @@ func ErrorResponse(c *gin.Context, status int, message string, err interface{})
 		_ = c.Error(e) // Add to Gin errors
 	}
 
-	c.JSON(status, Response{
+	code := errorCode(status, err)
+	if value, ok := c.Get(translatorKey); ok {
+		if localized, ok := value.(MessageTranslator)(code); ok {
+			message = localized
+		}
+	}
+
+	writeJSON(c, status, Response{
 		Success: false,
+		Code:    code,
 		Message: message,
 		Error:   err,
 	})
 }
 
-func PaginatedResponse(c *gin.Context, status int, message string, data interface{}, page, limit, total int) {
-	c.JSON(status, Response{
+// ErrorCoder is implemented by errors that name their own response code
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// statusCodes are the response codes of errors that do not name their own
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnprocessableEntity:   "validation_failed",
+	http.StatusTooManyRequests:       "rate_limited",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// errorCode is err's own code when it has one, otherwise the code for status
+func errorCode(status int, err interface{}) string {
+	if e, ok := err.(error); ok {
+		var coder ErrorCoder
+		if errors.As(e, &coder) {
+			return coder.ErrorCode()
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// MessageTranslator returns the localized message for an error code, reporting false
+// when there is none and the handler's message should be kept
+type MessageTranslator func(code string) (string, bool)
+
+const translatorKey = "message_translator"
+
+// SetMessageTranslator makes ErrorResponse localize messages for the rest of the request
+func SetMessageTranslator(c *gin.Context, translate MessageTranslator) {
+	c.Set(translatorKey, translate)
+}
+
+// PaginatedResponse writes data with a meta block and the matching Link header
+func PaginatedResponse(c *gin.Context, status int, message string, data interface{}, meta Meta) {
+	setLinkHeader(c, meta)
+	writeJSON(c, status, Response{
 		Success: true,
 		Message: message,
 		Data:    data,
-		Meta: &Meta{
-			Page:  page,
-			Limit: limit,
-			Total: total,
-		},
+		Meta:    &meta,
 	})
 }
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type codedError struct{}
+
+func (codedError) Error() string     { return "quota exceeded" }
+func (codedError) ErrorCode() string { return "quota_exceeded" }
+
+func TestErrorResponseCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		status    int
+		err       interface{}
+		translate MessageTranslator
+		wantCode  string
+		wantMsg   string
+	}{
+		{"from status", http.StatusNotFound, "post not found", nil, "not_found", "Post not found"},
+		{"unlisted server error", http.StatusBadGateway, nil, nil, "internal_error", "Post not found"},
+		{"error names its code", http.StatusUnprocessableEntity, fmt.Errorf("create: %w", codedError{}), nil, "quota_exceeded", "Post not found"},
+		{"translated", http.StatusNotFound, errors.New("post not found"), func(code string) (string, bool) { return "Tidak ditemukan: " + code, true }, "not_found", "Tidak ditemukan: not_found"},
+		{"no translation", http.StatusNotFound, nil, func(string) (string, bool) { return "", false }, "not_found", "Post not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			if tt.translate != nil {
+				SetMessageTranslator(c, tt.translate)
+			}
+			ErrorResponse(c, tt.status, "Post not found", tt.err)
+
+			var body Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode || body.Message != tt.wantMsg {
+				t.Fatalf("got code %q message %q, want %q %q", body.Code, body.Message, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
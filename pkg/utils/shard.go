@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// ShardKeyKey is the context key holding the tenant (user) ID that selects a shard
+const ShardKeyKey dbContextKey = "shard_key"
+
+// ShardResolver maps a shard key to the index of one of n shards (0 <= index < n)
+type ShardResolver interface {
+	Shard(key uint, n int) int
+}
+
+// HashResolver spreads keys evenly over the shards by hashing them. Adding a shard
+// moves most keys, so data must be rebalanced before it is used.
+type HashResolver struct{}
+
+func (HashResolver) Shard(key uint, n int) int {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(key))
+	h := fnv.New32a()
+	h.Write(b[:])
+	return int(h.Sum32() % uint32(n))
+}
+
+// LookupResolver places keys listed in Table on the given shard and resolves the
+// rest with Fallback (HashResolver when nil). Use it to pin or migrate single tenants.
+type LookupResolver struct {
+	Table    map[uint]int
+	Fallback ShardResolver
+}
+
+func (r LookupResolver) Shard(key uint, n int) int {
+	if shard, ok := r.Table[key]; ok {
+		return shard
+	}
+	if r.Fallback == nil {
+		return HashResolver{}.Shard(key, n)
+	}
+	return r.Fallback.Shard(key, n)
+}
+
+// Shards is the set of connections data is partitioned across. Shard 0 also serves
+// queries without a shard key, so it should be the primary database.
+type Shards struct {
+	dbs      []*gorm.DB
+	resolver ShardResolver
+}
+
+// NewShards checks that dbs is not empty and that every LookupResolver entry names one of them
+func NewShards(resolver ShardResolver, dbs ...*gorm.DB) (*Shards, error) {
+	if len(dbs) == 0 {
+		return nil, errors.New("shards: at least one database is required")
+	}
+	if lookup, ok := resolver.(LookupResolver); ok {
+		for key, shard := range lookup.Table {
+			if shard < 0 || shard >= len(dbs) {
+				return nil, fmt.Errorf("shards: key %d is mapped to shard %d of %d", key, shard, len(dbs))
+			}
+		}
+	}
+	return &Shards{dbs: dbs, resolver: resolver}, nil
+}
+
+// For returns the connection holding key's data
+func (s *Shards) For(key uint) *gorm.DB {
+	return s.dbs[s.resolver.Shard(key, len(s.dbs))]
+}
+
+// All returns every shard, for queries that must fan out (admin lists, exports, jobs)
+func (s *Shards) All() []*gorm.DB {
+	return s.dbs
+}
+
+var shards atomic.Pointer[Shards]
+
+// SetShards routes queries that carry a shard key (WithShardKey) through s; nil
+// restores the single-database behaviour. Call it once at startup.
+func SetShards(s *Shards) {
+	shards.Store(s)
+}
+
+// WithShardKey marks ctx so repository queries run on the shard holding key's data
+func WithShardKey(ctx context.Context, key uint) context.Context {
+	return context.WithValue(ctx, ShardKeyKey, key)
+}
+
+// ShardKey returns the shard key set by WithShardKey
+func ShardKey(ctx context.Context) (uint, bool) {
+	key, ok := ctx.Value(ShardKeyKey).(uint)
+	return key, ok
+}
+
+// shardDB returns the shard for ctx's shard key, or defaultDB when sharding is off
+// or ctx has no key
+func shardDB(ctx context.Context, defaultDB *gorm.DB) *gorm.DB {
+	s := shards.Load()
+	if s == nil {
+		return defaultDB
+	}
+	key, ok := ShardKey(ctx)
+	if !ok {
+		return defaultDB
+	}
+	return s.For(key)
+}
@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownSchema is returned when validating against a name that was never registered
+var ErrUnknownSchema = errors.New("validation: unknown schema")
+
+// FieldError is one violation. Field is a path into the document: "$" is the root,
+// "$.settings.items[0]" a nested value.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error lists every violation found in a document. It has the same JSON shape as the
+// services' validation errors, so handlers can return it as the error detail.
+type Error struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *Error) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Registry holds compiled schemas by name. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]*Schema
+}
+
+func NewRegistry() *Registry {
+	return &Registry{schemas: make(map[string]*Schema)}
+}
+
+// Register compiles schema and stores it under name, replacing any previous schema
+func (r *Registry) Register(name string, schema []byte) error {
+	compiled, err := Compile(schema)
+	if err != nil {
+		return fmt.Errorf("%w (schema %q)", err, name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[name] = compiled
+	return nil
+}
+
+// MustRegister is Register for schemas fixed at build time; it panics on an invalid schema
+func (r *Registry) MustRegister(name string, schema []byte) {
+	if err := r.Register(name, schema); err != nil {
+		panic(err)
+	}
+}
+
+// Validate checks data against the schema registered under name
+func (r *Registry) Validate(name string, data []byte) error {
+	r.mu.RLock()
+	schema, ok := r.schemas[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownSchema, name)
+	}
+	return schema.Validate(data)
+}
+
+// Default is the registry used by the package-level functions
+var Default = NewRegistry()
+
+// Register adds a schema to the Default registry
+func Register(name string, schema []byte) error {
+	return Default.Register(name, schema)
+}
+
+// MustRegister adds a schema to the Default registry, panicking if it is invalid
+func MustRegister(name string, schema []byte) {
+	Default.MustRegister(name, schema)
+}
+
+// Validate checks data against a schema in the Default registry
+func Validate(name string, data []byte) error {
+	return Default.Validate(name, data)
+}
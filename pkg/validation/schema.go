@@ -0,0 +1,424 @@
+// Package validation checks free-form JSON payloads against registered JSON Schemas.
+// It implements the subset of JSON Schema (2020-12) that settings and configuration
+// blobs need; schemas using any other keyword are rejected when they are compiled,
+// so a schema never silently accepts more than it says.
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	types    []string
+	enum     []any
+	constant *any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema // nil allows any extra property
+	noAdditional         bool    // additionalProperties: false
+	minProperties        *int
+	maxProperties        *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+}
+
+// annotations carry no validation meaning and are accepted and ignored
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+var formats = map[string]func(string) bool{
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+var jsonTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Compile parses a JSON Schema document
+func Compile(data []byte) (*Schema, error) {
+	return compile(data, "#")
+}
+
+func compile(data []byte, at string) (*Schema, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("validation: schema %s: must be an object: %w", at, err)
+	}
+
+	s := &Schema{}
+	// Sorted so the first error reported for a schema is stable
+	keywords := make([]string, 0, len(raw))
+	for keyword := range raw {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		value := raw[keyword]
+		var err error
+		switch keyword {
+		case "type":
+			err = s.compileType(value)
+		case "enum":
+			err = unmarshal(value, &s.enum)
+		case "const":
+			var c any
+			err = unmarshal(value, &c)
+			s.constant = &c
+		case "properties":
+			var props map[string]json.RawMessage
+			if err = json.Unmarshal(value, &props); err == nil {
+				s.properties = make(map[string]*Schema, len(props))
+				for name, prop := range props {
+					if s.properties[name], err = compile(prop, at+"/properties/"+name); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case "required":
+			err = json.Unmarshal(value, &s.required)
+		case "additionalProperties":
+			var allowed bool
+			if json.Unmarshal(value, &allowed) == nil {
+				s.noAdditional = !allowed
+			} else if s.additionalProperties, err = compile(value, at+"/additionalProperties"); err != nil {
+				return nil, err
+			}
+		case "minProperties":
+			err = json.Unmarshal(value, &s.minProperties)
+		case "maxProperties":
+			err = json.Unmarshal(value, &s.maxProperties)
+		case "items":
+			if s.items, err = compile(value, at+"/items"); err != nil {
+				return nil, err
+			}
+		case "minItems":
+			err = json.Unmarshal(value, &s.minItems)
+		case "maxItems":
+			err = json.Unmarshal(value, &s.maxItems)
+		case "uniqueItems":
+			err = json.Unmarshal(value, &s.uniqueItems)
+		case "minLength":
+			err = json.Unmarshal(value, &s.minLength)
+		case "maxLength":
+			err = json.Unmarshal(value, &s.maxLength)
+		case "pattern":
+			var pattern string
+			if err = json.Unmarshal(value, &pattern); err == nil {
+				s.pattern, err = regexp.Compile(pattern)
+			}
+		case "format":
+			if err = json.Unmarshal(value, &s.format); err == nil && formats[s.format] == nil {
+				err = fmt.Errorf("unsupported format %q", s.format)
+			}
+		case "minimum":
+			err = json.Unmarshal(value, &s.minimum)
+		case "maximum":
+			err = json.Unmarshal(value, &s.maximum)
+		case "exclusiveMinimum":
+			err = json.Unmarshal(value, &s.exclusiveMinimum)
+		case "exclusiveMaximum":
+			err = json.Unmarshal(value, &s.exclusiveMaximum)
+		default:
+			if !annotations[keyword] {
+				err = errors.New("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("validation: schema %s: %s: %w", at, keyword, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) compileType(value json.RawMessage) error {
+	var one string
+	if json.Unmarshal(value, &one) == nil {
+		s.types = []string{one}
+	} else if err := json.Unmarshal(value, &s.types); err != nil {
+		return err
+	}
+	for _, t := range s.types {
+		if !jsonTypes[t] {
+			return fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return nil
+}
+
+// unmarshal decodes with json.Number so enum and const compare numbers exactly
+func unmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Validate checks a JSON document against s. It returns an *Error listing every
+// violation, or a plain error when data is not valid JSON.
+func (s *Schema) Validate(data []byte) error {
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("validation: invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return errors.New("validation: invalid JSON: unexpected data after the top-level value")
+	}
+
+	var errs Error
+	s.validate(value, "$", &errs)
+	if len(errs.Fields) > 0 {
+		return &errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(value any, path string, errs *Error) {
+	if len(s.types) > 0 && !s.hasType(value) {
+		errs.add(path, "must be of type "+strings.Join(s.types, " or "))
+		return
+	}
+	if s.constant != nil && !equal(value, *s.constant) {
+		errs.add(path, "must equal "+render(*s.constant))
+	}
+	if s.enum != nil && !s.inEnum(value) {
+		rendered := make([]string, len(s.enum))
+		for i, v := range s.enum {
+			rendered[i] = render(v)
+		}
+		errs.add(path, "must be one of "+strings.Join(rendered, ", "))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		s.validateObject(v, path, errs)
+	case []any:
+		s.validateArray(v, path, errs)
+	case string:
+		s.validateString(v, path, errs)
+	case json.Number:
+		s.validateNumber(v, path, errs)
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string, errs *Error) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			errs.add(path+"."+name, "is required")
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		errs.add(path, fmt.Sprintf("must have at least %d properties", *s.minProperties))
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		errs.add(path, fmt.Sprintf("must have at most %d properties", *s.maxProperties))
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch prop, known := s.properties[name]; {
+		case known:
+			prop.validate(obj[name], path+"."+name, errs)
+		case s.noAdditional:
+			errs.add(path+"."+name, "is not allowed")
+		case s.additionalProperties != nil:
+			s.additionalProperties.validate(obj[name], path+"."+name, errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []any, path string, errs *Error) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		errs.add(path, fmt.Sprintf("must have at least %d items", *s.minItems))
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		errs.add(path, fmt.Sprintf("must have at most %d items", *s.maxItems))
+	}
+	if s.uniqueItems {
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					errs.add(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("duplicates item %d", j))
+					break
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateString(str, path string, errs *Error) {
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		errs.add(path, fmt.Sprintf("must be at least %d characters", *s.minLength))
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		errs.add(path, fmt.Sprintf("must be at most %d characters", *s.maxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		errs.add(path, "must match pattern "+s.pattern.String())
+	}
+	if s.format != "" && !formats[s.format](str) {
+		errs.add(path, "must be a valid "+s.format)
+	}
+}
+
+func (s *Schema) validateNumber(num json.Number, path string, errs *Error) {
+	f, err := num.Float64()
+	if err != nil {
+		errs.add(path, "is out of range")
+		return
+	}
+	if s.minimum != nil && f < *s.minimum {
+		errs.add(path, fmt.Sprintf("must be at least %v", *s.minimum))
+	}
+	if s.maximum != nil && f > *s.maximum {
+		errs.add(path, fmt.Sprintf("must be at most %v", *s.maximum))
+	}
+	if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+		errs.add(path, fmt.Sprintf("must be greater than %v", *s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+		errs.add(path, fmt.Sprintf("must be less than %v", *s.exclusiveMaximum))
+	}
+}
+
+func (s *Schema) hasType(value any) bool {
+	for _, t := range s.types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			// As in JSON Schema, 1.0 is an integer
+			if f, err := v.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Schema) inEnum(value any) bool {
+	for _, allowed := range s.enum {
+		if equal(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares decoded JSON values; numbers compare by value, so 1 equals 1.0
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := a.Float64()
+		fb, errB := b.Float64()
+		return errA == nil && errB == nil && fa == fb
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func render(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		want   []FieldError // nil means valid
+	}{
+		{"type match", `{"type":"string"}`, `"a"`, nil},
+		{"type mismatch", `{"type":"string"}`, `1`, []FieldError{{"$", "must be of type string"}}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"type list mismatch", `{"type":["string","null"]}`, `true`, []FieldError{{"$", "must be of type string or null"}}},
+		{"integer accepts 1.0", `{"type":"integer"}`, `1.0`, nil},
+		{"integer rejects 1.5", `{"type":"integer"}`, `1.5`, []FieldError{{"$", "must be of type integer"}}},
+		{"enum", `{"enum":["a",1]}`, `1.0`, nil},
+		{"enum mismatch", `{"enum":["a",1]}`, `"b"`, []FieldError{{"$", `must be one of "a", 1`}}},
+		{"const", `{"const":{"a":[1]}}`, `{"a":[1]}`, nil},
+		{"const mismatch", `{"const":{"a":[1]}}`, `{"a":[2]}`, []FieldError{{"$", `must equal {"a":[1]}`}}},
+
+		{"required", `{"type":"object","required":["a","b"]}`, `{"a":1}`, []FieldError{{"$.b", "is required"}}},
+		{"nested property", `{"properties":{"a":{"properties":{"b":{"type":"string"}}}}}`, `{"a":{"b":1}}`, []FieldError{{"$.a.b", "must be of type string"}}},
+		{"additionalProperties false", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"z":1,"y":1}`, []FieldError{{"$.y", "is not allowed"}, {"$.z", "is not allowed"}}},
+		{"additionalProperties schema", `{"additionalProperties":{"type":"number"}}`, `{"a":1,"b":"x"}`, []FieldError{{"$.b", "must be of type number"}}},
+		{"minProperties", `{"minProperties":2}`, `{"a":1}`, []FieldError{{"$", "must have at least 2 properties"}}},
+		{"maxProperties", `{"maxProperties":1}`, `{"a":1,"b":2}`, []FieldError{{"$", "must have at most 1 properties"}}},
+
+		{"items", `{"items":{"type":"string"}}`, `["a",1]`, []FieldError{{"$[1]", "must be of type string"}}},
+		{"minItems", `{"minItems":1}`, `[]`, []FieldError{{"$", "must have at least 1 items"}}},
+		{"maxItems", `{"maxItems":1}`, `[1,2]`, []FieldError{{"$", "must have at most 1 items"}}},
+		{"uniqueItems", `{"uniqueItems":true}`, `[1,2,1.0]`, []FieldError{{"$[2]", "duplicates item 0"}}},
+
+		{"minLength counts runes", `{"minLength":2}`, `"é"`, []FieldError{{"$", "must be at least 2 characters"}}},
+		{"maxLength", `{"maxLength":2}`, `"abc"`, []FieldError{{"$", "must be at most 2 characters"}}},
+		{"pattern", `{"pattern":"^[a-z]+$"}`, `"A1"`, []FieldError{{"$", "must match pattern ^[a-z]+$"}}},
+		{"format email", `{"format":"email"}`, `"Bob <bob@example.com>"`, []FieldError{{"$", "must be a valid email"}}},
+		{"format uri", `{"format":"uri"}`, `"/relative"`, []FieldError{{"$", "must be a valid uri"}}},
+		{"format date-time", `{"format":"date-time"}`, `"2024-01-02T03:04:05Z"`, nil},
+		{"format uuid", `{"format":"uuid"}`, `"not-a-uuid"`, []FieldError{{"$", "must be a valid uuid"}}},
+
+		{"minimum", `{"minimum":1}`, `0.5`, []FieldError{{"$", "must be at least 1"}}},
+		{"maximum", `{"maximum":1}`, `2`, []FieldError{{"$", "must be at most 1"}}},
+		{"exclusiveMinimum", `{"exclusiveMinimum":1}`, `1`, []FieldError{{"$", "must be greater than 1"}}},
+		{"exclusiveMaximum", `{"exclusiveMaximum":1}`, `1`, []FieldError{{"$", "must be less than 1"}}},
+
+		{"keywords skip other types", `{"minLength":5,"minimum":5,"minItems":5}`, `true`, nil},
+		{"every violation reported", `{"properties":{"a":{"type":"string"},"b":{"maximum":1}},"required":["c"]}`, `{"a":1,"b":2}`,
+			[]FieldError{{"$.c", "is required"}, {"$.a", "must be of type string"}, {"$.b", "must be at most 1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = schema.Validate([]byte(tt.data))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("got %v, want valid", err)
+				}
+				return
+			}
+			var verr *Error
+			if !errors.As(err, &verr) {
+				t.Fatalf("got %v, want *Error", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tt.want) {
+				t.Fatalf("got %+v, want %+v", verr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorShape(t *testing.T) {
+	err := &Error{Fields: []FieldError{{"$.a", "is required"}, {"$.b[0]", "must be of type string"}}}
+
+	data, _ := json.Marshal(err)
+	if want := `{"fields":[{"field":"$.a","message":"is required"},{"field":"$.b[0]","message":"must be of type string"}]}`; string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+	if want := "validation failed: $.a: is required; $.b[0]: must be of type string"; err.Error() != want {
+		t.Fatalf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"not an object", `[]`, "must be an object"},
+		{"unsupported keyword", `{"oneOf":[]}`, "#: oneOf: unsupported keyword"},
+		{"nested unsupported keyword", `{"properties":{"a":{"$ref":"#"}}}`, "#/properties/a: $ref: unsupported keyword"},
+		{"unknown type", `{"type":"float"}`, `unknown type "float"`},
+		{"unsupported format", `{"format":"ipv4"}`, `unsupported format "ipv4"`},
+		{"bad pattern", `{"pattern":"("}`, "pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]byte(tt.schema)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Compile([]byte(`{"title":"x","description":"y","default":1}`)); err != nil {
+		t.Fatalf("annotations: got %v, want them ignored", err)
+	}
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	schema, _ := Compile([]byte(`{}`))
+	for _, data := range []string{`{`, `1 2`} {
+		var verr *Error
+		if err := schema.Validate([]byte(data)); err == nil || errors.As(err, &verr) {
+			t.Fatalf("%s: got %v, want a plain error", data, err)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Validate("missing", []byte(`{}`)); !errors.Is(err, ErrUnknownSchema) {
+		t.Fatalf("got %v, want ErrUnknownSchema", err)
+	}
+	if err := r.Register("bad", []byte(`{"oneOf":[]}`)); err == nil || !strings.Contains(err.Error(), `schema "bad"`) {
+		t.Fatalf("got %v, want the schema name in the error", err)
+	}
+	r.MustRegister("settings", []byte(`{"type":"object"}`))
+	if err := r.Validate("settings", []byte(`[]`)); err == nil {
+		t.Fatal("got nil, want a validation error")
+	}
+}